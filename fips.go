@@ -0,0 +1,6 @@
+//go:build boringcrypto
+
+package main
+
+// Building with GOEXPERIMENT=boringcrypto restricts all TLS connections to FIPS approved settings.
+import _ "crypto/tls/fipsonly"
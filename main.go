@@ -2,26 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"io/fs"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/exporter"
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/setup"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
 )
 
 var (
 	conf       = flag.String("conf", "config.yaml", "The config path file")
 	addr       = flag.String("metrics-address", ":2112", "The address to listen on for HTTP requests.")
 	kubeconfig = flag.String("kubeconfig", "", "Path to the kubeconfig file to use.")
+	kubeCtx    = flag.String("context", "", "The context of the kubeconfig to use, the current one by default.")
 	tlsConf    = flag.String("metrics-tls-config", "", "The TLS config file for your metrics.")
+	confTmpl   = flag.Bool("conf-template", false, "Render the config file as a template with [[ ]] delimiters before parsing it.")
+	profile    = flag.String("default-profile", "", "The built-in config to use when the config file does not exist, e.g. warnings-to-stdout.")
+	lint       = flag.Bool("lint", false, "Validate the config, report unreachable routes and rules and exit.")
+	exportConf = flag.Bool("export-config", false, "Print the effective config as canonical JSON with the secrets masked and exit.")
+	dashboard  = flag.Bool("grafana-dashboard", false, "Print a Grafana dashboard for the configured receivers and exit.")
+	alerts     = flag.Bool("prometheus-rules", false, "Print Prometheus alerting rules for the configured receivers and exit.")
+	faults     = flag.Bool("fault-injection", false, "Inject the faults configured on the receivers, to rehearse sink outages. Do not use it in production.")
 )
 
 func main() {
@@ -29,10 +44,21 @@ func main() {
 
 	log.Info().Msg("Reading config file " + *conf)
 	configBytes, err := os.ReadFile(*conf)
+	if errors.Is(err, fs.ErrNotExist) && *profile != "" {
+		log.Info().Str("profile", *profile).Msg("Config file does not exist, using the default profile")
+		configBytes, err = setup.GetDefaultProfile(*profile)
+	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot read config file")
 	}
 
+	if *confTmpl {
+		configBytes, err = setup.RenderConfigTemplate(configBytes, setup.NewConfigTemplateData())
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot render config file")
+		}
+	}
+
 	configBytes = []byte(os.ExpandEnv(string(configBytes)))
 
 	cfg, err := setup.ParseConfigFromBytes(configBytes)
@@ -55,8 +81,13 @@ func main() {
 	case "json":
 		// Defaults to JSON already nothing to do
 	case "", "pretty":
+		out := os.Stdout
+		if *exportConf || *dashboard || *alerts {
+			// Stdout is reserved for the generated output
+			out = os.Stderr
+		}
 		log.Logger = log.Logger.Output(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+			Out:        out,
 			NoColor:    false,
 			TimeFormat: time.RFC3339,
 		})
@@ -72,34 +103,360 @@ func main() {
 		log.Fatal().Err(err).Msg("config validation failed")
 	}
 
-	kubecfg, err := kube.GetKubernetesConfig(*kubeconfig)
+	if *lint {
+		findings := cfg.Lint()
+		if len(findings) > 0 {
+			log.Fatal().Int("findings", len(findings)).Msg("config lint failed")
+		}
+		log.Info().Msg("config lint passed")
+		os.Exit(0)
+	}
+
+	if *exportConf {
+		out, err := setup.ExportConfig(&cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot export config")
+		}
+		_, _ = os.Stdout.Write(out)
+		os.Exit(0)
+	}
+
+	if *dashboard || *alerts {
+		generate := setup.GrafanaDashboard
+		if *alerts {
+			generate = setup.PrometheusRules
+		}
+		out, err := generate(&cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot generate the monitoring config")
+		}
+		_, _ = os.Stdout.Write(out)
+		os.Exit(0)
+	}
+
+	if cfg.TLSPolicy != nil {
+		if err := sinks.SetTLSPolicy(cfg.TLSPolicy); err != nil {
+			log.Fatal().Err(err).Msg("cannot apply TLS policy")
+		}
+		log.Info().Str("minVersion", cfg.TLSPolicy.MinVersion).Msg("TLS policy enforced for all sinks")
+	}
+
+	if cfg.Egress != nil {
+		if err := sinks.SetEgressPolicy(cfg.Egress); err != nil {
+			log.Fatal().Err(err).Msg("cannot apply egress policy")
+		}
+		log.Info().Strs("allowedHosts", cfg.Egress.AllowedHosts).Strs("allowedCIDRs", cfg.Egress.AllowedCIDRs).Msg("Egress policy enforced for all sinks")
+	}
+
+	if *faults {
+		sinks.EnableFaultInjection()
+		log.Warn().Msg("Fault injection enabled, the receivers with faults fail on purpose")
+	}
+
+	if cfg.RequestLogging != nil {
+		sinks.SetRequestLogging(cfg.RequestLogging)
+		log.Warn().Bool("bodies", cfg.RequestLogging.Bodies).Msg("Logging outbound sink requests, disable it once done debugging")
+	}
+
+	// The flags take precedence over the config
+	if *kubeconfig == "" {
+		*kubeconfig = cfg.Kubeconfig
+	}
+	if *kubeCtx == "" {
+		*kubeCtx = cfg.KubeContext
+	}
+	kubecfg, err := kube.GetKubernetesConfig(*kubeconfig, *kubeCtx)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot get kubeconfig")
 	}
 	kubecfg.QPS = cfg.KubeQPS
 	kubecfg.Burst = cfg.KubeBurst
+	if cfg.KubeProtobuf {
+		kube.UseProtobuf(kubecfg)
+	}
+
+	if cfg.TemplateLookups != nil {
+		// The template lookups only get objects, they are bounded by the timeout
+		lookupCfg := rest.CopyConfig(kubecfg)
+		lookupCfg.Timeout = time.Duration(cfg.KubeTimeoutSeconds) * time.Second
+		lookups, err := sinks.NewLookups(cfg.TemplateLookups, lookupCfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize template lookups")
+		}
+		sinks.SetLookups(lookups)
+		log.Info().Strs("namespaces", cfg.TemplateLookups.Namespaces).Msg("template lookups enabled")
+	}
 
 	metrics.Init(*addr, *tlsConf)
 	metricsStore := metrics.NewMetricsStore(cfg.MetricsNamePrefix)
+	sinks.SetMetricsStore(metricsStore)
+
+	registry := &exporter.ChannelBasedReceiverRegistry{
+		MetricsStore: metricsStore,
+		Prioritizer:  exporter.NewPrioritizer(cfg.Priorities),
+	}
+	if cfg.Budget != nil {
+		budget, err := exporter.NewBudget(cfg.Budget, metricsStore)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize budget")
+		}
+		registry.Budget = budget
+	}
+	if cfg.Audit != nil {
+		auditor, err := exporter.NewAuditor(cfg.Audit)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize delivery audit")
+		}
+		defer auditor.Close()
+		registry.Auditor = auditor
+	}
+
+	var snapshotter *exporter.Snapshotter
+	if cfg.Snapshot != nil {
+		var client kubernetes.Interface
+		if cfg.Snapshot.ConfigMap != nil {
+			if client, err = kubernetes.NewForConfig(kubecfg); err != nil {
+				log.Fatal().Err(err).Msg("cannot create kubernetes client for the snapshot")
+			}
+		}
+		if snapshotter, err = exporter.NewSnapshotter(cfg.Snapshot, client); err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize snapshot")
+		}
+		registry.OnClose = snapshotter.Collect
+	}
 
-	engine := exporter.NewEngine(&cfg, &exporter.ChannelBasedReceiverRegistry{MetricsStore: metricsStore})
+	engine := exporter.NewEngine(&cfg, registry)
+	if engine.Silencer != nil {
+		http.Handle("/api/v1/silences", engine.Silencer)
+		http.Handle("/api/v1/silences/slack", engine.Silencer)
+		log.Info().Msg("Silences API enabled on the metrics address")
+	}
+	if engine.SlackCommands != nil {
+		http.Handle("/api/v1/slack/commands", engine.SlackCommands)
+		log.Info().Msg("Slack commands enabled on the metrics address")
+	}
 	onEvent := engine.OnEvent
 	if len(cfg.ClusterName) != 0 {
 		onEvent = func(event *kube.EnhancedEvent) {
 			// note that per code this value is not set anywhere on the kubernetes side
 			// https://github.com/kubernetes/apimachinery/blob/v0.22.4/pkg/apis/meta/v1/types.go#L276
-			event.ClusterName = cfg.ClusterName
+			// The events of the other clusters already carry their name
+			if event.ClusterName == "" {
+				event.ClusterName = cfg.ClusterName
+			}
 			engine.OnEvent(event)
 		}
 	}
 
-	w := kube.NewEventWatcher(kubecfg, cfg.Namespace, cfg.MaxEventAgeSeconds, metricsStore, onEvent, cfg.OmitLookup, cfg.CacheSize, cfg.GetWatchKinds(), cfg.WatchReasons)
-
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	if cfg.Ingest != nil {
+		// The events of the agents keep their cluster name and are not sharded
+		ingest, err := exporter.NewIngest(cfg.Ingest, engine.OnEvent)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize ingest")
+		}
+		go func() {
+			if err := ingest.Serve(ctx); err != nil {
+				log.Fatal().Err(err).Msg("ingest server failed")
+			}
+		}()
+		log.Info().Str("address", cfg.Ingest.Address).Int("agents", len(cfg.Ingest.Agents)).Msg("ingest enabled")
+	}
+
+	if cfg.Falco != nil {
+		falco, err := exporter.NewFalco(cfg.Falco, onEvent)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize falco")
+		}
+		go func() {
+			if err := falco.Run(ctx); err != nil {
+				log.Fatal().Err(err).Msg("falco subscription failed")
+			}
+		}()
+		log.Info().Str("address", cfg.Falco.Address).Msg("falco alerts enabled")
+	}
+
+	if len(engine.Factories) > 0 {
+		clientset, err := kubernetes.NewForConfig(kubecfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create kubernetes client for receiver factories")
+		}
+		kube.WatchNamespaces(ctx, clientset, engine)
+		log.Info().Int("factories", len(engine.Factories)).Msg("receiver factories enabled")
+	}
+
+	// The events of the watchers are rate limited, after the other shards' events are filtered out
+	var rateLimiter *exporter.RateLimiter
+	if cfg.RateLimit != nil {
+		rateLimiter, err = exporter.NewRateLimiter(cfg.RateLimit, onEvent, metricsStore)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot initialize rate limit")
+		}
+		onEvent = rateLimiter.OnEvent
+		log.Info().Float32("eventsPerSecond", cfg.RateLimit.EventsPerSecond).Msg("rate limit enabled")
+	}
+
+	if cfg.Sharding.Enabled {
+		clientset, err := kubernetes.NewForConfig(kubecfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create kubernetes client for sharding")
+		}
+		sharder, err := kube.NewSharder(cfg.Sharding, clientset)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create sharder")
+		}
+		if err := sharder.Start(ctx); err != nil {
+			log.Fatal().Err(err).Msg("cannot join the shard group")
+		}
+		log.Info().Msg("sharding by namespace enabled")
+
+		shardedOnEvent := onEvent
+		onEvent = func(event *kube.EnhancedEvent) {
+			if sharder.Owns(event.InvolvedObject.Namespace) {
+				shardedOnEvent(event)
+			}
+		}
+	}
+
+	// The snapshot is restored when the watcher starts, so only the leader restores it
+	restoreSnapshot := func() {
+		if snapshotter == nil {
+			return
+		}
+		snapshot, err := snapshotter.Load(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot load the snapshot")
+			return
+		}
+		if snapshot != nil {
+			receivers := make([]string, 0, len(cfg.Receivers))
+			for _, r := range cfg.Receivers {
+				receivers = append(receivers, r.Name)
+			}
+			snapshot.Restore(registry, receivers)
+		}
+	}
+
+	// configureWatcher enables the features of the config on the watcher of a cluster
+	configureWatcher := func(w *kube.EventWatcher) {
+		w.SetBackfillWindow(cfg.GetBackfillWindow())
+		w.SetObjectSelector(cfg.GetObjectSelector())
+		w.SetMetadataCacheTTL(time.Duration(cfg.CacheTTLSeconds) * time.Second)
+		if cfg.KubeTimeoutSeconds > 0 {
+			w.SetLookupTimeout(time.Duration(cfg.KubeTimeoutSeconds) * time.Second)
+		}
+		w.SetResolveOwners(cfg.ResolveOwners)
+		w.SetPodEnrichment(cfg.EnrichPods)
+		if cfg.EnrichNodes {
+			w.SetNodeEnrichment()
+		}
+		if cfg.EnrichNamespaces {
+			w.SetNamespaceEnrichment()
+		}
+		if cfg.DeletedRecheck != nil {
+			w.SetDeletedRecheck(cfg.DeletedRecheck)
+		}
+		if cfg.Aggregation != nil {
+			w.SetAggregation(cfg.Aggregation)
+		}
+		if cfg.Manifests != nil {
+			w.SetManifests(cfg.Manifests)
+		}
+		if err := w.AddCustomSources(cfg.CustomSources, cfg.Namespace); err != nil {
+			log.Fatal().Err(err).Msg("cannot watch the custom sources")
+		}
+		if cfg.Policies != nil {
+			if err := w.AddPolicySources(cfg.Policies, cfg.Namespace); err != nil {
+				log.Fatal().Err(err).Msg("cannot watch the policy reports")
+			}
+		}
+		if cfg.Trivy != nil {
+			if err := w.AddTrivySources(cfg.Trivy, cfg.Namespace); err != nil {
+				log.Fatal().Err(err).Msg("cannot watch the vulnerability reports")
+			}
+		}
+	}
+
+	w := kube.NewEventWatcher(kubecfg, cfg.Namespace, cfg.Namespaces, cfg.MaxEventAgeSeconds, metricsStore, onEvent, cfg.OmitLookup, cfg.CacheSize, cfg.GetWatchKinds(), cfg.WatchReasons, cfg.GetFieldSelector(), cfg.EventsAPI, cfg.GetResyncPeriod())
+	configureWatcher(w)
+
+	// The other clusters are watched along with the one of the exporter, only their events are enriched
+	watchers := []*kube.EventWatcher{w}
+	// watcherClusters are the names of the clusters of the watchers, empty for the cluster of the exporter
+	watcherClusters := []string{""}
+	for _, cluster := range cfg.Clusters {
+		clusterCfg, err := cluster.RESTConfig()
+		if err != nil {
+			log.Fatal().Err(err).Str("cluster", cluster.Name).Msg("cannot get the kubeconfig of the cluster")
+		}
+		clusterCfg.QPS = cfg.KubeQPS
+		clusterCfg.Burst = cfg.KubeBurst
+		if cfg.KubeProtobuf {
+			kube.UseProtobuf(clusterCfg)
+		}
+		name := cluster.Name
+		clusterOnEvent := func(event *kube.EnhancedEvent) {
+			event.ClusterName = name
+			onEvent(event)
+		}
+		cw := kube.NewEventWatcher(clusterCfg, cfg.Namespace, cfg.Namespaces, cfg.MaxEventAgeSeconds, metricsStore, clusterOnEvent, cfg.OmitLookup, cfg.CacheSize, cfg.GetWatchKinds(), cfg.WatchReasons, cfg.GetFieldSelector(), cfg.EventsAPI, cfg.GetResyncPeriod())
+		configureWatcher(cw)
+		watchers = append(watchers, cw)
+		watcherClusters = append(watcherClusters, name)
+		log.Info().Str("cluster", name).Msg("watching the events of the cluster")
+	}
+	startWatchers := func() {
+		for _, watcher := range watchers {
+			watcher.Start()
+		}
+	}
+
+	newCheckpoint := func(namespace, name string) *kube.Checkpoint {
+		clientset, err := kubernetes.NewForConfig(kubecfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot create kubernetes client for the checkpoint")
+		}
+		return kube.NewCheckpoint(clientset, namespace, name)
+	}
+	// resumeFromCheckpoint makes every watcher process the events since its checkpoint and saves the checkpoints until
+	// the context is done, the returned channel is closed after the last save. The watchers of the other clusters have
+	// their own checkpoints in the same ConfigMap.
+	resumeFromCheckpoint := func(ctx context.Context, checkpoint *kube.Checkpoint, interval time.Duration) <-chan struct{} {
+		var wg sync.WaitGroup
+		for i, watcher := range watchers {
+			watcherCheckpoint := checkpoint
+			if watcherClusters[i] != "" {
+				watcherCheckpoint = checkpoint.ForCluster(watcherClusters[i])
+			}
+			logger := log.With().Str("cluster", watcherClusters[i]).Logger()
+			if last, err := watcherCheckpoint.Load(ctx); err != nil {
+				logger.Error().Err(err).Msg("cannot load the checkpoint")
+			} else if !last.IsZero() {
+				logger.Info().Time("checkpoint", last).Msg("resuming from the checkpoint")
+				watcher.SetCheckpoint(last)
+			} else if err := watcherCheckpoint.Save(ctx, time.Now()); err != nil {
+				// The first start is recorded right away, the next one does not backfill even if no event came meanwhile
+				logger.Error().Err(err).Msg("cannot save the first checkpoint")
+			}
+			wg.Add(1)
+			go func(watcher *kube.EventWatcher) {
+				defer wg.Done()
+				watcherCheckpoint.Run(ctx, watcher, interval)
+			}(watcher)
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		return done
+	}
+
+	var wasLeader bool
+	stopCheckpoint := func() {}
 	if cfg.LeaderElection.Enabled {
-		var wasLeader bool
 		log.Info().Msg("leader election enabled")
 
 		onStoppedLeading := func(ctx context.Context) {
@@ -111,12 +468,22 @@ func main() {
 			}
 		}
 
-		l, err := kube.NewLeaderElector(cfg.LeaderElection.LeaderElectionID, kubecfg,
+		var checkpoint *kube.Checkpoint
+		if cfg.LeaderElection.Checkpoint {
+			checkpoint = newCheckpoint(cfg.LeaderElection.GetNamespace(), cfg.LeaderElection.GetID()+"-checkpoint")
+		}
+
+		l, err := kube.NewLeaderElector(cfg.LeaderElection, kubecfg,
 			// this method gets called when this instance becomes the leader
-			func(_ context.Context) {
+			func(leadingCtx context.Context) {
 				wasLeader = true
 				log.Info().Msg("leader election won")
-				w.Start()
+				if checkpoint != nil {
+					// The previous leader may not have processed the latest events
+					resumeFromCheckpoint(leadingCtx, checkpoint, cfg.LeaderElection.GetCheckpointInterval())
+				}
+				restoreSnapshot()
+				startWatchers()
 			},
 			// this method gets called when the leader election loop is closed
 			// either due to context cancellation or due to losing the leader lease
@@ -140,16 +507,45 @@ func main() {
 		// so that we don't lose events until the next leader is elected. The new leader
 		// will only be elected after leaseDuration seconds.
 		if wasLeader {
-			log.Info().Msgf("waiting leaseDuration seconds before stopping: %s", kube.GetLeaseDuration())
-			time.Sleep(kube.GetLeaseDuration())
+			log.Info().Msgf("waiting leaseDuration seconds before stopping: %s", cfg.LeaderElection.GetLeaseDuration())
+			time.Sleep(cfg.LeaderElection.GetLeaseDuration())
 		}
 	} else {
 		log.Info().Msg("leader election disabled")
-		w.Start()
+		wasLeader = true
+		if cfg.Checkpoint != nil {
+			// The events that occurred while the exporter restarted may be older than maxEventAgeSeconds. The
+			// checkpoint is saved once more after the watchers stopped.
+			checkpoint := newCheckpoint(cfg.Checkpoint.GetNamespace(), cfg.Checkpoint.GetName())
+			checkpointCtx, cancel := context.WithCancel(context.Background())
+			done := resumeFromCheckpoint(checkpointCtx, checkpoint, cfg.Checkpoint.GetInterval())
+			stopCheckpoint = func() {
+				cancel()
+				<-done
+			}
+		}
+		restoreSnapshot()
+		startWatchers()
 		<-ctx.Done()
 	}
 
 	log.Info().Msg("Received signal to exit. Stopping.")
-	w.Stop()
+	for _, watcher := range watchers {
+		watcher.Stop()
+	}
+	stopCheckpoint()
+	if rateLimiter != nil {
+		rateLimiter.Stop()
+	}
 	engine.Stop()
+	if snapshotter != nil && wasLeader {
+		// The signal context is done, the snapshot gets its own deadline
+		saveCtx, cancelSave := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelSave()
+		if err := snapshotter.Save(saveCtx); err != nil {
+			log.Error().Err(err).Msg("cannot save the snapshot")
+		} else {
+			log.Info().Msg("Saved the snapshot")
+		}
+	}
 }
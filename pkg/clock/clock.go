@@ -0,0 +1,66 @@
+// Package clock is the time source of the engine. It is the real time, except in the integration tests of
+// pkg/testing, which control it.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and runs functions after a delay
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a function scheduled by AfterFunc
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+var (
+	mu      sync.RWMutex
+	current Clock = realClock{}
+)
+
+// Set replaces the clock, the returned function restores the previous one
+func Set(c Clock) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := current
+	current = c
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		current = previous
+	}
+}
+
+func get() Clock {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+func Now() time.Time {
+	return get().Now()
+}
+
+func Since(t time.Time) time.Duration {
+	return get().Now().Sub(t)
+}
+
+func AfterFunc(d time.Duration, f func()) Timer {
+	return get().AfterFunc(d, f)
+}
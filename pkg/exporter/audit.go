@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// AuditConfig enables the delivery audit trail. Exactly one destination should be configured.
+type AuditConfig struct {
+	File *AuditFileConfig `yaml:"file"`
+	S3   *AuditS3Config   `yaml:"s3"`
+}
+
+// AuditFileConfig writes the audit records to a file that is only ever appended to
+type AuditFileConfig struct {
+	Path string `yaml:"path"`
+}
+
+// AuditS3Config uploads the audit records in batches, every batch is written to a new object and never overwritten
+type AuditS3Config struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	Prefix string `yaml:"prefix"`
+	// FlushIntervalSeconds is the maximum time records are buffered before being uploaded, defaults to 60
+	FlushIntervalSeconds int `yaml:"flushIntervalSeconds"`
+	// BatchSize is the maximum number of records per object, defaults to 1000
+	BatchSize int `yaml:"batchSize"`
+	// Compression of the objects, one of none, gzip (default), zstd or snappy
+	Compression string `yaml:"compression"`
+}
+
+// AuditRecord describes a single delivery attempt of an event to a receiver
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Exporter   string    `json:"exporter"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Receiver   string    `json:"receiver"`
+	Sink       string    `json:"sink"`
+	EventUID   string    `json:"eventUID"`
+	Namespace  string    `json:"namespace"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Auditor records the delivery attempts made by the receiver registries
+type Auditor interface {
+	Record(rec AuditRecord)
+	Close()
+}
+
+func NewAuditor(cfg *AuditConfig) (Auditor, error) {
+	if cfg.File != nil && cfg.S3 != nil {
+		return nil, errors.New("audit: only one of file or s3 can be configured")
+	}
+	if cfg.File != nil {
+		return newFileAuditor(cfg.File)
+	}
+	if cfg.S3 != nil {
+		return newS3Auditor(cfg.S3)
+	}
+	return nil, errors.New("audit: no destination configured")
+}
+
+// newAuditRecord fills the parts of the record that only depend on the event
+func newAuditRecord(receiver, sink string, ev *kube.EnhancedEvent, started time.Time, err error) AuditRecord {
+	rec := AuditRecord{
+		Time:       started.UTC(),
+		Exporter:   auditIdentity(),
+		Cluster:    ev.ClusterName,
+		Receiver:   receiver,
+		Sink:       sink,
+		EventUID:   string(ev.UID),
+		Namespace:  ev.Namespace,
+		Kind:       ev.InvolvedObject.Kind,
+		Name:       ev.InvolvedObject.Name,
+		Reason:     ev.Reason,
+		Result:     "success",
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		rec.Result = "failure"
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+var (
+	auditIdentityOnce sync.Once
+	auditIdentityName string
+)
+
+// auditIdentity is the name of the exporter instance, the pod name when running in-cluster
+func auditIdentity() string {
+	auditIdentityOnce.Do(func() {
+		auditIdentityName, _ = os.Hostname()
+	})
+	return auditIdentityName
+}
+
+type fileAuditor struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newFileAuditor(cfg *AuditFileConfig) (*fileAuditor, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("audit: file path is required")
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: cannot open file: %w", err)
+	}
+	return &fileAuditor{file: f, encoder: json.NewEncoder(f)}, nil
+}
+
+func (a *fileAuditor) Record(rec AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.encoder.Encode(rec); err != nil {
+		log.Error().Err(err).Msg("Cannot write audit record")
+	}
+}
+
+func (a *fileAuditor) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_ = a.file.Sync()
+	_ = a.file.Close()
+}
+
+type s3Auditor struct {
+	cfg    *AuditS3Config
+	svc    *s3.S3
+	writer *batch.Writer
+}
+
+func newS3Auditor(cfg *AuditS3Config) (*s3Auditor, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("audit: s3 bucket is required")
+	}
+	if cfg.FlushIntervalSeconds == 0 {
+		cfg.FlushIntervalSeconds = 60
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = sinks.CompressionGzip
+	}
+	if err := sinks.ValidateCompression(cfg.Compression); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(cfg.Region)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &s3Auditor{cfg: cfg, svc: s3.New(sess)}
+	a.writer = batch.NewWriter(batch.WriterConfig{
+		BatchSize:  cfg.BatchSize,
+		MaxRetries: 3,
+		Interval:   time.Duration(cfg.FlushIntervalSeconds) * time.Second,
+	}, a.upload)
+	a.writer.Start()
+	return a, nil
+}
+
+func (a *s3Auditor) upload(ctx context.Context, items []interface{}) []bool {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, item := range items {
+		_ = encoder.Encode(item)
+	}
+
+	res := make([]bool, len(items))
+	body, err := sinks.Compress(a.cfg.Compression, buf.Bytes())
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot compress audit records")
+		return res
+	}
+
+	// The key is unique per upload so existing objects are never replaced
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s%s/%s-%d.ndjson%s", a.cfg.Prefix, now.Format("2006/01/02"), auditIdentity(), now.UnixNano(),
+		sinks.CompressionExtension(a.cfg.Compression))
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(a.cfg.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/x-ndjson"),
+	}
+	if encoding := sinks.CompressionContentEncoding(a.cfg.Compression); encoding != "" {
+		input.ContentEncoding = aws.String(encoding)
+	}
+	_, err = a.svc.PutObjectWithContext(ctx, input)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Cannot upload audit records")
+		return res
+	}
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+func (a *s3Auditor) Record(rec AuditRecord) {
+	a.writer.Submit(rec)
+}
+
+func (a *s3Auditor) Close() {
+	a.writer.Stop()
+}
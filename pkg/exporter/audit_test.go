@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func TestFileAuditor_RecordsDeliveries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := NewAuditor(&AuditConfig{File: &AuditFileConfig{Path: path}})
+	require.NoError(t, err)
+
+	registry := &SyncRegistry{Auditor: auditor}
+	registry.Register("in-mem", &sinks.InMemory{Config: &sinks.InMemoryConfig{}})
+
+	ev := &kube.EnhancedEvent{}
+	ev.UID = "uid-1"
+	ev.Namespace = "default"
+	ev.Reason = "BackOff"
+	registry.SendEvent("in-mem", ev)
+	auditor.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var rec AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "in-mem", rec.Receiver)
+	assert.Equal(t, "*sinks.InMemory", rec.Sink)
+	assert.Equal(t, "uid-1", rec.EventUID)
+	assert.Equal(t, "success", rec.Result)
+}
+
+func TestNewAuditor_RequiresSingleDestination(t *testing.T) {
+	_, err := NewAuditor(&AuditConfig{})
+	assert.Error(t, err)
+
+	_, err = NewAuditor(&AuditConfig{File: &AuditFileConfig{Path: "a"}, S3: &AuditS3Config{Bucket: "b"}})
+	assert.Error(t, err)
+}
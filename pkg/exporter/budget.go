@@ -0,0 +1,199 @@
+package exporter
+
+import (
+	"errors"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+const (
+	BudgetBlock      = "block"
+	BudgetShedNormal = "shedNormal"
+
+	// eventOverhead approximates the memory of the fixed fields of a queued event
+	eventOverhead = 512
+)
+
+// BudgetConfig limits the events queued for or being sent to the receivers, an event counts once per receiver. At the
+// limit, the watcher is blocked until the receivers catch up, or with shedNormal, Normal priority events are dropped
+// and only the higher priority events wait for room.
+type BudgetConfig struct {
+	MaxInFlight int `yaml:"maxInFlight,omitempty"`
+	// MaxBufferedBytes is a quantity like 256Mi, the size of the events is estimated
+	MaxBufferedBytes string `yaml:"maxBufferedBytes,omitempty"`
+	OnLimit          string `yaml:"onLimit,omitempty"`
+}
+
+func (c *BudgetConfig) validate() error {
+	if c.MaxInFlight < 0 {
+		return errors.New("maxInFlight must not be negative")
+	}
+	if _, err := c.maxBufferedBytes(); err != nil {
+		return err
+	}
+	if c.MaxInFlight == 0 && c.MaxBufferedBytes == "" {
+		return errors.New("maxInFlight or maxBufferedBytes must be set")
+	}
+	switch c.OnLimit {
+	case "", BudgetBlock, BudgetShedNormal:
+		return nil
+	}
+	return errors.New("onLimit must be block or shedNormal")
+}
+
+func (c *BudgetConfig) maxBufferedBytes() (int64, error) {
+	if c.MaxBufferedBytes == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(c.MaxBufferedBytes)
+	if err != nil || q.Sign() <= 0 {
+		return 0, errors.New("maxBufferedBytes must be a positive quantity like 256Mi")
+	}
+	return q.Value(), nil
+}
+
+// Budget accounts for the events held by the registry
+type Budget struct {
+	maxInFlight int
+	maxBytes    int64
+	shed        bool
+	metrics     *metrics.Store
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	inFlight int
+	bytes    int64
+	closed   bool
+}
+
+func NewBudget(cfg *BudgetConfig, metricsStore *metrics.Store) (*Budget, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	maxBytes, _ := cfg.maxBufferedBytes()
+	b := &Budget{
+		maxInFlight: cfg.MaxInFlight,
+		maxBytes:    maxBytes,
+		shed:        cfg.OnLimit == BudgetShedNormal,
+		metrics:     metricsStore,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b, nil
+}
+
+// full reports whether an event of the size does not fit. An event always fits if nothing is held, so an event larger
+// than the byte limit does not block forever.
+func (b *Budget) full(size int64) bool {
+	if b.inFlight == 0 {
+		return false
+	}
+	return (b.maxInFlight > 0 && b.inFlight >= b.maxInFlight) || (b.maxBytes > 0 && b.bytes+size > b.maxBytes)
+}
+
+// acquire takes room for an event, it blocks while the budget is full, unless the event is shed
+func (b *Budget) acquire(size int64, p Priority) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.closed && b.full(size) {
+		if b.shed && p == PriorityNormal {
+			if b.metrics != nil {
+				b.metrics.EventsShed.Inc()
+			}
+			return false
+		}
+		b.cond.Wait()
+	}
+	b.inFlight++
+	b.bytes += size
+	b.report()
+	return true
+}
+
+func (b *Budget) release(size int64) {
+	b.mu.Lock()
+	b.inFlight--
+	b.bytes -= size
+	b.report()
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// close lets all blocked and future events through, the receivers are going away
+func (b *Budget) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *Budget) report() {
+	if b.metrics != nil {
+		b.metrics.InFlightEvents.Set(float64(b.inFlight))
+		b.metrics.BufferedBytes.Set(float64(b.bytes))
+	}
+}
+
+// eventSize estimates the memory held by a queued copy of the event
+func eventSize(ev *kube.EnhancedEvent) int64 {
+	n := eventOverhead + len(ev.Name) + len(ev.Namespace) + len(ev.Reason) + len(ev.Message) + len(ev.Type) +
+		len(ev.Source.Component) + len(ev.Source.Host) + len(ev.ReportingController) + len(ev.ReportingInstance) +
+		len(ev.Action) + len(ev.ClusterName) + len(ev.InvolvedObject.Kind) + len(ev.InvolvedObject.Name) +
+		len(ev.InvolvedObject.Namespace) + len(ev.InvolvedObject.UID) + len(ev.InvolvedObject.FieldPath)
+	n += mapSize(ev.Labels) + mapSize(ev.Annotations) + mapSize(ev.InvolvedObject.Labels) +
+		mapSize(ev.InvolvedObject.Annotations)
+	for _, ref := range ev.InvolvedObject.OwnerReferences {
+		n += len(ref.APIVersion) + len(ref.Kind) + len(ref.Name) + len(ref.UID)
+	}
+	// The enrichments, the embedded manifests are the largest by far
+	n += mapSize(ev.NamespaceLabels) + mapSize(ev.NamespaceAnnotations) + mapSize(ev.Extracted) +
+		valueSize(ev.InvolvedObject.Manifest)
+	if o := ev.Owner; o != nil {
+		n += len(o.APIVersion) + len(o.Kind) + len(o.Name) + len(o.UID) + mapSize(o.Labels)
+	}
+	if node := ev.Node; node != nil {
+		n += len(node.Name) + len(node.Zone) + len(node.Region) + len(node.InstanceType) + mapSize(node.Labels)
+	}
+	if pod := ev.Pod; pod != nil {
+		n += len(pod.NodeName) + len(pod.ServiceAccount) + len(pod.QOSClass)
+		for _, c := range pod.Containers {
+			n += len(c.Name) + len(c.Image)
+		}
+	}
+	return int64(n)
+}
+
+// valueSize approximates the size of an unstructured value, like a manifest
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n := 0
+		for k, e := range v {
+			n += len(k) + valueSize(e)
+		}
+		return n
+	case []interface{}:
+		n := 0
+		for _, e := range v {
+			n += valueSize(e)
+		}
+		return n
+	case string:
+		return len(v)
+	case nil:
+		return 0
+	}
+	// Numbers and booleans
+	return 8
+}
+
+func mapSize(m map[string]string) int {
+	n := 0
+	for k, v := range m {
+		n += len(k) + len(v)
+	}
+	return n
+}
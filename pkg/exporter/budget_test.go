@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestBudgetConfig_Validate(t *testing.T) {
+	assert.Error(t, (&BudgetConfig{}).validate())
+	assert.Error(t, (&BudgetConfig{MaxInFlight: -1}).validate())
+	assert.Error(t, (&BudgetConfig{MaxBufferedBytes: "lots"}).validate())
+	assert.Error(t, (&BudgetConfig{MaxInFlight: 10, OnLimit: "drop"}).validate())
+	assert.NoError(t, (&BudgetConfig{MaxBufferedBytes: "64Mi", OnLimit: BudgetShedNormal}).validate())
+
+	b, err := NewBudget(&BudgetConfig{MaxBufferedBytes: "1Ki"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), b.maxBytes)
+}
+
+func TestBudget_Block(t *testing.T) {
+	b, err := NewBudget(&BudgetConfig{MaxInFlight: 2}, nil)
+	require.NoError(t, err)
+	require.True(t, b.acquire(100, PriorityNormal))
+	require.True(t, b.acquire(100, PriorityNormal))
+
+	acquired := make(chan bool)
+	go func() { acquired <- b.acquire(100, PriorityCritical) }()
+	select {
+	case <-acquired:
+		t.Fatal("acquired beyond the budget")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.release(100)
+	require.True(t, <-acquired)
+	assert.Equal(t, 2, b.inFlight)
+	assert.Equal(t, int64(200), b.bytes)
+
+	// Closing lets the blocked events through
+	go func() { acquired <- b.acquire(100, PriorityNormal) }()
+	b.close()
+	require.True(t, <-acquired)
+}
+
+func TestBudget_ShedNormal(t *testing.T) {
+	b, err := NewBudget(&BudgetConfig{MaxBufferedBytes: "1000", OnLimit: BudgetShedNormal}, nil)
+	require.NoError(t, err)
+
+	// An event larger than the budget still fits when nothing is held
+	require.True(t, b.acquire(2000, PriorityNormal))
+	assert.False(t, b.acquire(10, PriorityNormal))
+	b.release(2000)
+
+	require.True(t, b.acquire(600, PriorityNormal))
+	assert.False(t, b.acquire(600, PriorityNormal))
+	require.True(t, b.acquire(400, PriorityNormal))
+
+	acquired := make(chan bool)
+	go func() { acquired <- b.acquire(600, PriorityHigh) }()
+	b.release(600)
+	require.True(t, <-acquired)
+}
+
+func TestEventSize(t *testing.T) {
+	small := newPriorityTestEvent("Normal", "Pulled")
+	large := newPriorityTestEvent("Normal", "Pulled")
+	large.Message = string(make([]byte, 1000))
+	large.InvolvedObject.Labels = map[string]string{"app": "api"}
+	assert.Equal(t, eventSize(small)+1006, eventSize(large))
+}
+
+func TestEventSize_Enrichments(t *testing.T) {
+	plain := newPriorityTestEvent("Normal", "Pulled")
+	enriched := newPriorityTestEvent("Normal", "Pulled")
+	enriched.InvolvedObject.Manifest = map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "api"},
+		"spec": map[string]interface{}{
+			"replicas":   int64(3),
+			"containers": []interface{}{map[string]interface{}{"image": string(make([]byte, 4096))}},
+		},
+	}
+	enriched.NamespaceLabels = map[string]string{"team": "payments"}
+	enriched.Pod = &kube.PodInfo{Containers: []kube.ContainerInfo{{Name: "api", Image: "api:1"}}}
+	// The keys and values of the manifest, the namespace labels and the containers
+	assert.Equal(t, eventSize(plain)+8+4+3+4+8+8+10+5+4096+12+8, eventSize(enriched))
+}
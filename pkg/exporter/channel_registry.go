@@ -2,7 +2,10 @@ package exporter
 
 import (
 	"context"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -11,39 +14,130 @@ import (
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
 )
 
-// ChannelBasedReceiverRegistry creates two channels for each receiver. One is for receiving events and other one is
-// for breaking out of the infinite loop. Each message is passed to receivers
-// This might not be the best way to implement such feature. A ring buffer can be better
-// and we might need a mechanism to drop the vents
-// On closing, the registry sends a signal on all exit channels, and then waits for all to complete.
+// ChannelBasedReceiverRegistry creates a priority queue and an exit channel for each receiver. Each message is
+// queued with the priority of the event and delivered by the workers of the receiver, one by default, highest priority
+// first, so that a backlog of normal events does not hold back warnings. A receiver with ordered delivery has a FIFO
+// queue per worker instead, and the events of an object always go to the same one.
+// On closing, the registry closes all exit channels, and then waits for all to complete. Events that are still queued
+// are passed to OnClose or dropped.
 type ChannelBasedReceiverRegistry struct {
-	ch           map[string]chan kube.EnhancedEvent
+	// mu guards the maps, receivers can be added and removed while events are sent
+	mu           sync.RWMutex
+	queues       map[string]*receiverQueues
 	exitCh       map[string]chan interface{}
 	wg           *sync.WaitGroup
 	MetricsStore *metrics.Store
+	// Auditor, if set, records every delivery attempt
+	Auditor Auditor
+	// Prioritizer decides the delivery order, by default Warning events go first
+	Prioritizer *Prioritizer
+	// Budget, if set, limits the events held by the queues
+	Budget *Budget
+	// OnClose, if set, gets the events still queued for a receiver when the registry is closed, instead of dropping
+	// them
+	OnClose func(name string, queued []kube.EnhancedEvent)
+	closing atomic.Bool
+}
+
+// receiverQueues are the queues of a receiver, a shared one or one per worker with ordered delivery
+type receiverQueues struct {
+	lanes   []*priorityQueue
+	ordered bool
+
+	mu sync.Mutex
+	// depths are the queue depths of the lanes by priority, to report their sum
+	depths [][numPriorities]int
+}
+
+func newReceiverQueues(name string, delivery *sinks.DeliveryConfig, store *metrics.Store) *receiverQueues {
+	lanes := 1
+	q := &receiverQueues{}
+	if delivery != nil && delivery.Ordered && delivery.Workers > 1 {
+		lanes = delivery.Workers
+		q.ordered = true
+	}
+	q.depths = make([][numPriorities]int, lanes)
+	for i := 0; i < lanes; i++ {
+		var onDepth func(p Priority, depth int)
+		if store != nil {
+			lane := i
+			onDepth = func(p Priority, depth int) {
+				q.mu.Lock()
+				defer q.mu.Unlock()
+				q.depths[lane][p] = depth
+				total := 0
+				for _, d := range q.depths {
+					total += d[p]
+				}
+				store.QueueDepth.WithLabelValues(name, p.String()).Set(float64(total))
+			}
+		}
+		q.lanes = append(q.lanes, newPriorityQueue(onDepth))
+	}
+	return q
+}
+
+// push queues the event on the lane of its involved object
+func (q *receiverQueues) push(ev *kube.EnhancedEvent, p Priority) bool {
+	if !q.ordered {
+		return q.lanes[0].push(*ev, p)
+	}
+	key := string(ev.InvolvedObject.UID)
+	if key == "" {
+		key = ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	// The priorities would let a later warning overtake an earlier event of the object
+	return q.lanes[h.Sum32()%uint32(len(q.lanes))].push(*ev, PriorityNormal)
 }
 
 func (r *ChannelBasedReceiverRegistry) SendEvent(name string, event *kube.EnhancedEvent) {
-	ch := r.ch[name]
-	if ch == nil {
+	r.mu.RLock()
+	queues := r.queues[name]
+	r.mu.RUnlock()
+	if queues == nil {
 		log.Error().Str("name", name).Msg("There is no channel")
+		return
 	}
 
-	go func() {
-		ch <- *event
-	}()
+	priority := r.Prioritizer.Priority(event)
+	// The queued event holds the checkpoint until it is sent
+	event.Hold()
+	if r.Budget == nil {
+		if !queues.push(event, priority) {
+			event.Release()
+		}
+		return
+	}
+	size := eventSize(event)
+	if !r.Budget.acquire(size, priority) {
+		log.Debug().Str("sink", name).Str("event", event.Message).Msg("Shedding event, the budget is full")
+		event.Release()
+		return
+	}
+	if !queues.push(event, priority) {
+		r.Budget.release(size)
+		event.Release()
+	}
 }
 
 func (r *ChannelBasedReceiverRegistry) Register(name string, receiver sinks.Sink) {
-	if r.ch == nil {
-		r.ch = make(map[string]chan kube.EnhancedEvent)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.queues == nil {
+		r.queues = make(map[string]*receiverQueues)
 		r.exitCh = make(map[string]chan interface{})
 	}
+	if r.Prioritizer == nil {
+		r.Prioritizer = NewPrioritizer(nil)
+	}
 
-	ch := make(chan kube.EnhancedEvent)
+	delivery := sinks.DeliveryOf(receiver)
+	queues := newReceiverQueues(name, delivery, r.MetricsStore)
 	exitCh := make(chan interface{})
 
-	r.ch[name] = ch
+	r.queues[name] = queues
 	r.exitCh[name] = exitCh
 
 	if r.wg == nil {
@@ -51,21 +145,37 @@ func (r *ChannelBasedReceiverRegistry) Register(name string, receiver sinks.Sink
 	}
 	r.wg.Add(1)
 
+	workers := 1
+	if delivery != nil && delivery.Workers > 1 {
+		workers = delivery.Workers
+	}
+	var receiverWg sync.WaitGroup
+	receiverWg.Add(workers)
+	for i := 0; i < workers; i++ {
+		queue := queues.lanes[i%len(queues.lanes)]
+		go func() {
+			defer receiverWg.Done()
+			r.deliver(name, receiver, queue, exitCh)
+		}()
+	}
+
 	go func() {
-	Loop:
-		for {
-			select {
-			case ev := <-ch:
-				log.Debug().Str("sink", name).Str("event", ev.Message).Msg("sending event to sink")
-				err := receiver.Send(context.Background(), &ev)
-				if err != nil {
-					r.MetricsStore.SendErrors.Inc()
-					log.Debug().Err(err).Str("sink", name).Str("event", ev.Message).Msg("Cannot send event")
-				}
-			case <-exitCh:
-				log.Info().Str("sink", name).Msg("Closing the sink")
-				break Loop
+		receiverWg.Wait()
+		var queued []kube.EnhancedEvent
+		for _, queue := range queues.lanes {
+			queued = append(queued, queue.drain()...)
+		}
+		for i := range queued {
+			if r.Budget != nil {
+				r.Budget.release(eventSize(&queued[i]))
 			}
+			queued[i].Release()
+		}
+		if r.OnClose != nil && r.closing.Load() {
+			r.OnClose(name, queued)
+			log.Info().Str("sink", name).Int("kept", len(queued)).Msg("Closing the sink")
+		} else {
+			log.Info().Str("sink", name).Int("dropped", len(queued)).Msg("Closing the sink")
 		}
 		receiver.Close()
 		log.Info().Str("sink", name).Msg("Closed")
@@ -73,12 +183,81 @@ func (r *ChannelBasedReceiverRegistry) Register(name string, receiver sinks.Sink
 	}()
 }
 
+// deliver sends the events of the queue to the receiver until the exit channel is closed
+func (r *ChannelBasedReceiverRegistry) deliver(name string, receiver sinks.Sink, queue *priorityQueue, exitCh chan interface{}) {
+	sinkType := sinks.SinkType(receiver)
+	for {
+		// Exiting is checked before taking an event, so the queued events are left for OnClose
+		select {
+		case <-exitCh:
+			return
+		default:
+		}
+		ev, ok := queue.pop()
+		if !ok {
+			select {
+			case <-queue.notify:
+				continue
+			case <-exitCh:
+				return
+			}
+		}
+
+		// The size is taken before sending, the sinks may change the event
+		var size int64
+		if r.Budget != nil {
+			size = eventSize(&ev)
+		}
+
+		log.Debug().Str("sink", name).Str("event", ev.Message).Msg("sending event to sink")
+		started := time.Now()
+		err := receiver.Send(context.Background(), &ev)
+		if r.Auditor != nil {
+			r.Auditor.Record(newAuditRecord(name, sinkType, &ev, started, err))
+		}
+		if err != nil {
+			r.MetricsStore.SendErrors.Inc()
+			r.MetricsStore.ReceiverSendErrors.WithLabelValues(name).Inc()
+			log.Debug().Err(err).Str("sink", name).Str("event", ev.Message).Msg("Cannot send event")
+		} else if r.MetricsStore != nil {
+			r.MetricsStore.ReceiverEventsSent.WithLabelValues(name).Inc()
+		}
+		if r.Budget != nil {
+			r.Budget.release(size)
+		}
+		ev.Release()
+	}
+}
+
+// Unregister signals closing to the sink of the receiver without waiting for it
+func (r *ChannelBasedReceiverRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exitCh, ok := r.exitCh[name]
+	if !ok {
+		return
+	}
+	delete(r.queues, name)
+	delete(r.exitCh, name)
+	close(exitCh)
+}
+
 // Close signals closing to all sinks and waits for them to complete.
 // The wait could block indefinitely depending on the sink implementations.
 func (r *ChannelBasedReceiverRegistry) Close() {
+	// Unblock the watcher, the events are not delivered anymore anyway
+	if r.Budget != nil {
+		r.Budget.close()
+	}
+	r.closing.Store(true)
 	// Send exit command and wait for exit of all sinks
-	for _, ec := range r.exitCh {
-		ec <- 1
+	r.mu.Lock()
+	for name, ec := range r.exitCh {
+		close(ec)
+		delete(r.exitCh, name)
+	}
+	r.mu.Unlock()
+	if r.wg != nil {
+		r.wg.Wait()
 	}
-	r.wg.Wait()
 }
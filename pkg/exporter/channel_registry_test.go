@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// slowSink records the order of the events per object, taking a random time to send
+type slowSink struct {
+	mu      sync.Mutex
+	seen    map[types.UID][]int
+	active  int
+	maxSeen int
+}
+
+func (s *slowSink) Send(_ context.Context, ev *kube.EnhancedEvent) error {
+	s.mu.Lock()
+	s.active++
+	s.maxSeen = max(s.maxSeen, s.active)
+	s.mu.Unlock()
+
+	time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	seq, _ := strconv.Atoi(ev.Message)
+	s.seen[ev.InvolvedObject.UID] = append(s.seen[ev.InvolvedObject.UID], seq)
+	return nil
+}
+
+func (s *slowSink) Close() {}
+
+func TestChannelBasedReceiverRegistry_OrderedDelivery(t *testing.T) {
+	sink := &slowSink{seen: make(map[types.UID][]int)}
+	registry := &ChannelBasedReceiverRegistry{}
+	registry.Register("ordered", sinks.WithDelivery(sink, &sinks.DeliveryConfig{Workers: 4, Ordered: true}))
+	for seq := 0; seq < 50; seq++ {
+		for obj := 0; obj < 8; obj++ {
+			ev := newTestEvent("prod", "api-"+strconv.Itoa(obj), "BackOff")
+			ev.InvolvedObject.UID = types.UID("uid-" + strconv.Itoa(obj))
+			ev.Message = strconv.Itoa(seq)
+			// Warnings must not overtake the earlier events of the object
+			if seq%3 == 0 {
+				ev.Type = "Normal"
+			}
+			registry.SendEvent("ordered", ev)
+		}
+	}
+	require.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		n := 0
+		for _, seqs := range sink.seen {
+			n += len(seqs)
+		}
+		return n == 400
+	}, 10*time.Second, 10*time.Millisecond)
+	registry.Close()
+
+	for uid, seqs := range sink.seen {
+		require.Len(t, seqs, 50)
+		for i, seq := range seqs {
+			require.Equal(t, i, seq, "events of %s out of order", uid)
+		}
+	}
+	assert.Greater(t, sink.maxSeen, 1)
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
@@ -22,21 +25,65 @@ type Config struct {
 	// Route is the top route that the events will match
 	// TODO: There is currently a tight coupling with route and config, but not with receiver config and sink so
 	// TODO: I am not sure what to do here.
-	LogLevel           string                    `yaml:"logLevel"`
-	LogFormat          string                    `yaml:"logFormat"`
-	ThrottlePeriod     int64                     `yaml:"throttlePeriod"`
-	MaxEventAgeSeconds int64                     `yaml:"maxEventAgeSeconds"`
-	ClusterName        string                    `yaml:"clusterName,omitempty"`
-	Namespace          string                    `yaml:"namespace"`
-	LeaderElection     kube.LeaderElectionConfig `yaml:"leaderElection"`
-	WatchReasons       []string                  `yaml:"watchReasons,omitempty"`
-	Route              Route                     `yaml:"route"`
-	Receivers          []sinks.ReceiverConfig    `yaml:"receivers"`
-	KubeQPS            float32                   `yaml:"kubeQPS,omitempty"`
-	KubeBurst          int                       `yaml:"kubeBurst,omitempty"`
-	MetricsNamePrefix  string                    `yaml:"metricsNamePrefix,omitempty"`
-	OmitLookup         bool                      `yaml:"omitLookup,omitempty"`
-	CacheSize          int                       `yaml:"cacheSize,omitempty"`
+	LogLevel           string                      `yaml:"logLevel"`
+	LogFormat          string                      `yaml:"logFormat"`
+	ThrottlePeriod     int64                       `yaml:"throttlePeriod"`
+	MaxEventAgeSeconds int64                       `yaml:"maxEventAgeSeconds"`
+	BackfillWindow     string                      `yaml:"backfillWindow,omitempty"`
+	ResyncPeriod       string                      `yaml:"resyncPeriod,omitempty"`
+	DeletedRecheck     *kube.DeletedRecheckConfig  `yaml:"deletedRecheck,omitempty"`
+	Aggregation        *kube.AggregationConfig     `yaml:"aggregation,omitempty"`
+	ClusterName        string                      `yaml:"clusterName,omitempty"`
+	Clusters           []kube.ClusterConfig        `yaml:"clusters,omitempty"`
+	Namespace          string                      `yaml:"namespace"`
+	Namespaces         kube.NamespaceFilter        `yaml:"namespaces,omitempty"`
+	LeaderElection     kube.LeaderElectionConfig   `yaml:"leaderElection"`
+	Checkpoint         *kube.CheckpointConfig      `yaml:"checkpoint,omitempty"`
+	Sharding           kube.ShardingConfig         `yaml:"sharding"`
+	WatchReasons       []string                    `yaml:"watchReasons,omitempty"`
+	FieldSelector      string                      `yaml:"fieldSelector,omitempty"`
+	ObjectSelector     string                      `yaml:"objectSelector,omitempty"`
+	EventsAPI          string                      `yaml:"eventsAPI,omitempty"`
+	CustomSources      []kube.CustomSourceConfig   `yaml:"customSources,omitempty"`
+	Policies           *kube.PolicyConfig          `yaml:"policies,omitempty"`
+	Trivy              *kube.TrivyConfig           `yaml:"trivy,omitempty"`
+	Falco              *FalcoConfig                `yaml:"falco,omitempty"`
+	Route              Route                       `yaml:"route"`
+	Receivers          []sinks.ReceiverConfig      `yaml:"receivers"`
+	ReceiverGroups     []sinks.ReceiverGroup       `yaml:"receiverGroups,omitempty"`
+	ReceiverFactories  []ReceiverFactoryConfig     `yaml:"receiverFactories,omitempty"`
+	Kubeconfig         string                      `yaml:"kubeconfig,omitempty"`
+	KubeContext        string                      `yaml:"kubeContext,omitempty"`
+	KubeQPS            float32                     `yaml:"kubeQPS,omitempty"`
+	KubeBurst          int                         `yaml:"kubeBurst,omitempty"`
+	KubeTimeoutSeconds int                         `yaml:"kubeTimeoutSeconds,omitempty"`
+	KubeProtobuf       bool                        `yaml:"kubeProtobuf,omitempty"`
+	MetricsNamePrefix  string                      `yaml:"metricsNamePrefix,omitempty"`
+	OmitLookup         bool                        `yaml:"omitLookup,omitempty"`
+	ResolveOwners      bool                        `yaml:"resolveOwners,omitempty"`
+	EnrichNodes        bool                        `yaml:"enrichNodes,omitempty"`
+	EnrichPods         bool                        `yaml:"enrichPods,omitempty"`
+	EnrichNamespaces   bool                        `yaml:"enrichNamespaces,omitempty"`
+	Manifests          *kube.ManifestConfig        `yaml:"manifests,omitempty"`
+	CacheSize          int                         `yaml:"cacheSize,omitempty"`
+	CacheTTLSeconds    int                         `yaml:"cacheTTLSeconds,omitempty"`
+	Scrub              *ScrubConfig                `yaml:"scrub,omitempty"`
+	NormalizeReasons   *NormalizeConfig            `yaml:"normalizeReasons,omitempty"`
+	Extract            *ExtractConfig              `yaml:"extract,omitempty"`
+	Previous           *PreviousConfig             `yaml:"previous,omitempty"`
+	Priorities         *PriorityConfig             `yaml:"priorities,omitempty"`
+	TLSPolicy          *sinks.TLSPolicy            `yaml:"tlsPolicy,omitempty"`
+	Audit              *AuditConfig                `yaml:"audit,omitempty"`
+	Egress             *sinks.EgressPolicy         `yaml:"egress,omitempty"`
+	RequestLogging     *sinks.RequestLoggingConfig `yaml:"requestLogging,omitempty"`
+	Silences           *SilenceConfig              `yaml:"silences,omitempty"`
+	SlackCommands      *SlackCommandConfig         `yaml:"slackCommands,omitempty"`
+	Budget             *BudgetConfig               `yaml:"budget,omitempty"`
+	RateLimit          *RateLimitConfig            `yaml:"rateLimit,omitempty"`
+	Ingest             *IngestConfig               `yaml:"ingest,omitempty"`
+	Watchdogs          []WatchdogConfig            `yaml:"watchdogs,omitempty"`
+	Snapshot           *SnapshotConfig             `yaml:"snapshot,omitempty"`
+	TemplateLookups    *sinks.LookupConfig         `yaml:"templateLookups,omitempty"`
 }
 
 func (c *Config) SetDefaults() {
@@ -63,9 +110,97 @@ func (c *Config) Validate() error {
 	if err := c.validateMetricsNamePrefix(); err != nil {
 		return err
 	}
+	if err := c.validateSharding(); err != nil {
+		return err
+	}
+	if err := c.validateLeaderElection(); err != nil {
+		return err
+	}
+	if err := c.validateCheckpoint(); err != nil {
+		return err
+	}
+	if err := c.validateClusters(); err != nil {
+		return err
+	}
+	if err := c.validateNamespaces(); err != nil {
+		return err
+	}
+	if err := c.validateFieldSelector(); err != nil {
+		return err
+	}
+	if err := c.validateObjectSelector(); err != nil {
+		return err
+	}
+	if err := c.validateDeletedRecheck(); err != nil {
+		return err
+	}
+	if err := c.validateAggregation(); err != nil {
+		return err
+	}
+	if err := c.validateManifests(); err != nil {
+		return err
+	}
+	if err := c.validateCustomSources(); err != nil {
+		return err
+	}
+	if err := c.validateScrub(); err != nil {
+		return err
+	}
+	if err := c.validateNormalizeReasons(); err != nil {
+		return err
+	}
+	if err := c.validateExtract(); err != nil {
+		return err
+	}
+	if err := c.validatePrevious(); err != nil {
+		return err
+	}
+	if err := c.validateSilences(); err != nil {
+		return err
+	}
+	if err := c.validateBudget(); err != nil {
+		return err
+	}
+	if err := c.validateRateLimit(); err != nil {
+		return err
+	}
+	if err := c.validateIngest(); err != nil {
+		return err
+	}
+	if err := c.validateFalco(); err != nil {
+		return err
+	}
+	if err := c.validateSnapshot(); err != nil {
+		return err
+	}
+	if err := c.validateTemplateLookups(); err != nil {
+		return err
+	}
+	if err := c.validateSlackCommands(); err != nil {
+		return err
+	}
+	if err := c.validateTLSPolicy(); err != nil {
+		return err
+	}
+	if err := c.validateEgress(); err != nil {
+		return err
+	}
+	if err := c.validateReceiverGroups(); err != nil {
+		return err
+	}
+	if err := c.validateReceivers(); err != nil {
+		return err
+	}
+	if err := c.validateReceiverFactories(); err != nil {
+		return err
+	}
+	if err := c.validateWatchdogs(); err != nil {
+		return err
+	}
+	for _, finding := range c.Lint() {
+		log.Warn().Msg("config.route: " + finding)
+	}
 
-	// No duplicate receivers
-	// Receivers individually
 	// Routers recursive
 	return nil
 }
@@ -74,9 +209,90 @@ func (c *Config) validateDefaults() error {
 	if err := c.validateMaxEventAgeSeconds(); err != nil {
 		return err
 	}
+	if err := c.validateBackfillWindow(); err != nil {
+		return err
+	}
+	if err := c.validateResyncPeriod(); err != nil {
+		return err
+	}
+	if err := c.validateEventsAPI(); err != nil {
+		return err
+	}
+	if c.ResolveOwners && c.OmitLookup {
+		log.Error().Msg("config.resolveOwners needs the object lookups, it cannot be set with omitLookup")
+		return errors.New("validateDefaults failed")
+	}
+	if c.EnrichPods && c.OmitLookup {
+		log.Error().Msg("config.enrichPods needs the object lookups, it cannot be set with omitLookup")
+		return errors.New("validateDefaults failed")
+	}
+	if c.CacheTTLSeconds < 0 {
+		log.Error().Msg("config.cacheTTLSeconds must not be negative")
+		return errors.New("validateDefaults failed")
+	}
+	if c.KubeQPS < 0 || c.KubeBurst < 0 || c.KubeTimeoutSeconds < 0 {
+		log.Error().Msg("config.kubeQPS, config.kubeBurst and config.kubeTimeoutSeconds must not be negative")
+		return errors.New("validateDefaults failed")
+	}
+	return nil
+}
+
+func (c *Config) validateEventsAPI() error {
+	switch c.EventsAPI {
+	case "":
+		c.EventsAPI = kube.EventsAPICoreV1
+	case kube.EventsAPICoreV1, kube.EventsAPIEventsV1:
+	default:
+		log.Error().Str("eventsAPI", c.EventsAPI).Msgf("config.eventsAPI must be %s or %s", kube.EventsAPICoreV1, kube.EventsAPIEventsV1)
+		return errors.New("validateEventsAPI failed")
+	}
+	return nil
+}
+
+func (c *Config) validateBackfillWindow() error {
+	if c.BackfillWindow == "" {
+		return nil
+	}
+	window, err := time.ParseDuration(c.BackfillWindow)
+	if err != nil || window < 0 {
+		log.Error().Str("backfillWindow", c.BackfillWindow).Msg("config.backfillWindow must be a positive duration like 30m")
+		return errors.New("validateBackfillWindow failed")
+	}
+	// The checkpoint records that the events were processed, so they are only backfilled on the first start
+	if window > 0 && c.Checkpoint == nil && !(c.LeaderElection.Enabled && c.LeaderElection.Checkpoint) {
+		log.Error().Msg("config.backfillWindow needs config.checkpoint, or config.leaderElection.checkpoint, to only backfill on the first start")
+		return errors.New("validateBackfillWindow failed")
+	}
+	log.Info().Msg("config.backfillWindow=" + window.String())
+	return nil
+}
+
+// GetBackfillWindow returns the parsed backfill window, it is zero unless set
+func (c *Config) GetBackfillWindow() time.Duration {
+	window, _ := time.ParseDuration(c.BackfillWindow)
+	return window
+}
+
+func (c *Config) validateResyncPeriod() error {
+	if c.ResyncPeriod == "" {
+		return nil
+	}
+	// The informers do not resync more often than every second
+	period, err := time.ParseDuration(c.ResyncPeriod)
+	if err != nil || (period != 0 && period < time.Second) || period < 0 {
+		log.Error().Str("resyncPeriod", c.ResyncPeriod).Msg("config.resyncPeriod must be 0 or a duration of at least 1s like 10m")
+		return errors.New("validateResyncPeriod failed")
+	}
+	log.Info().Msg("config.resyncPeriod=" + period.String())
 	return nil
 }
 
+// GetResyncPeriod returns the parsed resync period of the event informers, it is zero, no resync, unless set
+func (c *Config) GetResyncPeriod() time.Duration {
+	period, _ := time.ParseDuration(c.ResyncPeriod)
+	return period
+}
+
 func (c *Config) validateMaxEventAgeSeconds() error {
 	if c.ThrottlePeriod == 0 && c.MaxEventAgeSeconds == 0 {
 		c.MaxEventAgeSeconds = 5
@@ -115,6 +331,363 @@ func (c *Config) validateMetricsNamePrefix() error {
 	return nil
 }
 
+func (c *Config) validateSharding() error {
+	if !c.Sharding.Enabled {
+		return nil
+	}
+	if c.LeaderElection.Enabled {
+		log.Error().Msg("cannot enable both leaderElection and sharding, every shard replica has to watch the events")
+		return errors.New("validateSharding failed")
+	}
+	if c.Sharding.LeaseDurationSeconds < 0 || c.Sharding.VirtualNodes < 0 {
+		log.Error().Msg("config.sharding.leaseDurationSeconds and config.sharding.virtualNodes must not be negative")
+		return errors.New("validateSharding failed")
+	}
+	return nil
+}
+
+func (c *Config) validateLeaderElection() error {
+	le := c.LeaderElection
+	if !le.Enabled {
+		return nil
+	}
+	if le.LeaseDurationSeconds < 0 || le.RenewDeadlineSeconds < 0 || le.RetryPeriodSeconds < 0 || le.CheckpointIntervalSeconds < 0 {
+		log.Error().Msg("config.leaderElection durations must not be negative")
+		return errors.New("validateLeaderElection failed")
+	}
+	// The other durations are checked by the leader elector, which only runs later
+	if le.RenewDeadlineSeconds > 0 && le.GetLeaseDuration() <= time.Duration(le.RenewDeadlineSeconds)*time.Second {
+		log.Error().Msg("config.leaderElection.renewDeadlineSeconds must be less than the leaseDurationSeconds")
+		return errors.New("validateLeaderElection failed")
+	}
+	return nil
+}
+
+func (c *Config) validateCheckpoint() error {
+	if c.Checkpoint == nil {
+		return nil
+	}
+	if c.LeaderElection.Enabled {
+		log.Error().Msg("config.checkpoint cannot be set with leader election, set config.leaderElection.checkpoint instead")
+		return errors.New("validateCheckpoint failed")
+	}
+	if err := c.Checkpoint.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.checkpoint is invalid")
+		return errors.New("validateCheckpoint failed")
+	}
+	return nil
+}
+
+func (c *Config) validateClusters() error {
+	names := map[string]bool{c.ClusterName: true}
+	for i, cluster := range c.Clusters {
+		if cluster.Name == "" || cluster.Kubeconfig == "" {
+			log.Error().Int("index", i).Msg("config.clusters: the name and the kubeconfig of a cluster must be set")
+			return errors.New("validateClusters failed")
+		}
+		if names[cluster.Name] {
+			log.Error().Str("cluster", cluster.Name).Msg("config.clusters: the names must be unique and differ from the clusterName")
+			return errors.New("validateClusters failed")
+		}
+		names[cluster.Name] = true
+	}
+	return nil
+}
+
+func (c *Config) validateNamespaces() error {
+	if c.Namespace != "" && len(c.Namespaces.Include) > 0 {
+		log.Error().Msg("cannot set both namespace and namespaces.include")
+		return errors.New("validateNamespaces failed")
+	}
+	if err := c.Namespaces.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.namespaces is invalid")
+		return errors.New("validateNamespaces failed")
+	}
+	return nil
+}
+
+func (c *Config) validateFieldSelector() error {
+	if _, err := fields.ParseSelector(c.FieldSelector); err != nil {
+		log.Error().Err(err).Msg("config.fieldSelector is invalid")
+		return errors.New("validateFieldSelector failed")
+	}
+	return nil
+}
+
+// GetFieldSelector returns the parsed field selector, it selects all the events unless set
+func (c *Config) GetFieldSelector() fields.Selector {
+	selector, _ := fields.ParseSelector(c.FieldSelector)
+	return selector
+}
+
+func (c *Config) validateObjectSelector() error {
+	if c.ObjectSelector == "" {
+		return nil
+	}
+	if _, err := labels.Parse(c.ObjectSelector); err != nil {
+		log.Error().Err(err).Msg("config.objectSelector is invalid")
+		return errors.New("validateObjectSelector failed")
+	}
+	if c.OmitLookup {
+		log.Warn().Msg("config.objectSelector needs the labels of the involved objects, which are not looked up with omitLookup")
+	}
+	return nil
+}
+
+// GetObjectSelector returns the parsed label selector of the involved objects, it is nil unless set
+func (c *Config) GetObjectSelector() labels.Selector {
+	if c.ObjectSelector == "" {
+		return nil
+	}
+	selector, _ := labels.Parse(c.ObjectSelector)
+	return selector
+}
+
+func (c *Config) validateScrub() error {
+	if c.Scrub == nil {
+		return nil
+	}
+	if _, err := NewScrubber(c.Scrub); err != nil {
+		log.Error().Err(err).Msg("config.scrub is invalid")
+		return errors.New("validateScrub failed")
+	}
+	return nil
+}
+
+func (c *Config) validateNormalizeReasons() error {
+	if c.NormalizeReasons == nil {
+		return nil
+	}
+	if _, err := NewNormalizer(c.NormalizeReasons); err != nil {
+		log.Error().Err(err).Msg("config.normalizeReasons is invalid")
+		return errors.New("validateNormalizeReasons failed")
+	}
+	return nil
+}
+
+func (c *Config) validateExtract() error {
+	if c.Extract == nil {
+		return nil
+	}
+	if _, err := NewExtractor(c.Extract); err != nil {
+		log.Error().Err(err).Msg("config.extract is invalid")
+		return errors.New("validateExtract failed")
+	}
+	return nil
+}
+
+func (c *Config) validatePrevious() error {
+	if c.Previous == nil {
+		return nil
+	}
+	if _, err := NewPreviousTracker(c.Previous, sinks.GetStateStore()); err != nil {
+		log.Error().Err(err).Msg("config.previous is invalid")
+		return errors.New("validatePrevious failed")
+	}
+	return nil
+}
+
+func (c *Config) validateBudget() error {
+	if c.Budget == nil {
+		return nil
+	}
+	if err := c.Budget.validate(); err != nil {
+		log.Error().Err(err).Msg("config.budget is invalid")
+		return errors.New("validateBudget failed")
+	}
+	return nil
+}
+
+func (c *Config) validateRateLimit() error {
+	if c.RateLimit == nil {
+		return nil
+	}
+	if err := c.RateLimit.validate(); err != nil {
+		log.Error().Err(err).Msg("config.rateLimit is invalid")
+		return errors.New("validateRateLimit failed")
+	}
+	return nil
+}
+
+func (c *Config) validateIngest() error {
+	if c.Ingest == nil {
+		return nil
+	}
+	if err := c.Ingest.validate(); err != nil {
+		log.Error().Err(err).Msg("config.ingest is invalid")
+		return errors.New("validateIngest failed")
+	}
+	return nil
+}
+
+func (c *Config) validateFalco() error {
+	if c.Falco == nil {
+		return nil
+	}
+	if err := c.Falco.validate(); err != nil {
+		log.Error().Err(err).Msg("config.falco is invalid")
+		return errors.New("validateFalco failed")
+	}
+	return nil
+}
+
+func (c *Config) validateAggregation() error {
+	if c.Aggregation == nil {
+		return nil
+	}
+	if err := c.Aggregation.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.aggregation is invalid")
+		return errors.New("validateAggregation failed")
+	}
+	return nil
+}
+
+func (c *Config) validateManifests() error {
+	if c.Manifests == nil {
+		return nil
+	}
+	if c.OmitLookup {
+		log.Error().Msg("config.manifests needs the object lookups, it cannot be set with omitLookup")
+		return errors.New("validateManifests failed")
+	}
+	if err := c.Manifests.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.manifests is invalid")
+		return errors.New("validateManifests failed")
+	}
+	return nil
+}
+
+func (c *Config) validateDeletedRecheck() error {
+	if c.DeletedRecheck == nil {
+		return nil
+	}
+	if c.OmitLookup {
+		log.Error().Msg("config.deletedRecheck needs the object lookups, it cannot be set with omitLookup")
+		return errors.New("validateDeletedRecheck failed")
+	}
+	if err := c.DeletedRecheck.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.deletedRecheck is invalid")
+		return errors.New("validateDeletedRecheck failed")
+	}
+	return nil
+}
+
+func (c *Config) validateCustomSources() error {
+	names := make(map[string]bool, len(c.CustomSources))
+	for i := range c.CustomSources {
+		source := &c.CustomSources[i]
+		if err := source.Validate(); err != nil {
+			log.Error().Err(err).Str("source", source.Name).Msg("custom source config is invalid")
+			return errors.New("validateCustomSources failed")
+		}
+		if names[source.Name] {
+			log.Error().Str("source", source.Name).Msg("custom source is defined more than once")
+			return errors.New("validateCustomSources failed")
+		}
+		names[source.Name] = true
+	}
+	if c.Policies != nil {
+		if err := c.Policies.Validate(); err != nil {
+			log.Error().Err(err).Msg("config.policies is invalid")
+			return errors.New("validateCustomSources failed")
+		}
+	}
+	if c.Trivy != nil {
+		if err := c.Trivy.Validate(); err != nil {
+			log.Error().Err(err).Msg("config.trivy is invalid")
+			return errors.New("validateCustomSources failed")
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateSnapshot() error {
+	if c.Snapshot == nil {
+		return nil
+	}
+	if err := c.Snapshot.validate(); err != nil {
+		log.Error().Err(err).Msg("config.snapshot is invalid")
+		return errors.New("validateSnapshot failed")
+	}
+	return nil
+}
+
+func (c *Config) validateTemplateLookups() error {
+	if c.TemplateLookups == nil {
+		return nil
+	}
+	if err := c.TemplateLookups.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.templateLookups is invalid")
+		return errors.New("validateTemplateLookups failed")
+	}
+	return nil
+}
+
+func (c *Config) validateSilences() error {
+	if c.Silences == nil {
+		return nil
+	}
+	if _, err := NewSilencer(c.Silences, sinks.GetStateStore()); err != nil {
+		log.Error().Err(err).Msg("config.silences is invalid")
+		return errors.New("validateSilences failed")
+	}
+	return nil
+}
+
+func (c *Config) validateSlackCommands() error {
+	if c.SlackCommands == nil {
+		return nil
+	}
+	if err := c.SlackCommands.validate(); err != nil {
+		log.Error().Err(err).Msg("config.slackCommands is invalid")
+		return errors.New("validateSlackCommands failed")
+	}
+	if name := c.SlackCommands.History; name != "" {
+		for i := range c.Receivers {
+			if c.Receivers[i].Name == name {
+				return nil
+			}
+		}
+		log.Error().Str("history", name).Msg("config.slackCommands.history is not a receiver")
+		return errors.New("validateSlackCommands failed")
+	}
+	return nil
+}
+
+func (c *Config) validateTLSPolicy() error {
+	if c.TLSPolicy == nil {
+		return nil
+	}
+	if err := c.TLSPolicy.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.tlsPolicy is invalid")
+		return errors.New("validateTLSPolicy failed")
+	}
+	for i := range c.Receivers {
+		if err := c.TLSPolicy.CheckReceiver(&c.Receivers[i]); err != nil {
+			log.Error().Err(err).Msg("receiver violates config.tlsPolicy")
+			return errors.New("validateTLSPolicy failed")
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateEgress() error {
+	if c.Egress == nil {
+		return nil
+	}
+	if err := c.Egress.Validate(); err != nil {
+		log.Error().Err(err).Msg("config.egress is invalid")
+		return errors.New("validateEgress failed")
+	}
+	for i := range c.Receivers {
+		if err := c.Egress.CheckReceiver(&c.Receivers[i]); err != nil {
+			log.Error().Err(err).Msg("receiver violates config.egress")
+			return errors.New("validateEgress failed")
+		}
+	}
+	return nil
+}
+
 func (c *Config) GetWatchKinds() []string {
 	kinds := make(map[string]struct{})
 
@@ -144,3 +717,94 @@ func (c *Config) GetWatchKinds() []string {
 
 	return result
 }
+
+// validateReceiverGroups applies the groups to their members, so the receivers are validated with the inherited
+// settings
+func (c *Config) validateReceiverGroups() error {
+	groups := make(map[string]*sinks.ReceiverGroup, len(c.ReceiverGroups))
+	for i := range c.ReceiverGroups {
+		g := &c.ReceiverGroups[i]
+		if g.Name == "" || groups[g.Name] != nil {
+			log.Error().Str("group", g.Name).Msg("receiver group names must be non-empty and unique")
+			return errors.New("validateReceiverGroups failed")
+		}
+		groups[g.Name] = g
+	}
+	for i := range c.Receivers {
+		r := &c.Receivers[i]
+		if r.Group == "" {
+			continue
+		}
+		g, ok := groups[r.Group]
+		if !ok {
+			log.Error().Str("receiver", r.Name).Str("group", r.Group).Msg("receiver group is not defined")
+			return errors.New("validateReceiverGroups failed")
+		}
+		if err := r.ApplyGroup(g); err != nil {
+			log.Error().Err(err).Str("receiver", r.Name).Msg("cannot apply the receiver group")
+			return errors.New("validateReceiverGroups failed")
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateWatchdogs() error {
+	receivers := make(map[string]bool, len(c.Receivers))
+	for _, r := range c.Receivers {
+		receivers[r.Name] = true
+	}
+	names := make(map[string]bool, len(c.Watchdogs))
+	for i := range c.Watchdogs {
+		w := &c.Watchdogs[i]
+		if err := w.validate(); err != nil {
+			log.Error().Err(err).Str("watchdog", w.Name).Msg("watchdog config is invalid")
+			return errors.New("validateWatchdogs failed")
+		}
+		if names[w.Name] {
+			log.Error().Str("watchdog", w.Name).Msg("watchdog is defined more than once")
+			return errors.New("validateWatchdogs failed")
+		}
+		names[w.Name] = true
+		for _, receiver := range w.Receivers {
+			if !receivers[receiver] {
+				log.Error().Str("watchdog", w.Name).Str("receiver", receiver).Msg("watchdog refers to an unknown receiver")
+				return errors.New("validateWatchdogs failed")
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateReceiverFactories() error {
+	names := make(map[string]bool, len(c.ReceiverFactories))
+	for i := range c.ReceiverFactories {
+		f := &c.ReceiverFactories[i]
+		if err := f.validate(); err != nil {
+			log.Error().Err(err).Str("factory", f.Name).Msg("receiver factory config is invalid")
+			return errors.New("validateReceiverFactories failed")
+		}
+		if names[f.Name] {
+			log.Error().Str("factory", f.Name).Msg("receiver factory is defined more than once")
+			return errors.New("validateReceiverFactories failed")
+		}
+		names[f.Name] = true
+	}
+	return nil
+}
+
+func (c *Config) validateReceivers() error {
+	names := make(map[string]bool, len(c.Receivers))
+	for i := range c.Receivers {
+		if names[c.Receivers[i].Name] {
+			log.Error().Str("receiver", c.Receivers[i].Name).Msg("receiver is defined more than once")
+			return errors.New("validateReceivers failed")
+		}
+		names[c.Receivers[i].Name] = true
+
+		if err := c.Receivers[i].Validate(); err != nil {
+			log.Error().Err(err).Str("receiver", c.Receivers[i].Name).Msg("receiver config is invalid")
+			return errors.New("validateReceivers failed")
+		}
+	}
+	return nil
+}
@@ -3,12 +3,17 @@ package exporter
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
 )
 
 func readConfig(t *testing.T, yml string) Config {
@@ -158,3 +163,184 @@ func TestSetDefaults(t *testing.T) {
 	require.Equal(t, rest.DefaultQPS, config.KubeQPS)
 	require.Equal(t, rest.DefaultBurst, config.KubeBurst)
 }
+
+func TestValidate_NegativeKubeTimeout(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Logger = log.Logger.Output(output)
+
+	config := Config{KubeTimeoutSeconds: -1}
+	err := config.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "config.kubeTimeoutSeconds must not be negative")
+}
+
+func TestValidate_CheckpointWithLeaderElection(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Logger = log.Logger.Output(output)
+
+	config := Config{
+		LeaderElection: kube.LeaderElectionConfig{Enabled: true},
+		Checkpoint:     &kube.CheckpointConfig{},
+	}
+	err := config.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "set config.leaderElection.checkpoint instead")
+}
+
+func TestValidate_DuplicateReceivers(t *testing.T) {
+	output := &bytes.Buffer{}
+	log.Logger = log.Logger.Output(output)
+
+	config := Config{
+		Receivers: []sinks.ReceiverConfig{
+			{Name: "stdout", Stdout: &sinks.StdoutConfig{}},
+			{Name: "stdout", Stdout: &sinks.StdoutConfig{}},
+		},
+	}
+	err := config.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, output.String(), "receiver is defined more than once")
+}
+
+func TestValidate_BackfillWindow(t *testing.T) {
+	config := Config{BackfillWindow: "30m", Checkpoint: &kube.CheckpointConfig{}}
+	require.NoError(t, config.Validate())
+	require.Equal(t, 30*time.Minute, config.GetBackfillWindow())
+
+	// Without a checkpoint, every start would backfill again
+	config = Config{BackfillWindow: "30m"}
+	require.Error(t, config.Validate())
+
+	config = Config{BackfillWindow: "30m", LeaderElection: kube.LeaderElectionConfig{Enabled: true, Checkpoint: true}}
+	require.NoError(t, config.Validate())
+
+	config = Config{}
+	require.NoError(t, config.Validate())
+	require.Zero(t, config.GetBackfillWindow())
+
+	config = Config{BackfillWindow: "half an hour"}
+	require.Error(t, config.Validate())
+}
+
+func TestValidate_Clusters(t *testing.T) {
+	cfg := readConfig(t, `
+clusterName: management
+clusters:
+  - name: prod-eu
+    kubeconfig: /etc/clusters/kubeconfig
+    context: prod-eu
+  - name: prod-us
+    kubeconfig: /etc/clusters/kubeconfig
+    context: prod-us
+`)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, kube.ClusterConfig{Name: "prod-eu", Kubeconfig: "/etc/clusters/kubeconfig", Context: "prod-eu"}, cfg.Clusters[0])
+
+	cfg = Config{ClusterName: "prod-eu", Clusters: []kube.ClusterConfig{{Name: "prod-eu", Kubeconfig: "/etc/kubeconfig"}}}
+	assert.Error(t, cfg.Validate())
+	cfg = Config{Clusters: []kube.ClusterConfig{{Name: "prod-eu"}}}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_FieldSelector(t *testing.T) {
+	config := Config{FieldSelector: "type=Warning,involvedObject.kind!=Node"}
+	require.NoError(t, config.Validate())
+	assert.Equal(t, "involvedObject.kind!=Node,type=Warning", config.GetFieldSelector().String())
+
+	config = Config{}
+	require.NoError(t, config.Validate())
+	assert.True(t, config.GetFieldSelector().Empty())
+
+	config = Config{FieldSelector: "type"}
+	assert.Error(t, config.Validate())
+}
+
+func TestValidate_ObjectSelector(t *testing.T) {
+	config := Config{ObjectSelector: "team in (payments, checkout),!sandbox"}
+	require.NoError(t, config.Validate())
+	assert.True(t, config.GetObjectSelector().Matches(labels.Set{"team": "payments"}))
+	assert.False(t, config.GetObjectSelector().Matches(labels.Set{"team": "payments", "sandbox": "true"}))
+
+	config = Config{}
+	require.NoError(t, config.Validate())
+	assert.Nil(t, config.GetObjectSelector())
+
+	config = Config{ObjectSelector: "team in payments"}
+	assert.Error(t, config.Validate())
+}
+
+func TestValidate_ReceiverGroups(t *testing.T) {
+	cfg := readConfig(t, `
+receiverGroups:
+  - name: platform
+    retry:
+      maxRetries: 5
+    tls:
+      caFile: /etc/platform/ca.crt
+    settings:
+      method: PUT
+      headers:
+        X-Team: platform
+        X-Source: exporter
+receivers:
+  - name: alerts
+    group: platform
+    webhook:
+      endpoint: https://alerts.example.com
+      headers:
+        X-Team: alerts
+  - name: audit
+    group: platform
+    retry:
+      maxRetries: 1
+    webhook:
+      endpoint: https://audit.example.com
+      method: POST
+      tls:
+        insecureSkipVerify: true
+`)
+	require.NoError(t, cfg.Validate())
+
+	alerts := cfg.Receivers[0]
+	assert.Equal(t, 5, *alerts.Retry.MaxRetries)
+	assert.Equal(t, "/etc/platform/ca.crt", alerts.Webhook.TLS.CaFile)
+	assert.Equal(t, "PUT", alerts.Webhook.Method)
+	assert.Equal(t, map[string]string{"X-Team": "alerts", "X-Source": "exporter"}, alerts.Webhook.Headers)
+
+	audit := cfg.Receivers[1]
+	assert.Equal(t, 1, *audit.Retry.MaxRetries)
+	assert.Equal(t, sinks.TLS{InsecureSkipVerify: true}, audit.Webhook.TLS)
+	assert.Equal(t, "POST", audit.Webhook.Method)
+	assert.Equal(t, map[string]string{"X-Team": "platform", "X-Source": "exporter"}, audit.Webhook.Headers)
+
+	// A member can turn off a setting of the group by setting its zero value
+	cfg = readConfig(t, `
+receiverGroups:
+  - name: logs
+    settings:
+      deDot: true
+      layout:
+        message: "{{ .Message }}"
+receivers:
+  - name: plain
+    group: logs
+    stdout: {}
+  - name: dotted
+    group: logs
+    stdout:
+      deDot: false
+`)
+	require.NoError(t, cfg.Validate())
+	assert.True(t, cfg.Receivers[0].Stdout.DeDot)
+	assert.False(t, cfg.Receivers[1].Stdout.DeDot)
+	assert.Equal(t, map[string]interface{}{"message": "{{ .Message }}"}, cfg.Receivers[1].Stdout.Layout)
+
+	cfg = readConfig(t, `
+receivers:
+  - name: alerts
+    group: missing
+    webhook:
+      endpoint: https://alerts.example.com
+`)
+	require.Error(t, cfg.Validate())
+}
@@ -1,20 +1,34 @@
 package exporter
 
 import (
-	"reflect"
-
 	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
 )
 
 // Engine is responsible for initializing the receivers from sinks
 type Engine struct {
 	Route    Route
 	Registry ReceiverRegistry
+	Scrubber *Scrubber
+	// Normalizer, if set, normalizes the reasons before the events are matched
+	Normalizer *Normalizer
+	// Extractor, if set, pulls fields out of the messages before the events are matched
+	Extractor *Extractor
+	Previous  *PreviousTracker
+	Silencer  *Silencer
+	// History keeps the last events in memory for the Slack commands, if no receiver keeps them
+	History       *sinks.MemoryHistory
+	SlackCommands *SlackCommands
+	// Factories create the receivers of the namespaces, they have to be notified about the namespaces
+	Factories []*ReceiverFactory
+	Watchdogs []*Watchdog
 }
 
 func NewEngine(config *Config, registry ReceiverRegistry) *Engine {
+	receivers := make(map[string]sinks.Sink, len(config.Receivers))
 	for _, v := range config.Receivers {
 		sink, err := v.GetSink()
 		if err != nil {
@@ -23,25 +37,170 @@ func NewEngine(config *Config, registry ReceiverRegistry) *Engine {
 
 		log.Info().
 			Str("name", v.Name).
-			Str("type", reflect.TypeOf(sink).String()).
+			Str("type", sinks.SinkType(sink)).
 			Msg("Registering sink")
 
 		registry.Register(v.Name, sink)
+		receivers[v.Name] = sink
 	}
 
-	return &Engine{
+	engine := &Engine{
 		Route:    config.Route,
 		Registry: registry,
 	}
+	engine.Route.BuildIndex()
+
+	if config.Scrub != nil {
+		scrubber, err := NewScrubber(config.Scrub)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize scrubber")
+		}
+		engine.Scrubber = scrubber
+	}
+
+	if config.NormalizeReasons != nil {
+		normalizer, err := NewNormalizer(config.NormalizeReasons)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize reason normalization")
+		}
+		engine.Normalizer = normalizer
+	}
+
+	if config.Extract != nil {
+		extractor, err := NewExtractor(config.Extract)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize extractors")
+		}
+		engine.Extractor = extractor
+	}
+
+	if config.Previous != nil {
+		tracker, err := NewPreviousTracker(config.Previous, sinks.GetStateStore())
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize previous occurrence tracking")
+		}
+		engine.Previous = tracker
+	}
+
+	if config.Silences != nil {
+		silencer, err := NewSilencer(config.Silences, sinks.GetStateStore())
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize silences")
+		}
+		engine.Silencer = silencer
+	}
+
+	if cfg := config.SlackCommands; cfg != nil {
+		var history sinks.EventHistory
+		if cfg.History != "" {
+			h, ok := sinks.AsEventHistory(receivers[cfg.History])
+			if !ok {
+				log.Fatal().Str("history", cfg.History).Msg("The receiver of the Slack command history does not keep the events")
+			}
+			history = h
+		} else {
+			size := cfg.HistorySize
+			if size == 0 {
+				size = defaultSlackCommandHistorySize
+			}
+			engine.History = sinks.NewMemoryHistory(size)
+			history = engine.History
+		}
+		commands, err := NewSlackCommands(cfg, history, engine.Silencer)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot initialize Slack commands")
+		}
+		engine.SlackCommands = commands
+	}
+
+	if len(config.ReceiverFactories) > 0 {
+		dynamic, ok := registry.(DynamicReceiverRegistry)
+		if !ok {
+			log.Fatal().Msg("The registry does not support receiver factories")
+		}
+		for i := range config.ReceiverFactories {
+			factory, err := NewReceiverFactory(&config.ReceiverFactories[i], dynamic)
+			if err != nil {
+				log.Fatal().Err(err).Str("name", config.ReceiverFactories[i].Name).Msg("Cannot initialize receiver factory")
+			}
+			engine.Factories = append(engine.Factories, factory)
+		}
+	}
+
+	for i := range config.Watchdogs {
+		cfg := &config.Watchdogs[i]
+		watchdog, err := NewWatchdog(cfg, engine.emitter(cfg.Receivers))
+		if err != nil {
+			log.Fatal().Err(err).Str("name", cfg.Name).Msg("Cannot initialize watchdog")
+		}
+		engine.Watchdogs = append(engine.Watchdogs, watchdog)
+	}
+
+	return engine
+}
+
+// emitter returns the function delivering the synthetic events to the receivers, or through the route if there are
+// none. They do not go through OnEvent, so the watchdogs do not see them.
+func (e *Engine) emitter(receivers []string) func(*kube.EnhancedEvent) {
+	if len(receivers) == 0 {
+		return func(ev *kube.EnhancedEvent) {
+			e.Route.ProcessEvent(ev, e.Registry)
+		}
+	}
+	return func(ev *kube.EnhancedEvent) {
+		for _, name := range receivers {
+			e.Registry.SendEvent(name, ev)
+		}
+	}
 }
 
 // OnEvent does not care whether event is add or update. Prior filtering should be done in the controller/watcher
 func (e *Engine) OnEvent(event *kube.EnhancedEvent) {
+	if e.Scrubber != nil {
+		e.Scrubber.Scrub(event)
+	}
+	if e.Normalizer != nil {
+		e.Normalizer.Normalize(event)
+	}
+	if e.Extractor != nil {
+		e.Extractor.Extract(event)
+	}
+	if e.Previous != nil {
+		e.Previous.Track(event)
+	}
+	if e.History != nil {
+		e.History.Add(event)
+	}
+	if e.Silencer != nil && e.Silencer.Silenced(event) {
+		log.Debug().Str("key", event.SilenceKey).Msg("Dropping silenced event")
+		return
+	}
+	for _, w := range e.Watchdogs {
+		w.Observe(event)
+	}
 	e.Route.ProcessEvent(event, e.Registry)
+	for _, f := range e.Factories {
+		f.Send(event)
+	}
+}
+
+func (e *Engine) OnNamespace(ns *corev1.Namespace) {
+	for _, f := range e.Factories {
+		f.OnNamespace(ns)
+	}
+}
+
+func (e *Engine) OnNamespaceDeleted(name string) {
+	for _, f := range e.Factories {
+		f.OnNamespaceDeleted(name)
+	}
 }
 
 // Stop stops all registered sinks
 func (e *Engine) Stop() {
+	for _, w := range e.Watchdogs {
+		w.Stop()
+	}
 	log.Info().Msg("Closing sinks")
 	e.Registry.Close()
 	log.Info().Msg("All sinks closed")
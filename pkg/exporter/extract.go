@@ -0,0 +1,168 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// builtinExtractors can be enabled by name in the extract config, they cover the details most often needed in rules
+var builtinExtractors = map[string]ExtractorConfig{
+	"probeStatusCode": {
+		Reason: `^(?:Unhealthy|ProbeWarning)$`,
+		Regex:  `statuscode: (?P<statusCode>\d{3})`,
+	},
+	"exitCode": {
+		Regex: `(?i)exit(?:ed with)? ?code:? ?(?P<exitCode>-?\d+)`,
+	},
+	"imagePullError": {
+		Reason: `^Failed$`,
+		Regex:  `^Failed to pull image "(?P<image>[^"]+)":(?:.*code = (?P<imagePullError>\w+))?`,
+	},
+}
+
+// grokPatterns are the grok patterns that can be used as %{NAME} or %{NAME:field}
+var grokPatterns = map[string]string{
+	"INT":          `[+-]?\d+`,
+	"NUMBER":       `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"WORD":         `\w+`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IP":           `(?:\d{1,3}\.){3}\d{1,3}|[0-9A-Fa-f:]*:[0-9A-Fa-f:]+`,
+	"HOSTNAME":     `[0-9A-Za-z][0-9A-Za-z.\-]*`,
+	"DURATION":     `(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+`,
+}
+
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// grokToRegex replaces the grok references of the pattern by the regular expressions, the named ones by named groups
+func grokToRegex(pattern string) (string, error) {
+	var unknown []string
+	expr := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		m := grokReference.FindStringSubmatch(ref)
+		expr, ok := grokPatterns[m[1]]
+		if !ok {
+			unknown = append(unknown, m[1])
+			return ref
+		}
+		if m[2] == "" {
+			return "(?:" + expr + ")"
+		}
+		return "(?P<" + m[2] + ">" + expr + ")"
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown grok patterns: %s", strings.Join(unknown, ", "))
+	}
+	return expr, nil
+}
+
+// ExtractorConfig pulls fields out of the message of the events with a regular expression or a grok pattern, the
+// named groups become the fields
+type ExtractorConfig struct {
+	Name string `yaml:"name"`
+	// Reason restricts the extractor to the events whose reason matches
+	Reason string `yaml:"reason"`
+	Regex  string `yaml:"regex"`
+	Grok   string `yaml:"grok"`
+}
+
+// ExtractConfig sets the extractors, all of them run in order and a later one overwrites a field of an earlier one
+type ExtractConfig struct {
+	// Builtin is a list of built-in extractors to enable: probeStatusCode, exitCode, imagePullError
+	Builtin    []string          `yaml:"builtin"`
+	Extractors []ExtractorConfig `yaml:"extractors"`
+}
+
+type extractor struct {
+	reason  *regexp.Regexp
+	message *regexp.Regexp
+}
+
+// Extractor sets the extracted fields of the events
+type Extractor struct {
+	extractors []extractor
+}
+
+func NewExtractor(cfg *ExtractConfig) (*Extractor, error) {
+	var configs []ExtractorConfig
+	for _, name := range cfg.Builtin {
+		builtin, ok := builtinExtractors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown built-in extractor: %s", name)
+		}
+		builtin.Name = name
+		configs = append(configs, builtin)
+	}
+	configs = append(configs, cfg.Extractors...)
+	if len(configs) == 0 {
+		return nil, errors.New("there are no extractors")
+	}
+
+	e := &Extractor{}
+	for _, c := range configs {
+		if (c.Regex == "") == (c.Grok == "") {
+			return nil, fmt.Errorf("extractor %s must have either a regex or a grok pattern", c.Name)
+		}
+		expr := c.Regex
+		if c.Grok != "" {
+			var err error
+			if expr, err = grokToRegex(c.Grok); err != nil {
+				return nil, fmt.Errorf("extractor %s: %w", c.Name, err)
+			}
+		}
+		var x extractor
+		var err error
+		if x.message, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("extractor %s: %w", c.Name, err)
+		}
+		if c.Reason != "" {
+			if x.reason, err = regexp.Compile(c.Reason); err != nil {
+				return nil, fmt.Errorf("extractor %s: reason: %w", c.Name, err)
+			}
+		}
+		if !hasNamedGroup(x.message) {
+			return nil, fmt.Errorf("extractor %s has no named group", c.Name)
+		}
+		e.extractors = append(e.extractors, x)
+	}
+	return e, nil
+}
+
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract sets the fields found in the message of the event
+func (e *Extractor) Extract(ev *kube.EnhancedEvent) {
+	for _, x := range e.extractors {
+		if x.reason != nil && !x.reason.MatchString(ev.Reason) {
+			continue
+		}
+		match := x.message.FindStringSubmatch(ev.Message)
+		if match == nil {
+			continue
+		}
+		for i, name := range x.message.SubexpNames() {
+			// Optional groups that did not match are left out
+			if name == "" || match[i] == "" {
+				continue
+			}
+			if ev.Extracted == nil {
+				ev.Extracted = make(map[string]string)
+			}
+			ev.Extracted[name] = match[i]
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestExtractor_Builtin(t *testing.T) {
+	e, err := NewExtractor(&ExtractConfig{Builtin: []string{"probeStatusCode", "exitCode", "imagePullError"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		reason, message string
+		want            map[string]string
+	}{
+		{"Unhealthy", "Readiness probe failed: HTTP probe failed with statuscode: 503", map[string]string{"statusCode": "503"}},
+		{"Killing", "Container api exited with code 137", map[string]string{"exitCode": "137"}},
+		{
+			"Failed",
+			`Failed to pull image "nginx:nope": rpc error: code = NotFound desc = failed to pull and unpack image`,
+			map[string]string{"image": "nginx:nope", "imagePullError": "NotFound"},
+		},
+		{"Failed", `Failed to pull image "private/app:1":`, map[string]string{"image": "private/app:1"}},
+		{"Scheduled", "Successfully assigned default/nginx to node-1", nil},
+	}
+	for _, tt := range tests {
+		ev := &kube.EnhancedEvent{}
+		ev.Reason = tt.reason
+		ev.Message = tt.message
+		e.Extract(ev)
+		assert.Equal(t, tt.want, ev.Extracted, tt.message)
+	}
+}
+
+func TestExtractor_Grok(t *testing.T) {
+	e, err := NewExtractor(&ExtractConfig{Extractors: []ExtractorConfig{
+		{Name: "backup", Reason: "^BackupFailed$", Grok: `backup %{NOTSPACE:backup} failed after %{DURATION:duration}`},
+	}})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "BackupFailed"
+	ev.Message = "backup nightly-42 failed after 1h2m"
+	e.Extract(ev)
+	assert.Equal(t, map[string]string{"backup": "nightly-42", "duration": "1h2m"}, ev.Extracted)
+
+	rule := Rule{Extracted: map[string]string{"backup": "^nightly-"}}
+	assert.True(t, rule.MatchesEvent(ev))
+	rule.Extracted["duration"] = "^5m$"
+	assert.False(t, rule.MatchesEvent(ev))
+
+	for _, cfg := range []ExtractConfig{
+		{Builtin: []string{"unknown"}},
+		{Extractors: []ExtractorConfig{{Name: "both", Regex: "(?P<a>a)", Grok: "%{WORD:a}"}}},
+		{Extractors: []ExtractorConfig{{Name: "unnamed", Regex: "(a)"}}},
+		{Extractors: []ExtractorConfig{{Name: "unknown", Grok: "%{NOPE:a}"}}},
+	} {
+		_, err := NewExtractor(&cfg)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,370 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const (
+	// falcoSubscribeMethod is the bidirectional stream of the outputs service of Falco
+	falcoSubscribeMethod = "/falco.outputs.service/sub"
+	falcoRetryInterval   = 10 * time.Second
+)
+
+// falcoPriorities are the priorities of Falco by their number in the protocol, the most severe first
+var falcoPriorities = []string{"emergency", "alert", "critical", "error", "warning", "notice", "informational", "debug"}
+
+// FalcoConfig subscribes to the alerts of Falco over its gRPC output, so the runtime security alerts go through the
+// same routes and receivers as the events. The alerts about a pod involve the pod, the others the node.
+type FalcoConfig struct {
+	// Address is host:port with mutual TLS, or unix:///run/falco/falco.sock
+	Address string   `yaml:"address"`
+	TLS     FalcoTLS `yaml:"tls"`
+	// MinPriority drops the less severe alerts, debug by default
+	MinPriority string `yaml:"minPriority,omitempty"`
+}
+
+// FalcoTLS are the client certificate and the CA of the gRPC server of Falco, it requires mutual TLS
+type FalcoTLS struct {
+	CAFile   string `yaml:"caFile"`
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+func (c *FalcoConfig) validate() error {
+	if c.Address == "" {
+		return errors.New("address must be set")
+	}
+	if !strings.HasPrefix(c.Address, "unix://") && (c.TLS.CAFile == "" || c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return errors.New("tls.caFile, tls.certFile and tls.keyFile must be set, Falco requires mutual TLS over the network")
+	}
+	if c.MinPriority != "" && falcoPriority(c.MinPriority) < 0 {
+		return fmt.Errorf("minPriority must be one of %s, got %q", strings.Join(falcoPriorities, ", "), c.MinPriority)
+	}
+	return nil
+}
+
+func falcoPriority(name string) int {
+	for i, p := range falcoPriorities {
+		if strings.EqualFold(p, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Falco receives the alerts of Falco
+type Falco struct {
+	cfg         *FalcoConfig
+	onEvent     func(*kube.EnhancedEvent)
+	minPriority int
+}
+
+func NewFalco(cfg *FalcoConfig, onEvent func(*kube.EnhancedEvent)) (*Falco, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	minPriority := len(falcoPriorities) - 1
+	if cfg.MinPriority != "" {
+		minPriority = falcoPriority(cfg.MinPriority)
+	}
+	return &Falco{cfg: cfg, onEvent: onEvent, minPriority: minPriority}, nil
+}
+
+func (f *Falco) credentials() (credentials.TransportCredentials, error) {
+	if strings.HasPrefix(f.cfg.Address, "unix://") {
+		return insecure.NewCredentials(), nil
+	}
+	cfg, err := f.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+func (f *Falco) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(f.cfg.TLS.CertFile, f.cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(f.cfg.TLS.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificate found in tls.caFile")
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool, MinVersion: tls.VersionTLS12}
+	sinks.ApplyTLSPolicy(cfg)
+	return cfg, nil
+}
+
+// Run subscribes to the alerts until the context is done, the subscription is renewed when it fails
+func (f *Falco) Run(ctx context.Context) error {
+	creds, err := f.credentials()
+	if err != nil {
+		return fmt.Errorf("cannot load the falco TLS config: %w", err)
+	}
+	conn, err := grpc.DialContext(ctx, f.cfg.Address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		err := f.subscribe(ctx, conn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Error().Err(err).Str("address", f.cfg.Address).Msg("Falco subscription failed, retrying")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(falcoRetryInterval):
+		}
+	}
+}
+
+func (f *Falco) subscribe(ctx context.Context, conn *grpc.ClientConn) error {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, falcoSubscribeMethod)
+	if err != nil {
+		return err
+	}
+	// The request is empty, it starts the subscription
+	if err := stream.SendMsg(&rawMessage{}); err != nil {
+		return err
+	}
+	log.Info().Str("address", f.cfg.Address).Msg("Subscribed to the Falco alerts")
+	for {
+		var msg rawMessage
+		if err := stream.RecvMsg(&msg); err != nil {
+			return err
+		}
+		alert, err := decodeFalcoAlert(msg)
+		if err != nil {
+			log.Warn().Err(err).Msg("Cannot decode a Falco alert")
+			continue
+		}
+		if falcoPriority(alert.priority) > f.minPriority {
+			continue
+		}
+		f.onEvent(alert.event())
+	}
+}
+
+// rawMessage is a protobuf message that is encoded and decoded by hand, Falco has no Go types in this module
+type rawMessage []byte
+
+// rawCodec passes the raw messages through
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *v.(*rawMessage), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*rawMessage) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// falcoAlert is the response of the outputs service
+type falcoAlert struct {
+	time     time.Time
+	priority string
+	source   string
+	rule     string
+	output   string
+	fields   map[string]string
+	hostname string
+	tags     []string
+}
+
+// falcoSources are the sources of the deprecated enum field
+var falcoSources = []string{"syscall", "k8s_audit", "internal", "plugins"}
+
+// decodeFalcoAlert decodes the response message: time = 1, priority = 2, the deprecated source enum = 3, rule = 4,
+// output = 5, output_fields = 6, hostname = 7, tags = 8 and source = 9
+func decodeFalcoAlert(b []byte) (*falcoAlert, error) {
+	alert := &falcoAlert{fields: make(map[string]string), priority: falcoPriorities[0]}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			if num == 2 && int(v) < len(falcoPriorities) {
+				alert.priority = falcoPriorities[v]
+			}
+			if num == 3 && alert.source == "" && int(v) < len(falcoSources) {
+				alert.source = falcoSources[v]
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case 1:
+				seconds, nanos := decodeTimestamp(v)
+				alert.time = time.Unix(seconds, nanos)
+			case 4:
+				alert.rule = string(v)
+			case 5:
+				alert.output = string(v)
+			case 6:
+				key, value := decodeMapEntry(v)
+				alert.fields[key] = value
+			case 7:
+				alert.hostname = string(v)
+			case 8:
+				alert.tags = append(alert.tags, string(v))
+			case 9:
+				alert.source = string(v)
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	if alert.rule == "" {
+		return nil, errors.New("the alert has no rule")
+	}
+	return alert, nil
+}
+
+// decodeTimestamp decodes a google.protobuf.Timestamp
+func decodeTimestamp(b []byte) (seconds int64, nanos int64) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.VarintType {
+			return seconds, nanos
+		}
+		v, m := protowire.ConsumeVarint(b[n:])
+		if m < 0 {
+			return seconds, nanos
+		}
+		b = b[n+m:]
+		switch num {
+		case 1:
+			seconds = int64(v)
+		case 2:
+			nanos = int64(v)
+		}
+	}
+	return seconds, nanos
+}
+
+// decodeMapEntry decodes an entry of a map<string, string>
+func decodeMapEntry(b []byte) (key, value string) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			return key, value
+		}
+		v, m := protowire.ConsumeBytes(b[n:])
+		if m < 0 {
+			return key, value
+		}
+		b = b[n+m:]
+		switch num {
+		case 1:
+			key = string(v)
+		case 2:
+			value = string(v)
+		}
+	}
+	return key, value
+}
+
+// falcoReason turns the rule into a reason like the ones of Kubernetes, e.g. Terminal shell in container becomes
+// TerminalShellInContainer
+func falcoReason(rule string) string {
+	var sb strings.Builder
+	for _, word := range strings.FieldsFunc(rule, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		runes := []rune(word)
+		sb.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+	return sb.String()
+}
+
+// event normalizes the alert, with the rule, the priority, the source and the tags as extracted fields
+func (a *falcoAlert) event() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	timestamp := a.time
+	if timestamp.IsZero() {
+		timestamp = clock.Now()
+	}
+	ev.Name = fmt.Sprintf("falco.%x", timestamp.UnixNano())
+	ev.Reason = falcoReason(a.rule)
+	ev.Message = a.output
+	ev.Type = corev1.EventTypeNormal
+	if falcoPriority(a.priority) <= falcoPriority("warning") {
+		ev.Type = corev1.EventTypeWarning
+	}
+	ev.Count = 1
+	ev.FirstTimestamp = metav1.NewTime(timestamp)
+	ev.LastTimestamp = ev.FirstTimestamp
+	ev.Source = corev1.EventSource{Component: "falco", Host: a.hostname}
+
+	ev.Extracted = map[string]string{"rule": a.rule, "priority": a.priority}
+	if a.source != "" {
+		ev.Extracted["source"] = a.source
+	}
+	if len(a.tags) > 0 {
+		tags := append([]string(nil), a.tags...)
+		sort.Strings(tags)
+		ev.Extracted["tags"] = strings.Join(tags, ",")
+	}
+	for field, name := range map[string]string{"container.name": "container", "container.image.repository": "image"} {
+		if value := a.fields[field]; value != "" {
+			ev.Extracted[name] = value
+		}
+	}
+
+	if pod := a.fields["k8s.pod.name"]; pod != "" {
+		ev.Namespace = a.fields["k8s.ns.name"]
+		ev.InvolvedObject.ObjectReference = corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: ev.Namespace, Name: pod}
+	} else {
+		ev.InvolvedObject.ObjectReference = corev1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: a.hostname}
+	}
+	return ev
+}
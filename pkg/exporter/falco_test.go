@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func appendFalcoString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// encodeFalcoAlert encodes a response of the outputs service of Falco
+func encodeFalcoAlert(priority uint64, rule, output string, fields map[string]string, tags ...string) []byte {
+	var timestamp []byte
+	timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 1700000000)
+	timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, 500)
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, timestamp)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, priority)
+	b = appendFalcoString(b, 4, rule)
+	b = appendFalcoString(b, 5, output)
+	for key, value := range fields {
+		var entry []byte
+		entry = appendFalcoString(entry, 1, key)
+		entry = appendFalcoString(entry, 2, value)
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	b = appendFalcoString(b, 7, "node-1")
+	for _, tag := range tags {
+		b = appendFalcoString(b, 8, tag)
+	}
+	return appendFalcoString(b, 9, "syscall")
+}
+
+func TestDecodeFalcoAlert(t *testing.T) {
+	alert, err := decodeFalcoAlert(encodeFalcoAlert(4, "Terminal shell in container", "A shell was spawned", map[string]string{
+		"k8s.ns.name":                "shop",
+		"k8s.pod.name":               "checkout-0",
+		"container.name":             "app",
+		"container.image.repository": "shop/checkout",
+	}, "shell", "container", "mitre_execution"))
+	require.NoError(t, err)
+	assert.Equal(t, "warning", alert.priority)
+	assert.Equal(t, time.Unix(1700000000, 500), alert.time)
+
+	ev := alert.event()
+	assert.Equal(t, "TerminalShellInContainer", ev.Reason)
+	assert.Equal(t, "A shell was spawned", ev.Message)
+	assert.Equal(t, "Warning", ev.Type)
+	assert.Equal(t, "falco", ev.Source.Component)
+	assert.Equal(t, "node-1", ev.Source.Host)
+	assert.Equal(t, "shop", ev.Namespace)
+	assert.Equal(t, "Pod", ev.InvolvedObject.Kind)
+	assert.Equal(t, "checkout-0", ev.InvolvedObject.Name)
+	assert.Equal(t, map[string]string{
+		"rule":      "Terminal shell in container",
+		"priority":  "warning",
+		"source":    "syscall",
+		"tags":      "container,mitre_execution,shell",
+		"container": "app",
+		"image":     "shop/checkout",
+	}, ev.Extracted)
+
+	// The alerts outside of pods involve the node
+	alert, err = decodeFalcoAlert(encodeFalcoAlert(5, "Read sensitive file untrusted", "/etc/shadow opened", nil))
+	require.NoError(t, err)
+	ev = alert.event()
+	assert.Equal(t, "Normal", ev.Type)
+	assert.Equal(t, "Node", ev.InvolvedObject.Kind)
+	assert.Equal(t, "node-1", ev.InvolvedObject.Name)
+
+	_, err = decodeFalcoAlert([]byte{0xff})
+	assert.Error(t, err)
+}
+
+func TestFalco_Subscribe(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "falco.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			assert.Equal(t, falcoSubscribeMethod, method)
+			var req rawMessage
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			for _, alert := range []rawMessage{
+				encodeFalcoAlert(2, "Drop and execute new binary", "binary executed", nil),
+				encodeFalcoAlert(7, "Debug rule", "debug output", nil),
+			} {
+				if err := stream.SendMsg(&alert); err != nil {
+					return err
+				}
+			}
+			<-stream.Context().Done()
+			return nil
+		}),
+	)
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	var mu sync.Mutex
+	var received []*kube.EnhancedEvent
+	falco, err := NewFalco(&FalcoConfig{Address: "unix://" + socket, MinPriority: "notice"}, func(ev *kube.EnhancedEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ev)
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- falco.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	// The debug alert is below the minimum priority
+	require.Len(t, received, 1)
+	assert.Equal(t, "DropAndExecuteNewBinary", received[0].Reason)
+}
+
+func TestFalcoConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&FalcoConfig{Address: "unix:///run/falco/falco.sock"}).validate())
+	assert.Error(t, (&FalcoConfig{}).validate())
+	assert.Error(t, (&FalcoConfig{Address: "falco:5060"}).validate())
+	assert.NoError(t, (&FalcoConfig{Address: "falco:5060", TLS: FalcoTLS{CAFile: "ca.crt", CertFile: "tls.crt", KeyFile: "tls.key"}}).validate())
+	assert.Error(t, (&FalcoConfig{Address: "unix:///run/falco/falco.sock", MinPriority: "severe"}).validate())
+}
+
+func TestFalco_TLSPolicy(t *testing.T) {
+	certFile, keyFile := writeTestKeyPair(t)
+	setTestTLSPolicy(t, &sinks.TLSPolicy{MinVersion: "1.3"})
+
+	f, err := NewFalco(&FalcoConfig{Address: "falco:5060", TLS: FalcoTLS{CAFile: certFile, CertFile: certFile, KeyFile: keyFile}}, nil)
+	require.NoError(t, err)
+	cfg, err := f.tlsConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+}
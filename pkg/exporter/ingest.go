@@ -0,0 +1,270 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/version"
+)
+
+const (
+	defaultIngestMaxBodyBytes = 64 << 20
+	ingestShutdownTimeout     = 5 * time.Second
+)
+
+// IngestConfig makes the exporter an aggregator receiving the events of agents, exporters with an aggregator
+// receiver. The events go through the same route as the watched ones.
+type IngestConfig struct {
+	Address string        `yaml:"address"`
+	Agents  []IngestAgent `yaml:"agents"`
+	TLS     IngestTLS     `yaml:"tls"`
+	// MaxBodyBytes limits the decompressed size of a batch
+	MaxBodyBytes int64 `yaml:"maxBodyBytes,omitempty"`
+}
+
+// IngestAgent is an agent allowed to send events. The events without a cluster name get the name of the agent, the
+// others must carry the name of the agent or one of its Clusters, so an agent can't send events as another cluster.
+type IngestAgent struct {
+	Name     string   `yaml:"name"`
+	Token    string   `yaml:"token"`
+	Clusters []string `yaml:"clusters,omitempty"`
+}
+
+// allows tells whether the agent may send the events of the cluster
+func (a *IngestAgent) allows(cluster string) bool {
+	return cluster == a.Name || slices.Contains(a.Clusters, cluster)
+}
+
+// IngestTLS serves the endpoint over TLS, with a ClientCAFile the agents must present a certificate signed by it
+type IngestTLS struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+func (c *IngestConfig) validate() error {
+	if c.Address == "" {
+		return errors.New("address must be set")
+	}
+	if len(c.Agents) == 0 {
+		return errors.New("at least one agent must be set")
+	}
+	names := make(map[string]bool, len(c.Agents))
+	for i, agent := range c.Agents {
+		if agent.Name == "" || agent.Token == "" {
+			return fmt.Errorf("agents[%d] must have a name and a token", i)
+		}
+		if names[agent.Name] {
+			return fmt.Errorf("agent %q is defined twice", agent.Name)
+		}
+		names[agent.Name] = true
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return errors.New("tls.certFile and tls.keyFile must be set together")
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		return errors.New("tls.clientCAFile requires tls.certFile")
+	}
+	if c.MaxBodyBytes < 0 {
+		return errors.New("maxBodyBytes must not be negative")
+	}
+	return nil
+}
+
+// Ingest receives the batches of the agents
+type Ingest struct {
+	cfg     *IngestConfig
+	onEvent func(*kube.EnhancedEvent)
+}
+
+func NewIngest(cfg *IngestConfig, onEvent func(*kube.EnhancedEvent)) (*Ingest, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = defaultIngestMaxBodyBytes
+	}
+	return &Ingest{cfg: cfg, onEvent: onEvent}, nil
+}
+
+// agent returns the agent the request is authenticated as
+func (i *Ingest) agent(r *http.Request) (*IngestAgent, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	for n := range i.cfg.Agents {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(i.cfg.Agents[n].Token)) == 1 {
+			return &i.cfg.Agents[n], true
+		}
+	}
+	return nil, false
+}
+
+func (i *Ingest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	agent, ok := i.agent(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodGet {
+		i.handshake(w, r, agent.Name)
+		return
+	}
+
+	// The agents predating the handshake send no schema version
+	if schema := r.Header.Get(sinks.WireSchemaHeader); schema != "" {
+		v, err := strconv.Atoi(schema)
+		if err != nil || !slices.Contains(sinks.WireSchemaVersions, v) {
+			http.Error(w, "unsupported schema version "+schema, http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	var body io.Reader
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+		body = r.Body
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	case "zstd":
+		zr, err := zstd.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, i.cfg.MaxBodyBytes+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > i.cfg.MaxBodyBytes {
+		http.Error(w, "batch too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var events []*kube.EnhancedEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The whole batch is rejected before any event is routed, the agent would retry the accepted part otherwise
+	for _, ev := range events {
+		if ev.ClusterName == "" {
+			ev.ClusterName = agent.Name
+		}
+		if !agent.allows(ev.ClusterName) {
+			log.Warn().Str("agent", agent.Name).Str("cluster", ev.ClusterName).Msg("Rejected a batch with the events of another cluster")
+			http.Error(w, "agent "+agent.Name+" may not send the events of cluster "+ev.ClusterName, http.StatusForbidden)
+			return
+		}
+	}
+	for _, ev := range events {
+		i.onEvent(ev)
+	}
+	log.Debug().Str("agent", agent.Name).Int("events", len(events)).Msg("Ingested a batch")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handshake tells the agent the schema versions and the compressions the aggregator supports
+func (i *Ingest) handshake(w http.ResponseWriter, r *http.Request, agent string) {
+	log.Debug().Str("agent", agent).Str("schemaVersions", r.Header.Get(sinks.WireSchemaHeader)).Msg("Handshake of an agent")
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sinks.WireHandshake{
+		SchemaVersions: sinks.WireSchemaVersions,
+		Compressions:   sinks.WireCompressions,
+		Version:        version.Version,
+	})
+}
+
+func (i *Ingest) tlsConfig() (*tls.Config, error) {
+	if i.cfg.TLS.CertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(i.cfg.TLS.CertFile, i.cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if i.cfg.TLS.ClientCAFile != "" {
+		ca, err := os.ReadFile(i.cfg.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificate found in tls.clientCAFile")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	sinks.ApplyTLSPolicy(cfg)
+	return cfg, nil
+}
+
+// Serve listens on the address until the context is done
+func (i *Ingest) Serve(ctx context.Context) error {
+	tlsConfig, err := i.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("cannot set up TLS: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/ingest", i)
+	server := &http.Server{
+		Addr:              i.cfg.Address,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ingestShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if tlsConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package exporter
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func TestIngest_FromAggregatorSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []*kube.EnhancedEvent
+	ingest, err := NewIngest(&IngestConfig{
+		Address: ":0",
+		Agents:  []IngestAgent{{Name: "edge-1", Token: "token-1"}, {Name: "edge-2", Token: "token-2", Clusters: []string{"dev-cluster"}}},
+	}, func(ev *kube.EnhancedEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ev)
+	})
+	require.NoError(t, err)
+	ts := httptest.NewServer(ingest)
+	defer ts.Close()
+
+	for _, compression := range []string{"", sinks.CompressionGzip} {
+		sink, err := sinks.NewAggregatorSink(&sinks.AggregatorConfig{
+			Endpoint:    ts.URL + "/api/v1/ingest",
+			Token:       "token-2",
+			Compression: compression,
+		})
+		require.NoError(t, err)
+		require.NoError(t, sink.Send(context.Background(), newTestEvent("prod", "api-0", "BackOff")))
+		ev := newTestEvent("dev", "api-1", "Pulled")
+		ev.ClusterName = "dev-cluster"
+		require.NoError(t, sink.Send(context.Background(), ev))
+		// Closing flushes the batch
+		sink.Close()
+	}
+
+	require.Len(t, received, 4)
+	assert.Equal(t, "edge-2", received[0].ClusterName)
+	assert.Equal(t, "BackOff", received[0].Reason)
+	assert.Equal(t, "api-0", received[0].InvolvedObject.Name)
+	assert.Equal(t, "dev-cluster", received[1].ClusterName)
+}
+
+func TestIngest_Rejects(t *testing.T) {
+	ingest, err := NewIngest(&IngestConfig{
+		Address:      ":0",
+		Agents:       []IngestAgent{{Name: "edge-1", Token: "token-1"}, {Name: "edge-2", Token: "token-2"}},
+		MaxBodyBytes: 32,
+	}, func(*kube.EnhancedEvent) { t.Fatal("unexpected event") })
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		token, encoding, schema, body string
+		status                        int
+	}{
+		{"wrong", "", "", "[]", http.StatusUnauthorized},
+		{"token-1", "br", "", "[]", http.StatusUnsupportedMediaType},
+		{"token-1", "", "99", "[]", http.StatusUnsupportedMediaType},
+		{"token-1", "", "", `[{"reason":"BackOff","message":"Back-off"}]`, http.StatusRequestEntityTooLarge},
+		{"token-1", "", "", "{", http.StatusBadRequest},
+		// The name of another agent is as foreign as any other cluster
+		{"token-1", "", "", `[{"clusterName":"edge-2"}]`, http.StatusForbidden},
+		{"token-1", "", "", `[{},{"clusterName":"x"}]`, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest", strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		req.Header.Set("Content-Encoding", tc.encoding)
+		req.Header.Set(sinks.WireSchemaHeader, tc.schema)
+		rec := httptest.NewRecorder()
+		ingest.ServeHTTP(rec, req)
+		assert.Equal(t, tc.status, rec.Code, tc)
+	}
+
+	_, err = NewIngest(&IngestConfig{Address: ":0", Agents: []IngestAgent{{Name: "a", Token: "x"}, {Name: "a", Token: "y"}}}, nil)
+	assert.Error(t, err)
+}
+
+func TestIngest_Handshake(t *testing.T) {
+	ingest, err := NewIngest(&IngestConfig{Address: ":0", Agents: []IngestAgent{{Name: "edge-1", Token: "token-1"}}}, nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ingest", nil)
+	req.Header.Set("Authorization", "Bearer token-1")
+	rec := httptest.NewRecorder()
+	ingest.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var handshake sinks.WireHandshake
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &handshake))
+	assert.Equal(t, sinks.WireSchemaVersions, handshake.SchemaVersions)
+	assert.Equal(t, sinks.WireCompressions, handshake.Compressions)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ingest", nil)
+	rec = httptest.NewRecorder()
+	ingest.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// writeTestKeyPair writes a self-signed certificate, which can also be used as its own CA, and its key
+func writeTestKeyPair(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "event-exporter"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// setTestTLSPolicy activates the policy until the end of the test
+func setTestTLSPolicy(t *testing.T, p *sinks.TLSPolicy) {
+	transport := http.DefaultTransport.(*http.Transport)
+	prev := transport.TLSClientConfig
+	if prev != nil {
+		transport.TLSClientConfig = prev.Clone()
+	}
+	require.NoError(t, sinks.SetTLSPolicy(p))
+	t.Cleanup(func() {
+		transport.TLSClientConfig = prev
+		_ = sinks.SetTLSPolicy(nil)
+	})
+}
+
+func TestIngest_TLSPolicy(t *testing.T) {
+	certFile, keyFile := writeTestKeyPair(t)
+	setTestTLSPolicy(t, &sinks.TLSPolicy{MinVersion: "1.3"})
+
+	ingest, err := NewIngest(&IngestConfig{
+		Address: ":0",
+		Agents:  []IngestAgent{{Name: "edge-1", Token: "token-1"}},
+		TLS:     IngestTLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile},
+	}, nil)
+	require.NoError(t, err)
+	cfg, err := ingest.tlsConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+}
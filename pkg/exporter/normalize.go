@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// ReasonMapping normalizes the events whose reason and message match the patterns. The named groups of the message
+// pattern can be used in the normalized reason and the fields, like ${probe}.
+type ReasonMapping struct {
+	Reason  string `yaml:"reason"`
+	Message string `yaml:"message"`
+	// Normalized is the stable reason, the reason of the event if empty
+	Normalized string            `yaml:"normalized"`
+	Category   string            `yaml:"category"`
+	Fields     map[string]string `yaml:"fields"`
+}
+
+// NormalizeConfig sets the reason mappings, they are tried in order before the built-in ones
+type NormalizeConfig struct {
+	Mappings []ReasonMapping `yaml:"mappings"`
+	// DisableBuiltin only keeps the mappings of the config
+	DisableBuiltin bool `yaml:"disableBuiltin"`
+}
+
+// builtinReasonMappings cover the reasons of the kubelet, the scheduler and the node problem detector whose wording
+// changed between Kubernetes versions, or whose meaning is only in the message
+var builtinReasonMappings = []ReasonMapping{
+	{
+		Reason:     `^Unhealthy$`,
+		Message:    `^(?P<probe>Liveness|Readiness|Startup) probe (?:failed|errored)`,
+		Normalized: "${probe}ProbeFailed",
+		Category:   "probe",
+		Fields:     map[string]string{"probe": "${probe}"},
+	},
+	{Reason: `^Unhealthy$`, Normalized: "ProbeFailed", Category: "probe"},
+	{
+		Reason:   `^ProbeWarning$`,
+		Message:  `^(?P<probe>Liveness|Readiness|Startup) probe`,
+		Category: "probe",
+		Fields:   map[string]string{"probe": "${probe}"},
+	},
+	{
+		Reason:   `^FailedScheduling$`,
+		Message:  `(?P<available>\d+)/(?P<total>\d+) nodes (?:are )?available`,
+		Category: "scheduling",
+		Fields:   map[string]string{"availableNodes": "${available}", "totalNodes": "${total}"},
+	},
+	{Reason: `^FailedScheduling$`, Category: "scheduling"},
+	{
+		Reason:     `^BackOff$`,
+		Message:    `^Back-off restarting failed container(?: (?P<container>\S+) in pod)?`,
+		Normalized: "CrashLoopBackOff",
+		Category:   "container",
+		Fields:     map[string]string{"container": "${container}"},
+	},
+	{
+		Reason:     `^BackOff$`,
+		Message:    `^Back-off pulling image "(?P<image>[^"]+)"`,
+		Normalized: "ImagePullBackOff",
+		Category:   "image",
+		Fields:     map[string]string{"image": "${image}"},
+	},
+	{
+		Reason:     `^Failed$`,
+		Message:    `^Failed to pull image "(?P<image>[^"]+)"`,
+		Normalized: "ImagePullFailed",
+		Category:   "image",
+		Fields:     map[string]string{"image": "${image}"},
+	},
+	{
+		Reason:     `^Failed$`,
+		Message:    `^Error: (?P<error>ErrImagePull|ImagePullBackOff|ErrImageNeverPull|InvalidImageName)`,
+		Normalized: "${error}",
+		Category:   "image",
+	},
+	{Reason: `^(?:OOMKilling|OOMKilled|SystemOOM)$`, Normalized: "OOMKilled", Category: "resources"},
+	{Reason: `^(?:Evicted|Evicting)$`, Normalized: "Evicted", Category: "eviction"},
+	{Reason: `^FailedCreatePodSand[Bb]ox$`, Normalized: "FailedCreatePodSandbox", Category: "sandbox"},
+	{Reason: `^(?:FailedMount|FailedAttachVolume|FailedMapVolume)$`, Category: "volume"},
+	{Reason: `^(?:NodeNotReady|NodeNotSchedulable)$`, Category: "node"},
+}
+
+type reasonMatcher struct {
+	mapping *ReasonMapping
+	reason  *regexp.Regexp
+	message *regexp.Regexp
+}
+
+// Normalizer sets the normalized reason of the events, the first matching mapping wins
+type Normalizer struct {
+	matchers []reasonMatcher
+}
+
+func NewNormalizer(cfg *NormalizeConfig) (*Normalizer, error) {
+	mappings := cfg.Mappings
+	if !cfg.DisableBuiltin {
+		mappings = append(append([]ReasonMapping{}, mappings...), builtinReasonMappings...)
+	}
+	n := &Normalizer{}
+	for i := range mappings {
+		m := reasonMatcher{mapping: &mappings[i]}
+		if m.mapping.Reason == "" && m.mapping.Message == "" {
+			return nil, fmt.Errorf("mappings[%d] must have a reason or a message pattern", i)
+		}
+		var err error
+		if m.mapping.Reason != "" {
+			if m.reason, err = regexp.Compile(m.mapping.Reason); err != nil {
+				return nil, fmt.Errorf("mappings[%d].reason: %w", i, err)
+			}
+		}
+		if m.mapping.Message != "" {
+			if m.message, err = regexp.Compile(m.mapping.Message); err != nil {
+				return nil, fmt.Errorf("mappings[%d].message: %w", i, err)
+			}
+		}
+		n.matchers = append(n.matchers, m)
+	}
+	if len(n.matchers) == 0 {
+		return nil, errors.New("there are no mappings")
+	}
+	return n, nil
+}
+
+// Normalize sets the normalized reason of the event, the reason of the event itself if no mapping matches
+func (n *Normalizer) Normalize(ev *kube.EnhancedEvent) {
+	for _, m := range n.matchers {
+		if m.reason != nil && !m.reason.MatchString(ev.Reason) {
+			continue
+		}
+		var match []int
+		if m.message != nil {
+			if match = m.message.FindStringSubmatchIndex(ev.Message); match == nil {
+				continue
+			}
+		}
+		expand := func(tmpl string) string {
+			if m.message == nil {
+				return tmpl
+			}
+			return string(m.message.ExpandString(nil, tmpl, ev.Message, match))
+		}
+
+		normalized := kube.NormalizedReason{Reason: ev.Reason, Category: m.mapping.Category}
+		if m.mapping.Normalized != "" {
+			if reason := expand(m.mapping.Normalized); reason != "" {
+				normalized.Reason = reason
+			}
+		}
+		for name, tmpl := range m.mapping.Fields {
+			// Optional groups that did not match are left out
+			if value := expand(tmpl); value != "" {
+				if normalized.Fields == nil {
+					normalized.Fields = make(map[string]string, len(m.mapping.Fields))
+				}
+				normalized.Fields[name] = value
+			}
+		}
+		ev.Normalized = normalized
+		return
+	}
+	ev.Normalized = kube.NormalizedReason{Reason: ev.Reason}
+}
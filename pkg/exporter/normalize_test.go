@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestNormalizer_Builtin(t *testing.T) {
+	n, err := NewNormalizer(&NormalizeConfig{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		reason, message string
+		want            kube.NormalizedReason
+	}{
+		{
+			"Unhealthy", "Readiness probe failed: HTTP probe failed with statuscode: 503",
+			kube.NormalizedReason{Reason: "ReadinessProbeFailed", Category: "probe", Fields: map[string]string{"probe": "Readiness"}},
+		},
+		{
+			"Unhealthy", "Liveness probe errored: rpc error",
+			kube.NormalizedReason{Reason: "LivenessProbeFailed", Category: "probe", Fields: map[string]string{"probe": "Liveness"}},
+		},
+		{
+			"FailedScheduling", "0/3 nodes are available: 3 Insufficient cpu.",
+			kube.NormalizedReason{Reason: "FailedScheduling", Category: "scheduling", Fields: map[string]string{"availableNodes": "0", "totalNodes": "3"}},
+		},
+		{
+			"FailedScheduling", "0/5 nodes available: insufficient memory",
+			kube.NormalizedReason{Reason: "FailedScheduling", Category: "scheduling", Fields: map[string]string{"availableNodes": "0", "totalNodes": "5"}},
+		},
+		{
+			"BackOff", "Back-off restarting failed container",
+			kube.NormalizedReason{Reason: "CrashLoopBackOff", Category: "container"},
+		},
+		{
+			"BackOff", "Back-off restarting failed container nginx in pod nginx_default(1234)",
+			kube.NormalizedReason{Reason: "CrashLoopBackOff", Category: "container", Fields: map[string]string{"container": "nginx"}},
+		},
+		{
+			"Failed", "Error: ErrImagePull",
+			kube.NormalizedReason{Reason: "ErrImagePull", Category: "image"},
+		},
+		{"OOMKilling", "Memory cgroup out of memory", kube.NormalizedReason{Reason: "OOMKilled", Category: "resources"}},
+		{"Scheduled", "Successfully assigned default/nginx", kube.NormalizedReason{Reason: "Scheduled"}},
+	}
+	for _, tt := range tests {
+		ev := &kube.EnhancedEvent{}
+		ev.Reason = tt.reason
+		ev.Message = tt.message
+		n.Normalize(ev)
+		assert.Equal(t, tt.want, ev.Normalized, tt.message)
+	}
+}
+
+func TestNormalizer_Mappings(t *testing.T) {
+	n, err := NewNormalizer(&NormalizeConfig{
+		Mappings: []ReasonMapping{
+			{
+				Reason:     "^BackOff$",
+				Message:    `container (?P<container>\S+)`,
+				Normalized: "Crashing",
+				Category:   "app",
+				Fields:     map[string]string{"container": "$container"},
+			},
+			{Reason: "^Drift$", Category: "gitops"},
+		},
+		DisableBuiltin: true,
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container api in pod api-0"
+	n.Normalize(ev)
+	assert.Equal(t, kube.NormalizedReason{Reason: "Crashing", Category: "app", Fields: map[string]string{"container": "api"}}, ev.Normalized)
+
+	ev.Reason = "Unhealthy"
+	ev.Message = "Readiness probe failed"
+	n.Normalize(ev)
+	assert.Equal(t, kube.NormalizedReason{Reason: "Unhealthy"}, ev.Normalized, "the built-in mappings are disabled")
+
+	rule := Rule{Category: "^gitops$"}
+	ev.Reason = "Drift"
+	n.Normalize(ev)
+	assert.True(t, rule.MatchesEvent(ev))
+
+	_, err = NewNormalizer(&NormalizeConfig{Mappings: []ReasonMapping{{Normalized: "Any"}}})
+	assert.Error(t, err)
+	_, err = NewNormalizer(&NormalizeConfig{Mappings: []ReasonMapping{{Reason: "("}}})
+	assert.Error(t, err)
+}
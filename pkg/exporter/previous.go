@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const defaultPreviousKey = "{{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}/{{ .Reason }}"
+
+// PreviousConfig enables remembering the occurrences of events in the state store, so that templates can refer to the
+// previous occurrence of an event with the same key as .Previous
+type PreviousConfig struct {
+	// Key is the template identifying repeated events, by default the involved object and the reason
+	Key string `yaml:"key"`
+	// TTLSeconds is how long an occurrence is remembered, defaults to a day
+	TTLSeconds int64 `yaml:"ttlSeconds"`
+}
+
+// PreviousTracker fills .Previous of the events and records them as the latest occurrence
+type PreviousTracker struct {
+	key   string
+	ttl   time.Duration
+	store sinks.StateStore
+}
+
+func NewPreviousTracker(cfg *PreviousConfig, store sinks.StateStore) (*PreviousTracker, error) {
+	key := cfg.Key
+	if key == "" {
+		key = defaultPreviousKey
+	}
+	if _, err := sinks.GetString(&kube.EnhancedEvent{}, key); err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	ttl := time.Duration(cfg.TTLSeconds) * time.Second
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &PreviousTracker{key: key, ttl: ttl, store: store}, nil
+}
+
+func (t *PreviousTracker) Track(ev *kube.EnhancedEvent) {
+	key, err := sinks.GetString(ev, t.key)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot render the key of the event occurrence")
+		return
+	}
+	key = "previous/" + key
+
+	var count int64
+	if values, ok := t.store.Get(key); ok {
+		count, _ = strconv.ParseInt(values["count"], 10, 64)
+		if lastSeen, err := time.Parse(time.RFC3339Nano, values["lastSeen"]); err == nil {
+			ev.Previous = kube.Occurrence{Count: count, LastSeen: lastSeen}
+		}
+	}
+
+	err = t.store.Set(key, map[string]string{
+		"count":    strconv.FormatInt(count+1, 10),
+		"lastSeen": clock.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err == nil {
+		err = t.store.Expire(key, t.ttl)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot record the event occurrence")
+	}
+}
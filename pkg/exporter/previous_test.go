@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func TestPreviousTracker(t *testing.T) {
+	store := sinks.NewInMemoryStateStore()
+	tracker, err := NewPreviousTracker(&PreviousConfig{}, store)
+	require.NoError(t, err)
+
+	newEvent := func() *kube.EnhancedEvent {
+		ev := &kube.EnhancedEvent{}
+		ev.InvolvedObject.Namespace = "default"
+		ev.InvolvedObject.Kind = "Pod"
+		ev.InvolvedObject.Name = "nginx"
+		ev.Reason = "BackOff"
+		return ev
+	}
+
+	first := newEvent()
+	tracker.Track(first)
+	require.Zero(t, first.Previous.Count)
+	require.Empty(t, first.Previous.Ago())
+
+	second := newEvent()
+	tracker.Track(second)
+	require.Equal(t, int64(1), second.Previous.Count)
+	require.WithinDuration(t, time.Now(), second.Previous.LastSeen, time.Second)
+	require.Equal(t, "0s", second.Previous.Ago())
+
+	other := newEvent()
+	other.Reason = "Killing"
+	tracker.Track(other)
+	require.Zero(t, other.Previous.Count)
+
+	// Forgotten once the key expires
+	require.NoError(t, store.Expire("previous/default/Pod/nginx/BackOff", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	third := newEvent()
+	tracker.Track(third)
+	require.Zero(t, third.Previous.Count)
+}
+
+func TestPreviousTrackerInvalidKey(t *testing.T) {
+	_, err := NewPreviousTracker(&PreviousConfig{Key: "{{ .Reason"}, sinks.NewInMemoryStateStore())
+	require.Error(t, err)
+}
+
+func TestOccurrenceAgo(t *testing.T) {
+	require.Equal(t, "2h", kube.Occurrence{LastSeen: time.Now().Add(-2*time.Hour - time.Minute)}.Ago())
+	require.Equal(t, "3d", kube.Occurrence{LastSeen: time.Now().Add(-73 * time.Hour)}.Ago())
+	require.Equal(t, "5m", kube.Occurrence{LastSeen: time.Now().Add(-5*time.Minute - time.Second)}.Ago())
+}
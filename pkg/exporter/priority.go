@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"sync"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// Priority decides the order in which the queued events of a receiver are delivered, lower values go first
+type Priority int
+
+const (
+	PriorityCritical Priority = iota
+	PriorityHigh
+	PriorityNormal
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// PriorityConfig assigns a priority to events with rules, an event matching any of the critical rules is critical,
+// otherwise one matching any of the high rules is high, and all other events are normal. Without high rules, Warning
+// events are high.
+type PriorityConfig struct {
+	Critical []Rule `yaml:"critical"`
+	High     []Rule `yaml:"high"`
+}
+
+var defaultHighPriorityRules = []Rule{{Type: "Warning"}}
+
+type Prioritizer struct {
+	critical []Rule
+	high     []Rule
+}
+
+func NewPrioritizer(cfg *PriorityConfig) *Prioritizer {
+	p := &Prioritizer{high: defaultHighPriorityRules}
+	if cfg != nil {
+		p.critical = cfg.Critical
+		if len(cfg.High) > 0 {
+			p.high = cfg.High
+		}
+	}
+	return p
+}
+
+func (p *Prioritizer) Priority(ev *kube.EnhancedEvent) Priority {
+	if matchesAnyRule(p.critical, ev) {
+		return PriorityCritical
+	}
+	if matchesAnyRule(p.high, ev) {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+func matchesAnyRule(rules []Rule, ev *kube.EnhancedEvent) bool {
+	for i := range rules {
+		if rules[i].MatchesEvent(ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityQueue is an unbounded FIFO queue per priority. The receiver loop is woken up through notify whenever an
+// event is pushed.
+type priorityQueue struct {
+	mu     sync.Mutex
+	queues [numPriorities][]kube.EnhancedEvent
+	notify chan struct{}
+	// closed is set once the queue is drained, later events are rejected
+	closed bool
+	// onDepth is called with the new queue depth whenever it changes, while holding the lock
+	onDepth func(p Priority, depth int)
+}
+
+func newPriorityQueue(onDepth func(p Priority, depth int)) *priorityQueue {
+	return &priorityQueue{
+		notify:  make(chan struct{}, 1),
+		onDepth: onDepth,
+	}
+}
+
+// push queues the event, it returns false if the queue was drained
+func (q *priorityQueue) push(ev kube.EnhancedEvent, p Priority) bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+	q.queues[p] = append(q.queues[p], ev)
+	q.reportDepth(p)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// pop returns the oldest event of the highest priority
+func (q *priorityQueue) pop() (kube.EnhancedEvent, bool) {
+	q.mu.Lock()
+	for p := range q.queues {
+		if len(q.queues[p]) == 0 {
+			continue
+		}
+		ev := q.queues[p][0]
+		q.queues[p][0] = kube.EnhancedEvent{}
+		q.queues[p] = q.queues[p][1:]
+		q.reportDepth(Priority(p))
+		q.mu.Unlock()
+		return ev, true
+	}
+	q.mu.Unlock()
+	return kube.EnhancedEvent{}, false
+}
+
+func (q *priorityQueue) reportDepth(p Priority) {
+	if q.onDepth != nil {
+		q.onDepth(p, len(q.queues[p]))
+	}
+}
+
+// len returns the number of queued events of all priorities
+func (q *priorityQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for p := range q.queues {
+		n += len(q.queues[p])
+	}
+	return n
+}
+
+// drain removes and returns all queued events and rejects the events pushed afterwards
+func (q *priorityQueue) drain() []kube.EnhancedEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	var events []kube.EnhancedEvent
+	for p := range q.queues {
+		events = append(events, q.queues[p]...)
+		q.queues[p] = nil
+		q.reportDepth(Priority(p))
+	}
+	return events
+}
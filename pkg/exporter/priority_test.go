@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func newPriorityTestEvent(eventType, reason string) *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = eventType
+	ev.Reason = reason
+	return ev
+}
+
+func TestPrioritizer(t *testing.T) {
+	p := NewPrioritizer(nil)
+	require.Equal(t, PriorityHigh, p.Priority(newPriorityTestEvent("Warning", "BackOff")))
+	require.Equal(t, PriorityNormal, p.Priority(newPriorityTestEvent("Normal", "Pulled")))
+
+	p = NewPrioritizer(&PriorityConfig{Critical: []Rule{{Reason: "OOMKilling|NodeNotReady"}}})
+	require.Equal(t, PriorityCritical, p.Priority(newPriorityTestEvent("Warning", "OOMKilling")))
+	require.Equal(t, PriorityHigh, p.Priority(newPriorityTestEvent("Warning", "BackOff")))
+
+	p = NewPrioritizer(&PriorityConfig{High: []Rule{{Reason: "Killing"}}})
+	require.Equal(t, PriorityNormal, p.Priority(newPriorityTestEvent("Warning", "BackOff")))
+	require.Equal(t, PriorityHigh, p.Priority(newPriorityTestEvent("Normal", "Killing")))
+}
+
+func TestPriorityQueue(t *testing.T) {
+	depths := make(map[Priority]int)
+	q := newPriorityQueue(func(p Priority, depth int) { depths[p] = depth })
+
+	q.push(*newPriorityTestEvent("Normal", "first"), PriorityNormal)
+	q.push(*newPriorityTestEvent("Normal", "second"), PriorityNormal)
+	q.push(*newPriorityTestEvent("Warning", "warning"), PriorityHigh)
+	q.push(*newPriorityTestEvent("Warning", "critical"), PriorityCritical)
+	require.Equal(t, 4, q.len())
+	require.Equal(t, 2, depths[PriorityNormal])
+
+	var reasons []string
+	for {
+		ev, ok := q.pop()
+		if !ok {
+			break
+		}
+		reasons = append(reasons, ev.Reason)
+	}
+	require.Equal(t, []string{"critical", "warning", "first", "second"}, reasons)
+	require.Equal(t, 0, depths[PriorityNormal])
+	require.Equal(t, 0, q.len())
+}
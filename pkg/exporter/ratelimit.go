@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+// defaultRateLimitBufferSize is the number of events held while the rate is exceeded
+const defaultRateLimitBufferSize = 1000
+
+// RateLimitConfig caps the events passed from the watchers to the receivers, so a controller emitting tens of
+// thousands of events cannot saturate every sink. The events above the rate wait in a bounded buffer, the events that
+// do not fit in it are dropped and counted in the events_throttled metric.
+type RateLimitConfig struct {
+	EventsPerSecond float32 `yaml:"eventsPerSecond"`
+	// Burst is the number of events let through at once, the events per second rounded up by default
+	Burst int `yaml:"burst,omitempty"`
+	// BufferSize is the number of events waiting for the rate, 1000 by default
+	BufferSize int `yaml:"bufferSize,omitempty"`
+}
+
+func (c *RateLimitConfig) validate() error {
+	if c.EventsPerSecond <= 0 {
+		return errors.New("eventsPerSecond must be positive")
+	}
+	if c.Burst < 0 || c.BufferSize < 0 {
+		return errors.New("burst and bufferSize must not be negative")
+	}
+	return nil
+}
+
+// RateLimiter passes the events to the handler at the rate of the config
+type RateLimiter struct {
+	limiter flowcontrol.RateLimiter
+	buffer  chan *kube.EnhancedEvent
+	fn      func(*kube.EnhancedEvent)
+	metrics *metrics.Store
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// mu guards the buffer against being closed while an event is sent to it
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewRateLimiter(cfg *RateLimitConfig, fn func(*kube.EnhancedEvent), metricsStore *metrics.Store) (*RateLimiter, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	burst := cfg.Burst
+	if burst == 0 {
+		burst = int(math.Ceil(float64(cfg.EventsPerSecond)))
+	}
+	size := cfg.BufferSize
+	if size == 0 {
+		size = defaultRateLimitBufferSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &RateLimiter{
+		limiter: flowcontrol.NewTokenBucketRateLimiter(cfg.EventsPerSecond, burst),
+		buffer:  make(chan *kube.EnhancedEvent, size),
+		fn:      fn,
+		metrics: metricsStore,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// OnEvent buffers the event, it is dropped if the buffer is full or the limiter stopped, e.g. for an event an ingest
+// request delivers during the shutdown. The buffered events hold the checkpoint.
+func (r *RateLimiter) OnEvent(ev *kube.EnhancedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		r.metrics.EventsThrottled.Inc()
+		return
+	}
+	ev.Hold()
+	select {
+	case r.buffer <- ev:
+	default:
+		r.metrics.EventsThrottled.Inc()
+		ev.Release()
+	}
+}
+
+func (r *RateLimiter) run() {
+	defer close(r.done)
+	for ev := range r.buffer {
+		// The wait is cancelled when the limiter stops, the buffered events are then passed right away
+		_ = r.limiter.Wait(r.ctx)
+		r.fn(ev)
+		ev.Release()
+	}
+}
+
+// Stop passes the buffered events to the handler and returns, the watchers must be stopped before
+func (r *RateLimiter) Stop() {
+	r.cancel()
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.buffer)
+	}
+	r.mu.Unlock()
+	<-r.done
+	r.limiter.Stop()
+}
@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+func TestRateLimitConfig_Validate(t *testing.T) {
+	assert.Error(t, (&RateLimitConfig{}).validate())
+	assert.Error(t, (&RateLimitConfig{EventsPerSecond: 10, BufferSize: -1}).validate())
+	assert.NoError(t, (&RateLimitConfig{EventsPerSecond: 0.5}).validate())
+}
+
+func TestRateLimiter_Overflow(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var received []string
+	fn := func(ev *kube.EnhancedEvent) {
+		if len(received) == 0 {
+			close(started)
+			<-release
+		}
+		received = append(received, ev.Message)
+	}
+	r, err := NewRateLimiter(&RateLimitConfig{EventsPerSecond: 0.001, Burst: 1, BufferSize: 2}, fn, metricsStore)
+	require.NoError(t, err)
+	send := func(message string) {
+		ev := &kube.EnhancedEvent{}
+		ev.Message = message
+		r.OnEvent(ev)
+	}
+
+	// The first event is passed, the handler holds it while the next ones fill the buffer
+	send("first")
+	<-started
+	send("second")
+	send("third")
+	send("fourth")
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricsStore.EventsThrottled))
+
+	// The buffered events are passed when the limiter stops, without waiting for the rate
+	close(release)
+	r.Stop()
+	assert.Equal(t, []string{"first", "second", "third"}, received)
+}
+
+func TestRateLimiter_OnEventAfterStop(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	r, err := NewRateLimiter(&RateLimitConfig{EventsPerSecond: 10}, func(*kube.EnhancedEvent) { t.Fatal("unexpected event") }, metricsStore)
+	require.NoError(t, err)
+	r.Stop()
+
+	// An ingest request still running during the shutdown
+	r.OnEvent(&kube.EnhancedEvent{})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricsStore.EventsThrottled))
+	r.Stop()
+}
@@ -0,0 +1,265 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/goccy/go-yaml"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// ReceiverFactoryConfig creates a receiver for every namespace with the annotation, so tenants can get the events of
+// their namespace delivered without changing the config, e.g. to a webhook URL set in the annotation. The receiver is
+// removed when the annotation or the namespace is deleted.
+type ReceiverFactoryConfig struct {
+	Name       string `yaml:"name"`
+	Annotation string `yaml:"annotation"`
+	// Match filters the events sent to the receivers, an event is sent if it matches any of the rules or there are none
+	Match []Rule `yaml:"match"`
+	// Receiver is the template of the receivers. Its string values are rendered with [[ ]] delimiters, so they can
+	// contain event templates, with .Namespace, .Value, the value of the annotation, .Labels and .Annotations of the
+	// namespace. The keys are not rendered, so the values from the namespace cannot change the structure.
+	Receiver map[string]interface{} `yaml:"receiver"`
+}
+
+type receiverTemplateData struct {
+	Namespace   string
+	Value       string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+func (c *ReceiverFactoryConfig) validate() error {
+	if c.Name == "" {
+		return errors.New("name must be non-empty")
+	}
+	if c.Annotation == "" {
+		return errors.New("annotation must be non-empty")
+	}
+	if len(c.Receiver) == 0 {
+		return errors.New("receiver must be non-empty")
+	}
+	_, err := c.template()
+	return err
+}
+
+func (c *ReceiverFactoryConfig) template() (interface{}, error) {
+	tmpl, err := c.compile(c.Receiver)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receiver template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateLeaf is a string value of the receiver template
+type templateLeaf struct {
+	source string
+	tmpl   *template.Template
+}
+
+// compile parses the string values of the node into templates, the maps and lists are copied
+func (c *ReceiverFactoryConfig) compile(node interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		compiled := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			var err error
+			if compiled[k], err = c.compile(v); err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	case []interface{}:
+		compiled := make([]interface{}, len(n))
+		for i, v := range n {
+			var err error
+			if compiled[i], err = c.compile(v); err != nil {
+				return nil, err
+			}
+		}
+		return compiled, nil
+	case string:
+		tmpl, err := template.New(c.Name).Delims("[[", "]]").Option("missingkey=error").Parse(n)
+		if err != nil {
+			return nil, err
+		}
+		return &templateLeaf{source: n, tmpl: tmpl}, nil
+	default:
+		return node, nil
+	}
+}
+
+// render executes the templates of the compiled node. The values from the namespace only end up in strings, and they
+// cannot add event templates, which could read e.g. the environment of the exporter.
+func render(node interface{}, data receiverTemplateData) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			var err error
+			if rendered[k], err = render(v, data); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(n))
+		for i, v := range n {
+			var err error
+			if rendered[i], err = render(v, data); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case *templateLeaf:
+		var buf strings.Builder
+		if err := n.tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		if strings.Count(buf.String(), "{{") > strings.Count(n.source, "{{") {
+			return nil, errors.New("the values of the namespace cannot contain event templates")
+		}
+		return buf.String(), nil
+	default:
+		return node, nil
+	}
+}
+
+// ReceiverFactory keeps a receiver for every namespace with the annotation and sends the events of the namespace to it
+type ReceiverFactory struct {
+	cfg      *ReceiverFactoryConfig
+	tmpl     interface{}
+	registry DynamicReceiverRegistry
+
+	mu sync.RWMutex
+	// values are the annotation values the receivers of the namespaces were created with
+	values map[string]string
+}
+
+func NewReceiverFactory(cfg *ReceiverFactoryConfig, registry DynamicReceiverRegistry) (*ReceiverFactory, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	tmpl, err := cfg.template()
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiverFactory{
+		cfg:      cfg,
+		tmpl:     tmpl,
+		registry: registry,
+		values:   make(map[string]string),
+	}, nil
+}
+
+func (f *ReceiverFactory) receiverName(namespace string) string {
+	return f.cfg.Name + "/" + namespace
+}
+
+// newReceiver renders the receiver template for the namespace
+func (f *ReceiverFactory) newReceiver(ns *corev1.Namespace, value string) (*sinks.ReceiverConfig, error) {
+	rendered, err := render(f.tmpl, receiverTemplateData{
+		Namespace:   ns.Name,
+		Value:       value,
+		Labels:      ns.Labels,
+		Annotations: ns.Annotations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The rendered strings are quoted by the encoder, so they stay strings
+	out, err := yaml.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+	var receiver sinks.ReceiverConfig
+	if err := yaml.Unmarshal(out, &receiver); err != nil {
+		return nil, err
+	}
+	receiver.Name = f.receiverName(ns.Name)
+	if err := receiver.ValidateSingleSink(); err != nil {
+		return nil, err
+	}
+	if err := receiver.Validate(); err != nil {
+		return nil, err
+	}
+	return &receiver, nil
+}
+
+// OnNamespace creates, replaces or removes the receiver of the namespace when its annotation changed
+func (f *ReceiverFactory) OnNamespace(ns *corev1.Namespace) {
+	value, annotated := ns.Annotations[f.cfg.Annotation]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, exists := f.values[ns.Name]
+	if annotated == exists && value == current {
+		return
+	}
+	if exists {
+		f.remove(ns.Name)
+	}
+	if !annotated {
+		return
+	}
+
+	name := f.receiverName(ns.Name)
+	receiver, err := f.newReceiver(ns, value)
+	var sink sinks.Sink
+	if err == nil {
+		sink, err = receiver.GetSink()
+	}
+	if err != nil {
+		log.Error().Err(err).Str("receiver", name).Msg("Cannot create the receiver of the namespace")
+		return
+	}
+	f.registry.Register(name, sink)
+	f.values[ns.Name] = value
+	log.Info().Str("receiver", name).Str("type", sinks.SinkType(sink)).Msg("Registered the receiver of the namespace")
+}
+
+func (f *ReceiverFactory) OnNamespaceDeleted(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.values[name]; exists {
+		f.remove(name)
+	}
+}
+
+func (f *ReceiverFactory) remove(namespace string) {
+	name := f.receiverName(namespace)
+	f.registry.Unregister(name)
+	delete(f.values, namespace)
+	log.Info().Str("receiver", name).Msg("Removed the receiver of the namespace")
+}
+
+// Send sends the event to the receiver of its namespace, if it has one and the event matches
+func (f *ReceiverFactory) Send(ev *kube.EnhancedEvent) {
+	namespace := ev.InvolvedObject.Namespace
+	f.mu.RLock()
+	_, exists := f.values[namespace]
+	f.mu.RUnlock()
+	if !exists || !f.matches(ev) {
+		return
+	}
+	f.registry.SendEvent(f.receiverName(namespace), ev)
+}
+
+func (f *ReceiverFactory) matches(ev *kube.EnhancedEvent) bool {
+	if len(f.cfg.Match) == 0 {
+		return true
+	}
+	for i := range f.cfg.Match {
+		if f.cfg.Match[i].MatchesEvent(ev) {
+			return true
+		}
+	}
+	return false
+}
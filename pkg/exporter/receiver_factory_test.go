@@ -0,0 +1,135 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestReceiverFactory(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	registry := &SyncRegistry{}
+	factory, err := NewReceiverFactory(&ReceiverFactoryConfig{
+		Name:       "tenant",
+		Annotation: "events.example.com/webhook",
+		Match:      []Rule{{Type: "Warning"}},
+		Receiver: map[string]interface{}{
+			"webhook": map[string]interface{}{
+				"endpoint": "[[ .Value ]]",
+				"layout":   map[string]interface{}{"namespace": "[[ .Namespace ]]", "reason": "{{ .Reason }}"},
+			},
+		},
+	}, registry)
+	require.NoError(t, err)
+	engine := &Engine{Registry: registry, Factories: []*ReceiverFactory{factory}}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team-a",
+		Annotations: map[string]string{"events.example.com/webhook": ts.URL + "/a"},
+	}}
+	engine.OnNamespace(ns)
+	engine.OnNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}})
+
+	send := func(namespace, eventType string) {
+		ev := &kube.EnhancedEvent{}
+		ev.InvolvedObject.Namespace = namespace
+		ev.Type = eventType
+		engine.OnEvent(ev)
+	}
+	send("team-a", "Warning")
+	send("team-a", "Normal")
+	send("team-b", "Warning")
+	assert.Equal(t, []string{"/a"}, paths)
+
+	// A changed annotation replaces the receiver
+	ns.Annotations["events.example.com/webhook"] = ts.URL + "/a2"
+	engine.OnNamespace(ns)
+	send("team-a", "Warning")
+	assert.Equal(t, []string{"/a", "/a2"}, paths)
+
+	engine.OnNamespaceDeleted("team-a")
+	send("team-a", "Warning")
+	assert.Equal(t, []string{"/a", "/a2"}, paths)
+	assert.Empty(t, registry.reg)
+}
+
+func TestReceiverFactory_InvalidReceiver(t *testing.T) {
+	registry := &SyncRegistry{}
+	factory, err := NewReceiverFactory(&ReceiverFactoryConfig{
+		Name:       "tenant",
+		Annotation: "events.example.com/layout-preset",
+		Receiver: map[string]interface{}{
+			"layoutPreset": "[[ .Value ]]",
+			"stdout":       map[string]interface{}{},
+		},
+	}, registry)
+	require.NoError(t, err)
+
+	factory.OnNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team-a",
+		Annotations: map[string]string{"events.example.com/layout-preset": "unknown"},
+	}})
+	assert.Empty(t, registry.reg)
+
+	_, err = NewReceiverFactory(&ReceiverFactoryConfig{
+		Name:       "tenant",
+		Annotation: "events.example.com/webhook",
+		Receiver:   map[string]interface{}{"webhook": map[string]interface{}{"endpoint": "[[ .Value "}},
+	}, registry)
+	assert.Error(t, err)
+}
+
+func TestReceiverFactory_Injection(t *testing.T) {
+	factory, err := NewReceiverFactory(&ReceiverFactoryConfig{
+		Name:       "tenant",
+		Annotation: "events.example.com/webhook",
+		Receiver: map[string]interface{}{
+			"webhook": map[string]interface{}{
+				"endpoint": "[[ .Value ]]",
+				"layout":   map[string]interface{}{"reason": "{{ .Reason }}"},
+			},
+		},
+	}, &SyncRegistry{})
+	require.NoError(t, err)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+
+	value := "http://x/\"\n  headers:\n    X-Injected: yes\nfile:\n  path: /tmp/pwned\nzz: \""
+	receiver, err := factory.newReceiver(ns, value)
+	require.NoError(t, err)
+	assert.Equal(t, value, receiver.Webhook.Endpoint)
+	assert.Empty(t, receiver.Webhook.Headers)
+	assert.Nil(t, receiver.File)
+
+	// The value cannot add event templates
+	_, err = factory.newReceiver(ns, `http://x/{{ env "AWS_SECRET_ACCESS_KEY" }}`)
+	assert.Error(t, err)
+
+	// A template setting two sinks is rejected
+	factory, err = NewReceiverFactory(&ReceiverFactoryConfig{
+		Name:       "tenant",
+		Annotation: "events.example.com/webhook",
+		Receiver: map[string]interface{}{
+			"webhook": map[string]interface{}{"endpoint": "[[ .Value ]]"},
+			"file":    map[string]interface{}{"path": "/tmp/[[ .Namespace ]]"},
+		},
+	}, &SyncRegistry{})
+	require.NoError(t, err)
+	_, err = factory.newReceiver(ns, "http://x/")
+	assert.Error(t, err)
+}
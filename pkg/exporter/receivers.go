@@ -11,3 +11,10 @@ type ReceiverRegistry interface {
 	Register(string, sinks.Sink)
 	Close()
 }
+
+// DynamicReceiverRegistry also removes receivers, to add and remove receivers at runtime
+type DynamicReceiverRegistry interface {
+	ReceiverRegistry
+	// Unregister closes the sink of the receiver, the events that are still queued are dropped
+	Unregister(string)
+}
@@ -8,24 +8,39 @@ import (
 
 // Route allows using rules to drop events or match events to specific receivers.
 // It also allows using routes recursively for complex route building to fit
-// most of the needs
+// most of the needs. A sub route inherits the matchers of its parent: it only
+// sees the events matching all of the parent's match rules. With inherit set to
+// false, it sees every event that was not dropped by its parents.
 type Route struct {
-	Drop   []Rule
-	Match  []Rule
-	Routes []Route
+	Drop    []Rule
+	Match   []Rule
+	Routes  []Route
+	Inherit *bool
+
+	// The indexes are nil unless BuildIndex is called
+	dropIndex  *ruleIndex
+	matchIndex *ruleIndex
+}
+
+// inherits is true unless inheriting the parent's matchers is disabled explicitly
+func (r *Route) inherits() bool {
+	return r.Inherit == nil || *r.Inherit
 }
 
 func (r *Route) ProcessEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry) {
 	// First determine whether we will drop the event: If any of the drop is matched, we break the loop
-	for _, v := range r.Drop {
-		if v.MatchesEvent(ev) {
-			return
-		}
+	dropped := false
+	r.dropIndex.visit(r.Drop, ev, func(rule *Rule) bool {
+		dropped = rule.MatchesEvent(ev)
+		return !dropped
+	})
+	if dropped {
+		return
 	}
 
-	// It has match rules, it should go to the matchers
+	// It has match rules, it should go to the matchers. The rules skipped by the index cannot match.
 	matchesAll := true
-	for _, rule := range r.Match {
+	visitedAll := r.matchIndex.visit(r.Match, ev, func(rule *Rule) bool {
 		if rule.MatchesEvent(ev) {
 			if rule.Receiver != "" {
 				log.Info().
@@ -41,12 +56,14 @@ func (r *Route) ProcessEvent(ev *kube.EnhancedEvent, registry ReceiverRegistry)
 		} else {
 			matchesAll = false
 		}
-	}
+		return true
+	})
+	matchesAll = matchesAll && visitedAll
 
 	// If all matches are satisfied, we can send them down to the rabbit hole
-	if matchesAll {
-		for _, subRoute := range r.Routes {
-			subRoute.ProcessEvent(ev, registry)
+	for i := range r.Routes {
+		if matchesAll || !r.Routes[i].inherits() {
+			r.Routes[i].ProcessEvent(ev, registry)
 		}
 	}
 }
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"sort"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// minIndexedRules is the number of rules from which a route uses an index, evaluating a few rules is cheaper
+const minIndexedRules = 8
+
+// ruleIndex finds the rules that can match an event without evaluating all of them. Every rule with an exact
+// namespace, kind or reason pattern like ^kube-system$ is stored under that value, the namespace taking precedence
+// over the kind and the reason. The other rules are always evaluated.
+type ruleIndex struct {
+	byNamespace map[string][]int
+	byKind      map[string][]int
+	byReason    map[string][]int
+	always      []int
+}
+
+func newRuleIndex(rules []Rule) *ruleIndex {
+	if len(rules) < minIndexedRules {
+		return nil
+	}
+	x := &ruleIndex{
+		byNamespace: make(map[string][]int),
+		byKind:      make(map[string][]int),
+		byReason:    make(map[string][]int),
+	}
+	for i := range rules {
+		if value, ok := exactValue(rules[i].Namespace); ok {
+			x.byNamespace[value] = append(x.byNamespace[value], i)
+		} else if value, ok := exactValue(rules[i].Kind); ok {
+			x.byKind[value] = append(x.byKind[value], i)
+		} else if value, ok := exactValue(rules[i].Reason); ok {
+			x.byReason[value] = append(x.byReason[value], i)
+		} else {
+			x.always = append(x.always, i)
+		}
+	}
+	return x
+}
+
+// visit calls fn in order for every rule that can match the event, all of them without an index, until fn returns
+// false. It returns whether every rule was visited.
+func (x *ruleIndex) visit(rules []Rule, ev *kube.EnhancedEvent, fn func(rule *Rule) bool) bool {
+	if x == nil {
+		for i := range rules {
+			if !fn(&rules[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	namespace := x.byNamespace[ev.Namespace]
+	kind := x.byKind[ev.InvolvedObject.Kind]
+	reason := x.byReason[ev.Reason]
+	candidates := make([]int, 0, len(x.always)+len(namespace)+len(kind)+len(reason))
+	candidates = append(candidates, x.always...)
+	candidates = append(candidates, namespace...)
+	candidates = append(candidates, kind...)
+	candidates = append(candidates, reason...)
+	// The rules are evaluated in the configured order, every rule is stored only once
+	sort.Ints(candidates)
+
+	for _, i := range candidates {
+		if !fn(&rules[i]) {
+			return false
+		}
+	}
+	return len(candidates) == len(rules)
+}
+
+// BuildIndex indexes the rules of the route and its sub routes, to speed up routing configs with many rules. The
+// rules must not be changed afterwards.
+func (r *Route) BuildIndex() {
+	r.dropIndex = newRuleIndex(r.Drop)
+	r.matchIndex = newRuleIndex(r.Match)
+	for i := range r.Routes {
+		r.Routes[i].BuildIndex()
+	}
+}
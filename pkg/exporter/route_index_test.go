@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// manyRulesRoute has a rule per team namespace, like a config routing the events of every team to its own receiver
+func manyRulesRoute(teams int) Route {
+	r := Route{
+		Drop: []Rule{{Type: "^Normal$"}},
+	}
+	for i := 0; i < teams; i++ {
+		r.Match = append(r.Match, Rule{
+			Namespace: fmt.Sprintf("^team-%d$", i),
+			Receiver:  fmt.Sprintf("team-%d", i),
+		})
+	}
+	r.Match = append(r.Match,
+		Rule{Kind: "^Node$", Receiver: "nodes"},
+		Rule{Reason: "OOM", Receiver: "oom"},
+	)
+	return r
+}
+
+func TestRuleIndex_SameAsLinear(t *testing.T) {
+	indexed := manyRulesRoute(20)
+	indexed.Routes = []Route{{Match: []Rule{{Receiver: "sub"}}}}
+	indexed.BuildIndex()
+	assert.NotNil(t, indexed.matchIndex)
+	assert.Nil(t, indexed.dropIndex)
+
+	linear := manyRulesRoute(20)
+	linear.Routes = []Route{{Match: []Rule{{Receiver: "sub"}}}}
+
+	events := []kube.EnhancedEvent{{}, {}, {}, {}, {}}
+	events[0].Namespace = "team-3"
+	events[1].Namespace = "team-30"
+	events[2].Namespace = "team-7"
+	events[2].InvolvedObject.Kind = "Node"
+	events[2].Reason = "SystemOOM"
+	events[3].Type = "Normal"
+	events[3].Namespace = "team-3"
+
+	for i := range events {
+		indexedReg := testReceiverRegistry{}
+		linearReg := testReceiverRegistry{}
+		indexed.ProcessEvent(&events[i], &indexedReg)
+		linear.ProcessEvent(&events[i], &linearReg)
+		assert.Equal(t, linearReg.rcvd, indexedReg.rcvd, "event %d", i)
+	}
+}
+
+func TestRuleIndex_AllMatched(t *testing.T) {
+	r := Route{Routes: []Route{{Match: []Rule{{Receiver: "sub"}}}}}
+	for i := 0; i < minIndexedRules; i++ {
+		r.Match = append(r.Match, Rule{Type: "Warning"})
+	}
+	r.Match[0].Kind = "^Pod$"
+	r.BuildIndex()
+
+	ev := kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.InvolvedObject.Kind = "Pod"
+	reg := testReceiverRegistry{}
+	r.ProcessEvent(&ev, &reg)
+	assert.True(t, reg.isEventRcvd("sub", &ev))
+
+	ev.InvolvedObject.Kind = "Node"
+	reg = testReceiverRegistry{}
+	r.ProcessEvent(&ev, &reg)
+	assert.False(t, reg.isEventRcvd("sub", &ev), "the rule skipped by the index does not match")
+}
+
+type nopReceiverRegistry struct{}
+
+func (nopReceiverRegistry) SendEvent(string, *kube.EnhancedEvent) {}
+
+func (nopReceiverRegistry) Register(string, sinks.Sink) {}
+
+func (nopReceiverRegistry) Close() {}
+
+func benchmarkRoute(b *testing.B, index bool) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(level)
+
+	r := manyRulesRoute(500)
+	if index {
+		r.BuildIndex()
+	}
+	ev := kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Namespace = "team-250"
+	ev.Reason = "BackOff"
+	ev.InvolvedObject.Kind = "Pod"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ProcessEvent(&ev, nopReceiverRegistry{})
+	}
+}
+
+func BenchmarkRoute_ProcessEvent_Linear(b *testing.B) {
+	benchmarkRoute(b, false)
+}
+
+func BenchmarkRoute_ProcessEvent_Indexed(b *testing.B) {
+	benchmarkRoute(b, true)
+}
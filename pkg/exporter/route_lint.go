@@ -0,0 +1,281 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+)
+
+// Lint checks the route tree for mistakes that are valid config but most likely not intended: invalid patterns,
+// rules and routes that can never be reached because of a drop rule or contradicting matchers, and match rules
+// without any effect. The findings are reported with the path of the rule or route, e.g. route.routes[1].match[0].
+func (r *Route) Lint() []string {
+	return r.lint("route", nil, nil)
+}
+
+// lint checks the route, inherited are the match rules and drops are the drop rules of the parents that apply to it
+func (r *Route) lint(path string, inherited []Rule, drops []ruleRef) []string {
+	var findings []string
+
+	for i := range r.Drop {
+		findings = append(findings, lintPatterns(fmt.Sprintf("%s.drop[%d]", path, i), &r.Drop[i])...)
+	}
+	for i := range r.Match {
+		findings = append(findings, lintPatterns(fmt.Sprintf("%s.match[%d]", path, i), &r.Match[i])...)
+	}
+
+	for i := range r.Drop {
+		drops = append(drops, ruleRef{path: fmt.Sprintf("%s.drop[%d]", path, i), rule: &r.Drop[i]})
+	}
+	for _, d := range drops {
+		if d.rule.isEmpty() {
+			return append(findings, fmt.Sprintf("%s drops every event, %s is unreachable", d.path, path))
+		}
+	}
+
+	for i := range r.Match {
+		rulePath := fmt.Sprintf("%s.match[%d]", path, i)
+		c := newConstraints(append(inherited[:len(inherited):len(inherited)], r.Match[i]))
+		if c.conflict != "" {
+			findings = append(findings, fmt.Sprintf("%s is unreachable, %s", rulePath, c.conflict))
+			continue
+		}
+		if d := c.droppedBy(drops); d != "" {
+			findings = append(findings, fmt.Sprintf("%s is unreachable, every event it matches is dropped by %s", rulePath, d))
+			continue
+		}
+		if r.Match[i].Receiver == "" && len(r.Routes) == 0 {
+			findings = append(findings, fmt.Sprintf("%s has no receiver and no sub routes, it has no effect", rulePath))
+		}
+	}
+
+	subInherited := append(inherited[:len(inherited):len(inherited)], r.Match...)
+	for i := range r.Routes {
+		subPath := fmt.Sprintf("%s.routes[%d]", path, i)
+		sub := &r.Routes[i]
+		if !sub.inherits() {
+			findings = append(findings, sub.lint(subPath, nil, drops)...)
+			continue
+		}
+		c := newConstraints(subInherited)
+		if c.conflict != "" {
+			findings = append(findings, fmt.Sprintf("%s is unreachable, %s", subPath, c.conflict))
+			continue
+		}
+		if d := c.droppedBy(drops); d != "" {
+			findings = append(findings, fmt.Sprintf("%s is unreachable, every event it matches is dropped by %s", subPath, d))
+			continue
+		}
+		findings = append(findings, sub.lint(subPath, subInherited, drops)...)
+	}
+
+	return findings
+}
+
+type ruleRef struct {
+	path string
+	rule *Rule
+}
+
+func lintPatterns(path string, r *Rule) []string {
+	var findings []string
+	check := func(field, pattern string) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			findings = append(findings, fmt.Sprintf("%s.%s is not a valid regular expression: %s", path, field, err))
+		}
+	}
+	patterns := r.patterns()
+	for _, field := range sortedFields(patterns) {
+		check(field, patterns[field])
+	}
+	for _, k := range sortedFields(r.Labels) {
+		check("labels."+k, r.Labels[k])
+	}
+	for _, k := range sortedFields(r.Annotations) {
+		check("annotations."+k, r.Annotations[k])
+	}
+	for _, k := range sortedFields(r.Extracted) {
+		check("extracted."+k, r.Extracted[k])
+	}
+	return findings
+}
+
+// patterns returns the set patterns of the rule by field
+func (r *Rule) patterns() map[string]string {
+	ret := make(map[string]string)
+	for field, pattern := range map[string]string{
+		"message":          r.Message,
+		"apiVersion":       r.APIVersion,
+		"kind":             r.Kind,
+		"namespace":        r.Namespace,
+		"reason":           r.Reason,
+		"type":             r.Type,
+		"component":        r.Component,
+		"host":             r.Host,
+		"clusterName":      r.ClusterName,
+		"ownerKind":        r.OwnerKind,
+		"ownerName":        r.OwnerName,
+		"normalizedReason": r.NormalizedReason,
+		"category":         r.Category,
+	} {
+		if pattern != "" {
+			ret[field] = pattern
+		}
+	}
+	return ret
+}
+
+// isEmpty is true if the rule matches every event
+func (r *Rule) isEmpty() bool {
+	return len(r.patterns()) == 0 && len(r.Labels) == 0 && len(r.Annotations) == 0 && len(r.Extracted) == 0 &&
+		r.MinCount <= 0 && r.Deleted == nil
+}
+
+// constraints are what is known about an event that matches all of a set of rules. Only exact patterns like ^Pod$
+// are evaluated, anything else could match any value.
+type constraints struct {
+	exact       map[string]string
+	labels      map[string]string
+	annotations map[string]string
+	extracted   map[string]string
+	minCount    int32
+	deleted     *bool
+	// conflict describes why no event can match all the rules, it is empty if there is none
+	conflict string
+}
+
+func newConstraints(rules []Rule) *constraints {
+	c := &constraints{
+		exact:       make(map[string]string),
+		labels:      make(map[string]string),
+		annotations: make(map[string]string),
+		extracted:   make(map[string]string),
+	}
+	// The exact values have to be known before they can be checked against the other patterns
+	for i := range rules {
+		c.addExact(c.exact, rules[i].patterns(), "")
+		c.addExact(c.labels, rules[i].Labels, "labels.")
+		c.addExact(c.annotations, rules[i].Annotations, "annotations.")
+		c.addExact(c.extracted, rules[i].Extracted, "extracted.")
+		if rules[i].MinCount > c.minCount {
+			c.minCount = rules[i].MinCount
+		}
+		if d := rules[i].Deleted; d != nil {
+			if c.deleted != nil && *c.deleted != *d && c.conflict == "" {
+				c.conflict = "deleted cannot be both true and false"
+			}
+			c.deleted = d
+		}
+	}
+	for i := range rules {
+		c.check(c.exact, rules[i].patterns(), "")
+		c.check(c.labels, rules[i].Labels, "labels.")
+		c.check(c.annotations, rules[i].Annotations, "annotations.")
+		c.check(c.extracted, rules[i].Extracted, "extracted.")
+	}
+	return c
+}
+
+func (c *constraints) addExact(exact map[string]string, patterns map[string]string, prefix string) {
+	for _, field := range sortedFields(patterns) {
+		value, ok := exactValue(patterns[field])
+		if !ok {
+			continue
+		}
+		if prev, ok := exact[field]; ok && prev != value && c.conflict == "" {
+			c.conflict = fmt.Sprintf("%s%s cannot be both %q and %q", prefix, field, prev, value)
+		}
+		exact[field] = value
+	}
+}
+
+func (c *constraints) check(exact map[string]string, patterns map[string]string, prefix string) {
+	for _, field := range sortedFields(patterns) {
+		pattern := patterns[field]
+		value, ok := exact[field]
+		if ok && c.conflict == "" && !matchString(pattern, value) {
+			c.conflict = fmt.Sprintf("%s%s %q does not match %q", prefix, field, value, pattern)
+		}
+	}
+}
+
+// implies is true if every event matching the constraints also matches the rule
+func (c *constraints) implies(r *Rule) bool {
+	covered := func(exact map[string]string, patterns map[string]string) bool {
+		for field, pattern := range patterns {
+			value, ok := exact[field]
+			if !ok || !matchString(pattern, value) {
+				return false
+			}
+		}
+		return true
+	}
+	return covered(c.exact, r.patterns()) &&
+		covered(c.labels, r.Labels) &&
+		covered(c.annotations, r.Annotations) &&
+		covered(c.extracted, r.Extracted) &&
+		c.minCount >= r.MinCount &&
+		(r.Deleted == nil || (c.deleted != nil && *c.deleted == *r.Deleted))
+}
+
+// droppedBy returns the path of the first drop rule matching every event that matches the constraints
+func (c *constraints) droppedBy(drops []ruleRef) string {
+	for _, d := range drops {
+		if c.implies(d.rule) {
+			return d.path
+		}
+	}
+	return ""
+}
+
+// exactValue returns the only value matching the pattern if it is anchored literal like ^kube-system$
+func exactValue(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) != 3 {
+		return "", false
+	}
+	begin, literal, end := re.Sub[0], re.Sub[1], re.Sub[2]
+	if begin.Op != syntax.OpBeginText || end.Op != syntax.OpEndText ||
+		literal.Op != syntax.OpLiteral || literal.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+	return string(literal.Rune), true
+}
+
+func sortedFields(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lint checks the routes, see Route.Lint, and reports the match rules sending to an undefined receiver
+func (c *Config) Lint() []string {
+	findings := c.Route.Lint()
+
+	receivers := make(map[string]bool, len(c.Receivers))
+	for i := range c.Receivers {
+		receivers[c.Receivers[i].Name] = true
+	}
+	var checkReceivers func(path string, r *Route)
+	checkReceivers = func(path string, r *Route) {
+		for i := range r.Match {
+			if name := r.Match[i].Receiver; name != "" && !receivers[name] {
+				findings = append(findings, fmt.Sprintf("%s.match[%d] sends to the undefined receiver %q", path, i, name))
+			}
+		}
+		for i := range r.Routes {
+			checkReceivers(fmt.Sprintf("%s.routes[%d]", path, i), &r.Routes[i])
+		}
+	}
+	checkReceivers("route", &c.Route)
+
+	return findings
+}
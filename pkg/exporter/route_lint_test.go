@@ -0,0 +1,134 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func TestRoute_Lint(t *testing.T) {
+	inherit := false
+	r := Route{
+		Drop:  []Rule{{Namespace: "^kube-system$"}},
+		Match: []Rule{{Kind: "Pod"}},
+		Routes: []Route{{
+			Match: []Rule{{
+				Namespace: "^kube-system$",
+				Receiver:  "system",
+			}},
+		}, {
+			Match: []Rule{{
+				Kind:     "^Node$",
+				Receiver: "nodes",
+			}},
+		}, {
+			Match: []Rule{{
+				Reason:   "[",
+				Receiver: "broken",
+			}},
+		}, {
+			Match: []Rule{{
+				Reason: "BackOff",
+			}},
+		}, {
+			Inherit: &inherit,
+			Match: []Rule{{
+				Kind:     "^Node$",
+				Receiver: "nodes",
+			}},
+		}},
+	}
+
+	assert.Equal(t, []string{
+		"route.routes[0].match[0] is unreachable, every event it matches is dropped by route.drop[0]",
+		"route.routes[1].match[0] is unreachable, kind \"Node\" does not match \"Pod\"",
+		"route.routes[2].match[0].reason is not a valid regular expression: error parsing regexp: missing closing ]: `[`",
+		"route.routes[3].match[0] has no receiver and no sub routes, it has no effect",
+	}, r.Lint())
+}
+
+func TestRoute_LintDroppedRoute(t *testing.T) {
+	r := Route{
+		Drop:  []Rule{{Type: "Normal"}},
+		Match: []Rule{{Type: "^Normal$"}},
+		Routes: []Route{{
+			Match: []Rule{{Receiver: "stdout"}},
+		}},
+	}
+
+	assert.Equal(t, []string{
+		"route.match[0] is unreachable, every event it matches is dropped by route.drop[0]",
+		"route.routes[0] is unreachable, every event it matches is dropped by route.drop[0]",
+	}, r.Lint())
+}
+
+func TestRoute_LintConflictingParent(t *testing.T) {
+	r := Route{
+		Match: []Rule{{Namespace: "^prod$"}, {Namespace: "^dev$"}},
+		Routes: []Route{{
+			Match: []Rule{{Receiver: "stdout"}},
+		}},
+	}
+
+	assert.Equal(t, []string{
+		"route.routes[0] is unreachable, namespace cannot be both \"prod\" and \"dev\"",
+	}, r.Lint())
+}
+
+func TestRoute_LintDeleted(t *testing.T) {
+	deleted, exists := true, false
+	r := Route{
+		Drop: []Rule{{Deleted: &deleted}},
+		Routes: []Route{
+			{Match: []Rule{{Kind: "^Job$", Deleted: &deleted, Receiver: "archive"}}},
+			{Match: []Rule{{Deleted: &exists}, {Deleted: &deleted}}, Routes: []Route{{Match: []Rule{{Receiver: "stdout"}}}}},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"route.routes[0].match[0] is unreachable, every event it matches is dropped by route.drop[0]",
+		"route.routes[1].match[1] is unreachable, every event it matches is dropped by route.drop[0]",
+		"route.routes[1].routes[0] is unreachable, deleted cannot be both true and false",
+	}, r.Lint())
+}
+
+func TestRoute_LintDropAll(t *testing.T) {
+	r := Route{
+		Drop:  []Rule{{}},
+		Match: []Rule{{Receiver: "stdout"}},
+	}
+
+	assert.Equal(t, []string{"route.drop[0] drops every event, route is unreachable"}, r.Lint())
+}
+
+func TestConfig_LintUndefinedReceiver(t *testing.T) {
+	c := Config{
+		Route: Route{
+			Routes: []Route{{
+				Match: []Rule{{Receiver: "stdout"}, {Receiver: "slack"}},
+			}},
+		},
+		Receivers: []sinks.ReceiverConfig{{Name: "stdout", Stdout: &sinks.StdoutConfig{}}},
+	}
+
+	assert.Equal(t, []string{"route.routes[0].match[1] sends to the undefined receiver \"slack\""}, c.Lint())
+}
+
+func TestExactValue(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"^Pod$":          "Pod",
+		`^kube\-system$`: "kube-system",
+		`\Aprod\z`:       "prod",
+	} {
+		value, ok := exactValue(pattern)
+		assert.True(t, ok, pattern)
+		assert.Equal(t, expected, value)
+	}
+
+	for _, pattern := range []string{"Pod", "^Pod", "^(?i)pod$", "^Pod|Node$", "^Po.$"} {
+		_, ok := exactValue(pattern)
+		assert.False(t, ok, pattern)
+	}
+}
@@ -225,3 +225,44 @@ func Test_GHIssue51(t *testing.T) {
 	assert.True(t, reg.isEventRcvd("elastic", &ev1))
 	assert.False(t, reg.isEventRcvd("elastic", &ev2))
 }
+
+func TestRoute_InheritFalse(t *testing.T) {
+	ev := kube.EnhancedEvent{}
+	ev.Namespace = "kube-system"
+	ev.Type = "Warning"
+
+	reg := testReceiverRegistry{}
+
+	inherit := false
+	r := Route{
+		Drop: []Rule{{
+			Reason: "Ignored",
+		}},
+		Match: []Rule{{
+			Namespace: "^prod$",
+		}},
+		Routes: []Route{{
+			Match: []Rule{{
+				Type:     "Warning",
+				Receiver: "prod-warnings",
+			}},
+		}, {
+			Inherit: &inherit,
+			Match: []Rule{{
+				Type:     "Warning",
+				Receiver: "all-warnings",
+			}},
+		}},
+	}
+
+	r.ProcessEvent(&ev, &reg)
+
+	assert.False(t, reg.isEventRcvd("prod-warnings", &ev))
+	assert.True(t, reg.isEventRcvd("all-warnings", &ev))
+
+	dropped := kube.EnhancedEvent{}
+	dropped.Type = "Warning"
+	dropped.Reason = "Ignored"
+	r.ProcessEvent(&dropped, &reg)
+	assert.False(t, reg.isEventRcvd("all-warnings", &dropped))
+}
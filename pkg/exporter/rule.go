@@ -27,13 +27,29 @@ type Rule struct {
 	MinCount    int32 `yaml:"minCount"`
 	Component   string
 	Host        string
+	ClusterName string `yaml:"clusterName"`
 	Receiver    string
+	// OwnerKind and OwnerName match the root owner of the involved object, they need the owners to be resolved
+	OwnerKind string `yaml:"ownerKind"`
+	OwnerName string `yaml:"ownerName"`
+	// NormalizedReason and Category match the normalized reason, they need the reasons to be normalized
+	NormalizedReason string `yaml:"normalizedReason"`
+	Category         string
+	// Extracted matches the fields found in the message by the extractors
+	Extracted map[string]string
+	// Deleted matches whether the involved object was deleted, either way if unset
+	Deleted *bool
 }
 
 // MatchesEvent compares the rule to an event and returns a boolean value to indicate
 // whether the event is compatible with the rule. All fields are compared as regular expressions
 // so the user must keep that in mind while writing rules.
 func (r *Rule) MatchesEvent(ev *kube.EnhancedEvent) bool {
+	var ownerKind, ownerName string
+	if ev.Owner != nil {
+		ownerKind, ownerName = ev.Owner.Kind, ev.Owner.Name
+	}
+
 	// These rules are just basic comparison rules, if one of them fails, it means the event does not match the rule
 	rules := [][2]string{
 		{r.Message, ev.Message},
@@ -44,6 +60,11 @@ func (r *Rule) MatchesEvent(ev *kube.EnhancedEvent) bool {
 		{r.Type, ev.Type},
 		{r.Component, ev.Source.Component},
 		{r.Host, ev.Source.Host},
+		{r.ClusterName, ev.ClusterName},
+		{r.OwnerKind, ownerKind},
+		{r.OwnerName, ownerName},
+		{r.NormalizedReason, ev.Normalized.Reason},
+		{r.Category, ev.Normalized.Category},
 	}
 
 	for _, v := range rules {
@@ -85,6 +106,18 @@ func (r *Rule) MatchesEvent(ev *kube.EnhancedEvent) bool {
 		}
 	}
 
+	// The extracted fields all need to be present too
+	for k, v := range r.Extracted {
+		val, ok := ev.Extracted[k]
+		if !ok || !matchString(v, val) {
+			return false
+		}
+	}
+
+	if r.Deleted != nil && *r.Deleted != ev.InvolvedObject.Deleted {
+		return false
+	}
+
 	// If minCount is not given via a config, it's already 0 and the count is already 1 and this passes.
 	if ev.Count < r.MinCount {
 		return false
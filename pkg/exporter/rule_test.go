@@ -357,3 +357,35 @@ func TestCount(t *testing.T) {
 
 	assert.False(t, r.MatchesEvent(ev))
 }
+
+func TestDeleted(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.Kind = "Job"
+	deleted, exists := true, false
+
+	assert.True(t, (&Rule{Kind: "Job"}).MatchesEvent(ev))
+	assert.False(t, (&Rule{Deleted: &deleted}).MatchesEvent(ev))
+	assert.True(t, (&Rule{Deleted: &exists}).MatchesEvent(ev))
+
+	ev.InvolvedObject.Deleted = true
+	assert.True(t, (&Rule{Kind: "Job", Deleted: &deleted}).MatchesEvent(ev))
+	assert.False(t, (&Rule{Deleted: &exists}).MatchesEvent(ev))
+}
+
+func TestClusterName(t *testing.T) {
+	ev := &kube.EnhancedEvent{ClusterName: "prod-eu"}
+	ev.Reason = "BackOff"
+
+	assert.True(t, (&Rule{ClusterName: "^prod-"}).MatchesEvent(ev))
+	assert.False(t, (&Rule{ClusterName: "^staging-", Reason: "BackOff"}).MatchesEvent(ev))
+}
+
+func TestOwner(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.Kind = "Pod"
+
+	assert.False(t, (&Rule{OwnerKind: "Deployment"}).MatchesEvent(ev))
+	ev.Owner = &kube.Owner{Kind: "Deployment", Name: "checkout"}
+	assert.True(t, (&Rule{OwnerKind: "Deployment", OwnerName: "^checkout$"}).MatchesEvent(ev))
+	assert.False(t, (&Rule{OwnerKind: "StatefulSet"}).MatchesEvent(ev))
+}
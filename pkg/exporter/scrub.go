@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const defaultScrubReplacement = "[REDACTED]"
+
+// builtinScrubDetectors are the detectors that can be enabled by name in the scrub config. They are intentionally
+// conservative, the goal is to catch the obvious leaks and not to be a full DLP solution.
+var builtinScrubDetectors = map[string]string{
+	"email":  `[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`,
+	"ipv4":   `\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b`,
+	"ipv6":   `[0-9a-fA-F]*:[0-9a-fA-F:.]*[0-9a-fA-F:]`,
+	"bearer": `(?i)bearer\s+[a-zA-Z0-9\-._~+/]+=*`,
+	"awsKey": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+}
+
+// builtinScrubValidators check the candidates of the detectors whose pattern alone is too broad, by the position of the
+// candidate in the input
+var builtinScrubValidators = map[string]func(in string, loc []int) bool{
+	"ipv6": validIPv6,
+}
+
+// validIPv6 accepts the addresses in all their forms, compressed with :: and IPv4-mapped, that are not part of a
+// longer word like std::string
+func validIPv6(in string, loc []int) bool {
+	if loc[0] > 0 && isWordByte(in[loc[0]-1]) || loc[1] < len(in) && isWordByte(in[loc[1]]) {
+		return false
+	}
+	candidate := in[loc[0]:loc[1]]
+	return strings.ContainsAny(candidate, "0123456789abcdefABCDEF") && net.ParseIP(candidate) != nil
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// ScrubPattern is a user defined pattern that should be masked
+type ScrubPattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+}
+
+// ScrubConfig configures masking of sensitive data in events before they are handed to any receiver
+type ScrubConfig struct {
+	// Detectors is a list of built-in detectors to enable: email, ipv4, ipv6, bearer, awsKey
+	Detectors   []string       `yaml:"detectors"`
+	Patterns    []ScrubPattern `yaml:"patterns"`
+	Replacement string         `yaml:"replacement"`
+	// Annotations also scrubs the values of the event and the involved object annotations
+	Annotations bool `yaml:"annotations"`
+}
+
+// Scrubber masks matches of the configured detectors in the event message and optionally in annotations
+type Scrubber struct {
+	patterns    []scrubPattern
+	replacement string
+	annotations bool
+}
+
+type scrubPattern struct {
+	re *regexp.Regexp
+	// valid filters the matches, all matches are masked if nil
+	valid func(in string, loc []int) bool
+}
+
+func NewScrubber(cfg *ScrubConfig) (*Scrubber, error) {
+	s := &Scrubber{
+		replacement: cfg.Replacement,
+		annotations: cfg.Annotations,
+	}
+	if s.replacement == "" {
+		s.replacement = defaultScrubReplacement
+	}
+
+	for _, name := range cfg.Detectors {
+		expr, ok := builtinScrubDetectors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown scrub detector: %s", name)
+		}
+		s.patterns = append(s.patterns, scrubPattern{re: regexp.MustCompile(expr), valid: builtinScrubValidators[name]})
+	}
+
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub pattern %q: %w", p.Name, err)
+		}
+		s.patterns = append(s.patterns, scrubPattern{re: re})
+	}
+
+	return s, nil
+}
+
+// Scrub masks the sensitive data in place
+func (s *Scrubber) Scrub(ev *kube.EnhancedEvent) {
+	ev.Message = s.scrubString(ev.Message)
+	if s.annotations {
+		ev.Annotations = s.scrubMap(ev.Annotations)
+		ev.InvolvedObject.Annotations = s.scrubMap(ev.InvolvedObject.Annotations)
+	}
+}
+
+func (s *Scrubber) scrubString(in string) string {
+	for _, p := range s.patterns {
+		if p.valid == nil {
+			in = p.re.ReplaceAllLiteralString(in, s.replacement)
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range p.re.FindAllStringIndex(in, -1) {
+			if !p.valid(in, loc) {
+				continue
+			}
+			b.WriteString(in[last:loc[0]])
+			b.WriteString(s.replacement)
+			last = loc[1]
+		}
+		if last > 0 {
+			b.WriteString(in[last:])
+			in = b.String()
+		}
+	}
+	return in
+}
+
+// scrubMap returns a copy so that maps shared with the metadata cache are not modified
+func (s *Scrubber) scrubMap(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return in
+	}
+	ret := make(map[string]string, len(in))
+	for k, v := range in {
+		ret[k] = s.scrubString(v)
+	}
+	return ret
+}
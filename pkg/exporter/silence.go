@@ -0,0 +1,418 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const (
+	defaultSilenceDuration    = time.Hour
+	defaultMaxSilenceDuration = 7 * 24 * time.Hour
+	slackRequestMaxAge        = 5 * time.Minute
+	slackResponseTimeout      = 5 * time.Second
+	maxSilenceRequestBytes    = 64 * 1024
+	filterSilencesKey         = "silence-filters"
+)
+
+// SilenceConfig enables muting events from chat. Responders create a temporary silence for the key of an event, e.g.
+// with a Slack button or a Teams action calling the API, and the events with that key are dropped until it expires.
+type SilenceConfig struct {
+	// Key is the template identifying the events a silence applies to, by default the involved object and the reason
+	Key string `yaml:"key"`
+	// Token authenticates the requests to the API as a bearer token
+	Token string `yaml:"token"`
+	// SlackSigningSecret verifies the requests of the Slack interactive buttons
+	SlackSigningSecret string `yaml:"slackSigningSecret"`
+	DefaultDuration    string `yaml:"defaultDuration"`
+	MaxDuration        string `yaml:"maxDuration"`
+}
+
+// Silence mutes the events with the key, or the events matching the filter, until it expires
+type Silence struct {
+	Key       string            `json:"key,omitempty"`
+	Filter    sinks.EventFilter `json:"filter,omitempty"`
+	Until     time.Time         `json:"until"`
+	CreatedBy string            `json:"createdBy,omitempty"`
+	Comment   string            `json:"comment,omitempty"`
+}
+
+// Silencer sets .SilenceKey of the events, drops the silenced ones and serves the API to manage the silences. The
+// silences are kept in the shared state store.
+type Silencer struct {
+	cfg             *SilenceConfig
+	key             string
+	defaultDuration time.Duration
+	maxDuration     time.Duration
+	store           sinks.StateStore
+	// filtersMu serializes the updates of the filter silences, which are kept in a single entry
+	filtersMu sync.Mutex
+}
+
+func NewSilencer(cfg *SilenceConfig, store sinks.StateStore) (*Silencer, error) {
+	if cfg.Token == "" && cfg.SlackSigningSecret == "" {
+		return nil, errors.New("token or slackSigningSecret must be set")
+	}
+	key := cfg.Key
+	if key == "" {
+		key = defaultPreviousKey
+	}
+	if _, err := sinks.GetString(&kube.EnhancedEvent{}, key); err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	s := &Silencer{
+		cfg:             cfg,
+		key:             key,
+		defaultDuration: defaultSilenceDuration,
+		maxDuration:     defaultMaxSilenceDuration,
+		store:           store,
+	}
+	if cfg.DefaultDuration != "" {
+		d, err := time.ParseDuration(cfg.DefaultDuration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("defaultDuration must be a positive duration like 1h, got %q", cfg.DefaultDuration)
+		}
+		s.defaultDuration = d
+	}
+	if cfg.MaxDuration != "" {
+		d, err := time.ParseDuration(cfg.MaxDuration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("maxDuration must be a positive duration like 24h, got %q", cfg.MaxDuration)
+		}
+		s.maxDuration = d
+	}
+	return s, nil
+}
+
+// Silenced sets the silence key of the event and reports whether it is silenced
+func (s *Silencer) Silenced(ev *kube.EnhancedEvent) bool {
+	key, err := sinks.GetString(ev, s.key)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot render the silence key of the event")
+		return false
+	}
+	ev.SilenceKey = key
+
+	if silence, ok := s.Get(key); ok && clock.Now().Before(silence.Until) {
+		return true
+	}
+	filters := s.filterSilences()
+	if len(filters) == 0 {
+		return false
+	}
+	record := sinks.NewHistoryRecord(ev)
+	for _, silence := range filters {
+		if silence.Filter.Matches(&record) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Silencer) Get(key string) (Silence, bool) {
+	values, ok := s.store.Get("silence/" + key)
+	if !ok {
+		return Silence{}, false
+	}
+	until, err := time.Parse(time.RFC3339, values["until"])
+	if err != nil {
+		return Silence{}, false
+	}
+	return Silence{Key: key, Until: until, CreatedBy: values["createdBy"], Comment: values["comment"]}, true
+}
+
+// Silence mutes the key for the duration, the default duration is used if it is zero
+func (s *Silencer) Silence(key string, duration time.Duration, createdBy, comment string) (Silence, error) {
+	if key == "" {
+		return Silence{}, errors.New("the key must be set")
+	}
+	duration, err := s.duration(duration)
+	if err != nil {
+		return Silence{}, err
+	}
+
+	silence := Silence{Key: key, Until: clock.Now().Add(duration).UTC().Truncate(time.Second), CreatedBy: createdBy, Comment: comment}
+	err = s.store.Set("silence/"+key, map[string]string{
+		"until":     silence.Until.Format(time.RFC3339),
+		"createdBy": createdBy,
+		"comment":   comment,
+	})
+	if err == nil {
+		err = s.store.Expire("silence/"+key, duration)
+	}
+	if err != nil {
+		return Silence{}, err
+	}
+	log.Info().Str("key", key).Time("until", silence.Until).Str("createdBy", createdBy).Msg("Silence created")
+	return silence, nil
+}
+
+func (s *Silencer) Unsilence(key string) error {
+	log.Info().Str("key", key).Msg("Silence removed")
+	return s.store.Delete("silence/" + key)
+}
+
+func (s *Silencer) duration(d time.Duration) (time.Duration, error) {
+	if d == 0 {
+		d = s.defaultDuration
+	}
+	if d < 0 || d > s.maxDuration {
+		return 0, fmt.Errorf("the duration must be positive and at most %s", s.maxDuration)
+	}
+	return d, nil
+}
+
+// SilenceFilter mutes the events matching the filter for the duration, e.g. every BackOff event of a namespace. The
+// filter silences are kept in a single entry of the state store, concurrent updates from several replicas may be lost.
+func (s *Silencer) SilenceFilter(filter sinks.EventFilter, duration time.Duration, createdBy, comment string) (Silence, error) {
+	if len(filter) == 0 {
+		return Silence{}, errors.New("the filter must be set")
+	}
+	if err := filter.Validate(); err != nil {
+		return Silence{}, err
+	}
+	duration, err := s.duration(duration)
+	if err != nil {
+		return Silence{}, err
+	}
+
+	silence := Silence{Filter: filter, Until: clock.Now().Add(duration).UTC().Truncate(time.Second), CreatedBy: createdBy, Comment: comment}
+	err = s.updateFilterSilences(func(silences map[string]string) {
+		value, _ := json.Marshal(silence)
+		silences[filter.String()] = string(value)
+	})
+	if err != nil {
+		return Silence{}, err
+	}
+	log.Info().Str("filter", filter.String()).Time("until", silence.Until).Str("createdBy", createdBy).Msg("Silence created")
+	return silence, nil
+}
+
+func (s *Silencer) UnsilenceFilter(filter sinks.EventFilter) error {
+	log.Info().Str("filter", filter.String()).Msg("Silence removed")
+	return s.updateFilterSilences(func(silences map[string]string) {
+		delete(silences, filter.String())
+	})
+}
+
+// filterSilences returns the filter silences that did not expire
+func (s *Silencer) filterSilences() []Silence {
+	values, _ := s.store.Get(filterSilencesKey)
+	now := clock.Now()
+	var ret []Silence
+	for _, value := range values {
+		var silence Silence
+		if json.Unmarshal([]byte(value), &silence) == nil && now.Before(silence.Until) {
+			ret = append(ret, silence)
+		}
+	}
+	return ret
+}
+
+// updateFilterSilences applies the change to the filter silences and drops the expired ones
+func (s *Silencer) updateFilterSilences(change func(silences map[string]string)) error {
+	s.filtersMu.Lock()
+	defer s.filtersMu.Unlock()
+
+	silences := make(map[string]string)
+	for _, silence := range s.filterSilences() {
+		value, _ := json.Marshal(silence)
+		silences[silence.Filter.String()] = string(value)
+	}
+	change(silences)
+	if len(silences) == 0 {
+		return s.store.Delete(filterSilencesKey)
+	}
+	return s.store.Set(filterSilencesKey, silences)
+}
+
+// ServeHTTP serves the silences API. The requests to /api/v1/silences are authenticated with the token: GET and DELETE
+// take the key as a query parameter, POST a JSON silence with a duration like 1h. The requests to
+// /api/v1/silences/slack are the Slack interactive buttons.
+func (s *Silencer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSilenceRequestBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/slack") {
+		s.serveSlack(w, r, body)
+		return
+	}
+
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		silence, ok := s.Get(r.URL.Query().Get("key"))
+		if !ok {
+			http.Error(w, "silence not found", http.StatusNotFound)
+			return
+		}
+		writeSilence(w, silence)
+	case http.MethodPost:
+		var req struct {
+			Key       string `json:"key"`
+			Duration  string `json:"duration"`
+			CreatedBy string `json:"createdBy"`
+			Comment   string `json:"comment"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "invalid silence: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		silence, err := s.silenceFor(req.Key, req.Duration, req.CreatedBy, req.Comment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeSilence(w, silence)
+	case http.MethodDelete:
+		if err := s.Unsilence(r.URL.Query().Get("key")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Silencer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return s.cfg.Token != "" && ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) == 1
+}
+
+func (s *Silencer) silenceFor(key, duration, createdBy, comment string) (Silence, error) {
+	var d time.Duration
+	if duration != "" {
+		var err error
+		if d, err = time.ParseDuration(duration); err != nil {
+			return Silence{}, fmt.Errorf("invalid duration %q", duration)
+		}
+	}
+	return s.Silence(key, d, createdBy, comment)
+}
+
+func writeSilence(w http.ResponseWriter, silence Silence) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(silence)
+}
+
+type slackInteraction struct {
+	User struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Actions []struct {
+		Value string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+func (s *Silencer) serveSlack(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.verifySlackSignature(r.Header, body, time.Now()) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &interaction); err != nil || len(interaction.Actions) == 0 {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	user := interaction.User.Username
+	if user == "" {
+		user = interaction.User.Name
+	}
+	// The value of the buttons added by the Slack sink is the duration and the key separated by a pipe
+	duration, key, _ := strings.Cut(interaction.Actions[0].Value, "|")
+	silence, err := s.silenceFor(key, duration, user, "Silenced from Slack")
+
+	text := fmt.Sprintf("Silenced `%s` until %s", key, silence.Until.Format(time.RFC1123))
+	if err != nil {
+		text = "Cannot silence the event: " + err.Error()
+	}
+	w.WriteHeader(http.StatusOK)
+	if interaction.ResponseURL != "" {
+		go respondToSlack(interaction.ResponseURL, text)
+	}
+}
+
+func (s *Silencer) verifySlackSignature(header http.Header, body []byte, now time.Time) bool {
+	return verifySlackSignature(s.cfg.SlackSigningSecret, header, body, now)
+}
+
+// verifySlackSignature checks the signature of the request, see https://api.slack.com/authentication/verifying-requests-from-slack
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+// respondToSlack shows the result only to the user who clicked the button
+func respondToSlack(responseURL, text string) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"response_type":    "ephemeral",
+		"replace_original": false,
+		"text":             text,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), slackResponseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cannot respond to the Slack action")
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func newTestSilencer(t *testing.T) *Silencer {
+	s, err := NewSilencer(&SilenceConfig{
+		Key:                "{{ .Namespace }}/{{ .Reason }}",
+		Token:              "s3cret",
+		SlackSigningSecret: "signing",
+		MaxDuration:        "24h",
+	}, sinks.NewInMemoryStateStore())
+	require.NoError(t, err)
+	return s
+}
+
+func TestSilencer_API(t *testing.T) {
+	s := newTestSilencer(t)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "prod"
+	ev.Reason = "BackOff"
+	assert.False(t, s.Silenced(ev))
+	assert.Equal(t, "prod/BackOff", ev.SilenceKey)
+
+	unauthorized := httptest.NewRecorder()
+	s.ServeHTTP(unauthorized, httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(`{"key":"prod/BackOff"}`)))
+	assert.Equal(t, http.StatusUnauthorized, unauthorized.Code)
+
+	tooLong := httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(`{"key":"prod/BackOff","duration":"48h"}`))
+	tooLong.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, tooLong)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	create := httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(`{"key":"prod/BackOff","duration":"2h","createdBy":"jane"}`))
+	create.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, create)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"createdBy":"jane"`)
+	assert.True(t, s.Silenced(ev))
+
+	remove := httptest.NewRequest(http.MethodDelete, "/api/v1/silences?key=prod%2FBackOff", nil)
+	remove.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, remove)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, s.Silenced(ev))
+}
+
+func TestSilencer_Slack(t *testing.T) {
+	s := newTestSilencer(t)
+
+	body := "payload=" + url.QueryEscape(`{"user":{"username":"jane"},"actions":[{"value":"1h|prod/BackOff"}]}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("signing"))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	forged := httptest.NewRequest(http.MethodPost, "/api/v1/silences/slack", strings.NewReader(body))
+	forged.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	forged.Header.Set("X-Slack-Signature", "v0=00")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, forged)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/silences/slack", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	silence, ok := s.Get("prod/BackOff")
+	require.True(t, ok)
+	assert.Equal(t, "jane", silence.CreatedBy)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), silence.Until, time.Minute)
+}
+
+func TestEngine_DropsSilencedEvents(t *testing.T) {
+	reg := &testReceiverRegistry{}
+	engine := &Engine{
+		Route:    Route{Match: []Rule{{Receiver: "stdout"}}},
+		Registry: reg,
+		Silencer: newTestSilencer(t),
+	}
+
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "prod"
+	ev.Reason = "BackOff"
+	_, err := engine.Silencer.Silence("prod/BackOff", time.Hour, "jane", "")
+	require.NoError(t, err)
+	engine.OnEvent(ev)
+	assert.Equal(t, 0, reg.count("stdout"))
+
+	other := &kube.EnhancedEvent{}
+	other.Namespace = "dev"
+	engine.OnEvent(other)
+	assert.Equal(t, 1, reg.count("stdout"))
+}
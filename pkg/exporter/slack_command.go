@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const (
+	defaultSlackCommandHistorySize = 1000
+	defaultSlackCommandMaxResults  = 10
+	// Slack shows an error if the command is not answered within 3 seconds
+	slackCommandTimeout       = 2 * time.Second
+	maxSlackCommandMessageLen = 200
+)
+
+// SlackCommandConfig serves a Slack slash command like /kube-events, to list the recent events and to mute events
+// from Slack. Muting requires the silences to be enabled.
+type SlackCommandConfig struct {
+	SigningSecret string `yaml:"signingSecret"`
+	// History is the name of a receiver keeping the events, like sqlite. By default, the last HistorySize events are
+	// kept in memory.
+	History     string `yaml:"history"`
+	HistorySize int    `yaml:"historySize"`
+	MaxResults  int    `yaml:"maxResults"`
+}
+
+func (c *SlackCommandConfig) validate() error {
+	if c.SigningSecret == "" {
+		return errors.New("signingSecret must be set")
+	}
+	if c.HistorySize < 0 || c.MaxResults < 0 {
+		return errors.New("historySize and maxResults must not be negative")
+	}
+	return nil
+}
+
+// SlackCommands answers the slash commands, the requests are verified with the signing secret of the Slack app
+type SlackCommands struct {
+	cfg      *SlackCommandConfig
+	history  sinks.EventHistory
+	silencer *Silencer
+}
+
+// NewSlackCommands returns the command handler, the silencer is nil if the silences are disabled
+func NewSlackCommands(cfg *SlackCommandConfig, history sinks.EventHistory, silencer *Silencer) (*SlackCommands, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxResults == 0 {
+		cfg.MaxResults = defaultSlackCommandMaxResults
+	}
+	return &SlackCommands{cfg: cfg, history: history, silencer: silencer}, nil
+}
+
+type slackCommandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func (c *SlackCommands) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSilenceRequestBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !verifySlackSignature(c.cfg.SigningSecret, r.Header, body, time.Now()) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), slackCommandTimeout)
+	defer cancel()
+	resp := c.run(ctx, form.Get("command"), form.Get("text"), form.Get("user_name"))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// run executes the command text, e.g. "recent ns=prod" or "mute reason=BackOff 2h"
+func (c *SlackCommands) run(ctx context.Context, command, text, user string) slackCommandResponse {
+	args := strings.Fields(text)
+	if len(args) == 0 {
+		return c.usage(command)
+	}
+
+	var resp slackCommandResponse
+	var err error
+	switch args[0] {
+	case "recent":
+		resp, err = c.recent(ctx, args[1:])
+	case "mute":
+		resp, err = c.mute(args[1:], user)
+	case "unmute":
+		resp, err = c.unmute(args[1:], user)
+	case "silences":
+		resp, err = c.silences()
+	default:
+		return c.usage(command)
+	}
+	if err != nil {
+		return slackCommandResponse{ResponseType: "ephemeral", Text: err.Error()}
+	}
+	return resp
+}
+
+func (c *SlackCommands) usage(command string) slackCommandResponse {
+	if command == "" {
+		command = "/kube-events"
+	}
+	return slackCommandResponse{ResponseType: "ephemeral", Text: strings.Join([]string{
+		"Usage:",
+		fmt.Sprintf("`%s recent [field=value ...] [count]` lists the recent events", command),
+		fmt.Sprintf("`%s mute field=value ... [duration]` mutes the matching events", command),
+		fmt.Sprintf("`%s unmute field=value ...` removes a mute", command),
+		fmt.Sprintf("`%s silences` lists the mutes", command),
+		"The fields are " + strings.Join(sinks.HistoryFields, ", ") + ", ns is short for namespace.",
+	}, "\n")}
+}
+
+// parseArgs splits the arguments into the filter and the others
+func parseArgs(args []string) (sinks.EventFilter, []string, error) {
+	filter := sinks.EventFilter{}
+	var rest []string
+	for _, arg := range args {
+		field, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			rest = append(rest, arg)
+			continue
+		}
+		if field == "ns" {
+			field = "namespace"
+		}
+		filter[field] = value
+	}
+	return filter, rest, filter.Validate()
+}
+
+func (c *SlackCommands) recent(ctx context.Context, args []string) (slackCommandResponse, error) {
+	if c.history == nil {
+		return slackCommandResponse{}, errors.New("the event history is disabled")
+	}
+	filter, rest, err := parseArgs(args)
+	if err != nil {
+		return slackCommandResponse{}, err
+	}
+	limit := c.cfg.MaxResults
+	if len(rest) > 0 {
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n <= 0 {
+			return slackCommandResponse{}, fmt.Errorf("invalid count %q", rest[0])
+		}
+		limit = min(n, c.cfg.MaxResults)
+	}
+
+	records, err := c.history.Recent(ctx, filter, limit)
+	if err != nil {
+		return slackCommandResponse{}, fmt.Errorf("cannot read the event history: %w", err)
+	}
+	if len(records) == 0 {
+		return slackCommandResponse{ResponseType: "ephemeral", Text: "No recent events match " + describeFilter(filter)}, nil
+	}
+	lines := make([]string, 0, len(records))
+	for _, r := range records {
+		message := r.Message
+		if runes := []rune(message); len(runes) > maxSlackCommandMessageLen {
+			message = string(runes[:maxSlackCommandMessageLen]) + "…"
+		}
+		lines = append(lines, fmt.Sprintf("`%s` *%s* %s %s %s/%s: %s",
+			r.Time.UTC().Format(time.DateTime), r.Type, r.Reason, r.Kind, r.Namespace, r.Name, message))
+	}
+	return slackCommandResponse{ResponseType: "ephemeral", Text: strings.Join(lines, "\n")}, nil
+}
+
+func (c *SlackCommands) mute(args []string, user string) (slackCommandResponse, error) {
+	if c.silencer == nil {
+		return slackCommandResponse{}, errors.New("the silences are disabled")
+	}
+	filter, rest, err := parseArgs(args)
+	if err != nil {
+		return slackCommandResponse{}, err
+	}
+	var duration time.Duration
+	if len(rest) > 0 {
+		if duration, err = time.ParseDuration(rest[0]); err != nil {
+			return slackCommandResponse{}, fmt.Errorf("invalid duration %q", rest[0])
+		}
+	}
+	silence, err := c.silencer.SilenceFilter(filter, duration, user, "Muted from Slack")
+	if err != nil {
+		return slackCommandResponse{}, err
+	}
+	// The mutes are shown in the channel, so everyone knows why events are missing
+	return slackCommandResponse{
+		ResponseType: "in_channel",
+		Text:         fmt.Sprintf("%s muted the events matching %s until %s", user, describeFilter(filter), silence.Until.Format(time.RFC1123)),
+	}, nil
+}
+
+func (c *SlackCommands) unmute(args []string, user string) (slackCommandResponse, error) {
+	if c.silencer == nil {
+		return slackCommandResponse{}, errors.New("the silences are disabled")
+	}
+	filter, _, err := parseArgs(args)
+	if err != nil {
+		return slackCommandResponse{}, err
+	}
+	if err := c.silencer.UnsilenceFilter(filter); err != nil {
+		return slackCommandResponse{}, err
+	}
+	return slackCommandResponse{
+		ResponseType: "in_channel",
+		Text:         fmt.Sprintf("%s unmuted the events matching %s", user, describeFilter(filter)),
+	}, nil
+}
+
+func (c *SlackCommands) silences() (slackCommandResponse, error) {
+	if c.silencer == nil {
+		return slackCommandResponse{}, errors.New("the silences are disabled")
+	}
+	silences := c.silencer.filterSilences()
+	if len(silences) == 0 {
+		return slackCommandResponse{ResponseType: "ephemeral", Text: "No events are muted"}, nil
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].Filter.String() < silences[j].Filter.String() })
+	lines := make([]string, 0, len(silences))
+	for _, silence := range silences {
+		lines = append(lines, fmt.Sprintf("%s until %s by %s",
+			describeFilter(silence.Filter), silence.Until.Format(time.RFC1123), silence.CreatedBy))
+	}
+	return slackCommandResponse{ResponseType: "ephemeral", Text: strings.Join(lines, "\n")}, nil
+}
+
+func describeFilter(filter sinks.EventFilter) string {
+	if len(filter) == 0 {
+		return "all events"
+	}
+	return "`" + filter.String() + "`"
+}
@@ -0,0 +1,126 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+func runSlackCommand(t *testing.T, c *SlackCommands, text string) slackCommandResponse {
+	body := url.Values{"command": {"/kube-events"}, "text": {text}, "user_name": {"jane"}}.Encode()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("signing"))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/slack/commands", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp slackCommandResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func newTestEvent(namespace, name, reason string) *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = namespace
+	ev.InvolvedObject.Namespace = namespace
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Name = name
+	ev.Reason = reason
+	ev.Type = "Warning"
+	ev.Message = reason + " of " + name
+	return ev
+}
+
+func TestSlackCommands_Recent(t *testing.T) {
+	history := sinks.NewMemoryHistory(3)
+	for i, ns := range []string{"prod", "dev", "prod", "prod", "dev"} {
+		history.Add(newTestEvent(ns, "api-"+strconv.Itoa(i), "BackOff"))
+	}
+	c, err := NewSlackCommands(&SlackCommandConfig{SigningSecret: "signing"}, history, nil)
+	require.NoError(t, err)
+
+	// The oldest events were overwritten
+	resp := runSlackCommand(t, c, "recent ns=prod")
+	assert.Equal(t, "ephemeral", resp.ResponseType)
+	lines := strings.Split(resp.Text, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "prod/api-3: BackOff of api-3")
+	assert.Contains(t, lines[1], "prod/api-2")
+
+	resp = runSlackCommand(t, c, "recent 1")
+	assert.Contains(t, resp.Text, "dev/api-4")
+	assert.NotContains(t, resp.Text, "\n")
+
+	resp = runSlackCommand(t, c, "recent reason=Killing")
+	assert.Equal(t, "No recent events match `reason=Killing`", resp.Text)
+
+	resp = runSlackCommand(t, c, "recent pod=api")
+	assert.Contains(t, resp.Text, `unknown field "pod"`)
+
+	resp = runSlackCommand(t, c, "mute reason=BackOff")
+	assert.Equal(t, "the silences are disabled", resp.Text)
+
+	resp = runSlackCommand(t, c, "")
+	assert.Contains(t, resp.Text, "Usage:")
+}
+
+func TestSlackCommands_Mute(t *testing.T) {
+	s := newTestSilencer(t)
+	c, err := NewSlackCommands(&SlackCommandConfig{SigningSecret: "signing"}, nil, s)
+	require.NoError(t, err)
+
+	backOff := newTestEvent("prod", "api", "BackOff")
+	other := newTestEvent("dev", "api", "BackOff")
+	assert.False(t, s.Silenced(backOff))
+
+	resp := runSlackCommand(t, c, "mute ns=prod reason=BackOff 48h")
+	assert.Contains(t, resp.Text, "at most 24h")
+
+	resp = runSlackCommand(t, c, "mute")
+	assert.Equal(t, "the filter must be set", resp.Text)
+
+	resp = runSlackCommand(t, c, "mute ns=prod reason=BackOff 2h")
+	assert.Equal(t, "in_channel", resp.ResponseType)
+	assert.Contains(t, resp.Text, "jane muted the events matching `namespace=prod,reason=BackOff`")
+	assert.True(t, s.Silenced(backOff))
+	assert.False(t, s.Silenced(other))
+
+	resp = runSlackCommand(t, c, "silences")
+	assert.Contains(t, resp.Text, "`namespace=prod,reason=BackOff` until")
+	assert.Contains(t, resp.Text, "by jane")
+
+	runSlackCommand(t, c, "unmute reason=BackOff namespace=prod")
+	assert.False(t, s.Silenced(backOff))
+	resp = runSlackCommand(t, c, "silences")
+	assert.Equal(t, "No events are muted", resp.Text)
+}
+
+func TestSlackCommands_RejectsUnsignedRequests(t *testing.T) {
+	c, err := NewSlackCommands(&SlackCommandConfig{SigningSecret: "signing"}, sinks.NewMemoryHistory(1), nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/slack/commands", strings.NewReader("text=recent"))
+	req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
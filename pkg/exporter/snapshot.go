@@ -0,0 +1,207 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const (
+	snapshotVersion = 1
+	snapshotKey     = "snapshot.json"
+	// maxConfigMapSnapshotBytes stays below the 1MiB limit of a ConfigMap
+	maxConfigMapSnapshotBytes = 900 << 10
+)
+
+// SnapshotConfig saves the events still queued for the receivers and the state store on shutdown, to a file on a
+// persistent volume or to a ConfigMap, and restores them on the next start.
+type SnapshotConfig struct {
+	Path      string                   `yaml:"path,omitempty"`
+	ConfigMap *SnapshotConfigMapConfig `yaml:"configMap,omitempty"`
+}
+
+type SnapshotConfigMapConfig struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+}
+
+func (c *SnapshotConfig) validate() error {
+	if (c.Path == "") == (c.ConfigMap == nil) {
+		return errors.New("either path or configMap must be set")
+	}
+	if c.ConfigMap != nil && (c.ConfigMap.Namespace == "" || c.ConfigMap.Name == "") {
+		return errors.New("configMap.namespace and configMap.name must be set")
+	}
+	return nil
+}
+
+// Snapshot is the saved state, the queued events are keyed by receiver
+type Snapshot struct {
+	Version int                             `json:"version"`
+	Created time.Time                       `json:"created"`
+	Queues  map[string][]kube.EnhancedEvent `json:"queues,omitempty"`
+	State   map[string]sinks.StateEntry     `json:"state,omitempty"`
+}
+
+// Snapshotter collects the queued events while the registry closes and saves them with the state store
+type Snapshotter struct {
+	cfg    *SnapshotConfig
+	client kubernetes.Interface
+
+	mu     sync.Mutex
+	queues map[string][]kube.EnhancedEvent
+}
+
+// NewSnapshotter returns the snapshotter, the client is only used with a ConfigMap
+func NewSnapshotter(cfg *SnapshotConfig, client kubernetes.Interface) (*Snapshotter, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &Snapshotter{cfg: cfg, client: client, queues: make(map[string][]kube.EnhancedEvent)}, nil
+}
+
+// Collect keeps the queued events of a receiver, it is the OnClose of the registry
+func (s *Snapshotter) Collect(name string, queued []kube.EnhancedEvent) {
+	if len(queued) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queues[name] = append(s.queues[name], queued...)
+}
+
+// Save writes the collected events and the state store
+func (s *Snapshotter) Save(ctx context.Context) error {
+	s.mu.Lock()
+	snapshot := Snapshot{Version: snapshotVersion, Created: time.Now().UTC(), Queues: s.queues}
+	s.mu.Unlock()
+	if store, ok := sinks.GetStateStore().(sinks.StateSnapshotter); ok {
+		snapshot.State = store.Snapshot()
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if s.cfg.ConfigMap != nil && len(data) > maxConfigMapSnapshotBytes {
+		// The state is small, the queues are dropped to fit
+		log.Warn().Int("bytes", len(data)).Msg("The snapshot does not fit in a ConfigMap, the queued events are dropped")
+		snapshot.Queues = nil
+		if data, err = json.Marshal(snapshot); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.Path != "" {
+		// Renaming makes the write atomic, a crash does not leave a partial snapshot
+		tmp := s.cfg.Path + ".tmp"
+		if err := os.MkdirAll(filepath.Dir(s.cfg.Path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, s.cfg.Path)
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.cfg.ConfigMap.Namespace)
+	cm, err := configMaps.Get(ctx, s.cfg.ConfigMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: s.cfg.ConfigMap.Name, Namespace: s.cfg.ConfigMap.Namespace}}
+		cm.Data = map[string]string{snapshotKey: string(data)}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[snapshotKey] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// Load reads and removes the snapshot, so it is restored only once. It returns nil if there is none.
+func (s *Snapshotter) Load(ctx context.Context) (*Snapshot, error) {
+	var data []byte
+	if s.cfg.Path != "" {
+		var err error
+		data, err = os.ReadFile(s.cfg.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(s.cfg.Path); err != nil {
+			return nil, err
+		}
+	} else {
+		configMaps := s.client.CoreV1().ConfigMaps(s.cfg.ConfigMap.Namespace)
+		cm, err := configMaps.Get(ctx, s.cfg.ConfigMap.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, ok := cm.Data[snapshotKey]
+		if !ok {
+			return nil, nil
+		}
+		data = []byte(value)
+		delete(cm.Data, snapshotKey)
+		if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// Restore merges the state into the state store and queues the events again for the receivers that still exist.
+// The events skip the route, they were already routed before the shutdown.
+func (s *Snapshot) Restore(registry ReceiverRegistry, receivers []string) {
+	if store, ok := sinks.GetStateStore().(sinks.StateSnapshotter); ok && len(s.State) > 0 {
+		store.Restore(s.State)
+	}
+	exists := make(map[string]bool, len(receivers))
+	for _, name := range receivers {
+		exists[name] = true
+	}
+	restored := 0
+	for name, events := range s.Queues {
+		if !exists[name] {
+			log.Warn().Str("receiver", name).Int("events", len(events)).Msg("Dropping the snapshot events of a removed receiver")
+			continue
+		}
+		for i := range events {
+			registry.SendEvent(name, &events[i])
+		}
+		restored += len(events)
+	}
+	log.Info().Int("events", restored).Int("state", len(s.State)).Time("created", s.Created).Msg("Restored the snapshot")
+}
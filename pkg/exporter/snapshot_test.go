@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// blockedSink does not return from Send until it is closed, so the later events stay queued
+type blockedSink struct {
+	sent    chan struct{}
+	release chan struct{}
+}
+
+func (s *blockedSink) Send(context.Context, *kube.EnhancedEvent) error {
+	s.sent <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func (s *blockedSink) Close() {}
+
+func TestSnapshotter_SaveAndRestore(t *testing.T) {
+	defer sinks.SetStateStore(sinks.GetStateStore())
+	store := sinks.NewInMemoryStateStore()
+	sinks.SetStateStore(store)
+	require.NoError(t, store.Set("incident/api", map[string]string{"id": "42"}))
+	require.NoError(t, store.Expire("incident/api", time.Hour))
+
+	path := filepath.Join(t.TempDir(), "snapshot", "snapshot.json")
+	snapshotter, err := NewSnapshotter(&SnapshotConfig{Path: path}, nil)
+	require.NoError(t, err)
+
+	sink := &blockedSink{sent: make(chan struct{}, 1), release: make(chan struct{})}
+	registry := &ChannelBasedReceiverRegistry{OnClose: snapshotter.Collect}
+	registry.Register("slack", sink)
+	registry.SendEvent("slack", newTestEvent("prod", "api-0", "BackOff"))
+	<-sink.sent
+	registry.SendEvent("slack", newTestEvent("prod", "api-1", "BackOff"))
+	registry.SendEvent("slack", newTestEvent("prod", "api-2", "Killing"))
+	go func() {
+		// Let the sink return once the registry is closing
+		time.Sleep(50 * time.Millisecond)
+		close(sink.release)
+	}()
+	registry.Close()
+	require.NoError(t, snapshotter.Save(context.Background()))
+
+	// A new process starts with an empty state store
+	store = sinks.NewInMemoryStateStore()
+	sinks.SetStateStore(store)
+	snapshot, err := snapshotter.Load(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.NoFileExists(t, path)
+
+	memory := &sinks.InMemory{}
+	sync := &SyncRegistry{}
+	sync.Register("slack", memory)
+	sync.Register("other", &sinks.InMemory{})
+	snapshot.Queues["removed"] = []kube.EnhancedEvent{*newTestEvent("dev", "api", "BackOff")}
+	snapshot.Restore(sync, []string{"slack", "other"})
+
+	require.Len(t, memory.Events, 2)
+	assert.Equal(t, "api-1", memory.Events[0].InvolvedObject.Name)
+	assert.Equal(t, "Killing", memory.Events[1].Reason)
+	values, ok := store.Get("incident/api")
+	require.True(t, ok)
+	assert.Equal(t, "42", values["id"])
+
+	// The snapshot is restored once
+	snapshot, err = snapshotter.Load(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+}
+
+func TestSnapshotter_ConfigMap(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	snapshotter, err := NewSnapshotter(&SnapshotConfig{ConfigMap: &SnapshotConfigMapConfig{Namespace: "monitoring", Name: "exporter-snapshot"}}, client)
+	require.NoError(t, err)
+
+	snapshot, err := snapshotter.Load(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	snapshotter.Collect("slack", []kube.EnhancedEvent{*newTestEvent("prod", "api-0", "BackOff")})
+	require.NoError(t, snapshotter.Save(context.Background()))
+	snapshot, err = snapshotter.Load(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	require.Len(t, snapshot.Queues["slack"], 1)
+	assert.Equal(t, "api-0", snapshot.Queues["slack"][0].InvolvedObject.Name)
+
+	_, err = NewSnapshotter(&SnapshotConfig{}, nil)
+	assert.Error(t, err)
+}
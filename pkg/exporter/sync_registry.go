@@ -2,6 +2,8 @@ package exporter
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -12,17 +14,34 @@ import (
 // SyncRegistry is for development purposes and performs poorly and blocks when an event is received so it is
 // not suited for high volume & production workloads
 type SyncRegistry struct {
+	mu  sync.RWMutex
 	reg map[string]sinks.Sink
+	// Auditor, if set, records every delivery attempt
+	Auditor Auditor
 }
 
 func (s *SyncRegistry) SendEvent(name string, event *kube.EnhancedEvent) {
-	err := s.reg[name].Send(context.Background(), event)
+	s.mu.RLock()
+	sink := s.reg[name]
+	s.mu.RUnlock()
+	if sink == nil {
+		log.Error().Str("name", name).Msg("There is no sink")
+		return
+	}
+
+	started := time.Now()
+	err := sink.Send(context.Background(), event)
+	if s.Auditor != nil {
+		s.Auditor.Record(newAuditRecord(name, sinks.SinkType(sink), event, started, err))
+	}
 	if err != nil {
 		log.Debug().Err(err).Str("sink", name).Str("event", string(event.UID)).Msg("Cannot send event")
 	}
 }
 
 func (s *SyncRegistry) Register(name string, sink sinks.Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.reg == nil {
 		s.reg = make(map[string]sinks.Sink)
 	}
@@ -30,6 +49,16 @@ func (s *SyncRegistry) Register(name string, sink sinks.Sink) {
 	s.reg[name] = sink
 }
 
+func (s *SyncRegistry) Unregister(name string) {
+	s.mu.Lock()
+	sink, ok := s.reg[name]
+	delete(s.reg, name)
+	s.mu.Unlock()
+	if ok {
+		sink.Close()
+	}
+}
+
 func (s *SyncRegistry) Close() {
 	for name, sink := range s.reg {
 		log.Info().Str("sink", name).Msg("Closing sink")
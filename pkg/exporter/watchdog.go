@@ -0,0 +1,190 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+const (
+	defaultWatchdogMinCount = 2
+	defaultWatchdogReason   = "{{ .Reason }}Cleared"
+	defaultWatchdogMessage  = "{{ .Reason }} has not occurred for {{ .Previous.Ago }} after {{ .Previous.Count }} occurrences"
+)
+
+// WatchdogConfig emits a "cleared" notification when a recurring event stops. Once the events with the same key were
+// seen MinCount times, and then not for the Quiet duration, a Normal event is emitted with the rendered Reason and
+// Message, which see the last event with .Previous set to the number of occurrences and the time of the last one.
+type WatchdogConfig struct {
+	Name string `yaml:"name"`
+	// Match selects the watched events, all events if empty
+	Match []Rule `yaml:"match"`
+	// Key is the template identifying repeated events, by default the involved object and the reason
+	Key      string `yaml:"key"`
+	MinCount int64  `yaml:"minCount"`
+	Quiet    string `yaml:"quiet"`
+	Reason   string `yaml:"reason"`
+	Message  string `yaml:"message"`
+	// Receivers get the notifications, they go through the route if empty
+	Receivers []string `yaml:"receivers"`
+}
+
+func (c *WatchdogConfig) validate() error {
+	if c.Name == "" {
+		return errors.New("name must be non-empty")
+	}
+	if quiet, err := time.ParseDuration(c.Quiet); err != nil || quiet <= 0 {
+		return errors.New("quiet must be a positive duration like 10m")
+	}
+	if c.MinCount < 0 {
+		return errors.New("minCount must not be negative")
+	}
+	for _, tmpl := range []string{c.Key, c.Reason, c.Message} {
+		if _, err := sinks.GetString(&kube.EnhancedEvent{}, tmpl); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return nil
+}
+
+type watchdogEntry struct {
+	last  kube.EnhancedEvent
+	count int64
+	seen  time.Time
+	timer clock.Timer
+}
+
+// Watchdog tracks the watched events by key and emits the notifications
+type Watchdog struct {
+	cfg   *WatchdogConfig
+	quiet time.Duration
+	emit  func(*kube.EnhancedEvent)
+
+	mu      sync.Mutex
+	entries map[string]*watchdogEntry
+	stopped bool
+}
+
+func NewWatchdog(cfg *WatchdogConfig, emit func(*kube.EnhancedEvent)) (*Watchdog, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Key == "" {
+		cfg.Key = defaultPreviousKey
+	}
+	if cfg.MinCount == 0 {
+		cfg.MinCount = defaultWatchdogMinCount
+	}
+	if cfg.Reason == "" {
+		cfg.Reason = defaultWatchdogReason
+	}
+	if cfg.Message == "" {
+		cfg.Message = defaultWatchdogMessage
+	}
+	quiet, _ := time.ParseDuration(cfg.Quiet)
+	return &Watchdog{
+		cfg:     cfg,
+		quiet:   quiet,
+		emit:    emit,
+		entries: make(map[string]*watchdogEntry),
+	}, nil
+}
+
+// Observe records an occurrence of the event if it is watched
+func (w *Watchdog) Observe(ev *kube.EnhancedEvent) {
+	if len(w.cfg.Match) > 0 && !matchesAnyRule(w.cfg.Match, ev) {
+		return
+	}
+	key, err := sinks.GetString(ev, w.cfg.Key)
+	if err != nil {
+		log.Warn().Err(err).Str("watchdog", w.cfg.Name).Msg("Cannot render the key of the event")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	entry, ok := w.entries[key]
+	if !ok {
+		entry = &watchdogEntry{}
+		entry.timer = clock.AfterFunc(w.quiet, func() { w.expire(key, entry) })
+		w.entries[key] = entry
+	} else {
+		entry.timer.Reset(w.quiet)
+	}
+	entry.last = *ev
+	entry.seen = clock.Now()
+	// An update of a Kubernetes event carries the number of occurrences
+	entry.count = max(entry.count+1, int64(ev.Count))
+}
+
+func (w *Watchdog) expire(key string, entry *watchdogEntry) {
+	w.mu.Lock()
+	// The entry may have been seen again while the timer fired
+	if w.stopped || w.entries[key] != entry || clock.Since(entry.seen) < w.quiet {
+		w.mu.Unlock()
+		return
+	}
+	delete(w.entries, key)
+	last, count, seen := entry.last, entry.count, entry.seen
+	w.mu.Unlock()
+
+	if count < w.cfg.MinCount {
+		return
+	}
+	ev, err := w.cleared(&last, count, seen)
+	if err != nil {
+		log.Warn().Err(err).Str("watchdog", w.cfg.Name).Msg("Cannot render the cleared notification")
+		return
+	}
+	log.Debug().Str("watchdog", w.cfg.Name).Str("key", key).Msg("Recurring event stopped")
+	w.emit(ev)
+}
+
+// cleared returns the notification for the last event of a stopped series
+func (w *Watchdog) cleared(last *kube.EnhancedEvent, count int64, seen time.Time) (*kube.EnhancedEvent, error) {
+	// A shallow copy, the maps of the event are not changed
+	ev := *last
+	ev.Previous = kube.Occurrence{Count: count, LastSeen: seen}
+	reason, err := sinks.GetString(&ev, w.cfg.Reason)
+	if err != nil {
+		return nil, err
+	}
+	message, err := sinks.GetString(&ev, w.cfg.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	now := metav1.Now()
+	ev.UID = ""
+	ev.Type = "Normal"
+	ev.Reason = reason
+	ev.Message = message
+	ev.Count = 1
+	ev.FirstTimestamp = now
+	ev.LastTimestamp = now
+	ev.EventTime = metav1.NewMicroTime(now.Time)
+	ev.Series = nil
+	return &ev, nil
+}
+
+// Stop drops the tracked events without notifications
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for key, entry := range w.entries {
+		entry.timer.Stop()
+		delete(w.entries, key)
+	}
+}
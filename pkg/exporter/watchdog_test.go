@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestWatchdog(t *testing.T) {
+	var mu sync.Mutex
+	var emitted []*kube.EnhancedEvent
+	w, err := NewWatchdog(&WatchdogConfig{
+		Name:  "probes",
+		Match: []Rule{{Reason: "Unhealthy|BackOff"}},
+		Quiet: "100ms",
+	}, func(ev *kube.EnhancedEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		emitted = append(emitted, ev)
+	})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	unhealthy := newTestEvent("prod", "api-0", "Unhealthy")
+	unhealthy.UID = "uid-1"
+	w.Observe(unhealthy)
+	time.Sleep(60 * time.Millisecond)
+	unhealthy.Count = 3
+	w.Observe(unhealthy)
+	// A single occurrence is not recurring, other reasons are not watched
+	w.Observe(newTestEvent("prod", "api-1", "Unhealthy"))
+	w.Observe(newTestEvent("prod", "api-0", "Killing"))
+
+	// The second occurrence restarted the quiet period
+	time.Sleep(60 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, emitted)
+	mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(emitted) == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, emitted, 1)
+	ev := emitted[0]
+	assert.Equal(t, "UnhealthyCleared", ev.Reason)
+	assert.Equal(t, "Normal", ev.Type)
+	assert.Equal(t, "api-0", ev.InvolvedObject.Name)
+	assert.Empty(t, ev.UID)
+	assert.Regexp(t, `^Unhealthy has not occurred for \S+ after 3 occurrences$`, ev.Message)
+	assert.Equal(t, int64(3), ev.Previous.Count)
+}
+
+func TestWatchdogConfig_Validate(t *testing.T) {
+	assert.Error(t, (&WatchdogConfig{Name: "a"}).validate())
+	assert.Error(t, (&WatchdogConfig{Name: "a", Quiet: "-1m"}).validate())
+	assert.Error(t, (&WatchdogConfig{Name: "a", Quiet: "10m", Message: "{{ .Reason "}).validate())
+	assert.NoError(t, (&WatchdogConfig{Name: "a", Quiet: "10m"}).validate())
+}
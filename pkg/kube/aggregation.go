@@ -0,0 +1,126 @@
+package kube
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+)
+
+// maxAggregationWindow bounds the window, the events are held in memory meanwhile
+const maxAggregationWindow = 5 * time.Minute
+
+// AggregationConfig collapses the identical events, of the same involved object with the same reason and message,
+// within a window into one event, to reduce the noise of event storms. The first event of a key opens the window, the
+// event is delivered when the window closes, with the number of events it stands for in .Aggregated.
+type AggregationConfig struct {
+	// WindowSeconds is how long the events are held, 30 by default
+	WindowSeconds int `yaml:"windowSeconds,omitempty"`
+}
+
+func (c *AggregationConfig) Validate() error {
+	if c.WindowSeconds < 0 || time.Duration(c.WindowSeconds)*time.Second > maxAggregationWindow {
+		return fmt.Errorf("windowSeconds must be between 0 and %d", int(maxAggregationWindow.Seconds()))
+	}
+	return nil
+}
+
+func (c *AggregationConfig) window() time.Duration {
+	if c.WindowSeconds == 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.WindowSeconds) * time.Second
+}
+
+type aggregation struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[string]*aggregatedEvent
+}
+
+type aggregatedEvent struct {
+	ev    *EnhancedEvent
+	timer clock.Timer
+}
+
+// SetAggregation makes the watcher collapse the identical events within the window of the config
+func (e *EventWatcher) SetAggregation(cfg *AggregationConfig) {
+	e.aggregation = &aggregation{window: cfg.window(), pending: make(map[string]*aggregatedEvent)}
+}
+
+// aggregationKey identifies the identical events, the message is hashed to keep the key short
+func aggregationKey(ev *EnhancedEvent) string {
+	object := string(ev.InvolvedObject.UID)
+	if object == "" {
+		object = ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(ev.Message))
+	return fmt.Sprintf("%s/%s/%x", object, ev.Reason, h.Sum64())
+}
+
+// deliver passes the event to the handler, or holds it until the window of its key closes. The event is released once
+// passed.
+func (e *EventWatcher) deliver(ev *EnhancedEvent) {
+	a := e.aggregation
+	if a == nil {
+		e.fn(ev)
+		ev.Release()
+		return
+	}
+	key := aggregationKey(ev)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if pending, ok := a.pending[key]; ok {
+		// The latest event is delivered, it keeps the first timestamp of the window
+		ev.Aggregated = pending.ev.Aggregated + 1
+		if first := pending.ev.FirstTimestamp; !first.IsZero() && (ev.FirstTimestamp.IsZero() || first.Before(&ev.FirstTimestamp)) {
+			ev.FirstTimestamp = first
+		}
+		// The first event of the window holds the checkpoint until the window closes
+		ev.tracker, pending.ev.tracker = pending.ev.tracker, ev.tracker
+		pending.ev.Release()
+		pending.ev = ev
+		return
+	}
+	ev.Aggregated = 1
+	pending := &aggregatedEvent{ev: ev}
+	a.pending[key] = pending
+	e.wg.Add(1)
+	pending.timer = clock.AfterFunc(a.window, func() {
+		defer e.wg.Done()
+		a.mu.Lock()
+		current, ok := a.pending[key]
+		delete(a.pending, key)
+		a.mu.Unlock()
+		if ok {
+			e.fn(current.ev)
+			current.ev.Release()
+		}
+	})
+}
+
+// flushAggregation delivers the held events right away, when the watcher stops
+func (e *EventWatcher) flushAggregation() {
+	a := e.aggregation
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	events := make([]*EnhancedEvent, 0, len(a.pending))
+	for key, pending := range a.pending {
+		if pending.timer.Stop() {
+			e.wg.Done()
+		}
+		events = append(events, pending.ev)
+		delete(a.pending, key)
+	}
+	a.mu.Unlock()
+	for _, ev := range events {
+		e.fn(ev)
+		ev.Release()
+	}
+}
@@ -0,0 +1,89 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+func TestEventWatcher_Aggregation(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+	ew.SetAggregation(&AggregationConfig{})
+	ew.aggregation.window = 50 * time.Millisecond
+
+	delivered := make(chan *EnhancedEvent, 10)
+	ew.fn = func(e *EnhancedEvent) {
+		delivered <- e
+	}
+	ew.setStartUpTime(time.Now().Add(-time.Minute))
+
+	first := metav1.NewTime(time.Now().Add(-30 * time.Second).Truncate(time.Second))
+	event := func(name, message string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name},
+			FirstTimestamp: first,
+			LastTimestamp:  metav1.Now(),
+			InvolvedObject: corev1.ObjectReference{UID: "pod-1", Kind: "Pod", Name: "checkout-1"},
+			Reason:         "BackOff",
+			Message:        message,
+		}
+	}
+	ew.onEvent(event("event-1", "Back-off restarting failed container"))
+	later := event("event-2", "Back-off restarting failed container")
+	later.FirstTimestamp = metav1.Now()
+	ew.onEvent(later)
+	ew.onEvent(event("event-3", "Back-off restarting failed container"))
+	ew.onEvent(event("event-4", "Back-off pulling image"))
+
+	var received []*EnhancedEvent
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-delivered:
+			received = append(received, ev)
+		case <-time.After(time.Second):
+			t.Fatal("the aggregated events were not delivered")
+		}
+	}
+	byMessage := map[string]*EnhancedEvent{}
+	for _, ev := range received {
+		byMessage[ev.Message] = ev
+	}
+	restarting := byMessage["Back-off restarting failed container"]
+	require.NotNil(t, restarting)
+	assert.Equal(t, int32(3), restarting.Aggregated)
+	assert.Equal(t, "event-3", restarting.Name)
+	assert.True(t, restarting.FirstTimestamp.Equal(&first))
+	assert.Equal(t, int32(1), byMessage["Back-off pulling image"].Aggregated)
+}
+
+func TestEventWatcher_AggregationFlushedOnStop(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+	ew.stopper = make(chan struct{})
+	ew.SetAggregation(&AggregationConfig{WindowSeconds: 300})
+
+	var received []*EnhancedEvent
+	ew.fn = func(e *EnhancedEvent) {
+		received = append(received, e)
+	}
+	ew.setStartUpTime(time.Now().Add(-time.Minute))
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), Reason: "BackOff"})
+	require.Empty(t, received)
+
+	ew.Stop()
+	require.Len(t, received, 1)
+	assert.Equal(t, int32(1), received[0].Aggregated)
+
+	require.Error(t, (&AggregationConfig{WindowSeconds: 3600}).Validate())
+}
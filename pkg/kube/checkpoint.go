@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	checkpointKey                    = "lastEventTime"
+	defaultCheckpointIntervalSeconds = 10
+)
+
+// CheckpointConfig makes a single replica save the time of the last event it processed in a ConfigMap, and resume from
+// it after a restart instead of discarding the events older than maxEventAgeSeconds. With leader election, the
+// checkpoint of the leaderElection config is used instead.
+type CheckpointConfig struct {
+	// Name of the ConfigMap, kubernetes-event-exporter-checkpoint by default
+	Name string `yaml:"name,omitempty"`
+	// Namespace of the ConfigMap, the one of the exporter by default
+	Namespace       string `yaml:"namespace,omitempty"`
+	IntervalSeconds int    `yaml:"intervalSeconds,omitempty"`
+}
+
+func (c *CheckpointConfig) Validate() error {
+	if c.IntervalSeconds < 0 {
+		return fmt.Errorf("intervalSeconds must not be negative")
+	}
+	return nil
+}
+
+func (c *CheckpointConfig) GetName() string {
+	if c.Name == "" {
+		return defaultLeaderElectionID + "-checkpoint"
+	}
+	return c.Name
+}
+
+func (c *CheckpointConfig) GetNamespace() string {
+	return namespaceOrInCluster(c.Namespace)
+}
+
+func (c *CheckpointConfig) GetInterval() time.Duration {
+	return secondsOrDefault(c.IntervalSeconds, defaultCheckpointIntervalSeconds*time.Second)
+}
+
+// Checkpoint persists the time of the last event the watcher processed in a ConfigMap shared by the replicas, so the
+// next leader resumes from it after a failover instead of discarding the events older than maxEventAgeSeconds.
+type Checkpoint struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	key       string
+}
+
+func NewCheckpoint(client kubernetes.Interface, namespace, name string) *Checkpoint {
+	return &Checkpoint{client: client, namespace: namespace, name: name, key: checkpointKey}
+}
+
+var invalidConfigMapKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// ForCluster returns the checkpoint of the watcher of another cluster, it is kept in the same ConfigMap under its own key
+func (c *Checkpoint) ForCluster(cluster string) *Checkpoint {
+	return &Checkpoint{
+		client:    c.client,
+		namespace: c.namespace,
+		name:      c.name,
+		key:       checkpointKey + "." + invalidConfigMapKeyChars.ReplaceAllString(cluster, "_"),
+	}
+}
+
+// Load returns the time of the checkpoint, it is zero if there is none yet
+func (c *Checkpoint) Load(ctx context.Context) (time.Time, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	value, ok := cm.Data[c.key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// Save records the time of the last processed event, creating the ConfigMap if needed. The checkpoints of the clusters
+// share the ConfigMap, a conflicting update is retried.
+func (c *Checkpoint) Save(ctx context.Context, t time.Time) error {
+	configMaps := c.client.CoreV1().ConfigMaps(c.namespace)
+	value := t.UTC().Format(time.RFC3339Nano)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, c.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      c.name,
+					Namespace: c.namespace,
+					Labels:    map[string]string{"app.kubernetes.io/managed-by": "kubernetes-event-exporter"},
+				},
+				Data: map[string]string{c.key: value},
+			}
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Created by the checkpoint of another cluster meanwhile, retried as a conflict
+				return apierrors.NewConflict(corev1.Resource("configmaps"), c.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[c.key] = value
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Run saves the time of the last event processed by the watcher every interval until the context is done, and once
+// more then
+func (c *Checkpoint) Run(ctx context.Context, w *EventWatcher, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultCheckpointIntervalSeconds * time.Second
+	}
+	var saved time.Time
+	save := func(ctx context.Context) {
+		last := w.LastProcessed()
+		// It can move back, when an older event arrives late and is held
+		if last.IsZero() || last.Equal(saved) {
+			return
+		}
+		if err := c.Save(ctx, last); err != nil {
+			log.Warn().Err(err).Str("configmap", c.namespace+"/"+c.name).Msg("Failed to save the checkpoint")
+			return
+		}
+		saved = last
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			save(ctx)
+		case <-ctx.Done():
+			// The context is done, the last save gets its own deadline
+			saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			save(saveCtx)
+			cancel()
+			return
+		}
+	}
+}
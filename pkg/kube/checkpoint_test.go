@@ -0,0 +1,72 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+func TestCheckpoint_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	checkpoint := NewCheckpoint(fake.NewSimpleClientset(), "monitoring", "kubernetes-event-exporter-checkpoint")
+
+	last, err := checkpoint.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero())
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, checkpoint.Save(ctx, first))
+	require.NoError(t, checkpoint.Save(ctx, first.Add(time.Minute)))
+	last, err = checkpoint.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.Equal(first.Add(time.Minute)))
+}
+
+func TestCheckpoint_ForCluster(t *testing.T) {
+	ctx := context.Background()
+	checkpoint := NewCheckpoint(fake.NewSimpleClientset(), "monitoring", "kubernetes-event-exporter-checkpoint")
+	prod := checkpoint.ForCluster("prod/eu")
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, prod.Save(ctx, first))
+	last, err := checkpoint.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.IsZero(), "the clusters have their own checkpoints")
+
+	require.NoError(t, checkpoint.Save(ctx, first.Add(time.Minute)))
+	last, err = prod.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.Equal(first))
+	last, err = checkpoint.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, last.Equal(first.Add(time.Minute)))
+}
+
+func TestCheckpoint_Run(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(60, metricsStore)
+	checkpoint := NewCheckpoint(fake.NewSimpleClientset(), "monitoring", "kubernetes-event-exporter-checkpoint")
+
+	latest := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ew.progress.track(latest).release()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checkpoint.Run(ctx, ew, time.Hour)
+		close(done)
+	}()
+	// The checkpoint is saved once more when the leadership is lost
+	cancel()
+	<-done
+
+	last, err := checkpoint.Load(context.Background())
+	require.NoError(t, err)
+	assert.True(t, last.Equal(latest))
+}
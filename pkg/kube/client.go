@@ -1,8 +1,7 @@
 package kube
 
 import (
-	"os"
-
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -10,7 +9,7 @@ import (
 
 // GetKubernetesClient returns the client if it's possible in cluster, otherwise tries to read HOME
 func GetKubernetesClient() (*kubernetes.Clientset, error) {
-	config, err := GetKubernetesConfig("")
+	config, err := GetKubernetesConfig("", "")
 	if err != nil {
 		return nil, err
 	}
@@ -18,9 +17,12 @@ func GetKubernetesClient() (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-func GetKubernetesConfig(kubeconfig string) (*rest.Config, error) {
-	if len(kubeconfig) > 0 {
-		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+// GetKubernetesConfig returns the config of the kubeconfig and its context if either is set, to run outside of the
+// cluster. Otherwise it returns the in-cluster config, and falls back to the kubeconfig of $KUBECONFIG or
+// ~/.kube/config with its current context.
+func GetKubernetesConfig(kubeconfig, context string) (*rest.Config, error) {
+	if len(kubeconfig) > 0 || len(context) > 0 {
+		return kubeconfigConfig(kubeconfig, context)
 	}
 
 	// If kubeconfig is not set, try to use in cluster config.
@@ -31,6 +33,21 @@ func GetKubernetesConfig(kubeconfig string) (*rest.Config, error) {
 		return nil, err
 	}
 
-	// Read KUBECONFIG env variable as fallback
-	return clientcmd.BuildConfigFromFlags("", os.Getenv("KUBECONFIG"))
+	return kubeconfigConfig("", "")
+}
+
+// kubeconfigConfig loads the kubeconfig, $KUBECONFIG or ~/.kube/config if the path is empty, with the context, the
+// current one if empty
+func kubeconfigConfig(kubeconfig, context string) (*rest.Config, error) {
+	loader := clientcmd.NewDefaultClientConfigLoadingRules()
+	loader.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, overrides).ClientConfig()
+}
+
+// UseProtobuf makes the typed clients of the config talk protobuf to the API server, it is cheaper to encode and decode
+// than JSON. The dynamic clients keep using JSON, the custom resources have no protobuf encoding.
+func UseProtobuf(config *rest.Config) {
+	config.ContentType = runtime.ContentTypeProtobuf
+	config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
 }
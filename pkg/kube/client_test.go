@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+  - name: dev
+    cluster:
+      server: https://dev.example.com
+  - name: prod
+    cluster:
+      server: https://prod.example.com
+contexts:
+  - name: dev
+    context:
+      cluster: dev
+      user: admin
+  - name: prod
+    context:
+      cluster: prod
+      user: admin
+users:
+  - name: admin
+    user:
+      token: secret
+`
+
+func TestGetKubernetesConfig_Kubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0o600))
+
+	config, err := GetKubernetesConfig(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dev.example.com", config.Host)
+
+	config, err = GetKubernetesConfig(path, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "https://prod.example.com", config.Host)
+
+	_, err = GetKubernetesConfig(path, "staging")
+	assert.Error(t, err)
+
+	// The context alone selects it in the kubeconfig of $KUBECONFIG
+	t.Setenv("KUBECONFIG", path)
+	config, err = GetKubernetesConfig("", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "https://prod.example.com", config.Host)
+}
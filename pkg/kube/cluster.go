@@ -0,0 +1,20 @@
+package kube
+
+import (
+	"k8s.io/client-go/rest"
+)
+
+// ClusterConfig is a cluster whose events are watched in addition to the ones of the cluster of the exporter. Its
+// events carry its name, which is available in templates as .ClusterName and matched by the clusterName of rules.
+type ClusterConfig struct {
+	Name string `yaml:"name"`
+	// Kubeconfig is the path of the kubeconfig of the cluster, and Context the context to use in it, the current one
+	// by default. Several clusters can share a kubeconfig with different contexts.
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context,omitempty"`
+}
+
+// RESTConfig loads the client config of the cluster
+func (c *ClusterConfig) RESTConfig() (*rest.Config, error) {
+	return kubeconfigConfig(c.Kubeconfig, c.Context)
+}
@@ -0,0 +1,368 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CustomSourceConfig converts the objects of a custom resource into events, e.g. the results of Argo Rollouts
+// AnalysisRuns or Kyverno PolicyReports, so they go through the same routes and receivers as the Kubernetes events.
+// An event is created per object, or per item of the list at Items, when it is added and whenever the rendered event
+// changes. Updates rendering the same event are ignored.
+type CustomSourceConfig struct {
+	Name      string `yaml:"name"`
+	Group     string `yaml:"group"`
+	Version   string `yaml:"version"`
+	Resource  string `yaml:"resource"`
+	Namespace string `yaml:"namespace,omitempty"`
+	// ClusterScoped is set for the resources without a namespace, the namespace is then ignored
+	ClusterScoped bool `yaml:"clusterScoped,omitempty"`
+	// Items is the dot separated path of a list in the object, e.g. results, an event is created per item
+	Items   string              `yaml:"items,omitempty"`
+	Mapping CustomSourceMapping `yaml:"mapping"`
+	// EmitExisting creates the events of the objects that existed when the exporter started, they are skipped by
+	// default so a restart does not emit them again
+	EmitExisting bool `yaml:"emitExisting,omitempty"`
+}
+
+// CustomSourceMapping are the templates rendering the fields of the event, with the object as .Object and the item
+// as .Item. An object or item whose reason renders empty is skipped.
+type CustomSourceMapping struct {
+	Reason  string `yaml:"reason"`
+	Message string `yaml:"message"`
+	// Type is Normal if it renders empty
+	Type string `yaml:"type,omitempty"`
+	// Component is the name of the source if it renders empty
+	Component string `yaml:"component,omitempty"`
+	// Fields are set as the extracted fields of the event, so rules and templates can use them
+	Fields map[string]string `yaml:"fields,omitempty"`
+	// InvolvedObject is the custom object itself if unset
+	InvolvedObject *CustomSourceObjectMapping `yaml:"involvedObject,omitempty"`
+}
+
+// CustomSourceObjectMapping renders the involved object of the event, e.g. the resource a policy report is about. The
+// event is in the namespace of that object. The custom object is the involved object if the name renders empty.
+type CustomSourceObjectMapping struct {
+	APIVersion string `yaml:"apiVersion,omitempty"`
+	Kind       string `yaml:"kind"`
+	Namespace  string `yaml:"namespace,omitempty"`
+	Name       string `yaml:"name"`
+	UID        string `yaml:"uid,omitempty"`
+}
+
+func (c *CustomSourceConfig) Validate() error {
+	if c.Name == "" || c.Version == "" || c.Resource == "" {
+		return errors.New("name, version and resource must be set")
+	}
+	if c.Mapping.Reason == "" {
+		return errors.New("mapping.reason must be set")
+	}
+	_, err := newCustomSourceTemplates(&c.Mapping)
+	return err
+}
+
+func (c *CustomSourceConfig) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: c.Group, Version: c.Version, Resource: c.Resource}
+}
+
+type customSourceTemplates struct {
+	reason, message, eventType, component *template.Template
+	fields                                map[string]*template.Template
+	// object renders the fields of the involved object, it is nil unless mapped
+	object map[string]*template.Template
+}
+
+func newCustomSourceTemplates(m *CustomSourceMapping) (*customSourceTemplates, error) {
+	parse := func(name, text string) (*template.Template, error) {
+		tmpl, err := template.New(name).Funcs(sprig.TxtFuncMap()).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("mapping.%s is not a valid template: %w", name, err)
+		}
+		return tmpl, nil
+	}
+	t := &customSourceTemplates{fields: make(map[string]*template.Template, len(m.Fields))}
+	var err error
+	if t.reason, err = parse("reason", m.Reason); err != nil {
+		return nil, err
+	}
+	if t.message, err = parse("message", m.Message); err != nil {
+		return nil, err
+	}
+	if t.eventType, err = parse("type", m.Type); err != nil {
+		return nil, err
+	}
+	if t.component, err = parse("component", m.Component); err != nil {
+		return nil, err
+	}
+	for name, text := range m.Fields {
+		if t.fields[name], err = parse("fields."+name, text); err != nil {
+			return nil, err
+		}
+	}
+	if o := m.InvolvedObject; o != nil {
+		t.object = make(map[string]*template.Template)
+		for name, text := range map[string]string{"apiVersion": o.APIVersion, "kind": o.Kind, "namespace": o.Namespace, "name": o.Name, "uid": o.UID} {
+			if t.object[name], err = parse("involvedObject."+name, text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// customSourceData is the data of the mapping templates
+type customSourceData struct {
+	Object map[string]interface{}
+	Item   interface{}
+}
+
+func renderMapping(tmpl *template.Template, data *customSourceData) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	// The missing keys of the object print as <no value>, they are empty like in the other templates
+	return strings.TrimSpace(strings.ReplaceAll(buf.String(), "<no value>", "")), nil
+}
+
+// customSource turns the objects of one custom resource into events
+type customSource struct {
+	cfg       *CustomSourceConfig
+	templates *customSourceTemplates
+	fn        EventHandler
+
+	mu sync.Mutex
+	// seen are the keys of the events last created for an object
+	seen map[types.UID]map[string]bool
+}
+
+func newCustomSource(cfg *CustomSourceConfig, fn EventHandler) (*customSource, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("custom source %s: %w", cfg.Name, err)
+	}
+	templates, _ := newCustomSourceTemplates(&cfg.Mapping)
+	return &customSource{cfg: cfg, templates: templates, fn: fn, seen: make(map[types.UID]map[string]bool)}, nil
+}
+
+// AddCustomSources watches the custom resources along with the events, the sources without a namespace watch the one
+// of the watcher
+func (e *EventWatcher) AddCustomSources(sources []CustomSourceConfig, namespace string) error {
+	for i := range sources {
+		source, err := newCustomSource(&sources[i], func(ev *EnhancedEvent) {
+			e.metricsStore.EventsProcessed.Inc()
+			e.fn(ev)
+		})
+		if err != nil {
+			return err
+		}
+		ns := sources[i].Namespace
+		if sources[i].ClusterScoped {
+			ns = ""
+		} else if ns == "" {
+			ns = namespace
+		}
+		e.informers = append(e.informers, source.informer(e.dynamicClient, ns))
+	}
+	return nil
+}
+
+func (s *customSource) informer(client dynamic.Interface, namespace string) cache.SharedInformer {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, namespace, nil)
+	informer := factory.ForResource(s.cfg.gvr()).Informer()
+	informer.AddEventHandler(s)
+	return informer
+}
+
+func (s *customSource) OnAdd(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	// The objects of the initial list are only remembered, unless they were created after the start
+	emit := s.cfg.EmitExisting || u.GetCreationTimestamp().Time.After(startUpTime)
+	s.sync(u, emit)
+}
+
+func (s *customSource) OnUpdate(_, newObj interface{}) {
+	if u, ok := newObj.(*unstructured.Unstructured); ok {
+		s.sync(u, true)
+	}
+}
+
+func (s *customSource) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		s.mu.Lock()
+		delete(s.seen, u.GetUID())
+		s.mu.Unlock()
+	}
+}
+
+// sync renders the events of the object and emits those that were not created for it before
+func (s *customSource) sync(u *unstructured.Unstructured, emit bool) {
+	events := s.events(u)
+	keys := make(map[string]bool, len(events))
+
+	s.mu.Lock()
+	seen := s.seen[u.GetUID()]
+	var created []*EnhancedEvent
+	for key, ev := range events {
+		keys[key] = true
+		if !seen[key] {
+			created = append(created, ev)
+		}
+	}
+	s.seen[u.GetUID()] = keys
+	s.mu.Unlock()
+
+	if !emit {
+		return
+	}
+	// The order of the items is kept
+	sort.SliceStable(created, func(i, j int) bool { return created[i].Name < created[j].Name })
+	for _, ev := range created {
+		s.fn(ev)
+	}
+}
+
+// events renders the events of the object by their key, the items that cannot be rendered are logged and skipped
+func (s *customSource) events(u *unstructured.Unstructured) map[string]*EnhancedEvent {
+	items := []interface{}{nil}
+	if s.cfg.Items != "" {
+		list, found, err := unstructured.NestedSlice(u.Object, strings.Split(s.cfg.Items, ".")...)
+		if err != nil || !found {
+			return nil
+		}
+		items = list
+	}
+
+	events := make(map[string]*EnhancedEvent, len(items))
+	for i, item := range items {
+		ev, err := s.event(u, &customSourceData{Object: u.Object, Item: item}, i)
+		if err != nil {
+			log.Warn().Err(err).Str("source", s.cfg.Name).Str("object", u.GetNamespace()+"/"+u.GetName()).Msg("Cannot render the event of a custom source")
+			continue
+		}
+		if ev == nil {
+			continue
+		}
+		events[eventKey(ev)] = ev
+	}
+	return events
+}
+
+func (s *customSource) event(u *unstructured.Unstructured, data *customSourceData, index int) (*EnhancedEvent, error) {
+	reason, err := renderMapping(s.templates.reason, data)
+	if err != nil || reason == "" {
+		return nil, err
+	}
+	ev := &EnhancedEvent{}
+	ev.Reason = reason
+	if ev.Message, err = renderMapping(s.templates.message, data); err != nil {
+		return nil, err
+	}
+	if ev.Type, err = renderMapping(s.templates.eventType, data); err != nil {
+		return nil, err
+	}
+	if ev.Type == "" {
+		ev.Type = corev1.EventTypeNormal
+	}
+	if ev.Source.Component, err = renderMapping(s.templates.component, data); err != nil {
+		return nil, err
+	}
+	if ev.Source.Component == "" {
+		ev.Source.Component = s.cfg.Name
+	}
+	for name, tmpl := range s.templates.fields {
+		value, err := renderMapping(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+		if ev.Extracted == nil {
+			ev.Extracted = make(map[string]string)
+		}
+		ev.Extracted[name] = value
+	}
+
+	now := metav1.NewTime(time.Now())
+	// The index keeps the items of an object in order, the name is unique per object and item
+	ev.Name = fmt.Sprintf("%s.%04d", u.GetName(), index)
+	ev.Namespace = u.GetNamespace()
+	ev.Count = 1
+	ev.FirstTimestamp = now
+	ev.LastTimestamp = now
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{
+		APIVersion:      u.GetAPIVersion(),
+		Kind:            u.GetKind(),
+		Namespace:       u.GetNamespace(),
+		Name:            u.GetName(),
+		UID:             u.GetUID(),
+		ResourceVersion: u.GetResourceVersion(),
+	}
+	ev.InvolvedObject.Labels = u.GetLabels()
+	ev.InvolvedObject.Annotations = u.GetAnnotations()
+	ev.InvolvedObject.OwnerReferences = u.GetOwnerReferences()
+	if s.templates.object == nil {
+		return ev, nil
+	}
+
+	object := make(map[string]string, len(s.templates.object))
+	for name, tmpl := range s.templates.object {
+		if object[name], err = renderMapping(tmpl, data); err != nil {
+			return nil, err
+		}
+	}
+	if object["name"] != "" {
+		ev.Namespace = object["namespace"]
+		ev.InvolvedObject = EnhancedObjectReference{ObjectReference: corev1.ObjectReference{
+			APIVersion: object["apiVersion"],
+			Kind:       object["kind"],
+			Namespace:  object["namespace"],
+			Name:       object["name"],
+			UID:        types.UID(object["uid"]),
+		}}
+	}
+	return ev, nil
+}
+
+// eventKey identifies the rendered content of an event, regardless of when it was created
+func eventKey(ev *EnhancedEvent) string {
+	h := sha256.New()
+	o := ev.InvolvedObject
+	for _, s := range []string{ev.Reason, ev.Message, ev.Type, ev.Source.Component, o.Kind, o.Namespace, o.Name} {
+		h.Write([]byte(s + "\x00"))
+	}
+	names := make([]string, 0, len(ev.Extracted))
+	for name := range ev.Extracted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name + "=" + ev.Extracted[name] + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newAnalysisRun(phase string, results ...interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "AnalysisRun",
+		"metadata": map[string]interface{}{
+			"name":      "checkout-7f9c",
+			"namespace": "shop",
+			"uid":       "run-1",
+			"labels":    map[string]interface{}{"app": "checkout"},
+		},
+		"status": map[string]interface{}{
+			"phase":         phase,
+			"metricResults": results,
+		},
+	}}
+	u.SetCreationTimestamp(metav1.NewTime(time.Now().Add(time.Minute)))
+	return u
+}
+
+func TestCustomSource(t *testing.T) {
+	var events []*EnhancedEvent
+	source, err := newCustomSource(&CustomSourceConfig{
+		Name:     "analysisruns",
+		Group:    "argoproj.io",
+		Version:  "v1alpha1",
+		Resource: "analysisruns",
+		Items:    "status.metricResults",
+		Mapping: CustomSourceMapping{
+			Reason:  `{{ if ne .Item.phase "Successful" }}Analysis{{ .Item.phase }}{{ end }}`,
+			Message: "{{ .Item.name }}: {{ .Item.message }}",
+			Type:    "Warning",
+			Fields:  map[string]string{"metric": "{{ .Item.name }}", "phase": "{{ .Object.status.phase }}"},
+		},
+	}, func(ev *EnhancedEvent) {
+		events = append(events, ev)
+	})
+	require.NoError(t, err)
+
+	errorRate := map[string]interface{}{"name": "error-rate", "phase": "Failed", "message": "error rate 7%"}
+	latency := map[string]interface{}{"name": "latency", "phase": "Successful"}
+	source.OnAdd(newAnalysisRun("Running", latency, errorRate))
+	require.Len(t, events, 1)
+	ev := events[0]
+	assert.Equal(t, "AnalysisFailed", ev.Reason)
+	assert.Equal(t, "error-rate: error rate 7%", ev.Message)
+	assert.Equal(t, "Warning", ev.Type)
+	assert.Equal(t, "analysisruns", ev.Source.Component)
+	assert.Equal(t, map[string]string{"metric": "error-rate", "phase": "Running"}, ev.Extracted)
+	assert.Equal(t, "AnalysisRun", ev.InvolvedObject.Kind)
+	assert.Equal(t, "shop", ev.Namespace)
+	assert.Equal(t, map[string]string{"app": "checkout"}, ev.InvolvedObject.Labels)
+
+	// Updates rendering the same events are ignored
+	latency["message"] = "p99 120ms"
+	source.OnUpdate(nil, newAnalysisRun("Running", latency, errorRate))
+	assert.Len(t, events, 1)
+
+	errorRate["message"] = "error rate 12%"
+	source.OnUpdate(nil, newAnalysisRun("Running", latency, errorRate))
+	require.Len(t, events, 2)
+	assert.Equal(t, "error-rate: error rate 12%", events[1].Message)
+}
+
+func TestCustomSource_Existing(t *testing.T) {
+	var events []*EnhancedEvent
+	source, err := newCustomSource(&CustomSourceConfig{
+		Name:     "analysisruns",
+		Version:  "v1alpha1",
+		Resource: "analysisruns",
+		Mapping:  CustomSourceMapping{Reason: "Analysis{{ .Object.status.phase }}", Message: "{{ .Object.status.message }}"},
+	}, func(ev *EnhancedEvent) {
+		events = append(events, ev)
+	})
+	require.NoError(t, err)
+
+	// The objects created before the start are only remembered
+	run := newAnalysisRun("Running")
+	run.SetCreationTimestamp(metav1.NewTime(startUpTime.Add(-time.Hour)))
+	source.OnAdd(run)
+	assert.Empty(t, events)
+
+	run = newAnalysisRun("Successful")
+	source.OnUpdate(nil, run)
+	require.Len(t, events, 1)
+	assert.Equal(t, "AnalysisSuccessful", events[0].Reason)
+	assert.Equal(t, "", events[0].Message)
+	assert.Equal(t, "Normal", events[0].Type)
+
+	assert.Error(t, (&CustomSourceConfig{Name: "x", Version: "v1", Resource: "x", Mapping: CustomSourceMapping{Reason: "{{ .Object"}}).Validate())
+}
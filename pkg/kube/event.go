@@ -2,17 +2,85 @@ package kube
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
 )
 
 type EnhancedEvent struct {
 	corev1.Event   `json:",inline"`
 	ClusterName    string                  `json:"clusterName"`
 	InvolvedObject EnhancedObjectReference `json:"involvedObject"`
+	// Replayed is set for the events of the backfill window, which were created before the exporter started
+	Replayed bool `json:"replayed,omitempty"`
+	// Owner is the root controller of the involved object, it is nil unless the owners are resolved
+	Owner *Owner `json:"owner,omitempty"`
+	// Node is the node of the node-scoped events, it is nil unless the nodes are enriched
+	Node *NodeInfo `json:"node,omitempty"`
+	// Pod are the details of the spec of the involved Pod, it is nil unless the Pods are enriched
+	Pod *PodInfo `json:"pod,omitempty"`
+	// NamespaceLabels and NamespaceAnnotations are the ones of the namespace of the involved object, they are empty
+	// unless the namespaces are enriched
+	NamespaceLabels      map[string]string `json:"namespaceLabels,omitempty"`
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+	// Aggregated is the number of identical events collapsed into this one, it is zero unless the events are aggregated
+	Aggregated int32 `json:"aggregated,omitempty"`
+	// Extracted are the fields the extractors found in the message
+	Extracted map[string]string `json:"extracted,omitempty"`
+	// Previous is only available in templates, it is empty unless the occurrences are tracked
+	Previous Occurrence `json:"-"`
+	// SilenceKey is only available in templates, it is empty unless silences are enabled
+	SilenceKey string `json:"-"`
+	// Normalized is only available in templates and rules, it is empty unless the reasons are normalized
+	Normalized NormalizedReason `json:"-"`
+	// Locale is only available in templates, it is set from the template settings of the receiver
+	Locale Locale `json:"-"`
+	// tracker keeps the checkpoint from passing the event until it is delivered, it is shared by the copies
+	tracker *tracker
+}
+
+// NormalizedReason is the stable form of a reason whose wording differs across Kubernetes versions and controllers
+type NormalizedReason struct {
+	Reason   string
+	Category string
+	// Fields are the details parsed from the message, like the probe type of an Unhealthy event
+	Fields map[string]string
+}
+
+// Locale is the timezone and the language of the timestamps rendered by the templates, UTC and English if empty
+type Locale struct {
+	Location *time.Location
+	Language string
+}
+
+// Occurrence describes when an event with the same key was seen before
+type Occurrence struct {
+	// Count is the number of times the event was seen before
+	Count    int64
+	LastSeen time.Time
+}
+
+// Ago returns the time since the occurrence in a short form like 5m or 2h, it is empty if there was no occurrence
+func (o Occurrence) Ago() string {
+	if o.LastSeen.IsZero() {
+		return ""
+	}
+	d := clock.Since(o.LastSeen)
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
 }
 
 // DeDot replaces all dots in the labels and annotations with underscores. This is required for example in the
@@ -24,6 +92,18 @@ func (e EnhancedEvent) DeDot() EnhancedEvent {
 	c.Annotations = dedotMap(e.Annotations)
 	c.InvolvedObject.Labels = dedotMap(e.InvolvedObject.Labels)
 	c.InvolvedObject.Annotations = dedotMap(e.InvolvedObject.Annotations)
+	c.NamespaceLabels = dedotMap(e.NamespaceLabels)
+	c.NamespaceAnnotations = dedotMap(e.NamespaceAnnotations)
+	if e.Owner != nil {
+		owner := *e.Owner
+		owner.Labels = dedotMap(e.Owner.Labels)
+		c.Owner = &owner
+	}
+	if e.Node != nil {
+		node := *e.Node
+		node.Labels = dedotMap(e.Node.Labels)
+		c.Node = &node
+	}
 	return c
 }
 
@@ -45,6 +125,8 @@ type EnhancedObjectReference struct {
 	Annotations            map[string]string       `json:"annotations,omitempty"`
 	OwnerReferences        []metav1.OwnerReference `json:"ownerReferences,omitempty"`
 	Deleted                bool                    `json:"deleted"`
+	// Manifest is the sanitized object, it is empty unless the manifests of its kind are embedded
+	Manifest map[string]interface{} `json:"manifest,omitempty"`
 }
 
 // ToJSON does not return an error because we are %99 confident it is JSON serializable.
@@ -72,3 +154,13 @@ func (e *EnhancedEvent) GetTimestampISO8601() string {
 	layout := "2006-01-02T15:04:05.000Z"
 	return timestamp.Format(layout)
 }
+
+// GetTimestampRFC3339 returns the timestamp in UTC, as expected by Alertmanager and most APIs
+func (e *EnhancedEvent) GetTimestampRFC3339() string {
+	timestamp := e.FirstTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = e.EventTime.Time
+	}
+
+	return timestamp.UTC().Format(time.RFC3339)
+}
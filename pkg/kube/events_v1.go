@@ -0,0 +1,69 @@
+package kube
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+)
+
+const (
+	// EventsAPICoreV1 watches the core v1 Events, the default
+	EventsAPICoreV1 = "v1"
+	// EventsAPIEventsV1 watches the events.k8s.io/v1 Events, which carry the series of the deduplicated events
+	EventsAPIEventsV1 = "events.k8s.io/v1"
+)
+
+// eventFromEventsV1 converts an events.k8s.io/v1 Event to the core v1 Event the exporter works with. The count and the
+// last timestamp come from the series of an event seen more than once, and from the deprecated fields of an event
+// converted by the API server from a core v1 Event.
+func eventFromEventsV1(in *eventsv1.Event) *corev1.Event {
+	out := &corev1.Event{
+		ObjectMeta:          in.ObjectMeta,
+		InvolvedObject:      in.Regarding,
+		Related:             in.Related,
+		Reason:              in.Reason,
+		Message:             in.Note,
+		Type:                in.Type,
+		Action:              in.Action,
+		EventTime:           in.EventTime,
+		ReportingController: in.ReportingController,
+		ReportingInstance:   in.ReportingInstance,
+		Source:              in.DeprecatedSource,
+		Count:               in.DeprecatedCount,
+		FirstTimestamp:      in.DeprecatedFirstTimestamp,
+		LastTimestamp:       in.DeprecatedLastTimestamp,
+	}
+	out.Kind = "Event"
+	out.APIVersion = "v1"
+	if out.Source.Component == "" {
+		out.Source.Component = in.ReportingController
+	}
+	if out.Source.Host == "" {
+		out.Source.Host = in.ReportingInstance
+	}
+
+	if in.Series != nil {
+		out.Series = &corev1.EventSeries{Count: in.Series.Count, LastObservedTime: in.Series.LastObservedTime}
+		out.Count = in.Series.Count
+		out.LastTimestamp.Time = in.Series.LastObservedTime.Time
+	}
+	if out.Count == 0 {
+		out.Count = 1
+	}
+	// The eventTime of the new API is the first time the event was observed
+	if out.FirstTimestamp.IsZero() {
+		out.FirstTimestamp.Time = in.EventTime.Time
+	}
+	if out.LastTimestamp.IsZero() {
+		out.LastTimestamp.Time = in.EventTime.Time
+	}
+	return out
+}
+
+// isSeriesUpdate reports whether an update of an events.k8s.io/v1 Event is a new occurrence of its series. The
+// recorders of the new API update the series of an event instead of creating a new one when it is seen again.
+func isSeriesUpdate(oldEvent, newEvent *eventsv1.Event) bool {
+	if newEvent.Series == nil {
+		return false
+	}
+	return oldEvent.Series == nil || newEvent.Series.Count > oldEvent.Series.Count
+}
@@ -17,6 +17,45 @@ import (
 type LeaderElectionConfig struct {
 	Enabled          bool   `yaml:"enabled"`
 	LeaderElectionID string `yaml:"leaderElectionID"`
+	// Namespace of the Lease, the one of the exporter by default
+	Namespace            string `yaml:"namespace,omitempty"`
+	LeaseDurationSeconds int    `yaml:"leaseDurationSeconds,omitempty"`
+	RenewDeadlineSeconds int    `yaml:"renewDeadlineSeconds,omitempty"`
+	RetryPeriodSeconds   int    `yaml:"retryPeriodSeconds,omitempty"`
+	// Checkpoint makes the leader save the time of the last processed event in the <leaderElectionID>-checkpoint
+	// ConfigMap every CheckpointIntervalSeconds, 10 by default, and the next leader resume from it
+	Checkpoint                bool `yaml:"checkpoint,omitempty"`
+	CheckpointIntervalSeconds int  `yaml:"checkpointIntervalSeconds,omitempty"`
+}
+
+// GetID returns the name of the Lease
+func (c LeaderElectionConfig) GetID() string {
+	if c.LeaderElectionID == "" {
+		return defaultLeaderElectionID
+	}
+	return c.LeaderElectionID
+}
+
+// GetNamespace returns the namespace of the Lease and of the checkpoint
+func (c LeaderElectionConfig) GetNamespace() string {
+	return namespaceOrInCluster(c.Namespace)
+}
+
+// GetLeaseDuration returns how long the other replicas wait before taking over the lease of a leader that stopped
+// renewing it
+func (c LeaderElectionConfig) GetLeaseDuration() time.Duration {
+	return secondsOrDefault(c.LeaseDurationSeconds, defaultLeaseDuration)
+}
+
+func (c LeaderElectionConfig) GetCheckpointInterval() time.Duration {
+	return secondsOrDefault(c.CheckpointIntervalSeconds, defaultCheckpointIntervalSeconds*time.Second)
+}
+
+func secondsOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
 }
 
 const (
@@ -28,22 +67,9 @@ const (
 	defaultRetryPeriod      = 2 * time.Second
 )
 
-func GetLeaseDuration() time.Duration {
-	return defaultLeaseDuration
-}
-
-// NewResourceLock creates a new config map resource lock for use in a leader
+// NewResourceLock creates a new lease resource lock for use in a leader
 // election loop
-func newResourceLock(config *rest.Config, leaderElectionID string) (resourcelock.Interface, error) {
-	if leaderElectionID == "" {
-		leaderElectionID = defaultLeaderElectionID
-	}
-
-	leaderElectionNamespace, err := getInClusterNamespace()
-	if err != nil {
-		leaderElectionNamespace = defaultNamespace
-	}
-
+func newResourceLock(config *rest.Config, cfg LeaderElectionConfig) (resourcelock.Interface, error) {
 	// Leader id, needs to be unique
 	id, err := os.Hostname()
 	if err != nil {
@@ -58,8 +84,8 @@ func newResourceLock(config *rest.Config, leaderElectionID string) (resourcelock
 	}
 
 	return resourcelock.New(resourcelock.LeasesResourceLock,
-		leaderElectionNamespace,
-		leaderElectionID,
+		cfg.GetNamespace(),
+		cfg.GetID(),
 		client.CoreV1(),
 		client.CoordinationV1(),
 		resourcelock.ResourceLockConfig{
@@ -67,6 +93,18 @@ func newResourceLock(config *rest.Config, leaderElectionID string) (resourcelock
 		})
 }
 
+// namespaceOrInCluster returns the namespace if set, the one of the exporter otherwise
+func namespaceOrInCluster(namespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	namespace, err := getInClusterNamespace()
+	if err != nil {
+		return defaultNamespace
+	}
+	return namespace
+}
+
 func getInClusterNamespace() (string, error) {
 	// Check whether the namespace file exists.
 	// If not, we are not running in cluster so can't guess the namespace.
@@ -86,17 +124,17 @@ func getInClusterNamespace() (string, error) {
 }
 
 // NewLeaderElector return  a leader elector object using client-go
-func NewLeaderElector(leaderElectionID string, config *rest.Config, startFunc func(context.Context), stopFunc func(), newLeaderFunc func(string)) (*leaderelection.LeaderElector, error) {
-	resourceLock, err := newResourceLock(config, leaderElectionID)
+func NewLeaderElector(cfg LeaderElectionConfig, config *rest.Config, startFunc func(context.Context), stopFunc func(), newLeaderFunc func(string)) (*leaderelection.LeaderElector, error) {
+	resourceLock, err := newResourceLock(config, cfg)
 	if err != nil {
 		return &leaderelection.LeaderElector{}, err
 	}
 
 	l, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
 		Lock:          resourceLock,
-		LeaseDuration: defaultLeaseDuration,
-		RenewDeadline: defaultRenewDeadline,
-		RetryPeriod:   defaultRetryPeriod,
+		LeaseDuration: cfg.GetLeaseDuration(),
+		RenewDeadline: secondsOrDefault(cfg.RenewDeadlineSeconds, defaultRenewDeadline),
+		RetryPeriod:   secondsOrDefault(cfg.RetryPeriodSeconds, defaultRetryPeriod),
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: startFunc,
 			OnStoppedLeading: stopFunc,
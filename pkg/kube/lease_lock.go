@@ -0,0 +1,153 @@
+package kube
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	lockLabel             = "kubernetes-event-exporter/lock"
+	lockRetryInterval     = 100 * time.Millisecond
+	lockReleaseTimeout    = 5 * time.Second
+	defaultLockExpiration = 15 * time.Second
+)
+
+// LeaseLock is a short-lived lock shared by the replicas, to serialize the read-modify-write cycles on shared state.
+// Every lock is a Lease, it expires after the duration in case its holder crashes.
+type LeaseLock struct {
+	client    kubernetes.Interface
+	namespace string
+	prefix    string
+	hostname  string
+	duration  time.Duration
+}
+
+func NewLeaseLock(client kubernetes.Interface, namespace, prefix string, duration time.Duration) (*LeaseLock, error) {
+	if duration <= 0 {
+		duration = defaultLockExpiration
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &LeaseLock{
+		client:    client,
+		namespace: namespace,
+		prefix:    prefix,
+		hostname:  strings.ToLower(hostname),
+		duration:  duration,
+	}, nil
+}
+
+// leaseName maps the key, which may contain any character, to a valid name
+func (l *LeaseLock) leaseName(key string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return fmt.Sprintf("%s-lock-%x", l.prefix, h.Sum64())
+}
+
+// Lock blocks until the lock for the key is acquired or the context is done. The returned function releases it.
+func (l *LeaseLock) Lock(ctx context.Context, key string) (func(), error) {
+	name := l.leaseName(key)
+	// Every acquisition has its own identity, so the goroutines of a replica exclude each other as well
+	token := make([]byte, 4)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	identity := l.hostname + "-" + hex.EncodeToString(token)
+
+	for {
+		lease, err := l.tryAcquire(ctx, name, identity)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil {
+			return func() { l.release(lease) }, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("cannot acquire the lease %s/%s: %w", l.namespace, name, ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// tryAcquire returns the lease if it was acquired, nil if it is held by someone else
+func (l *LeaseLock) tryAcquire(ctx context.Context, name, identity string) (*coordinationv1.Lease, error) {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	now := metav1.NowMicro()
+	duration := int32(l.duration / time.Second)
+	if duration < 1 {
+		duration = 1
+	}
+
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{lockLabel: l.prefix},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return nil, nil
+		}
+		return lease, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" && !leaseExpired(lease, now.Time) {
+		return nil, nil
+	}
+
+	// Take over the released or expired lease, the update fails with a conflict if another replica was faster
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	lease, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return nil, nil
+	}
+	return lease, err
+}
+
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second).Before(now)
+}
+
+// release deletes the lease, unless it expired and was taken over in the meantime
+func (l *LeaseLock) release(lease *coordinationv1.Lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), lockReleaseTimeout)
+	defer cancel()
+	err := l.client.CoordinationV1().Leases(l.namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+	if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+		log.Warn().Err(err).Str("lease", lease.Name).Msg("Failed to release the lock")
+	}
+}
@@ -0,0 +1,63 @@
+package kube
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaseLock_Exclusive(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	a, err := NewLeaseLock(client, "monitoring", "threads", time.Minute)
+	require.NoError(t, err)
+	b, err := NewLeaseLock(client, "monitoring", "threads", time.Minute)
+	require.NoError(t, err)
+
+	unlock, err := a.Lock(context.Background(), "cluster/upgrade")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = b.Lock(ctx, "cluster/upgrade")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Other keys are not affected
+	unlockOther, err := b.Lock(context.Background(), "cluster/other")
+	require.NoError(t, err)
+	unlockOther()
+
+	unlock()
+	unlock, err = b.Lock(context.Background(), "cluster/upgrade")
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestLeaseLock_TakesOverExpired(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	crashed, err := NewLeaseLock(client, "monitoring", "threads", time.Second)
+	require.NoError(t, err)
+	_, err = crashed.Lock(context.Background(), "key")
+	require.NoError(t, err)
+
+	// Let the lease expire without releasing it
+	leases := client.CoordinationV1().Leases("monitoring")
+	lease, err := leases.Get(context.Background(), crashed.leaseName("key"), metav1.GetOptions{})
+	require.NoError(t, err)
+	past := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	lease.Spec.RenewTime = &past
+	_, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	l, err := NewLeaseLock(client, "monitoring", "threads", time.Second)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err := l.Lock(ctx, "key")
+	require.NoError(t, err)
+	unlock()
+}
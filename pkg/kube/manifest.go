@@ -0,0 +1,71 @@
+package kube
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// lastAppliedAnnotation duplicates the manifest applied with kubectl, including the data of the Secrets
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ManifestConfig embeds the full manifest of the involved objects of the kinds into their events, for the sinks that
+// archive the events for audits. The manifests are sanitized: the managed fields and the last applied configuration
+// are removed, and so are the data of the Secrets.
+type ManifestConfig struct {
+	// Kinds are regular expressions of the kinds whose manifests are embedded
+	Kinds []string `yaml:"kinds"`
+}
+
+func (c *ManifestConfig) Validate() error {
+	if len(c.Kinds) == 0 {
+		return fmt.Errorf("kinds must not be empty")
+	}
+	for _, kind := range c.Kinds {
+		if _, err := regexp.Compile(kind); err != nil {
+			return fmt.Errorf("kind %q is not a valid regular expression: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// SetManifests makes the watcher embed the manifests of the involved objects of the kinds of the config
+func (e *EventWatcher) SetManifests(cfg *ManifestConfig) {
+	cache, ok := e.objectMetadataCache.(*ObjectMetadataCache)
+	if !ok {
+		return
+	}
+	for _, kind := range cfg.Kinds {
+		cache.manifestKinds = append(cache.manifestKinds, regexp.MustCompile(kind))
+	}
+}
+
+// keepsManifest is true if the manifests of the objects of the kind are embedded
+func (o *ObjectMetadataCache) keepsManifest(kind string) bool {
+	for _, re := range o.manifestKinds {
+		if re.MatchString(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeManifest returns a copy of the manifest without the fields that are noise or secret
+func sanitizeManifest(manifest map[string]interface{}) map[string]interface{} {
+	sanitized := runtime.DeepCopyJSON(manifest)
+	if metadata, ok := sanitized["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, lastAppliedAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	if sanitized["kind"] == "Secret" && sanitized["apiVersion"] == "v1" {
+		delete(sanitized, "data")
+		delete(sanitized, "stringData")
+	}
+	return sanitized
+}
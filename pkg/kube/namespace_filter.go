@@ -0,0 +1,56 @@
+package kube
+
+import (
+	"path"
+	"strings"
+)
+
+// NamespaceFilter selects the namespaces whose events are watched. The entries are globs like team-*. The literal
+// namespaces to include get an informer each, the globs need the events of all the namespaces to be watched and
+// filtered. The excluded namespaces are always filtered.
+type NamespaceFilter struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+// Validate checks the syntax of the globs
+func (f NamespaceFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches is true if the events of the namespace are watched
+func (f NamespaceFilter) Matches(namespace string) bool {
+	for _, pattern := range f.Exclude {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// informerNamespaces returns the namespaces to create the informers for, the namespace of the watcher unless all the
+// included ones are literal
+func (f NamespaceFilter) informerNamespaces(namespace string) []string {
+	if len(f.Include) == 0 {
+		return []string{namespace}
+	}
+	for _, pattern := range f.Include {
+		if strings.ContainsAny(pattern, `*?[\`) {
+			return []string{namespace}
+		}
+	}
+	return f.Include
+}
@@ -0,0 +1,34 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceFilter_Matches(t *testing.T) {
+	filter := NamespaceFilter{Include: []string{"team-*", "shop"}, Exclude: []string{"team-sandbox-*"}}
+	assert.True(t, filter.Matches("team-payments"))
+	assert.True(t, filter.Matches("shop"))
+	assert.False(t, filter.Matches("team-sandbox-1"))
+	assert.False(t, filter.Matches("kube-system"))
+
+	filter = NamespaceFilter{Exclude: []string{"kube-*"}}
+	assert.True(t, filter.Matches("default"))
+	assert.False(t, filter.Matches("kube-system"))
+	assert.True(t, NamespaceFilter{}.Matches(""))
+}
+
+func TestNamespaceFilter_InformerNamespaces(t *testing.T) {
+	assert.Equal(t, []string{""}, NamespaceFilter{}.informerNamespaces(""))
+	assert.Equal(t, []string{"monitoring"}, NamespaceFilter{Exclude: []string{"kube-*"}}.informerNamespaces("monitoring"))
+	// The literal namespaces get an informer each
+	assert.Equal(t, []string{"shop", "payments"}, NamespaceFilter{Include: []string{"shop", "payments"}}.informerNamespaces(""))
+	// A glob needs all the namespaces
+	assert.Equal(t, []string{""}, NamespaceFilter{Include: []string{"shop", "team-*"}}.informerNamespaces(""))
+}
+
+func TestNamespaceFilter_Validate(t *testing.T) {
+	assert.NoError(t, NamespaceFilter{Include: []string{"team-*"}}.Validate())
+	assert.Error(t, NamespaceFilter{Exclude: []string{"team-[a"}}.Validate())
+}
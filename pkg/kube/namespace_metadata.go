@@ -0,0 +1,30 @@
+package kube
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+)
+
+// SetNamespaceEnrichment makes the watcher attach the labels and annotations of the namespace of the involved object
+// to the events. The namespaces are watched, so the events do not cause lookups.
+func (e *EventWatcher) SetNamespaceEnrichment() {
+	factory := informers.NewSharedInformerFactory(e.clientset, 0)
+	informer := factory.Core().V1().Namespaces().Informer()
+	e.namespaceStore = informer.GetStore()
+	e.informers = append(e.informers, informer)
+}
+
+// namespaceOf returns the namespace of the involved object, it is nil for the cluster-scoped objects and the unknown
+// namespaces
+func (e *EventWatcher) namespaceOf(event *corev1.Event) *corev1.Namespace {
+	name := event.InvolvedObject.Namespace
+	if name == "" {
+		return nil
+	}
+	obj, exists, err := e.namespaceStore.GetByKey(name)
+	if err != nil || !exists {
+		return nil
+	}
+	ns, _ := obj.(*corev1.Namespace)
+	return ns
+}
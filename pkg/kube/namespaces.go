@@ -0,0 +1,44 @@
+package kube
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespaceHandler is notified about the namespaces of the cluster
+type NamespaceHandler interface {
+	// OnNamespace is called when a namespace is added or changed, and on every resync
+	OnNamespace(ns *corev1.Namespace)
+	OnNamespaceDeleted(name string)
+}
+
+// WatchNamespaces calls the handler for the namespaces until the context is done
+func WatchNamespaces(ctx context.Context, client kubernetes.Interface, handler NamespaceHandler) {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	informer := factory.Core().V1().Namespaces().Informer()
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				handler.OnNamespace(ns)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				handler.OnNamespace(ns)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				handler.OnNamespaceDeleted(ns.Name)
+			}
+		},
+	})
+	go informer.Run(ctx.Done())
+}
@@ -0,0 +1,71 @@
+package kube
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+)
+
+// NodeInfo is the node of a node-scoped event, with the failure domain it is in
+type NodeInfo struct {
+	Name         string            `json:"name"`
+	Zone         string            `json:"zone,omitempty"`
+	Region       string            `json:"region,omitempty"`
+	InstanceType string            `json:"instanceType,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// SetNodeEnrichment makes the watcher attach the node to the events of the Nodes and to the events reported by the
+// kubelets. The nodes are watched, so the events do not cause lookups.
+func (e *EventWatcher) SetNodeEnrichment() {
+	factory := informers.NewSharedInformerFactory(e.clientset, 0)
+	informer := factory.Core().V1().Nodes().Informer()
+	e.nodes = informer.GetStore()
+	e.informers = append(e.informers, informer)
+}
+
+// eventNodeName returns the node the event is about, or the node of the kubelet that reported it
+func eventNodeName(event *corev1.Event) string {
+	if event.InvolvedObject.Kind == "Node" {
+		return event.InvolvedObject.Name
+	}
+	if event.Source.Component == "kubelet" {
+		return event.Source.Host
+	}
+	if event.ReportingController == "kubelet" {
+		return event.ReportingInstance
+	}
+	return ""
+}
+
+// nodeInfo returns the node of the event, it is nil if the event is not node-scoped or the node is unknown
+func (e *EventWatcher) nodeInfo(event *corev1.Event) *NodeInfo {
+	name := eventNodeName(event)
+	if name == "" {
+		return nil
+	}
+	obj, exists, err := e.nodes.GetByKey(name)
+	if err != nil || !exists {
+		return nil
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+	return &NodeInfo{
+		Name:         node.Name,
+		Zone:         firstLabel(node.Labels, corev1.LabelTopologyZone, corev1.LabelFailureDomainBetaZone),
+		Region:       firstLabel(node.Labels, corev1.LabelTopologyRegion, corev1.LabelFailureDomainBetaRegion),
+		InstanceType: firstLabel(node.Labels, corev1.LabelInstanceTypeStable, corev1.LabelInstanceType),
+		Labels:       node.Labels,
+	}
+}
+
+// firstLabel returns the value of the first of the labels that is set, for the labels that replaced deprecated ones
+func firstLabel(labels map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := labels[key]; ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+func TestEventWatcher_NodeEnrichment(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+	ew.nodes = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, ew.nodes.Add(&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "node-1",
+		Labels: map[string]string{
+			corev1.LabelTopologyZone:          "eu-west-1a",
+			corev1.LabelFailureDomainBetaZone: "deprecated",
+			corev1.LabelTopologyRegion:        "eu-west-1",
+			corev1.LabelInstanceType:          "m5.large",
+		},
+	}}))
+
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.setStartUpTime(time.Now())
+
+	// A Node event
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-1"}})
+	// A Pod event reported by the kubelet
+	ew.onEvent(&corev1.Event{
+		LastTimestamp:  metav1.Now(),
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "checkout-1"},
+		Source:         corev1.EventSource{Component: "kubelet", Host: "node-1"},
+	})
+	// A Pod event of the scheduler
+	ew.onEvent(&corev1.Event{
+		LastTimestamp:  metav1.Now(),
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "checkout-1"},
+		Source:         corev1.EventSource{Component: "default-scheduler"},
+	})
+
+	require.Len(t, received, 3)
+	for _, ev := range received[:2] {
+		require.NotNil(t, ev.Node)
+		assert.Equal(t, "node-1", ev.Node.Name)
+		assert.Equal(t, "eu-west-1a", ev.Node.Zone)
+		assert.Equal(t, "eu-west-1", ev.Node.Region)
+		assert.Equal(t, "m5.large", ev.Node.InstanceType)
+	}
+	assert.Nil(t, received[2].Node)
+}
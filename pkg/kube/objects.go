@@ -2,16 +2,22 @@ package kube
 
 import (
 	"context"
+	"regexp"
 	"strings"
+	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/restmapper"
 
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
 )
 
@@ -21,6 +27,17 @@ type ObjectMetadataProvider interface {
 
 type ObjectMetadataCache struct {
 	cache *lru.ARCCache
+	// ttl is how long the metadata is cached, forever if zero
+	ttl time.Duration
+	// manifestKinds are the kinds whose sanitized manifests are kept
+	manifestKinds []*regexp.Regexp
+	fetch         func(reference *v1.ObjectReference, clientset *kubernetes.Clientset, dynClient dynamic.Interface, metricsStore *metrics.Store) (ObjectMetadata, error)
+}
+
+// cachedObjectMetadata is the metadata of an object along with when it is looked up again
+type cachedObjectMetadata struct {
+	metadata  ObjectMetadata
+	expiresAt time.Time
 }
 
 var _ ObjectMetadataProvider = &ObjectMetadataCache{}
@@ -30,6 +47,18 @@ type ObjectMetadata struct {
 	Labels          map[string]string
 	OwnerReferences []metav1.OwnerReference
 	Deleted         bool
+	// Pod are the details of the spec of a Pod, it is nil for the other kinds
+	Pod *PodInfo
+	// Manifest is the sanitized object, it is nil unless the manifests of its kind are embedded
+	Manifest map[string]interface{}
+}
+
+// SetMetadataCacheTTL makes the watcher look the metadata of the objects up again after the TTL, so the labels and
+// annotations that changed are picked up even if the events do not carry the resource version of the objects
+func (e *EventWatcher) SetMetadataCacheTTL(ttl time.Duration) {
+	if cache, ok := e.objectMetadataCache.(*ObjectMetadataCache); ok {
+		cache.ttl = ttl
+	}
 }
 
 func NewObjectMetadataProvider(size int) ObjectMetadataProvider {
@@ -40,6 +69,7 @@ func NewObjectMetadataProvider(size int) ObjectMetadataProvider {
 
 	var o ObjectMetadataProvider = &ObjectMetadataCache{
 		cache: cache,
+		fetch: fetchObjectMetadata,
 	}
 
 	return o
@@ -50,10 +80,55 @@ func (o *ObjectMetadataCache) GetObjectMetadata(reference *v1.ObjectReference, c
 	// We use "UID/ResourceVersion" as cache key so that if the object is updated we get the new metadata.
 	cacheKey := strings.Join([]string{string(reference.UID), reference.ResourceVersion}, "/")
 	if val, ok := o.cache.Get(cacheKey); ok {
-		metricsStore.KubeApiReadCacheHits.Inc()
-		return val.(ObjectMetadata), nil
+		cached := val.(cachedObjectMetadata)
+		if cached.expiresAt.IsZero() || clock.Now().Before(cached.expiresAt) {
+			metricsStore.KubeApiReadCacheHits.Inc()
+			return cached.metadata, nil
+		}
+		o.cache.Remove(cacheKey)
+	}
+
+	objectMetadata, err := o.fetch(reference, clientset, dynClient, metricsStore)
+	if apierrors.IsNotFound(err) {
+		o.invalidate(reference.UID)
+	}
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+	// The manifests are only kept for the kinds they are embedded for, they are large
+	if objectMetadata.Manifest != nil && o.keepsManifest(reference.Kind) {
+		objectMetadata.Manifest = sanitizeManifest(objectMetadata.Manifest)
+	} else {
+		objectMetadata.Manifest = nil
+	}
+	// The metadata of an object being deleted is looked up again, to find it deleted
+	if objectMetadata.Deleted {
+		o.invalidate(reference.UID)
+		return objectMetadata, nil
+	}
+	cached := cachedObjectMetadata{metadata: objectMetadata}
+	if o.ttl > 0 {
+		cached.expiresAt = clock.Now().Add(o.ttl)
 	}
+	o.cache.Add(cacheKey, cached)
+	return objectMetadata, nil
+}
 
+// invalidate removes the metadata of the object cached for any of its resource versions
+func (o *ObjectMetadataCache) invalidate(uid types.UID) {
+	if uid == "" {
+		return
+	}
+	prefix := string(uid) + "/"
+	for _, key := range o.cache.Keys() {
+		if k, ok := key.(string); ok && strings.HasPrefix(k, prefix) {
+			o.cache.Remove(key)
+		}
+	}
+}
+
+// fetchObjectMetadata reads the metadata of the object from the API, bypassing the cache
+func fetchObjectMetadata(reference *v1.ObjectReference, clientset *kubernetes.Clientset, dynClient dynamic.Interface, metricsStore *metrics.Store) (ObjectMetadata, error) {
 	var group, version string
 	s := strings.Split(reference.APIVersion, "/")
 	if len(s) == 1 {
@@ -77,9 +152,14 @@ func (o *ObjectMetadataCache) GetObjectMetadata(reference *v1.ObjectReference, c
 		return ObjectMetadata{}, err
 	}
 
+	// The owners of namespaced objects can be cluster-scoped
+	namespace := reference.Namespace
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		namespace = ""
+	}
 	item, err := dynClient.
 		Resource(mapping.Resource).
-		Namespace(reference.Namespace).
+		Namespace(namespace).
 		Get(context.Background(), reference.Name, metav1.GetOptions{})
 
 	metricsStore.KubeApiReadRequests.Inc()
@@ -92,12 +172,15 @@ func (o *ObjectMetadataCache) GetObjectMetadata(reference *v1.ObjectReference, c
 		OwnerReferences: item.GetOwnerReferences(),
 		Labels:          item.GetLabels(),
 		Annotations:     item.GetAnnotations(),
+		Manifest:        item.Object,
 	}
 
 	if item.GetDeletionTimestamp() != nil {
 		objectMetadata.Deleted = true
 	}
+	if mapping.Resource.Group == "" && mapping.Resource.Resource == "pods" {
+		objectMetadata.Pod = podInfo(item)
+	}
 
-	o.cache.Add(cacheKey, objectMetadata)
 	return objectMetadata, nil
 }
@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return time.AfterFunc(d, f)
+}
+
+func TestObjectMetadataCache(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	c := &fakeClock{now: time.Now()}
+	defer clock.Set(c)()
+
+	cache := NewObjectMetadataProvider(16).(*ObjectMetadataCache)
+	cache.ttl = 10 * time.Minute
+	labels := map[string]string{"team": "payments"}
+	var fetched int
+	var err error
+	deleted := false
+	cache.fetch = func(*corev1.ObjectReference, *kubernetes.Clientset, dynamic.Interface, *metrics.Store) (ObjectMetadata, error) {
+		fetched++
+		return ObjectMetadata{Labels: labels, Deleted: deleted}, err
+	}
+	get := func(resourceVersion string) ObjectMetadata {
+		metadata, _ := cache.GetObjectMetadata(&corev1.ObjectReference{UID: "pod-1", ResourceVersion: resourceVersion}, nil, nil, metricsStore)
+		return metadata
+	}
+
+	assert.Equal(t, labels, get("").Labels)
+	assert.Equal(t, labels, get("").Labels)
+	require.Equal(t, 1, fetched)
+
+	// The labels changed, they are picked up once the TTL expired
+	labels = map[string]string{"team": "checkout"}
+	c.now = c.now.Add(11 * time.Minute)
+	assert.Equal(t, labels, get("").Labels)
+	require.Equal(t, 2, fetched)
+
+	// The metadata of every resource version is dropped when the object is gone
+	get("42")
+	require.Equal(t, 3, fetched)
+	err = errors.NewNotFound(schema.GroupResource{Resource: "pods"}, "checkout-1")
+	_, getErr := cache.GetObjectMetadata(&corev1.ObjectReference{UID: "pod-1", ResourceVersion: "43"}, nil, nil, metricsStore)
+	require.Error(t, getErr)
+	assert.Empty(t, cache.cache.Keys())
+
+	// The metadata of an object being deleted is not cached
+	err, deleted = nil, true
+	assert.True(t, get("44").Deleted)
+	assert.True(t, get("44").Deleted)
+	require.Equal(t, 6, fetched)
+}
+
+func TestObjectMetadataCacheManifests(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+
+	cache := NewObjectMetadataProvider(16).(*ObjectMetadataCache)
+	cache.manifestKinds = []*regexp.Regexp{regexp.MustCompile("^Secret$")}
+	manifest := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":          "db",
+			"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			"annotations":   map[string]interface{}{lastAppliedAnnotation: "{}"},
+		},
+		"type": "Opaque",
+		"data": map[string]interface{}{"password": "c2VjcmV0"},
+	}
+	cache.fetch = func(*corev1.ObjectReference, *kubernetes.Clientset, dynamic.Interface, *metrics.Store) (ObjectMetadata, error) {
+		return ObjectMetadata{Manifest: manifest}, nil
+	}
+
+	metadata, err := cache.GetObjectMetadata(&corev1.ObjectReference{Kind: "Secret", UID: "secret-1"}, nil, nil, metricsStore)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db"},
+		"type":       "Opaque",
+	}, metadata.Manifest)
+	// The fetched object is left untouched
+	assert.Contains(t, manifest, "data")
+
+	// The manifests of the other kinds are not kept
+	metadata, err = cache.GetObjectMetadata(&corev1.ObjectReference{Kind: "ConfigMap", UID: "configmap-1"}, nil, nil, metricsStore)
+	require.NoError(t, err)
+	assert.Nil(t, metadata.Manifest)
+}
@@ -0,0 +1,61 @@
+package kube
+
+import (
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxOwnerDepth bounds the walk of the owner chain, in case the owner references form a cycle
+const maxOwnerDepth = 10
+
+// Owner is the root of the owner chain of the involved object, e.g. the Deployment of a Pod through its ReplicaSet
+type Owner struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Name       string            `json:"name"`
+	UID        types.UID         `json:"uid"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// SetResolveOwners makes the watcher walk the owner references of the involved objects up to their root controller
+func (e *EventWatcher) SetResolveOwners(resolve bool) {
+	e.resolveOwners = resolve
+}
+
+// controllerOf returns the managing controller among the owners, or the first owner if none is a controller
+func controllerOf(owners []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range owners {
+		if owners[i].Controller != nil && *owners[i].Controller {
+			return &owners[i]
+		}
+	}
+	if len(owners) > 0 {
+		return &owners[0]
+	}
+	return nil
+}
+
+// resolveOwner walks the owner chain from the owners of the involved object. The owners are in the namespace of the
+// object, or cluster-scoped. The walk stops at an owner that cannot be looked up, which is then the root without its
+// labels. It is nil for the objects without owners.
+func (e *EventWatcher) resolveOwner(namespace string, owners []metav1.OwnerReference) *Owner {
+	var root *Owner
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		ref := controllerOf(owners)
+		if ref == nil {
+			break
+		}
+		root = &Owner{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, UID: ref.UID}
+		reference := &corev1.ObjectReference{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, UID: ref.UID, Namespace: namespace}
+		metadata, err := e.objectMetadataCache.GetObjectMetadata(reference, e.lookupClientset, e.lookupDynamicClient, e.metricsStore)
+		if err != nil {
+			log.Debug().Err(err).Str("kind", ref.Kind).Str("name", ref.Name).Msg("Failed to get the metadata of the owner")
+			break
+		}
+		root.Labels = metadata.Labels
+		owners = metadata.OwnerReferences
+	}
+	return root
+}
@@ -0,0 +1,66 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+// ownerChainProvider returns the metadata of the objects by kind and name
+type ownerChainProvider map[string]ObjectMetadata
+
+func (p ownerChainProvider) GetObjectMetadata(reference *corev1.ObjectReference, _ *kubernetes.Clientset, _ dynamic.Interface, _ *metrics.Store) (ObjectMetadata, error) {
+	metadata, ok := p[reference.Kind+"/"+reference.Name]
+	if !ok {
+		return ObjectMetadata{}, errors.NewForbidden(schema.GroupResource{}, reference.Name, nil)
+	}
+	return metadata, nil
+}
+
+func TestEventWatcher_ResolveOwner(t *testing.T) {
+	controller := true
+	ownedBy := func(apiVersion, kind, name string, isController *bool) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID("uid-" + name), Controller: isController}}
+	}
+	provider := ownerChainProvider{
+		"Pod/checkout-7d9f-x2k": {OwnerReferences: ownedBy("apps/v1", "ReplicaSet", "checkout-7d9f", &controller)},
+		"ReplicaSet/checkout-7d9f": {
+			Labels:          map[string]string{"pod-template-hash": "7d9f"},
+			OwnerReferences: ownedBy("apps/v1", "Deployment", "checkout", &controller),
+		},
+		"Deployment/checkout": {Labels: map[string]string{"team": "payments"}},
+	}
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.objectMetadataCache = provider
+	ew.SetResolveOwners(true)
+
+	owner := ew.resolveOwner("shop", provider["Pod/checkout-7d9f-x2k"].OwnerReferences)
+	require.NotNil(t, owner)
+	assert.Equal(t, &Owner{APIVersion: "apps/v1", Kind: "Deployment", Name: "checkout", UID: "uid-checkout", Labels: map[string]string{"team": "payments"}}, owner)
+
+	// The walk stops at the owners that cannot be looked up
+	owner = ew.resolveOwner("shop", ownedBy("batch/v1", "CronJob", "report", nil))
+	assert.Equal(t, &Owner{APIVersion: "batch/v1", Kind: "CronJob", Name: "report", UID: "uid-report"}, owner)
+
+	assert.Nil(t, ew.resolveOwner("shop", nil))
+}
+
+func TestControllerOf(t *testing.T) {
+	controller := true
+	owners := []metav1.OwnerReference{{Name: "first"}, {Name: "controller", Controller: &controller}}
+	assert.Equal(t, "controller", controllerOf(owners).Name)
+	assert.Equal(t, "first", controllerOf(owners[:1]).Name)
+	assert.Nil(t, controllerOf(nil))
+}
@@ -0,0 +1,67 @@
+package kube
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PodInfo are the key details of the spec of a Pod, so the sinks can show e.g. the image of a failing container
+// without looking the Pod up again
+type PodInfo struct {
+	NodeName       string          `json:"nodeName,omitempty"`
+	ServiceAccount string          `json:"serviceAccount,omitempty"`
+	QOSClass       string          `json:"qosClass,omitempty"`
+	Containers     []ContainerInfo `json:"containers,omitempty"`
+}
+
+type ContainerInfo struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Init  bool   `json:"init,omitempty"`
+}
+
+// Image returns the image of the container, it is empty if there is no such container
+func (p *PodInfo) Image(container string) string {
+	for _, c := range p.Containers {
+		if c.Name == container {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+// Images returns the images of the containers, without the init containers
+func (p *PodInfo) Images() []string {
+	images := make([]string, 0, len(p.Containers))
+	for _, c := range p.Containers {
+		if !c.Init {
+			images = append(images, c.Image)
+		}
+	}
+	return images
+}
+
+// SetPodEnrichment makes the watcher attach the details of the spec of the Pods to their events
+func (e *EventWatcher) SetPodEnrichment(enrich bool) {
+	e.enrichPods = enrich
+}
+
+// podInfo reads the details of a Pod looked up with the dynamic client
+func podInfo(pod *unstructured.Unstructured) *PodInfo {
+	info := &PodInfo{}
+	info.NodeName, _, _ = unstructured.NestedString(pod.Object, "spec", "nodeName")
+	info.ServiceAccount, _, _ = unstructured.NestedString(pod.Object, "spec", "serviceAccountName")
+	info.QOSClass, _, _ = unstructured.NestedString(pod.Object, "status", "qosClass")
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", field)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(container, "name")
+			image, _, _ := unstructured.NestedString(container, "image")
+			info.Containers = append(info.Containers, ContainerInfo{Name: name, Image: image, Init: field == "initContainers"})
+		}
+	}
+	return info
+}
@@ -0,0 +1,38 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPodInfo(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"nodeName":           "node-1",
+			"serviceAccountName": "checkout",
+			"initContainers": []interface{}{
+				map[string]interface{}{"name": "migrate", "image": "registry.example.com/checkout-migrations:1.4.2"},
+			},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "registry.example.com/checkout:1.4.2"},
+				map[string]interface{}{"name": "proxy", "image": "envoyproxy/envoy:v1.30.1"},
+			},
+		},
+		"status": map[string]interface{}{"qosClass": "Burstable"},
+	}}
+
+	info := podInfo(pod)
+	assert.Equal(t, "node-1", info.NodeName)
+	assert.Equal(t, "checkout", info.ServiceAccount)
+	assert.Equal(t, "Burstable", info.QOSClass)
+	assert.Equal(t, []ContainerInfo{
+		{Name: "migrate", Image: "registry.example.com/checkout-migrations:1.4.2", Init: true},
+		{Name: "app", Image: "registry.example.com/checkout:1.4.2"},
+		{Name: "proxy", Image: "envoyproxy/envoy:v1.30.1"},
+	}, info.Containers)
+	assert.Equal(t, "envoyproxy/envoy:v1.30.1", info.Image("proxy"))
+	assert.Empty(t, info.Image("missing"))
+	assert.Equal(t, []string{"registry.example.com/checkout:1.4.2", "envoyproxy/envoy:v1.30.1"}, info.Images())
+}
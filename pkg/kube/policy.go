@@ -0,0 +1,164 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+)
+
+// PolicyConfig turns the results of the policy engines into events about the violating resources, with the policy,
+// the rule and the severity as extracted fields, so the policy alerts use the same routes and receivers.
+type PolicyConfig struct {
+	// Kyverno watches the PolicyReports and the ClusterPolicyReports
+	Kyverno bool `yaml:"kyverno,omitempty"`
+	// Gatekeeper watches the audit violations in the status of the constraints
+	Gatekeeper bool `yaml:"gatekeeper,omitempty"`
+	// Results are the results of the policy reports that become events, fail and error by default
+	Results []string `yaml:"results,omitempty"`
+	// EmitExisting creates the events of the violations that existed when the exporter started
+	EmitExisting bool `yaml:"emitExisting,omitempty"`
+}
+
+var policyReportResults = []string{"pass", "fail", "warn", "error", "skip"}
+
+func (c *PolicyConfig) Validate() error {
+	if !c.Kyverno && !c.Gatekeeper {
+		return errors.New("at least one of kyverno and gatekeeper must be enabled")
+	}
+	for _, result := range c.Results {
+		if !slices.Contains(policyReportResults, result) {
+			return fmt.Errorf("results must be some of %s, got %q", strings.Join(policyReportResults, ", "), result)
+		}
+	}
+	return nil
+}
+
+const (
+	policyReportGroup    = "wgpolicyk8s.io"
+	policyReportVersion  = "v1alpha2"
+	constraintsGroup     = "constraints.gatekeeper.sh"
+	constraintsVersion   = "v1beta1"
+	policyViolation      = "PolicyViolation"
+	policyReportTypeTmpl = `{{ if has .Item.result (list "fail" "error" "warn") }}Warning{{ end }}`
+)
+
+// kyvernoSources watches the namespaced and the cluster reports. The results of the reports of Kyverno 1.10 and newer
+// are about the scope of the report, those of the older reports list their resources.
+func (c *PolicyConfig) kyvernoSources() []CustomSourceConfig {
+	results := c.Results
+	if len(results) == 0 {
+		results = []string{"fail", "error"}
+	}
+	mapping := CustomSourceMapping{
+		Reason:    fmt.Sprintf(`{{ if has .Item.result (list "%s") }}%s{{ end }}`, strings.Join(results, `" "`), policyViolation),
+		Message:   `{{ .Item.policy }}{{ with .Item.rule }}/{{ . }}{{ end }}: {{ .Item.message }}`,
+		Type:      policyReportTypeTmpl,
+		Component: "kyverno",
+		Fields: map[string]string{
+			"policy":   "{{ .Item.policy }}",
+			"rule":     "{{ .Item.rule }}",
+			"result":   "{{ .Item.result }}",
+			"severity": "{{ .Item.severity }}",
+			"category": "{{ .Item.category }}",
+		},
+		InvolvedObject: &CustomSourceObjectMapping{
+			APIVersion: `{{ with .Item.resources }}{{ (index . 0).apiVersion }}{{ else }}{{ .Object.scope.apiVersion }}{{ end }}`,
+			Kind:       `{{ with .Item.resources }}{{ (index . 0).kind }}{{ else }}{{ .Object.scope.kind }}{{ end }}`,
+			Namespace:  `{{ with .Item.resources }}{{ (index . 0).namespace }}{{ else }}{{ .Object.scope.namespace }}{{ end }}`,
+			Name:       `{{ with .Item.resources }}{{ (index . 0).name }}{{ else }}{{ .Object.scope.name }}{{ end }}`,
+			UID:        `{{ with .Item.resources }}{{ (index . 0).uid }}{{ else }}{{ .Object.scope.uid }}{{ end }}`,
+		},
+	}
+	return []CustomSourceConfig{
+		{
+			Name:         "kyverno-policyreports",
+			Group:        policyReportGroup,
+			Version:      policyReportVersion,
+			Resource:     "policyreports",
+			Items:        "results",
+			Mapping:      mapping,
+			EmitExisting: c.EmitExisting,
+		},
+		{
+			Name:          "kyverno-clusterpolicyreports",
+			Group:         policyReportGroup,
+			Version:       policyReportVersion,
+			Resource:      "clusterpolicyreports",
+			ClusterScoped: true,
+			Items:         "results",
+			Mapping:       mapping,
+			EmitExisting:  c.EmitExisting,
+		},
+	}
+}
+
+// gatekeeperSource watches the constraints of a constraint template, their kinds are only known to the API
+func (c *PolicyConfig) gatekeeperSource(resource string) CustomSourceConfig {
+	return CustomSourceConfig{
+		Name:          "gatekeeper-" + resource,
+		Group:         constraintsGroup,
+		Version:       constraintsVersion,
+		Resource:      resource,
+		ClusterScoped: true,
+		Items:         "status.violations",
+		Mapping: CustomSourceMapping{
+			Reason:    policyViolation,
+			Message:   "{{ .Object.kind }}/{{ .Object.metadata.name }}: {{ .Item.message }}",
+			Type:      `{{ if has .Item.enforcementAction (list "deny" "warn") }}Warning{{ end }}`,
+			Component: "gatekeeper",
+			Fields: map[string]string{
+				"policy":            "{{ .Object.kind }}",
+				"rule":              "{{ .Object.metadata.name }}",
+				"enforcementAction": "{{ .Item.enforcementAction }}",
+				"severity":          `{{ with .Object.metadata.labels }}{{ .severity }}{{ end }}`,
+			},
+			InvolvedObject: &CustomSourceObjectMapping{
+				APIVersion: "{{ with .Item.group }}{{ . }}/{{ end }}{{ .Item.version }}",
+				Kind:       "{{ .Item.kind }}",
+				Namespace:  "{{ .Item.namespace }}",
+				Name:       "{{ .Item.name }}",
+			},
+		},
+		EmitExisting: c.EmitExisting,
+	}
+}
+
+// sources returns the custom sources of the enabled policy engines. The constraints are discovered once, those of
+// the constraint templates created later are not watched until the exporter restarts.
+func (c *PolicyConfig) sources(client discovery.DiscoveryInterface) ([]CustomSourceConfig, error) {
+	var sources []CustomSourceConfig
+	if c.Kyverno {
+		sources = append(sources, c.kyvernoSources()...)
+	}
+	if c.Gatekeeper {
+		list, err := client.ServerResourcesForGroupVersion(constraintsGroup + "/" + constraintsVersion)
+		if apierrors.IsNotFound(err) {
+			log.Warn().Msg("Gatekeeper constraints are not installed, no constraint is watched")
+			return sources, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot discover the gatekeeper constraints: %w", err)
+		}
+		for _, resource := range list.APIResources {
+			// Skip the subresources like status
+			if !strings.Contains(resource.Name, "/") {
+				sources = append(sources, c.gatekeeperSource(resource.Name))
+			}
+		}
+	}
+	return sources, nil
+}
+
+// AddPolicySources watches the results of the enabled policy engines along with the events
+func (e *EventWatcher) AddPolicySources(cfg *PolicyConfig, namespace string) error {
+	sources, err := cfg.sources(e.clientset.Discovery())
+	if err != nil {
+		return err
+	}
+	return e.AddCustomSources(sources, namespace)
+}
@@ -0,0 +1,109 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func collectPolicyEvents(t *testing.T, cfg CustomSourceConfig, obj map[string]interface{}) []*EnhancedEvent {
+	var events []*EnhancedEvent
+	source, err := newCustomSource(&cfg, func(ev *EnhancedEvent) {
+		events = append(events, ev)
+	})
+	require.NoError(t, err)
+	u := &unstructured.Unstructured{Object: obj}
+	u.SetCreationTimestamp(metav1.NewTime(time.Now().Add(time.Minute)))
+	source.OnAdd(u)
+	return events
+}
+
+func TestPolicyConfig_Kyverno(t *testing.T) {
+	cfg := &PolicyConfig{Kyverno: true}
+	sources := cfg.kyvernoSources()
+	require.Len(t, sources, 2)
+
+	// A report of Kyverno 1.10 and newer is about the resource of its scope
+	events := collectPolicyEvents(t, sources[0], map[string]interface{}{
+		"apiVersion": "wgpolicyk8s.io/v1alpha2",
+		"kind":       "PolicyReport",
+		"metadata":   map[string]interface{}{"name": "8a2b", "namespace": "shop", "uid": "report-1"},
+		"scope":      map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "namespace": "shop", "name": "checkout", "uid": "deploy-1"},
+		"results": []interface{}{
+			map[string]interface{}{"policy": "require-limits", "rule": "check-limits", "result": "fail", "severity": "medium", "message": "limits are required"},
+			map[string]interface{}{"policy": "disallow-latest", "rule": "check-tag", "result": "pass"},
+		},
+	})
+	require.Len(t, events, 1)
+	ev := events[0]
+	assert.Equal(t, "PolicyViolation", ev.Reason)
+	assert.Equal(t, "Warning", ev.Type)
+	assert.Equal(t, "kyverno", ev.Source.Component)
+	assert.Equal(t, "require-limits/check-limits: limits are required", ev.Message)
+	assert.Equal(t, map[string]string{"policy": "require-limits", "rule": "check-limits", "result": "fail", "severity": "medium"}, ev.Extracted)
+	assert.Equal(t, "shop", ev.Namespace)
+	assert.Equal(t, "Deployment", ev.InvolvedObject.Kind)
+	assert.Equal(t, "checkout", ev.InvolvedObject.Name)
+	assert.Equal(t, "apps/v1", ev.InvolvedObject.APIVersion)
+
+	// The results of older cluster reports list their resources
+	events = collectPolicyEvents(t, sources[1], map[string]interface{}{
+		"apiVersion": "wgpolicyk8s.io/v1alpha2",
+		"kind":       "ClusterPolicyReport",
+		"metadata":   map[string]interface{}{"name": "cpol-require-labels", "uid": "report-2"},
+		"results": []interface{}{
+			map[string]interface{}{
+				"policy": "require-labels", "result": "error", "message": "validation error",
+				"resources": []interface{}{map[string]interface{}{"apiVersion": "v1", "kind": "Namespace", "name": "legacy"}},
+			},
+		},
+	})
+	require.Len(t, events, 1)
+	assert.Equal(t, "require-labels: validation error", events[0].Message)
+	assert.Equal(t, "Namespace", events[0].InvolvedObject.Kind)
+	assert.Equal(t, "legacy", events[0].InvolvedObject.Name)
+	assert.Empty(t, events[0].Namespace)
+}
+
+func TestPolicyConfig_Gatekeeper(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: "constraints.gatekeeper.sh/v1beta1",
+		APIResources: []metav1.APIResource{{Name: "k8srequiredlabels"}, {Name: "k8srequiredlabels/status"}},
+	}}
+	cfg := &PolicyConfig{Kyverno: true, Gatekeeper: true}
+	sources, err := cfg.sources(client.Discovery())
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	assert.Equal(t, "gatekeeper-k8srequiredlabels", sources[2].Name)
+
+	events := collectPolicyEvents(t, sources[2], map[string]interface{}{
+		"apiVersion": "constraints.gatekeeper.sh/v1beta1",
+		"kind":       "K8sRequiredLabels",
+		"metadata":   map[string]interface{}{"name": "must-have-owner", "uid": "constraint-1", "labels": map[string]interface{}{"severity": "high"}},
+		"status": map[string]interface{}{
+			"violations": []interface{}{
+				map[string]interface{}{"enforcementAction": "deny", "group": "apps", "version": "v1", "kind": "Deployment", "namespace": "shop", "name": "checkout", "message": "you must provide labels: {\"owner\"}"},
+				map[string]interface{}{"enforcementAction": "dryrun", "version": "v1", "kind": "Pod", "namespace": "shop", "name": "debug", "message": "you must provide labels: {\"owner\"}"},
+			},
+		},
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, "Warning", events[0].Type)
+	assert.Equal(t, "Normal", events[1].Type)
+	assert.Equal(t, "gatekeeper", events[0].Source.Component)
+	assert.Equal(t, `K8sRequiredLabels/must-have-owner: you must provide labels: {"owner"}`, events[0].Message)
+	assert.Equal(t, map[string]string{"policy": "K8sRequiredLabels", "rule": "must-have-owner", "enforcementAction": "deny", "severity": "high"}, events[0].Extracted)
+	assert.Equal(t, "apps/v1", events[0].InvolvedObject.APIVersion)
+	assert.Equal(t, "v1", events[1].InvolvedObject.APIVersion)
+	assert.Equal(t, "shop", events[0].Namespace)
+
+	assert.Error(t, (&PolicyConfig{}).Validate())
+	assert.Error(t, (&PolicyConfig{Kyverno: true, Results: []string{"failed"}}).Validate())
+}
@@ -0,0 +1,86 @@
+package kube
+
+import (
+	"sync"
+	"time"
+)
+
+// progress tracks the events from the watcher to the receivers, so that the checkpoint does not pass an event that is
+// still looked up, rechecked, aggregated or queued. The checkpoint is the time of the oldest event not delivered yet,
+// or of the latest delivered event when there is none.
+type progress struct {
+	mu      sync.Mutex
+	pending map[*tracker]struct{}
+	last    time.Time
+}
+
+// tracker counts the holds of an event, it is done when the last one is released
+type tracker struct {
+	progress  *progress
+	timestamp time.Time
+	holds     int
+}
+
+func newProgress() *progress {
+	return &progress{pending: make(map[*tracker]struct{})}
+}
+
+// track starts tracking an event, it is held until released once
+func (p *progress) track(timestamp time.Time) *tracker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := &tracker{progress: p, timestamp: timestamp, holds: 1}
+	p.pending[t] = struct{}{}
+	return t
+}
+
+func (t *tracker) hold() {
+	t.progress.mu.Lock()
+	defer t.progress.mu.Unlock()
+	t.holds++
+}
+
+func (t *tracker) release() {
+	p := t.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t.holds--
+	if t.holds > 0 {
+		return
+	}
+	delete(p.pending, t)
+	if t.timestamp.After(p.last) {
+		p.last = t.timestamp
+	}
+}
+
+// checkpoint returns the time up to which the events were delivered, it is zero until one was
+func (p *progress) checkpoint() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var oldest time.Time
+	for t := range p.pending {
+		if oldest.IsZero() || t.timestamp.Before(oldest) {
+			oldest = t.timestamp
+		}
+	}
+	if !oldest.IsZero() {
+		return oldest
+	}
+	return p.last
+}
+
+// Hold keeps the checkpoint from passing the event until it is released, for the handlers that deliver it later, e.g.
+// from a queue. Every Hold must be followed by a Release, they do nothing for the events the watcher does not track.
+func (e *EnhancedEvent) Hold() {
+	if e.tracker != nil {
+		e.tracker.hold()
+	}
+}
+
+// Release ends a Hold of the event
+func (e *EnhancedEvent) Release() {
+	if e.tracker != nil {
+		e.tracker.release()
+	}
+}
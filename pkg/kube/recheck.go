@@ -0,0 +1,125 @@
+package kube
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+)
+
+// maxDeletedRecheckDelay bounds the delay, the events are held in memory meanwhile
+const maxDeletedRecheckDelay = 5 * time.Minute
+
+// DeletedRecheckConfig delays the events of the objects that still exist when the event arrives, and looks the objects
+// up again before delivering the events. The events of objects deleted meanwhile, e.g. the Jobs cleaned up by the TTL
+// controller right after completing, are then marked as deleted and can be dropped or routed with the deleted matcher.
+// The delayed events are delivered after the events of the other kinds that arrived meanwhile.
+type DeletedRecheckConfig struct {
+	// DelaySeconds is how long the events are held, 10 by default
+	DelaySeconds int `yaml:"delaySeconds,omitempty"`
+	// Kinds are regular expressions of the kinds whose events are delayed, all kinds if empty
+	Kinds []string `yaml:"kinds,omitempty"`
+}
+
+func (c *DeletedRecheckConfig) Validate() error {
+	if c.DelaySeconds < 0 || time.Duration(c.DelaySeconds)*time.Second > maxDeletedRecheckDelay {
+		return fmt.Errorf("delaySeconds must be between 0 and %d", int(maxDeletedRecheckDelay.Seconds()))
+	}
+	for _, kind := range c.Kinds {
+		if _, err := regexp.Compile(kind); err != nil {
+			return fmt.Errorf("kind %q is not a valid regular expression: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+func (c *DeletedRecheckConfig) delay() time.Duration {
+	if c.DelaySeconds == 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.DelaySeconds) * time.Second
+}
+
+// rechecks are the events waiting for their recheck
+type rechecks struct {
+	mu      sync.Mutex
+	pending map[*EnhancedEvent]clock.Timer
+}
+
+// SetDeletedRecheck makes the watcher recheck whether the involved objects were deleted before delivering their events
+func (e *EventWatcher) SetDeletedRecheck(cfg *DeletedRecheckConfig) {
+	e.deletedRecheck = cfg
+	e.rechecks = &rechecks{pending: make(map[*EnhancedEvent]clock.Timer)}
+	e.recheckObject = func(reference *corev1.ObjectReference) (ObjectMetadata, error) {
+		// The cache is keyed by the resource version of the event, it would answer the same
+		return fetchObjectMetadata(reference, e.lookupClientset, e.lookupDynamicClient, e.metricsStore)
+	}
+}
+
+// shouldRecheck is true for the events of the objects that were found and whose kind is rechecked
+func (e *EventWatcher) shouldRecheck(ev *EnhancedEvent) bool {
+	if e.deletedRecheck == nil || ev.InvolvedObject.Deleted {
+		return false
+	}
+	if len(e.deletedRecheck.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range e.deletedRecheck.Kinds {
+		if matched, _ := regexp.MatchString(kind, ev.InvolvedObject.Kind); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// recheckLater delivers the event after the delay, marked as deleted if its object is gone by then
+func (e *EventWatcher) recheckLater(ev *EnhancedEvent) {
+	r := e.rechecks
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.wg.Add(1)
+	r.pending[ev] = clock.AfterFunc(e.deletedRecheck.delay(), func() {
+		defer e.wg.Done()
+		r.mu.Lock()
+		delete(r.pending, ev)
+		r.mu.Unlock()
+		metadata, err := e.recheckObject(&ev.InvolvedObject.ObjectReference)
+		switch {
+		case apierrors.IsNotFound(err):
+			ev.InvolvedObject.Deleted = true
+		case err != nil:
+			log.Debug().Err(err).Str("object", ev.InvolvedObject.Name).Msg("Failed to recheck the object, delivering the event as is")
+		default:
+			ev.InvolvedObject.Deleted = metadata.Deleted
+		}
+		e.deliver(ev)
+	})
+}
+
+// flushRechecks delivers the events waiting for their recheck right away and as they are, when the watcher stops. The
+// delay can be longer than the termination grace period of the pod.
+func (e *EventWatcher) flushRechecks() {
+	r := e.rechecks
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	events := make([]*EnhancedEvent, 0, len(r.pending))
+	for ev, timer := range r.pending {
+		if timer.Stop() {
+			e.wg.Done()
+			events = append(events, ev)
+		}
+		delete(r.pending, ev)
+	}
+	r.mu.Unlock()
+	for _, ev := range events {
+		e.deliver(ev)
+	}
+}
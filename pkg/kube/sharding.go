@@ -0,0 +1,247 @@
+package kube
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ShardingConfig is used to split the namespaces between multiple replicas. Every replica keeps a Lease to announce
+// itself, and the namespaces are assigned to the live replicas with consistent hashing.
+type ShardingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Group is the name prefix of the leases, replicas with the same group share the namespaces
+	Group                string `yaml:"group"`
+	LeaseDurationSeconds int32  `yaml:"leaseDurationSeconds"`
+	// VirtualNodes is the number of points per replica on the hash ring, more points spread the namespaces more evenly
+	VirtualNodes int `yaml:"virtualNodes"`
+}
+
+const (
+	defaultShardingGroup         = "kubernetes-event-exporter"
+	defaultShardLeaseDuration    = 30
+	defaultShardVirtualNodes     = 100
+	shardGroupLabel              = "kubernetes-event-exporter/shard-group"
+	shardLeaseDeleteTimeout      = 5 * time.Second
+	shardLeaseRenewPerExpiration = 3
+)
+
+// Sharder decides which namespaces are handled by this replica
+type Sharder struct {
+	cfg       ShardingConfig
+	client    kubernetes.Interface
+	namespace string
+	identity  string
+
+	mu      sync.RWMutex
+	ring    *hashRing
+	members []string
+}
+
+func NewSharder(cfg ShardingConfig, client kubernetes.Interface) (*Sharder, error) {
+	if cfg.Group == "" {
+		cfg.Group = defaultShardingGroup
+	}
+	if cfg.LeaseDurationSeconds == 0 {
+		cfg.LeaseDurationSeconds = defaultShardLeaseDuration
+	}
+	if cfg.VirtualNodes == 0 {
+		cfg.VirtualNodes = defaultShardVirtualNodes
+	}
+
+	namespace, err := getInClusterNamespace()
+	if err != nil {
+		namespace = defaultNamespace
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sharder{
+		cfg:       cfg,
+		client:    client,
+		namespace: strings.TrimSpace(namespace),
+		identity:  strings.ToLower(identity),
+	}, nil
+}
+
+// Start announces this replica and computes the initial assignment, so that the events can be filtered before the
+// watcher is started. The lease is renewed until the context is cancelled, after which it is deleted to hand over the
+// namespaces to the other replicas right away.
+func (s *Sharder) Start(ctx context.Context) error {
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.cfg.LeaseDurationSeconds) * time.Second / shardLeaseRenewPerExpiration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				s.release()
+				return
+			case <-ticker.C:
+				if err := s.sync(ctx); err != nil {
+					log.Error().Err(err).Msg("sharding: cannot sync the shard members")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Owns returns whether this replica handles the events of the namespace. Cluster scoped objects use the empty
+// namespace, so they are handled by a single replica as well.
+func (s *Sharder) Owns(namespace string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ring == nil {
+		return false
+	}
+	return s.ring.get(namespace) == s.identity
+}
+
+func (s *Sharder) leaseName() string {
+	return s.cfg.Group + "-" + s.identity
+}
+
+func (s *Sharder) sync(ctx context.Context) error {
+	if err := s.renew(ctx); err != nil {
+		return err
+	}
+
+	leases, err := s.client.CoordinationV1().Leases(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: shardGroupLabel + "=" + s.cfg.Group,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	members := []string{s.identity}
+	for _, lease := range leases.Items {
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == s.identity {
+			continue
+		}
+		if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if expiry.After(now) {
+			members = append(members, *lease.Spec.HolderIdentity)
+		}
+	}
+	sort.Strings(members)
+	s.setMembers(members)
+	return nil
+}
+
+func (s *Sharder) setMembers(members []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ring != nil && strings.Join(members, ",") == strings.Join(s.members, ",") {
+		return
+	}
+	s.members = members
+	s.ring = newHashRing(members, s.cfg.VirtualNodes)
+	log.Info().Strs("members", members).Str("identity", s.identity).Msg("sharding: shard members changed")
+}
+
+func (s *Sharder) renew(ctx context.Context) error {
+	leases := s.client.CoordinationV1().Leases(s.namespace)
+	now := metav1.NowMicro()
+	identity := s.identity
+	duration := s.cfg.LeaseDurationSeconds
+
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   s.leaseName(),
+				Labels: map[string]string{shardGroupLabel: s.cfg.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *Sharder) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), shardLeaseDeleteTimeout)
+	defer cancel()
+	err := s.client.CoordinationV1().Leases(s.namespace).Delete(ctx, s.leaseName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Warn().Err(err).Msg("sharding: cannot delete the shard lease")
+	}
+}
+
+// hashRing maps keys to members with consistent hashing, so that only the keys of a member that joins or leaves move
+type hashRing struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+func newHashRing(members []string, virtualNodes int) *hashRing {
+	r := &hashRing{owners: make(map[uint64]string, len(members)*virtualNodes)}
+	for _, member := range members {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(member + "#" + strconv.Itoa(i))
+			r.points = append(r.points, point)
+			r.owners[point] = member
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+func (r *hashRing) get(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hashKey is FNV-1a followed by the murmur3 finalizer, FNV alone spreads similar keys like "a#1" and "a#2" poorly
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	k := h.Sum64()
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
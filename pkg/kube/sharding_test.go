@@ -0,0 +1,70 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestSharder(client *fake.Clientset, identity string) *Sharder {
+	return &Sharder{
+		cfg:       ShardingConfig{Enabled: true, Group: "test", LeaseDurationSeconds: 30, VirtualNodes: 100},
+		client:    client,
+		namespace: "default",
+		identity:  identity,
+	}
+}
+
+func TestSharder_NamespacesAreOwnedOnce(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx := context.Background()
+
+	sharders := []*Sharder{newTestSharder(client, "a"), newTestSharder(client, "b"), newTestSharder(client, "c")}
+	for _, s := range sharders {
+		require.NoError(t, s.sync(ctx))
+	}
+	// The first replicas only see the others after the next sync
+	for _, s := range sharders {
+		require.NoError(t, s.sync(ctx))
+		assert.Equal(t, []string{"a", "b", "c"}, s.members)
+	}
+
+	owned := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		ns := fmt.Sprintf("ns-%d", i)
+		owners := 0
+		for _, s := range sharders {
+			if s.Owns(ns) {
+				owners++
+				owned[s.identity]++
+			}
+		}
+		assert.Equal(t, 1, owners, ns)
+	}
+	for _, s := range sharders {
+		assert.Greater(t, owned[s.identity], 50, s.identity)
+	}
+
+	// When a replica leaves, only its namespaces move
+	before := make(map[string]bool)
+	for i := 0; i < 300; i++ {
+		before[fmt.Sprintf("ns-%d", i)] = sharders[0].Owns(fmt.Sprintf("ns-%d", i))
+	}
+	sharders[2].release()
+	require.NoError(t, sharders[0].sync(ctx))
+	assert.Equal(t, []string{"a", "b"}, sharders[0].members)
+	for ns, wasOwned := range before {
+		if wasOwned {
+			assert.True(t, sharders[0].Owns(ns), ns)
+		}
+	}
+}
+
+func TestSharder_NotOwnedBeforeSync(t *testing.T) {
+	s := newTestSharder(fake.NewSimpleClientset(), "a")
+	assert.False(t, s.Owns("default"))
+}
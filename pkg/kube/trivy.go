@@ -0,0 +1,81 @@
+package kube
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TrivyConfig turns the vulnerabilities of the VulnerabilityReports of the Trivy Operator into events about the
+// scanned workloads, with the vulnerability, the package and the image as extracted fields.
+type TrivyConfig struct {
+	// Severities are the severities of the vulnerabilities that become events, CRITICAL and HIGH by default
+	Severities []string `yaml:"severities,omitempty"`
+	// EmitExisting creates the events of the vulnerabilities reported before the exporter started
+	EmitExisting bool `yaml:"emitExisting,omitempty"`
+}
+
+var trivySeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+func (c *TrivyConfig) Validate() error {
+	for _, severity := range c.Severities {
+		if !slices.Contains(trivySeverities, severity) {
+			return fmt.Errorf("severities must be some of %s, got %q", strings.Join(trivySeverities, ", "), severity)
+		}
+	}
+	return nil
+}
+
+const (
+	trivyGroup         = "aquasecurity.github.io"
+	trivyVersion       = "v1alpha1"
+	trivyVulnerability = "Vulnerability"
+	// trivyLabel reads a label the operator sets on the report, they name the scanned resource and container
+	trivyLabel = `{{ with .Object.metadata.labels }}{{ index . "trivy-operator.%s" }}{{ end }}`
+	// trivyResourceAPIVersion is the API version of the kinds of workloads the operator scans
+	trivyResourceAPIVersion = `{{ with .Object.metadata.labels }}{{ $kind := index . "trivy-operator.resource.kind" }}` +
+		`{{ if has $kind (list "Deployment" "ReplicaSet" "StatefulSet" "DaemonSet") }}apps/v1` +
+		`{{ else if has $kind (list "Job" "CronJob") }}batch/v1{{ else }}v1{{ end }}{{ end }}`
+)
+
+// source watches the reports, an event is created per vulnerability of the configured severities
+func (c *TrivyConfig) source() CustomSourceConfig {
+	severities := c.Severities
+	if len(severities) == 0 {
+		severities = []string{"CRITICAL", "HIGH"}
+	}
+	return CustomSourceConfig{
+		Name:     "trivy-vulnerabilityreports",
+		Group:    trivyGroup,
+		Version:  trivyVersion,
+		Resource: "vulnerabilityreports",
+		Items:    "report.vulnerabilities",
+		Mapping: CustomSourceMapping{
+			Reason:    fmt.Sprintf(`{{ if has .Item.severity (list "%s") }}%s{{ end }}`, strings.Join(severities, `" "`), trivyVulnerability),
+			Message:   "{{ .Item.vulnerabilityID }} in {{ .Item.resource }} {{ .Item.installedVersion }}{{ with .Item.fixedVersion }}, fixed in {{ . }}{{ end }}: {{ .Item.title }}",
+			Type:      "Warning",
+			Component: "trivy-operator",
+			Fields: map[string]string{
+				"vulnerability":    "{{ .Item.vulnerabilityID }}",
+				"severity":         "{{ .Item.severity }}",
+				"package":          "{{ .Item.resource }}",
+				"installedVersion": "{{ .Item.installedVersion }}",
+				"fixedVersion":     "{{ .Item.fixedVersion }}",
+				"image":            "{{ with .Object.report.artifact }}{{ .repository }}{{ with .tag }}:{{ . }}{{ end }}{{ end }}",
+				"container":        fmt.Sprintf(trivyLabel, "container.name"),
+			},
+			InvolvedObject: &CustomSourceObjectMapping{
+				APIVersion: trivyResourceAPIVersion,
+				Kind:       fmt.Sprintf(trivyLabel, "resource.kind"),
+				Namespace:  fmt.Sprintf(trivyLabel, "resource.namespace"),
+				Name:       fmt.Sprintf(trivyLabel, "resource.name"),
+			},
+		},
+		EmitExisting: c.EmitExisting,
+	}
+}
+
+// AddTrivySources watches the vulnerability reports along with the events
+func (e *EventWatcher) AddTrivySources(cfg *TrivyConfig, namespace string) error {
+	return e.AddCustomSources([]CustomSourceConfig{cfg.source()}, namespace)
+}
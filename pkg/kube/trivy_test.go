@@ -0,0 +1,88 @@
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func vulnerabilityReport(vulnerabilities ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "aquasecurity.github.io/v1alpha1",
+		"kind":       "VulnerabilityReport",
+		"metadata": map[string]interface{}{
+			"name":      "replicaset-checkout-7d4b9c-app",
+			"namespace": "shop",
+			"labels": map[string]interface{}{
+				"trivy-operator.resource.kind":      "ReplicaSet",
+				"trivy-operator.resource.name":      "checkout-7d4b9c",
+				"trivy-operator.resource.namespace": "shop",
+				"trivy-operator.container.name":     "app",
+			},
+		},
+		"report": map[string]interface{}{
+			"artifact":        map[string]interface{}{"repository": "shop/checkout", "tag": "1.4.2"},
+			"vulnerabilities": vulnerabilities,
+		},
+	}
+}
+
+func TestTrivyConfig_Source(t *testing.T) {
+	cfg := &TrivyConfig{}
+	events := collectPolicyEvents(t, cfg.source(), vulnerabilityReport(
+		map[string]interface{}{
+			"vulnerabilityID":  "CVE-2023-44487",
+			"resource":         "golang.org/x/net",
+			"installedVersion": "v0.7.0",
+			"fixedVersion":     "0.17.0",
+			"severity":         "HIGH",
+			"title":            "HTTP/2 rapid reset",
+		},
+		map[string]interface{}{
+			"vulnerabilityID":  "CVE-2023-39325",
+			"resource":         "stdlib",
+			"installedVersion": "1.20.1",
+			"severity":         "MEDIUM",
+			"title":            "excessive resource consumption",
+		},
+	))
+	require.Len(t, events, 1)
+	ev := events[0]
+	assert.Equal(t, "Vulnerability", ev.Reason)
+	assert.Equal(t, "Warning", ev.Type)
+	assert.Equal(t, "trivy-operator", ev.Source.Component)
+	assert.Equal(t, "CVE-2023-44487 in golang.org/x/net v0.7.0, fixed in 0.17.0: HTTP/2 rapid reset", ev.Message)
+	assert.Equal(t, map[string]string{
+		"vulnerability":    "CVE-2023-44487",
+		"severity":         "HIGH",
+		"package":          "golang.org/x/net",
+		"installedVersion": "v0.7.0",
+		"fixedVersion":     "0.17.0",
+		"image":            "shop/checkout:1.4.2",
+		"container":        "app",
+	}, ev.Extracted)
+	assert.Equal(t, "shop", ev.Namespace)
+	assert.Equal(t, "apps/v1", ev.InvolvedObject.APIVersion)
+	assert.Equal(t, "ReplicaSet", ev.InvolvedObject.Kind)
+	assert.Equal(t, "checkout-7d4b9c", ev.InvolvedObject.Name)
+
+	// The severities replace the default ones
+	cfg = &TrivyConfig{Severities: []string{"MEDIUM"}}
+	events = collectPolicyEvents(t, cfg.source(), vulnerabilityReport(map[string]interface{}{
+		"vulnerabilityID":  "CVE-2023-39325",
+		"resource":         "stdlib",
+		"installedVersion": "1.20.1",
+		"severity":         "MEDIUM",
+		"title":            "excessive resource consumption",
+	}))
+	require.Len(t, events, 1)
+	assert.Equal(t, "CVE-2023-39325 in stdlib 1.20.1: excessive resource consumption", events[0].Message)
+	assert.NotContains(t, events[0].Extracted, "fixedVersion")
+}
+
+func TestTrivyConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&TrivyConfig{}).Validate())
+	assert.NoError(t, (&TrivyConfig{Severities: []string{"CRITICAL", "LOW"}}).Validate())
+	assert.Error(t, (&TrivyConfig{Severities: []string{"critical"}}).Validate())
+}
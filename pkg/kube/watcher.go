@@ -8,9 +8,12 @@ import (
 
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -33,29 +36,57 @@ type EventWatcher struct {
 	fn                  EventHandler
 	maxEventAgeSeconds  time.Duration
 	metricsStore        *metrics.Store
+	config              *rest.Config
 	dynamicClient       *dynamic.DynamicClient
 	clientset           *kubernetes.Clientset
+	// lookupDynamicClient and lookupClientset make the object lookups, they are the clients of the watches unless the
+	// lookups have a timeout
+	lookupDynamicClient *dynamic.DynamicClient
+	lookupClientset     *kubernetes.Clientset
 	watchKinds          map[string]struct{}
+	backfillWindow      time.Duration
+	deletedRecheck      *DeletedRecheckConfig
+	recheckObject       func(reference *corev1.ObjectReference) (ObjectMetadata, error)
+	rechecks            *rechecks
+	checkpoint          time.Time
+	namespaces          NamespaceFilter
+	objectSelector      labels.Selector
+	aggregation         *aggregation
+	resolveOwners       bool
+	nodes               cache.Store
+	enrichPods          bool
+	namespaceStore      cache.Store
+	// progress tracks the events until they are delivered, for the checkpoint
+	progress *progress
 }
 
-func NewEventWatcher(config *rest.Config, namespace string, MaxEventAgeSeconds int64, metricsStore *metrics.Store, fn EventHandler, omitLookup bool, cacheSize int, watchKinds []string, watchReasons []string) *EventWatcher {
+func NewEventWatcher(config *rest.Config, namespace string, namespaces NamespaceFilter, MaxEventAgeSeconds int64, metricsStore *metrics.Store, fn EventHandler, omitLookup bool, cacheSize int, watchKinds []string, watchReasons []string, fieldSelector fields.Selector, eventsAPI string, resyncPeriod time.Duration) *EventWatcher {
 	clientset := kubernetes.NewForConfigOrDie(config)
+	dynamicClient := dynamic.NewForConfigOrDie(config)
 	informerList := make([]cache.SharedInformer, 0)
 
-	if len(watchReasons) == 0 {
-		// Default behavior: one informer, no reason filtering
-		factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
-		informerList = append(informerList, factory.Core().V1().Events().Informer())
-	} else {
+	eventsInformer := func(factory informers.SharedInformerFactory) cache.SharedInformer {
+		if eventsAPI == EventsAPIEventsV1 {
+			return factory.Events().V1().Events().Informer()
+		}
+		return factory.Core().V1().Events().Informer()
+	}
+
+	for _, ns := range namespaces.informerNamespaces(namespace) {
+		if len(watchReasons) == 0 {
+			// Default behavior: one informer, no reason filtering
+			factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod, informers.WithNamespace(ns), withFieldSelector(fieldSelector))
+			informerList = append(informerList, eventsInformer(factory))
+			continue
+		}
 		// Create one informer per reason
 		for _, reason := range watchReasons {
-			// Create a new variable for the closure to capture.
-			r := reason
-			tweakListOptions := func(options *metav1.ListOptions) {
-				options.FieldSelector = fields.OneTermEqualSelector("reason", r).String()
+			selector := fields.OneTermEqualSelector("reason", reason)
+			if fieldSelector != nil && !fieldSelector.Empty() {
+				selector = fields.AndSelectors(fieldSelector, selector)
 			}
-			factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(tweakListOptions))
-			informerList = append(informerList, factory.Core().V1().Events().Informer())
+			factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod, informers.WithNamespace(ns), withFieldSelector(selector))
+			informerList = append(informerList, eventsInformer(factory))
 		}
 	}
 
@@ -67,9 +98,14 @@ func NewEventWatcher(config *rest.Config, namespace string, MaxEventAgeSeconds i
 		fn:                  fn,
 		maxEventAgeSeconds:  time.Second * time.Duration(MaxEventAgeSeconds),
 		metricsStore:        metricsStore,
-		dynamicClient:       dynamic.NewForConfigOrDie(config),
+		config:              config,
+		dynamicClient:       dynamicClient,
 		clientset:           clientset,
+		lookupDynamicClient: dynamicClient,
+		lookupClientset:     clientset,
 		watchKinds:          kindsToMap(watchKinds),
+		progress:            newProgress(),
+		namespaces:          namespaces,
 	}
 
 	for _, informer := range watcher.informers {
@@ -82,21 +118,65 @@ func NewEventWatcher(config *rest.Config, namespace string, MaxEventAgeSeconds i
 	return watcher
 }
 
+// SetLookupTimeout bounds the requests of the object lookups. The watches are long-running requests the timeout would
+// cut, so the lookups get their own clients.
+func (e *EventWatcher) SetLookupTimeout(timeout time.Duration) {
+	config := rest.CopyConfig(e.config)
+	config.Timeout = timeout
+	e.lookupClientset = kubernetes.NewForConfigOrDie(config)
+	e.lookupDynamicClient = dynamic.NewForConfigOrDie(config)
+}
+
+// withFieldSelector makes the API server filter the events, a nil or empty selector selects all of them
+func withFieldSelector(selector fields.Selector) informers.SharedInformerOption {
+	return informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		if selector != nil && !selector.Empty() {
+			options.FieldSelector = selector.String()
+		}
+	})
+}
+
 func (e *EventWatcher) OnAdd(obj interface{}) {
-	event := obj.(*corev1.Event)
-	e.onEvent(event)
+	switch event := obj.(type) {
+	case *corev1.Event:
+		e.onEvent(event)
+	case *eventsv1.Event:
+		e.onEvent(eventFromEventsV1(event))
+	}
 }
 
 func (e *EventWatcher) OnUpdate(oldObj, newObj interface{}) {
-	// Ignore updates
+	// The resyncs pass the events again unchanged, they are processed again
+	if isResync(oldObj, newObj) {
+		e.OnAdd(newObj)
+		return
+	}
+	// Ignore updates, except the new occurrences of the series of the events.k8s.io/v1 Events
+	oldEvent, ok := oldObj.(*eventsv1.Event)
+	if !ok {
+		return
+	}
+	if newEvent := newObj.(*eventsv1.Event); isSeriesUpdate(oldEvent, newEvent) {
+		e.onEvent(eventFromEventsV1(newEvent))
+	}
+}
+
+// isResync is true for the updates of the periodic resync of the informer, the object did not change
+func isResync(oldObj, newObj interface{}) bool {
+	oldMeta, err := meta.Accessor(oldObj)
+	if err != nil {
+		return false
+	}
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return false
+	}
+	return oldMeta.GetResourceVersion() == newMeta.GetResourceVersion()
 }
 
 // Ignore events older than the maxEventAgeSeconds
 func (e *EventWatcher) isEventDiscarded(event *corev1.Event) bool {
-	timestamp := event.LastTimestamp.Time
-	if timestamp.IsZero() {
-		timestamp = event.EventTime.Time
-	}
+	timestamp := eventTimestamp(event)
 	eventAge := time.Since(timestamp)
 	if eventAge > e.maxEventAgeSeconds {
 		// Log discarded events if they were created after the watcher started
@@ -114,8 +194,54 @@ func (e *EventWatcher) isEventDiscarded(event *corev1.Event) bool {
 	return false
 }
 
+// SetBackfillWindow makes the watcher process the events that were created up to the window before the exporter
+// started, regardless of their age. These events come from the initial list and are marked as replayed. With a
+// checkpoint, the events were processed before and the backfill only happens on the first start.
+func (e *EventWatcher) SetBackfillWindow(window time.Duration) {
+	e.backfillWindow = window
+}
+
+func (e *EventWatcher) isEventReplayed(event *corev1.Event) bool {
+	if e.backfillWindow == 0 || !e.checkpoint.IsZero() {
+		return false
+	}
+	timestamp := eventTimestamp(event)
+	return timestamp.Before(startUpTime) && startUpTime.Sub(timestamp) <= e.backfillWindow
+}
+
+// SetCheckpoint makes the watcher process the events since the checkpoint regardless of their age, e.g. the ones the
+// previous leader did not process. The timestamps have a resolution of a second, so the events of the second of the
+// checkpoint are processed again.
+func (e *EventWatcher) SetCheckpoint(checkpoint time.Time) {
+	e.checkpoint = checkpoint
+}
+
+func (e *EventWatcher) isEventAfterCheckpoint(event *corev1.Event) bool {
+	return !e.checkpoint.IsZero() && !eventTimestamp(event).Before(e.checkpoint.Truncate(time.Second))
+}
+
+// LastProcessed returns the time up to which the events were delivered: the time of the oldest event still being
+// processed, looked up, rechecked, aggregated or queued for the receivers, or of the latest delivered event if there is
+// none. It is zero until an event was.
+func (e *EventWatcher) LastProcessed() time.Time {
+	return e.progress.checkpoint()
+}
+
+// eventTimestamp is when the event last occurred
+func eventTimestamp(event *corev1.Event) time.Time {
+	if event.LastTimestamp.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.LastTimestamp.Time
+}
+
 func (e *EventWatcher) onEvent(event *corev1.Event) {
-	if e.isEventDiscarded(event) {
+	if !e.namespaces.Matches(event.Namespace) {
+		return
+	}
+
+	replayed := e.isEventReplayed(event)
+	if !replayed && !e.isEventAfterCheckpoint(event) && e.isEventDiscarded(event) {
 		return
 	}
 
@@ -128,15 +254,27 @@ func (e *EventWatcher) onEvent(event *corev1.Event) {
 
 	e.metricsStore.EventsProcessed.Inc()
 
+	// The event is held until it is delivered, or dropped
 	ev := &EnhancedEvent{
-		Event: *event.DeepCopy(),
+		Event:    *event.DeepCopy(),
+		Replayed: replayed,
+		tracker:  e.progress.track(eventTimestamp(event)),
 	}
 	ev.Event.ManagedFields = nil
+	if e.nodes != nil {
+		ev.Node = e.nodeInfo(event)
+	}
+	if e.namespaceStore != nil {
+		if ns := e.namespaceOf(event); ns != nil {
+			ev.NamespaceLabels = ns.Labels
+			ev.NamespaceAnnotations = ns.Annotations
+		}
+	}
 
 	if e.omitLookup || !e.shouldLookup(ev) {
 		ev.InvolvedObject.ObjectReference = *event.InvolvedObject.DeepCopy()
 	} else {
-		objectMetadata, err := e.objectMetadataCache.GetObjectMetadata(&event.InvolvedObject, e.clientset, e.dynamicClient, e.metricsStore)
+		objectMetadata, err := e.objectMetadataCache.GetObjectMetadata(&event.InvolvedObject, e.lookupClientset, e.lookupDynamicClient, e.metricsStore)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				ev.InvolvedObject.Deleted = true
@@ -153,10 +291,35 @@ func (e *EventWatcher) onEvent(event *corev1.Event) {
 			ev.InvolvedObject.OwnerReferences = objectMetadata.OwnerReferences
 			ev.InvolvedObject.ObjectReference = *event.InvolvedObject.DeepCopy()
 			ev.InvolvedObject.Deleted = objectMetadata.Deleted
+			ev.InvolvedObject.Manifest = objectMetadata.Manifest
+			if e.enrichPods {
+				ev.Pod = objectMetadata.Pod
+			}
+			if e.resolveOwners {
+				ev.Owner = e.resolveOwner(event.InvolvedObject.Namespace, objectMetadata.OwnerReferences)
+			}
+			if e.matchesObjectSelector(ev) && e.shouldRecheck(ev) {
+				e.recheckLater(ev)
+				return
+			}
 		}
 	}
 
-	e.fn(ev)
+	if !e.matchesObjectSelector(ev) {
+		ev.Release()
+		return
+	}
+	e.deliver(ev)
+}
+
+// SetObjectSelector makes the watcher drop the events whose involved object does not match the label selector. The
+// labels come from the lookup of the object, without them the selector is matched against no labels.
+func (e *EventWatcher) SetObjectSelector(selector labels.Selector) {
+	e.objectSelector = selector
+}
+
+func (e *EventWatcher) matchesObjectSelector(ev *EnhancedEvent) bool {
+	return e.objectSelector == nil || e.objectSelector.Matches(labels.Set(ev.InvolvedObject.Labels))
 }
 
 func (e *EventWatcher) OnDelete(obj interface{}) {
@@ -175,6 +338,9 @@ func (e *EventWatcher) Start() {
 
 func (e *EventWatcher) Stop() {
 	close(e.stopper)
+	// The rechecked events go through the aggregation
+	e.flushRechecks()
+	e.flushAggregation()
 	e.wg.Wait()
 }
 
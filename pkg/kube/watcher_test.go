@@ -11,12 +11,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
 )
@@ -70,6 +73,7 @@ func newMockEventWatcher(MaxEventAgeSeconds int64, metricsStore *metrics.Store)
 		maxEventAgeSeconds:  time.Second * time.Duration(MaxEventAgeSeconds),
 		fn:                  func(event *EnhancedEvent) {},
 		metricsStore:        metricsStore,
+		progress:            newProgress(),
 	}
 	return watcher
 }
@@ -235,6 +239,62 @@ func TestEventWatcher_EventAge_whenEventCreatedAfterStartupAndAfterMaxAge(t *tes
 	metrics.DestroyMetricsStore(metricsStore)
 }
 
+func TestEventWatcher_BackfillWindow(t *testing.T) {
+	var MaxEventAgeSeconds int64 = 60
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(MaxEventAgeSeconds, metricsStore)
+	ew.omitLookup = true
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.SetBackfillWindow(30 * time.Minute)
+
+	startup := time.Now()
+	ew.setStartUpTime(startup)
+
+	// 20m before startup, within the backfill window -> processed and replayed
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: startup.Add(-20 * time.Minute)}})
+	require.Len(t, received, 1)
+	assert.True(t, received[0].Replayed)
+
+	// 40m before startup, outside the backfill window -> discarded
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: startup.Add(-40 * time.Minute)}})
+	require.Len(t, received, 1)
+
+	// after startup -> processed as usual
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: time.Now()}})
+	require.Len(t, received, 2)
+	assert.False(t, received[1].Replayed)
+}
+
+func TestEventWatcher_BackfillWindowWithCheckpoint(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(60, metricsStore)
+	ew.omitLookup = true
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.SetBackfillWindow(30 * time.Minute)
+
+	startup := time.Now()
+	ew.setStartUpTime(startup)
+	checkpoint := startup.Add(-10 * time.Minute)
+	ew.SetCheckpoint(checkpoint)
+
+	// Within the backfill window but before the checkpoint -> processed before the restart, discarded
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: startup.Add(-20 * time.Minute)}})
+	require.Empty(t, received)
+
+	// Since the checkpoint -> processed, not replayed
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: checkpoint.Add(time.Minute)}})
+	require.Len(t, received, 1)
+	assert.False(t, received[0].Replayed)
+}
+
 func TestOnEvent_WithObjectMetadata(t *testing.T) {
 	metricsStore := metrics.NewMetricsStore("test_")
 	defer metrics.DestroyMetricsStore(metricsStore)
@@ -302,3 +362,265 @@ func TestOnEvent_DeletedObjects(t *testing.T) {
 	require.Equal(t, map[string]string(nil), event.InvolvedObject.Labels)
 	require.Equal(t, []metav1.OwnerReference(nil), event.InvolvedObject.OwnerReferences)
 }
+
+func TestOnEvent_DeletedRecheck(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.SetDeletedRecheck(&DeletedRecheckConfig{DelaySeconds: 1, Kinds: []string{"^Job$"}})
+	ew.recheckObject = func(reference *corev1.ObjectReference) (ObjectMetadata, error) {
+		return ObjectMetadata{}, errors.NewNotFound(schema.GroupResource{}, reference.Name)
+	}
+
+	delivered := make(chan *EnhancedEvent, 2)
+	ew.fn = func(e *EnhancedEvent) {
+		delivered <- e
+	}
+
+	startup := time.Now().Add(-10 * time.Minute)
+	ew.setStartUpTime(startup)
+	for _, kind := range []string{"Job", "Pod"} {
+		ew.onEvent(&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "event-" + kind},
+			LastTimestamp:  metav1.Time{Time: startup.Add(8 * time.Minute)},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, UID: "test", Name: "test-1"},
+		})
+	}
+
+	// The Pod event is delivered right away, the Job event after the recheck found the Job deleted
+	pod := <-delivered
+	require.Equal(t, "Pod", pod.InvolvedObject.Kind)
+	require.False(t, pod.InvolvedObject.Deleted)
+	job := <-delivered
+	require.Equal(t, "Job", job.InvolvedObject.Kind)
+	require.True(t, job.InvolvedObject.Deleted)
+	require.Equal(t, map[string]string{"test": "test"}, job.InvolvedObject.Labels)
+
+	require.Error(t, (&DeletedRecheckConfig{DelaySeconds: 3600}).Validate())
+	require.Error(t, (&DeletedRecheckConfig{Kinds: []string{"("}}).Validate())
+}
+
+func TestEventWatcher_RechecksFlushedOnStop(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.stopper = make(chan struct{})
+	ew.SetDeletedRecheck(&DeletedRecheckConfig{DelaySeconds: 300})
+	ew.recheckObject = func(reference *corev1.ObjectReference) (ObjectMetadata, error) {
+		t.Error("the object is not rechecked on stop")
+		return ObjectMetadata{}, nil
+	}
+
+	var received []*EnhancedEvent
+	ew.fn = func(e *EnhancedEvent) {
+		received = append(received, e)
+	}
+	startup := time.Now().Add(-10 * time.Minute)
+	ew.setStartUpTime(startup)
+	ew.onEvent(&corev1.Event{
+		LastTimestamp:  metav1.Time{Time: startup.Add(8 * time.Minute)},
+		InvolvedObject: corev1.ObjectReference{Kind: "Job", UID: "test", Name: "test-1"},
+	})
+	require.Empty(t, received)
+
+	stopped := make(chan struct{})
+	go func() {
+		ew.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for the recheck")
+	}
+	require.Len(t, received, 1)
+	assert.False(t, received[0].InvolvedObject.Deleted)
+}
+
+func TestEventWatcher_EventsV1(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+
+	var received []EnhancedEvent
+	ew.fn = func(e *EnhancedEvent) {
+		received = append(received, *e)
+	}
+
+	startup := time.Now().Add(-10 * time.Minute)
+	ew.setStartUpTime(startup)
+	first := &eventsv1.Event{
+		ObjectMeta:          metav1.ObjectMeta{Name: "pod-1.17a8", Namespace: "shop", ResourceVersion: "1"},
+		EventTime:           metav1.NewMicroTime(startup.Add(8 * time.Minute)),
+		Regarding:           corev1.ObjectReference{Kind: "Pod", Namespace: "shop", Name: "pod-1"},
+		Reason:              "BackOff",
+		Note:                "Back-off restarting failed container",
+		Type:                corev1.EventTypeWarning,
+		ReportingController: "kubelet",
+		ReportingInstance:   "node-1",
+	}
+	ew.OnAdd(first)
+
+	// The series is updated when the event is seen again
+	second := first.DeepCopy()
+	second.ResourceVersion = "2"
+	second.Series = &eventsv1.EventSeries{Count: 2, LastObservedTime: metav1.NewMicroTime(startup.Add(9 * time.Minute))}
+	ew.OnUpdate(first, second)
+	// Other updates are ignored
+	third := second.DeepCopy()
+	third.ResourceVersion = "3"
+	ew.OnUpdate(second, third)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "Back-off restarting failed container", received[0].Message)
+	assert.Equal(t, "Pod", received[0].InvolvedObject.Kind)
+	assert.Equal(t, corev1.EventSource{Component: "kubelet", Host: "node-1"}, received[0].Source)
+	assert.Equal(t, int32(1), received[0].Count)
+	assert.True(t, received[0].FirstTimestamp.Time.Equal(first.EventTime.Time))
+	assert.True(t, received[0].LastTimestamp.Time.Equal(first.EventTime.Time))
+
+	assert.Equal(t, int32(2), received[1].Count)
+	assert.True(t, received[1].FirstTimestamp.Time.Equal(first.EventTime.Time))
+	assert.True(t, received[1].LastTimestamp.Time.Equal(second.Series.LastObservedTime.Time))
+}
+
+func TestEventWatcher_Checkpoint(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(60, metricsStore)
+	ew.omitLookup = true
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	assert.True(t, ew.LastProcessed().IsZero())
+
+	startup := time.Now()
+	ew.setStartUpTime(startup)
+	checkpoint := startup.Add(-10 * time.Minute)
+	ew.SetCheckpoint(checkpoint)
+
+	// Since the checkpoint -> processed regardless of the age
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: checkpoint.Add(time.Minute)}})
+	require.Len(t, received, 1)
+	assert.False(t, received[0].Replayed)
+
+	// Before the checkpoint -> discarded
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: checkpoint.Add(-time.Minute)}})
+	require.Len(t, received, 1)
+
+	latest := time.Now()
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: latest}})
+	require.Len(t, received, 2)
+	assert.True(t, ew.LastProcessed().Equal(latest))
+}
+
+func TestEventWatcher_CheckpointHeldUntilDelivered(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(600, metricsStore)
+	ew.omitLookup = true
+	var queued []*EnhancedEvent
+	// The handler queues the events, like the receivers do
+	ew.fn = func(event *EnhancedEvent) {
+		event.Hold()
+		queued = append(queued, event)
+	}
+	ew.setStartUpTime(time.Now().Add(-time.Hour))
+
+	first := time.Now().Add(-2 * time.Minute)
+	second := time.Now().Add(-time.Minute)
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: first}})
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: second}})
+	require.Len(t, queued, 2)
+
+	// The first event is still queued -> the checkpoint stays before it
+	queued[1].Release()
+	assert.True(t, ew.LastProcessed().Equal(first))
+	queued[0].Release()
+	assert.True(t, ew.LastProcessed().Equal(second))
+
+	// An aggregated event holds the checkpoint until the window closes
+	ew.fn = func(event *EnhancedEvent) {}
+	ew.SetAggregation(&AggregationConfig{WindowSeconds: 300})
+	third := time.Now()
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: third}, Reason: "BackOff"})
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Time{Time: third.Add(time.Second)}, Reason: "BackOff"})
+	assert.True(t, ew.LastProcessed().Equal(third))
+	ew.flushAggregation()
+	assert.True(t, ew.LastProcessed().Equal(third.Add(time.Second)))
+}
+
+func TestOnEvent_ObjectSelector(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.setStartUpTime(time.Now())
+
+	// The mock provider labels the objects with test=test
+	ew.SetObjectSelector(labels.SelectorFromSet(labels.Set{"test": "test"}))
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), InvolvedObject: corev1.ObjectReference{Name: "test-1"}})
+	require.Len(t, received, 1)
+
+	ew.SetObjectSelector(labels.SelectorFromSet(labels.Set{"team": "payments"}))
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), InvolvedObject: corev1.ObjectReference{Name: "test-1"}})
+	require.Len(t, received, 1)
+}
+
+func TestOnEvent_NamespaceEnrichment(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+	ew.namespaceStore = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	require.NoError(t, ew.namespaceStore.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "shop",
+		Labels:      map[string]string{"team": "payments"},
+		Annotations: map[string]string{"escalation": "#payments-oncall"},
+	}}))
+
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.setStartUpTime(time.Now())
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), InvolvedObject: corev1.ObjectReference{Namespace: "shop", Name: "checkout-1"}})
+	ew.onEvent(&corev1.Event{LastTimestamp: metav1.Now(), InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-1"}})
+
+	require.Len(t, received, 2)
+	assert.Equal(t, map[string]string{"team": "payments"}, received[0].NamespaceLabels)
+	assert.Equal(t, map[string]string{"escalation": "#payments-oncall"}, received[0].NamespaceAnnotations)
+	assert.Nil(t, received[1].NamespaceLabels)
+}
+
+func TestEventWatcher_Resync(t *testing.T) {
+	metricsStore := metrics.NewMetricsStore("test_")
+	defer metrics.DestroyMetricsStore(metricsStore)
+	ew := newMockEventWatcher(300, metricsStore)
+	ew.omitLookup = true
+	var received []*EnhancedEvent
+	ew.fn = func(event *EnhancedEvent) {
+		received = append(received, event)
+	}
+	ew.setStartUpTime(time.Now().Add(-10 * time.Minute))
+
+	event := &corev1.Event{
+		ObjectMeta:    metav1.ObjectMeta{Name: "pod-1.17a8", Namespace: "shop", ResourceVersion: "1"},
+		LastTimestamp: metav1.Time{Time: time.Now()},
+	}
+	ew.OnAdd(event)
+	// The resync passes the unchanged event again -> processed again
+	ew.OnUpdate(event, event.DeepCopy())
+	require.Len(t, received, 2)
+
+	// The updates of the core v1 Events are ignored
+	updated := event.DeepCopy()
+	updated.ResourceVersion = "2"
+	ew.OnUpdate(event, updated)
+	require.Len(t, received, 2)
+}
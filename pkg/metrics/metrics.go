@@ -23,6 +23,14 @@ type Store struct {
 	BuildInfo            prometheus.GaugeFunc
 	KubeApiReadCacheHits prometheus.Counter
 	KubeApiReadRequests  prometheus.Counter
+	QueueDepth           *prometheus.GaugeVec
+	PayloadsTruncated    *prometheus.CounterVec
+	EventsShed           prometheus.Counter
+	EventsThrottled      prometheus.Counter
+	InFlightEvents       prometheus.Gauge
+	BufferedBytes        prometheus.Gauge
+	ReceiverEventsSent   *prometheus.CounterVec
+	ReceiverSendErrors   *prometheus.CounterVec
 }
 
 // promLogger implements promhttp.Logger
@@ -130,6 +138,38 @@ func NewMetricsStore(name_prefix string) *Store {
 			Name: name_prefix + "kube_api_read_cache_misses",
 			Help: "The total number of read requests served from kube-apiserver when looking up object metadata",
 		}),
+		QueueDepth: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: name_prefix + "receiver_queue_depth",
+			Help: "The number of events waiting to be sent to a receiver, by priority",
+		}, []string{"receiver", "priority"}),
+		PayloadsTruncated: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: name_prefix + "payloads_truncated",
+			Help: "The total number of payloads that were truncated to fit the size limit of the sink",
+		}, []string{"sink"}),
+		EventsShed: promauto.NewCounter(prometheus.CounterOpts{
+			Name: name_prefix + "events_shed",
+			Help: "The total number of Normal events dropped because the budget was full",
+		}),
+		EventsThrottled: promauto.NewCounter(prometheus.CounterOpts{
+			Name: name_prefix + "events_throttled",
+			Help: "The total number of events dropped because they exceeded the rate limit and its buffer was full",
+		}),
+		InFlightEvents: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: name_prefix + "in_flight_events",
+			Help: "The number of events queued for or being sent to the receivers, counted once per receiver",
+		}),
+		BufferedBytes: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: name_prefix + "buffered_bytes",
+			Help: "The estimated size of the events queued for or being sent to the receivers",
+		}),
+		ReceiverEventsSent: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: name_prefix + "receiver_events_sent",
+			Help: "The total number of events sent to a receiver",
+		}, []string{"receiver"}),
+		ReceiverSendErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: name_prefix + "receiver_send_errors",
+			Help: "The total number of events that could not be sent to a receiver",
+		}, []string{"receiver"}),
 	}
 }
 
@@ -141,5 +181,13 @@ func DestroyMetricsStore(store *Store) {
 	prometheus.Unregister(store.BuildInfo)
 	prometheus.Unregister(store.KubeApiReadCacheHits)
 	prometheus.Unregister(store.KubeApiReadRequests)
+	prometheus.Unregister(store.QueueDepth)
+	prometheus.Unregister(store.PayloadsTruncated)
+	prometheus.Unregister(store.EventsShed)
+	prometheus.Unregister(store.EventsThrottled)
+	prometheus.Unregister(store.InFlightEvents)
+	prometheus.Unregister(store.BufferedBytes)
+	prometheus.Unregister(store.ReceiverEventsSent)
+	prometheus.Unregister(store.ReceiverSendErrors)
 	store = nil
 }
@@ -0,0 +1,113 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/exporter"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// ExportConfig returns the config as canonical JSON, with the keys sorted and the unset values omitted, so the running
+// config can be compared with the desired one. The values of fields with a sensitive name like token or password, the
+// fields the sinks mark as secret, like webhook URLs carrying a token, and the credentials in URLs are masked.
+func ExportConfig(cfg *exporter.Config) ([]byte, error) {
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(out, &doc); err != nil {
+		return nil, err
+	}
+	doc, _ = canonicalValue("", "", doc)
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalValue converts the YAML value of the field name of the map parent to JSON values and masks the secrets, it
+// returns false for unset values
+func canonicalValue(parent, name string, v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			if value, ok := canonicalValue(name, k, value); ok {
+				ret[k] = value
+			}
+		}
+		return ret, len(ret) > 0
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			key := fmt.Sprint(k)
+			if value, ok := canonicalValue(name, key, value); ok {
+				ret[key] = value
+			}
+		}
+		return ret, len(ret) > 0
+	case []interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, value := range v {
+			// The elements are kept even if they are empty, their position is meaningful
+			value, _ := canonicalValue(parent, name, value)
+			ret = append(ret, value)
+		}
+		return ret, len(ret) > 0
+	case string:
+		if v == "" {
+			return nil, false
+		}
+		if sinks.IsSensitiveName(name) || isSecretField(parent, name) {
+			return "REDACTED", true
+		}
+		return maskURL(v), true
+	default:
+		return v, true
+	}
+}
+
+// isSecretField reports whether the field of a sink config holds a credential. The settings of the receiver groups are
+// shared by the sinks of their members, so they are checked against all sinks.
+func isSecretField(parent, name string) bool {
+	if parent == "settings" {
+		parent = ""
+	}
+	return sinks.IsSecretField(parent, name)
+}
+
+// maskURL masks the user info and the sensitive query parameters of a URL, other values are returned as they are
+func maskURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	if u.User != nil {
+		return sinks.RedactURL(u)
+	}
+	for k := range u.Query() {
+		if sinks.IsSensitiveName(k) {
+			return sinks.RedactURL(u)
+		}
+	}
+	return s
+}
@@ -0,0 +1,289 @@
+package setup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/exporter"
+)
+
+// monitoredReceivers returns the receivers that can get events, those the routes or the watchdogs send to, in the
+// order of the config. The other receivers are left out of the dashboard and the alerts.
+func monitoredReceivers(cfg *exporter.Config) []string {
+	used := make(map[string]bool)
+	var walk func(r *exporter.Route)
+	walk = func(r *exporter.Route) {
+		for i := range r.Match {
+			if r.Match[i].Receiver != "" {
+				used[r.Match[i].Receiver] = true
+			}
+		}
+		for i := range r.Routes {
+			walk(&r.Routes[i])
+		}
+	}
+	walk(&cfg.Route)
+	for _, w := range cfg.Watchdogs {
+		for _, name := range w.Receivers {
+			used[name] = true
+		}
+	}
+
+	var receivers []string
+	for _, r := range cfg.Receivers {
+		if used[r.Name] {
+			receivers = append(receivers, r.Name)
+		}
+	}
+	return receivers
+}
+
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          map[string]string `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type grafanaPanel struct {
+	ID         int                `json:"id"`
+	Type       string             `json:"type"`
+	Title      string             `json:"title"`
+	GridPos    grafanaGridPos     `json:"gridPos"`
+	Datasource *grafanaDatasource `json:"datasource,omitempty"`
+	Targets    []grafanaTarget    `json:"targets,omitempty"`
+	Collapsed  *bool              `json:"collapsed,omitempty"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaTarget struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+}
+
+// dashboardBuilder lays out the panels in rows of two
+type dashboardBuilder struct {
+	panels []grafanaPanel
+	y      int
+	x      int
+}
+
+func (b *dashboardBuilder) row(title string) {
+	if b.x > 0 {
+		b.x, b.y = 0, b.y+8
+	}
+	collapsed := false
+	b.panels = append(b.panels, grafanaPanel{
+		ID:        len(b.panels) + 1,
+		Type:      "row",
+		Title:     title,
+		GridPos:   grafanaGridPos{H: 1, W: 24, Y: b.y},
+		Collapsed: &collapsed,
+	})
+	b.y++
+}
+
+func (b *dashboardBuilder) timeseries(title string, targets ...grafanaTarget) {
+	for i := range targets {
+		targets[i].RefID = string(rune('A' + i))
+	}
+	b.panels = append(b.panels, grafanaPanel{
+		ID:         len(b.panels) + 1,
+		Type:       "timeseries",
+		Title:      title,
+		GridPos:    grafanaGridPos{H: 8, W: 12, X: b.x, Y: b.y},
+		Datasource: &grafanaDatasource{Type: "prometheus", UID: "${datasource}"},
+		Targets:    targets,
+	})
+	if b.x == 0 {
+		b.x = 12
+	} else {
+		b.x, b.y = 0, b.y+8
+	}
+}
+
+// GrafanaDashboard returns a Grafana dashboard with the overall metrics of the exporter and a row per receiver the
+// routes send to, with its sent events, its errors and its queue depth
+func GrafanaDashboard(cfg *exporter.Config) ([]byte, error) {
+	p := cfg.MetricsNamePrefix
+	b := &dashboardBuilder{}
+
+	b.row("Overview")
+	b.timeseries("Events",
+		grafanaTarget{Expr: fmt.Sprintf("sum(rate(%sevents_sent[5m]))", p), LegendFormat: "processed"},
+		grafanaTarget{Expr: fmt.Sprintf("sum(rate(%sevents_discarded[5m]))", p), LegendFormat: "discarded"},
+	)
+	b.timeseries("Errors",
+		grafanaTarget{Expr: fmt.Sprintf("sum(rate(%ssend_event_errors[5m]))", p), LegendFormat: "send"},
+		grafanaTarget{Expr: fmt.Sprintf("sum(rate(%swatch_errors[5m]))", p), LegendFormat: "watch"},
+	)
+	b.timeseries("In-flight events",
+		grafanaTarget{Expr: fmt.Sprintf("sum(%sin_flight_events)", p), LegendFormat: "events"},
+	)
+	if cfg.Budget != nil {
+		b.timeseries("Memory budget",
+			grafanaTarget{Expr: fmt.Sprintf("sum(%sbuffered_bytes)", p), LegendFormat: "buffered bytes"},
+			grafanaTarget{Expr: fmt.Sprintf("sum(rate(%sevents_shed[5m]))", p), LegendFormat: "shed events"},
+		)
+	}
+
+	for _, name := range monitoredReceivers(cfg) {
+		selector := fmt.Sprintf(`{receiver=%q}`, name)
+		b.row("Receiver " + name)
+		b.timeseries("Sent and failed events",
+			grafanaTarget{Expr: fmt.Sprintf("sum(rate(%sreceiver_events_sent%s[5m]))", p, selector), LegendFormat: "sent"},
+			grafanaTarget{Expr: fmt.Sprintf("sum(rate(%sreceiver_send_errors%s[5m]))", p, selector), LegendFormat: "failed"},
+		)
+		b.timeseries("Queue depth",
+			grafanaTarget{Expr: fmt.Sprintf("sum by (priority) (%sreceiver_queue_depth%s)", p, selector), LegendFormat: "{{priority}}"},
+		)
+	}
+
+	dashboard := grafanaDashboard{
+		UID:           "kubernetes-event-exporter",
+		Title:         "Kubernetes Event Exporter",
+		Tags:          []string{"kubernetes-event-exporter"},
+		SchemaVersion: 39,
+		Refresh:       "1m",
+		Time:          map[string]string{"from": "now-6h", "to": "now"},
+		Templating: grafanaTemplating{List: []grafanaVariable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+		}},
+		Panels: b.panels,
+	}
+	if cfg.ClusterName != "" {
+		dashboard.Title += " " + cfg.ClusterName
+		dashboard.UID += "-" + cfg.ClusterName
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dashboard); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type prometheusRuleFile struct {
+	Groups []prometheusRuleGroup `yaml:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string           `yaml:"name"`
+	Rules []prometheusRule `yaml:"rules"`
+}
+
+type prometheusRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// receiverBacklogThreshold is the queue depth of a receiver that is alerted on when it lasts
+const receiverBacklogThreshold = 100
+
+// PrometheusRules returns Prometheus alerting rules for the exporter: the watch errors, the discarded events, the
+// events shed by the memory budget if it is configured, and per receiver the routes send to, the failing deliveries
+// and the growing queue
+func PrometheusRules(cfg *exporter.Config) ([]byte, error) {
+	p := cfg.MetricsNamePrefix
+	rules := []prometheusRule{
+		{
+			Alert:       "KubernetesEventExporterDown",
+			Expr:        fmt.Sprintf("absent(%sbuild_info)", p),
+			For:         "10m",
+			Labels:      map[string]string{"severity": "critical"},
+			Annotations: map[string]string{"summary": "The Kubernetes event exporter is not scraped, events are not exported."},
+		},
+		{
+			Alert:       "KubernetesEventExporterWatchErrors",
+			Expr:        fmt.Sprintf("increase(%swatch_errors[10m]) > 0", p),
+			For:         "10m",
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"summary": "The exporter cannot watch the events of the cluster."},
+		},
+		{
+			Alert:       "KubernetesEventExporterEventsDiscarded",
+			Expr:        fmt.Sprintf("increase(%sevents_discarded[30m]) > 0", p),
+			Labels:      map[string]string{"severity": "info"},
+			Annotations: map[string]string{"summary": "Events older than maxEventAgeSeconds were discarded, the exporter is lagging behind."},
+		},
+	}
+	if cfg.Budget != nil {
+		rules = append(rules, prometheusRule{
+			Alert:       "KubernetesEventExporterEventsShed",
+			Expr:        fmt.Sprintf("increase(%sevents_shed[10m]) > 0", p),
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"summary": "Normal events were dropped because the memory budget is full."},
+		})
+	}
+	if cfg.RateLimit != nil {
+		rules = append(rules, prometheusRule{
+			Alert:       "KubernetesEventExporterEventsThrottled",
+			Expr:        fmt.Sprintf("increase(%sevents_throttled[10m]) > 0", p),
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"summary": "Events were dropped because they exceeded the rate limit."},
+		})
+	}
+
+	for _, name := range monitoredReceivers(cfg) {
+		selector := fmt.Sprintf(`{receiver=%q}`, name)
+		failed := fmt.Sprintf("sum(rate(%sreceiver_send_errors%s[10m]))", p, selector)
+		sent := fmt.Sprintf("sum(rate(%sreceiver_events_sent%s[10m]))", p, selector)
+		rules = append(rules,
+			prometheusRule{
+				Alert:  "KubernetesEventExporterReceiverFailing",
+				Expr:   fmt.Sprintf("%s / (%s + %s) > 0.1", failed, failed, sent),
+				For:    "10m",
+				Labels: map[string]string{"severity": "warning", "receiver": name},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("More than 10%% of the events could not be sent to the receiver %s.", name),
+				},
+			},
+			prometheusRule{
+				Alert:  "KubernetesEventExporterReceiverBacklog",
+				Expr:   fmt.Sprintf("sum(%sreceiver_queue_depth%s) > %d", p, selector, receiverBacklogThreshold),
+				For:    "15m",
+				Labels: map[string]string{"severity": "warning", "receiver": name},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("More than %d events are waiting to be sent to the receiver %s.", receiverBacklogThreshold, name),
+				},
+			},
+		)
+	}
+
+	return yaml.Marshal(prometheusRuleFile{Groups: []prometheusRuleGroup{{Name: "kubernetes-event-exporter", Rules: rules}}})
+}
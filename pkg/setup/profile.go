@@ -0,0 +1,55 @@
+package setup
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultProfiles are built-in configs that can be used instead of a config file, e.g. to evaluate the exporter on a
+// new cluster without writing any YAML
+var DefaultProfiles = map[string]string{
+	"warnings-to-stdout": `logLevel: info
+logFormat: json
+maxEventAgeSeconds: 60
+route:
+  routes:
+    - match:
+        - type: "Warning"
+          receiver: "stdout"
+receivers:
+  - name: "stdout"
+    stdout:
+      encoding: text
+`,
+	"all-to-stdout": `logLevel: info
+logFormat: json
+maxEventAgeSeconds: 60
+route:
+  routes:
+    - match:
+        - receiver: "stdout"
+receivers:
+  - name: "stdout"
+    stdout:
+      encoding: text
+`,
+}
+
+// DefaultProfileNames returns the names of the built-in profiles, sorted
+func DefaultProfileNames() []string {
+	names := make([]string, 0, len(DefaultProfiles))
+	for name := range DefaultProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetDefaultProfile returns the config of the built-in profile
+func GetDefaultProfile(name string) ([]byte, error) {
+	profile, ok := DefaultProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown default profile %q, available profiles: %v", name, DefaultProfileNames())
+	}
+	return []byte(profile), nil
+}
@@ -2,33 +2,127 @@ package setup
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/exporter"
 )
 
+// ParseConfigFromBytes parses the config, which may consist of multiple YAML documents separated by ---. The documents
+// are merged in order: lists like the receivers and routes are appended, other values set in a later document override
+// the earlier ones, even with a zero value like false. Empty documents are skipped. Anchors and aliases are scoped to
+// the document they are defined in.
 func ParseConfigFromBytes(configBytes []byte) (exporter.Config, error) {
 	var config exporter.Config
-	err := yaml.Unmarshal(configBytes, &config)
+	file, err := parser.ParseBytes(configBytes, 0)
 	if err != nil {
-		errMsg := err.Error()
-		errLines := strings.Split(errMsg, "\n")
-		if len(errLines) > 0 {
-			errMsg = errLines[0]
-		}
-		for _, line := range errLines {
-			if strings.Contains(line, "> ") {
-				errMsg += ": [ line " + line + "]"
-				if strings.Contains(line, "{{") {
-					errMsg += ": " + "Need to wrap values with special characters in quotes"
-				}
+		return exporter.Config{}, errors.New("Cannot parse config to YAML: " + formatYAMLError(err))
+	}
+	merged := false
+	for i, doc := range file.Docs {
+		body := documentBody(doc)
+		if body == nil {
+			continue
+		}
+		var docConfig exporter.Config
+		var present interface{}
+		err := yaml.NodeToValue(body, &docConfig)
+		if err == nil {
+			err = yaml.NodeToValue(body, &present)
+		}
+		if err != nil {
+			errMsg := formatYAMLError(err)
+			if i > 0 {
+				errMsg = fmt.Sprintf("document %d: %s", i+1, errMsg)
 			}
+			return exporter.Config{}, errors.New("Cannot parse config to YAML: " + errMsg)
 		}
-		errMsg = "Cannot parse config to YAML: " + errMsg
-		return exporter.Config{}, errors.New(errMsg)
+
+		if !merged {
+			config = docConfig
+			merged = true
+			continue
+		}
+		mergeValue(reflect.ValueOf(&config).Elem(), reflect.ValueOf(docConfig), present)
 	}
 
 	return config, nil
 }
+
+// documentBody returns the content of the document, nil if it is empty. The parser nests the document following an
+// empty one into it.
+func documentBody(doc *ast.DocumentNode) ast.Node {
+	body := doc.Body
+	for {
+		nested, ok := body.(*ast.DocumentNode)
+		if !ok {
+			return body
+		}
+		body = nested.Body
+	}
+}
+
+func formatYAMLError(err error) string {
+	errMsg := err.Error()
+	errLines := strings.Split(errMsg, "\n")
+	if len(errLines) > 0 {
+		errMsg = errLines[0]
+	}
+	if strings.Contains(errMsg, "anchor by alias") {
+		errMsg += " (anchors must be defined before they are used, in the same document)"
+	}
+	for _, line := range errLines {
+		if strings.Contains(line, "> ") {
+			errMsg += ": [ line " + line + "]"
+			if strings.Contains(line, "{{") {
+				errMsg += ": " + "Need to wrap values with special characters in quotes"
+			}
+		}
+	}
+	return errMsg
+}
+
+// mergeValue merges src into dst: slices are appended, maps are merged, structs are merged field by field and other
+// values replace dst. Only the fields set in the document are merged, present is its decoded value.
+func mergeValue(dst, src reflect.Value, present interface{}) {
+	switch src.Kind() {
+	case reflect.Struct:
+		keys, _ := present.(map[string]interface{})
+		for i := 0; i < src.NumField(); i++ {
+			value, ok := keys[yamlFieldName(src.Type().Field(i))]
+			if ok && dst.Field(i).CanSet() {
+				mergeValue(dst.Field(i), src.Field(i), value)
+			}
+		}
+	case reflect.Slice:
+		if src.Len() > 0 {
+			dst.Set(reflect.AppendSlice(dst, src))
+		}
+	case reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(src.Type()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			dst.SetMapIndex(iter.Key(), iter.Value())
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// yamlFieldName is the key of the field, the lower case field name if the tag does not set it
+func yamlFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
@@ -0,0 +1,69 @@
+package setup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// The config itself contains templates for the receivers, so the config templates use different delimiters
+const (
+	ConfigTemplateLeftDelim  = "[["
+	ConfigTemplateRightDelim = "]]"
+)
+
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// ConfigTemplateData is available to the config template. The pod values are usually exposed with the downward API as
+// the NODE_NAME, POD_NAME and POD_NAMESPACE environment variables.
+type ConfigTemplateData struct {
+	Env          map[string]string
+	NodeName     string
+	PodName      string
+	PodNamespace string
+}
+
+func NewConfigTemplateData() ConfigTemplateData {
+	data := ConfigTemplateData{
+		Env:          make(map[string]string),
+		NodeName:     os.Getenv("NODE_NAME"),
+		PodName:      os.Getenv("POD_NAME"),
+		PodNamespace: os.Getenv("POD_NAMESPACE"),
+	}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			data.Env[k] = v
+		}
+	}
+	if data.PodName == "" {
+		data.PodName, _ = os.Hostname()
+	}
+	if data.PodNamespace == "" {
+		if ns, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			data.PodNamespace = strings.TrimSpace(string(ns))
+		}
+	}
+	return data
+}
+
+// RenderConfigTemplate evaluates the config file as a template, e.g. `name: "slack-[[ .Env.CLUSTER ]]"`
+func RenderConfigTemplate(configBytes []byte, data ConfigTemplateData) ([]byte, error) {
+	tmpl, err := template.New("config").
+		Delims(ConfigTemplateLeftDelim, ConfigTemplateRightDelim).
+		Funcs(sprig.TxtFuncMap()).
+		Option("missingkey=error").
+		Parse(string(configBytes))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse config template: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("cannot render config template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
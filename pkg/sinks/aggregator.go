@@ -0,0 +1,221 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// AggregatorConfig makes the exporter an agent forwarding its events to a central exporter with the ingest endpoint
+// enabled. The events are sent in compressed batches, the token identifies the agent. The schema version and the
+// compression are negotiated with the aggregator before the first batch.
+type AggregatorConfig struct {
+	Endpoint string `yaml:"endpoint"`
+	Token    string `yaml:"token"`
+	// Compression is zstd by default, gzip or none. Another one is used if the aggregator does not support it.
+	Compression string `yaml:"compression"`
+	TLS         TLS    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+type Aggregator struct {
+	cfg         *AggregatorConfig
+	client      *http.Client
+	batchWriter *batch.Writer
+
+	// format is negotiated with the aggregator, nil until the handshake succeeded
+	mu     sync.Mutex
+	format *wireFormat
+}
+
+func NewAggregatorSink(cfg *AggregatorConfig) (*Aggregator, error) {
+	if cfg.Endpoint == "" || cfg.Token == "" {
+		return nil, errors.New("aggregator.endpoint and aggregator.token config options must be non-empty")
+	}
+	switch cfg.Compression {
+	case "":
+		cfg.Compression = CompressionZstd
+	case CompressionZstd, CompressionGzip, CompressionNone:
+	default:
+		return nil, fmt.Errorf("aggregator.compression must be zstd, gzip or none, got %q", cfg.Compression)
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	a := &Aggregator{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+	}
+	a.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		a.write,
+	)
+	a.batchWriter.Start()
+	return a, nil
+}
+
+func (a *Aggregator) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	a.batchWriter.Submit(json.RawMessage(ev.ToJSON()))
+	return nil
+}
+
+// wireFormat returns the negotiated wire format, doing the handshake if needed
+func (a *Aggregator) wireFormat(ctx context.Context) (wireFormat, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.format != nil {
+		return *a.format, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.cfg.Endpoint, nil)
+	if err != nil {
+		return wireFormat{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	// The agent tells the versions it supports, for the logs of the aggregator
+	versions := make([]string, 0, len(WireSchemaVersions))
+	for _, v := range WireSchemaVersions {
+		versions = append(versions, strconv.Itoa(v))
+	}
+	req.Header.Set(WireSchemaHeader, strings.Join(versions, ","))
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return wireFormat{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	handshake := WireHandshake{}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		// The aggregator predates the handshake
+		handshake = legacyWireHandshake
+	case resp.StatusCode/100 == 2:
+		if err := json.Unmarshal(body, &handshake); err != nil {
+			return wireFormat{}, fmt.Errorf("cannot parse the handshake: %w", err)
+		}
+	default:
+		return wireFormat{}, fmt.Errorf("handshake failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	format, err := negotiateWireFormat(handshake, a.cfg.Compression)
+	if err != nil {
+		return wireFormat{}, err
+	}
+	if format.compression != a.cfg.Compression {
+		log.Warn().Str("configured", a.cfg.Compression).Str("used", format.compression).Msg("aggregator: the aggregator does not support the compression")
+	}
+	log.Info().Int("schemaVersion", format.schemaVersion).Str("compression", format.compression).Str("aggregatorVersion", handshake.Version).Msg("aggregator: negotiated the wire format")
+	a.format = &format
+	return format, nil
+}
+
+func (a *Aggregator) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	format, err := a.wireFormat(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("aggregator: cannot negotiate the wire format")
+		return res
+	}
+
+	events := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		events = append(events, item.(json.RawMessage))
+	}
+	payload, err := json.Marshal(events)
+	if err == nil {
+		payload, err = Compress(format.compression, payload)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("aggregator: cannot encode the batch")
+		return res
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		log.Error().Err(err).Msg("aggregator: cannot create request")
+		return res
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding := CompressionContentEncoding(format.compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.cfg.Token)
+	req.Header.Set(WireSchemaHeader, strconv.Itoa(format.schemaVersion))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Int("events", len(items)).Msg("aggregator: send failed")
+		return res
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	// A rejected batch is not retried, unless the aggregator is overloaded
+	switch {
+	case resp.StatusCode/100 == 2:
+	case resp.StatusCode == http.StatusUnsupportedMediaType:
+		// The aggregator changed, e.g. it was rolled back, the batch is retried after a new handshake
+		log.Warn().Str("response", string(body)).Msg("aggregator: wire format rejected, negotiating again")
+		a.mu.Lock()
+		a.format = nil
+		a.mu.Unlock()
+		return res
+	case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests:
+		log.Error().Int("status", resp.StatusCode).Str("response", string(body)).Int("events", len(items)).Msg("aggregator: batch rejected")
+	default:
+		log.Error().Int("status", resp.StatusCode).Str("response", string(body)).Msg("aggregator: send failed")
+		return res
+	}
+
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+func (a *Aggregator) Close() {
+	a.batchWriter.Stop()
+	a.client.CloseIdleConnections()
+}
@@ -0,0 +1,61 @@
+package sinks
+
+const (
+	// LayoutPresetAlertmanager renders the event as an Alertmanager webhook message with a single firing alert
+	LayoutPresetAlertmanager = "alertmanager"
+)
+
+// layoutPresets are created on every use since the layouts are not safe to share between sinks
+var layoutPresets = map[string]func() map[string]interface{}{
+	LayoutPresetAlertmanager: alertmanagerLayout,
+}
+
+// alertLabels identify the alert, Alertmanager groups and deduplicates alerts by them
+func alertLabels() map[string]interface{} {
+	return map[string]interface{}{
+		"alertname": "{{ .Reason }}",
+		"severity":  `{{ if eq .Type "Warning" }}warning{{ else }}info{{ end }}`,
+		"cluster":   "{{ .ClusterName }}",
+		"namespace": "{{ .InvolvedObject.Namespace }}",
+		"kind":      "{{ .InvolvedObject.Kind }}",
+		"name":      "{{ .InvolvedObject.Name }}",
+		"reason":    "{{ .Reason }}",
+		"source":    "{{ .Source.Component }}",
+	}
+}
+
+func alertAnnotations() map[string]interface{} {
+	return map[string]interface{}{
+		"summary":     "{{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}: {{ .Reason }}",
+		"description": "{{ .Message }}",
+		"count":       "{{ .Count }}",
+	}
+}
+
+// alertmanagerLayout follows the payload Alertmanager sends to webhook receivers (version 4), so receivers built for
+// Alertmanager can be used directly
+func alertmanagerLayout() map[string]interface{} {
+	return map[string]interface{}{
+		"version":  "4",
+		"status":   "firing",
+		"receiver": "kubernetes-event-exporter",
+		"groupKey": "{{ .InvolvedObject.UID }}/{{ .Reason }}",
+		"groupLabels": map[string]interface{}{
+			"alertname": "{{ .Reason }}",
+		},
+		"commonLabels":      alertLabels(),
+		"commonAnnotations": alertAnnotations(),
+		"externalURL":       "",
+		"alerts": []interface{}{
+			map[string]interface{}{
+				"status":       "firing",
+				"labels":       alertLabels(),
+				"annotations":  alertAnnotations(),
+				"startsAt":     "{{ .GetTimestampRFC3339 }}",
+				"endsAt":       "0001-01-01T00:00:00Z",
+				"generatorURL": "",
+				"fingerprint":  "{{ .UID }}",
+			},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// ClickHouseConfig writes events in batches to a ClickHouse table over the HTTP interface
+type ClickHouseConfig struct {
+	// Endpoint is the HTTP interface, e.g. http://clickhouse:8123
+	Endpoint string `yaml:"endpoint"`
+	Database string `yaml:"database"`
+	Table    string `yaml:"table"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	TLS      TLS    `yaml:"tls"`
+	// AsyncInsert lets the server buffer the inserts, useful with small batches
+	AsyncInsert bool `yaml:"asyncInsert"`
+	// CreateTable creates the table with a schema mapped from the event fields if it does not exist. It is ignored when
+	// a layout is set, since the columns are then defined by the layout.
+	CreateTable bool `yaml:"createTable"`
+	// Layout maps column names to templates, by default the event fields are mapped to columns automatically
+	Layout map[string]interface{} `yaml:"layout"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+const clickHouseCreateTable = `CREATE TABLE IF NOT EXISTS %s (
+	timestamp DateTime64(3),
+	cluster LowCardinality(String),
+	namespace LowCardinality(String),
+	name String,
+	uid String,
+	reason LowCardinality(String),
+	type LowCardinality(String),
+	message String,
+	count UInt32,
+	kind LowCardinality(String),
+	object_name String,
+	object_namespace String,
+	object_uid String,
+	source_component LowCardinality(String),
+	source_host String,
+	labels Map(String, String),
+	annotations Map(String, String),
+	raw String
+) ENGINE = MergeTree ORDER BY (namespace, timestamp)`
+
+type ClickHouse struct {
+	cfg         *ClickHouseConfig
+	client      *http.Client
+	table       string
+	batchWriter *batch.Writer
+}
+
+func NewClickHouseSink(cfg *ClickHouseConfig) (*ClickHouse, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("clickhouse.endpoint config option must be non-empty")
+	}
+	if cfg.Table == "" {
+		return nil, errors.New("clickhouse.table config option must be non-empty")
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	c := &ClickHouse{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		table: cfg.Table,
+	}
+	if cfg.Database != "" {
+		c.table = cfg.Database + "." + cfg.Table
+	}
+
+	if cfg.CreateTable && cfg.Layout == nil {
+		if err := c.exec(context.Background(), fmt.Sprintf(clickHouseCreateTable, c.table), nil); err != nil {
+			return nil, fmt.Errorf("cannot create clickhouse table: %w", err)
+		}
+	}
+
+	c.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		c.insert,
+	)
+	c.batchWriter.Start()
+
+	return c, nil
+}
+
+// clickHouseRow maps the event to the columns of the default table
+func clickHouseRow(ev *kube.EnhancedEvent) map[string]interface{} {
+	labels := ev.InvolvedObject.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := ev.InvolvedObject.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"timestamp":        time.UnixMilli(ev.GetTimestampMs()).UTC().Format("2006-01-02 15:04:05.000"),
+		"cluster":          ev.ClusterName,
+		"namespace":        ev.Namespace,
+		"name":             ev.Name,
+		"uid":              string(ev.UID),
+		"reason":           ev.Reason,
+		"type":             ev.Type,
+		"message":          ev.Message,
+		"count":            ev.Count,
+		"kind":             ev.InvolvedObject.Kind,
+		"object_name":      ev.InvolvedObject.Name,
+		"object_namespace": ev.InvolvedObject.Namespace,
+		"object_uid":       string(ev.InvolvedObject.UID),
+		"source_component": ev.Source.Component,
+		"source_host":      ev.Source.Host,
+		"labels":           labels,
+		"annotations":      annotations,
+		"raw":              string(ev.ToJSON()),
+	}
+}
+
+func (c *ClickHouse) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, c.cfg.Layout)
+	if layout != nil {
+		row, err := convertLayoutTemplate(layout, ev)
+		if err != nil {
+			return err
+		}
+		c.batchWriter.Submit(row)
+		return nil
+	}
+
+	c.batchWriter.Submit(clickHouseRow(ev))
+	return nil
+}
+
+func (c *ClickHouse) insert(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			log.Error().Err(err).Msg("clickhouse: cannot encode row")
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", c.table)
+	err := c.exec(ctx, query, buf)
+	if err != nil {
+		log.Error().Err(err).Int("rows", len(items)).Msg("clickhouse: insert failed")
+		return res
+	}
+
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+// exec runs the query, the optional body is sent as the data of an INSERT
+func (c *ClickHouse) exec(ctx context.Context, query string, body io.Reader) error {
+	params := url.Values{}
+	params.Set("date_time_input_format", "best_effort")
+	if c.cfg.AsyncInsert {
+		params.Set("async_insert", "1")
+		params.Set("wait_for_async_insert", "1")
+	}
+
+	var reqBody io.Reader = strings.NewReader(query)
+	if body != nil {
+		params.Set("query", query)
+		reqBody = body
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.cfg.Endpoint, "/")+"/?"+params.Encode(), reqBody)
+	if err != nil {
+		return err
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("not successfull (200) response: " + string(respBody))
+	}
+	return nil
+}
+
+func (c *ClickHouse) Close() {
+	c.batchWriter.Stop()
+	c.client.CloseIdleConnections()
+}
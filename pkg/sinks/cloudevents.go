@@ -0,0 +1,215 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	CloudEventsModeBinary     = "binary"
+	CloudEventsModeStructured = "structured"
+
+	cloudEventsSpecVersion = "1.0"
+
+	defaultCloudEventsSource  = "kubernetes-event-exporter{{ with .ClusterName }}/{{ . }}{{ end }}"
+	defaultCloudEventsType    = "io.k8s.core.v1.event"
+	defaultCloudEventsSubject = "{{ .InvolvedObject.Kind }}/{{ with .InvolvedObject.Namespace }}{{ . }}/{{ end }}{{ .InvolvedObject.Name }}"
+)
+
+// cloudEventsAttributeName is the format of the names of the extension attributes of the spec
+var cloudEventsAttributeName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// CloudEventsConfig sends the events as CloudEvents 1.0 over HTTP, e.g. to a Knative Eventing broker or an Argo
+// Events webhook source. In the binary mode the attributes are headers and the body is the data, in the structured
+// mode the body is the whole CloudEvent as JSON. The data is the event as JSON, or its layout.
+type CloudEventsConfig struct {
+	Endpoint string `yaml:"endpoint"`
+	// Mode is binary (default) or structured
+	Mode string `yaml:"mode,omitempty"`
+	// Source, Type and Subject are the templates of the attributes
+	Source  string `yaml:"source,omitempty"`
+	Type    string `yaml:"type,omitempty"`
+	Subject string `yaml:"subject,omitempty"`
+	// Extensions are templates of extension attributes, the empty ones are left out
+	Extensions map[string]string      `yaml:"extensions,omitempty"`
+	Headers    map[string]string      `yaml:"headers,omitempty"`
+	Layout     map[string]interface{} `yaml:"layout"`
+	TLS        TLS                    `yaml:"tls"`
+}
+
+func (c *CloudEventsConfig) validate() error {
+	if c.Mode != "" && c.Mode != CloudEventsModeBinary && c.Mode != CloudEventsModeStructured {
+		return fmt.Errorf("cloudEvents.mode must be %s or %s, got %q", CloudEventsModeBinary, CloudEventsModeStructured, c.Mode)
+	}
+	for name := range c.Extensions {
+		if !cloudEventsAttributeName.MatchString(name) {
+			return fmt.Errorf("cloudEvents.extensions: %q must be lowercase letters and digits", name)
+		}
+		switch name {
+		case "specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema", "data":
+			return fmt.Errorf("cloudEvents.extensions: %q is a context attribute of the spec", name)
+		}
+	}
+	return nil
+}
+
+type CloudEvents struct {
+	cfg    *CloudEventsConfig
+	client *http.Client
+}
+
+func NewCloudEventsSink(cfg *CloudEventsConfig) (Sink, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("cloudEvents.endpoint config option must be non-empty")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = CloudEventsModeBinary
+	}
+	if cfg.Source == "" {
+		cfg.Source = defaultCloudEventsSource
+	}
+	if cfg.Type == "" {
+		cfg.Type = defaultCloudEventsType
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultCloudEventsSubject
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+	return &CloudEvents{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+// cloudEventID identifies an occurrence of the event, so the receivers can drop the duplicates of a retry
+func cloudEventID(ev *kube.EnhancedEvent) string {
+	if ev.UID != "" {
+		return string(ev.UID) + "-" + strconv.Itoa(int(ev.Count))
+	}
+	sum := sha256.Sum256([]byte(ev.Namespace + "/" + ev.Name + "/" + ev.GetTimestampRFC3339()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cloudEventTime is when the event last occurred
+func cloudEventTime(ev *kube.EnhancedEvent) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	return time.UnixMilli(ev.GetTimestampMs())
+}
+
+// attributes renders the context attributes and the extensions, without the data
+func (c *CloudEvents) attributes(ev *kube.EnhancedEvent) (map[string]string, error) {
+	attributes := map[string]string{
+		"specversion":     cloudEventsSpecVersion,
+		"id":              cloudEventID(ev),
+		"time":            cloudEventTime(ev).UTC().Format(time.RFC3339Nano),
+		"datacontenttype": "application/json",
+	}
+	for name, tmpl := range map[string]string{"source": c.cfg.Source, "type": c.cfg.Type, "subject": c.cfg.Subject} {
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("cannot render %s: %w", name, err)
+		}
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	if attributes["source"] == "" || attributes["type"] == "" {
+		return nil, errors.New("the source and the type of a CloudEvent must not be empty")
+	}
+	for name, tmpl := range c.cfg.Extensions {
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("cannot render extension %s: %w", name, err)
+		}
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	return attributes, nil
+}
+
+// request builds the HTTP request of the event in the configured content mode
+func (c *CloudEvents) request(ctx context.Context, ev *kube.EnhancedEvent) (*http.Request, error) {
+	data, err := serializeEventWithLayout(resolveLayout(ctx, c.cfg.Layout), ev)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := c.attributes(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	body := data
+	if c.cfg.Mode == CloudEventsModeStructured {
+		envelope := make(map[string]interface{}, len(attributes)+1)
+		for name, value := range attributes {
+			envelope[name] = value
+		}
+		envelope["data"] = json.RawMessage(data)
+		if body, err = json.Marshal(envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range c.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	if c.cfg.Mode == CloudEventsModeStructured {
+		req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+		return req, nil
+	}
+	for name, value := range attributes {
+		if name == "datacontenttype" {
+			req.Header.Set("Content-Type", value)
+			continue
+		}
+		req.Header.Set("Ce-"+name, value)
+	}
+	return req, nil
+}
+
+func (c *CloudEvents) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	req, err := c.request(ctx, ev)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (c *CloudEvents) Close() {
+	c.client.CloseIdleConnections()
+}
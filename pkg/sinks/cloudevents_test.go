@@ -0,0 +1,101 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func cloudEventsTestEvent() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.UID = "3f2a"
+	ev.Count = 2
+	ev.ClusterName = "prod-eu"
+	ev.Reason = "BackOff"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+	ev.LastTimestamp = metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	return ev
+}
+
+func TestCloudEvents_Binary(t *testing.T) {
+	var header http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s, err := NewCloudEventsSink(&CloudEventsConfig{
+		Endpoint:   server.URL,
+		Extensions: map[string]string{"reason": "{{ .Reason }}", "team": `{{ index .InvolvedObject.Labels "team" }}`},
+		Layout:     map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Send(context.Background(), cloudEventsTestEvent()))
+
+	assert.Equal(t, `{"reason":"BackOff"}`, body)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "1.0", header.Get("Ce-Specversion"))
+	assert.Equal(t, "3f2a-2", header.Get("Ce-Id"))
+	assert.Equal(t, "kubernetes-event-exporter/prod-eu", header.Get("Ce-Source"))
+	assert.Equal(t, "io.k8s.core.v1.event", header.Get("Ce-Type"))
+	assert.Equal(t, "Pod/shop/checkout-1", header.Get("Ce-Subject"))
+	assert.Equal(t, "2024-05-01T12:00:00Z", header.Get("Ce-Time"))
+	assert.Equal(t, "BackOff", header.Get("Ce-Reason"))
+	// The empty extensions are left out
+	assert.NotContains(t, header, "Ce-Team")
+}
+
+func TestCloudEvents_Structured(t *testing.T) {
+	s, err := NewCloudEventsSink(&CloudEventsConfig{
+		Endpoint: "http://broker-ingress.knative-eventing/default/default",
+		Mode:     CloudEventsModeStructured,
+		Type:     "io.k8s.event.{{ .Reason | lower }}",
+		Layout:   map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	req, err := s.(*CloudEvents).request(context.Background(), cloudEventsTestEvent())
+	require.NoError(t, err)
+	assert.Equal(t, "application/cloudevents+json; charset=utf-8", req.Header.Get("Content-Type"))
+	assert.Empty(t, req.Header.Get("Ce-Id"))
+
+	var envelope map[string]interface{}
+	require.NoError(t, json.NewDecoder(req.Body).Decode(&envelope))
+	assert.Equal(t, map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "3f2a-2",
+		"source":          "kubernetes-event-exporter/prod-eu",
+		"type":            "io.k8s.event.backoff",
+		"subject":         "Pod/shop/checkout-1",
+		"time":            "2024-05-01T12:00:00Z",
+		"datacontenttype": "application/json",
+		"data":            map[string]interface{}{"reason": "BackOff"},
+	}, envelope)
+}
+
+func TestCloudEventsConfig_Validate(t *testing.T) {
+	_, err := NewCloudEventsSink(&CloudEventsConfig{})
+	assert.Error(t, err)
+	_, err = NewCloudEventsSink(&CloudEventsConfig{Endpoint: "http://broker", Mode: "batched"})
+	assert.Error(t, err)
+	_, err = NewCloudEventsSink(&CloudEventsConfig{Endpoint: "http://broker", Extensions: map[string]string{"Team": "payments"}})
+	assert.Error(t, err)
+	_, err = NewCloudEventsSink(&CloudEventsConfig{Endpoint: "http://broker", Extensions: map[string]string{"subject": "x"}})
+	assert.Error(t, err)
+}
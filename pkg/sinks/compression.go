@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for the sinks and uploads that write whole files or objects
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+)
+
+func ValidateCompression(codec string) error {
+	switch codec {
+	case "", CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy:
+		return nil
+	default:
+		return fmt.Errorf("unknown compression %q, must be one of none, gzip, zstd or snappy", codec)
+	}
+}
+
+// NewCompressWriter wraps w, the returned writer must be closed to flush the compressed data. Snappy uses the framing
+// format, so the result can be streamed and concatenated.
+func NewCompressWriter(codec string, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case "", CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionSnappy:
+		return s2.NewWriter(w, s2.WriterSnappyCompat()), nil
+	default:
+		return nil, ValidateCompression(codec)
+	}
+}
+
+// Compress compresses a whole payload
+func Compress(codec string, data []byte) ([]byte, error) {
+	if codec == "" || codec == CompressionNone {
+		return data, nil
+	}
+	buf := &bytes.Buffer{}
+	w, err := NewCompressWriter(codec, buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CompressionExtension is the file name extension of the codec, including the dot
+func CompressionExtension(codec string) string {
+	switch codec {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	case CompressionSnappy:
+		return ".sz"
+	default:
+		return ""
+	}
+}
+
+// CompressionContentEncoding is the HTTP Content-Encoding of the codec, snappy has no registered encoding
+func CompressionContentEncoding(codec string) string {
+	switch codec {
+	case CompressionGzip, CompressionZstd:
+		return codec
+	default:
+		return ""
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
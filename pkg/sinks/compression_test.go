@@ -0,0 +1,94 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"reason":"BackOff","message":"Back-off restarting failed container"}`+"\n"), 100)
+
+	decompress := map[string]func(r io.Reader) (io.Reader, error){
+		CompressionNone: func(r io.Reader) (io.Reader, error) { return r, nil },
+		CompressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		CompressionZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		CompressionSnappy: func(r io.Reader) (io.Reader, error) {
+			return s2.NewReader(r), nil
+		},
+	}
+	for codec, newReader := range decompress {
+		compressed, err := Compress(codec, data)
+		require.NoError(t, err, codec)
+		if codec != CompressionNone {
+			assert.Less(t, len(compressed), len(data)/5, codec)
+		}
+
+		r, err := newReader(bytes.NewReader(compressed))
+		require.NoError(t, err, codec)
+		res, err := io.ReadAll(r)
+		require.NoError(t, err, codec)
+		assert.Equal(t, data, res, codec)
+	}
+
+	_, err := Compress("lzma", data)
+	assert.Error(t, err)
+}
+
+func TestFileConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&FileConfig{Compression: "gzip"}).Validate())
+	assert.NoError(t, (&FileConfig{Compression: "zstd"}).Validate())
+	assert.Error(t, (&FileConfig{Compression: "lzma"}).Validate())
+}
+
+func TestFile_CompressesRotatedFiles(t *testing.T) {
+	for _, codec := range []string{CompressionZstd, CompressionSnappy} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "events.log")
+		// A file rotated before a restart
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "events-2024-01-01T00-00-00.000.log"), []byte("old\n"), 0o644))
+
+		f := newCompressingFile(&FileConfig{Path: path, Compression: codec, MaxBackups: 2})
+		f.maxSize = 10
+		for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+			_, err := f.Write([]byte(line))
+			require.NoError(t, err, codec)
+			// The rotated files are named after the millisecond they were rotated in
+			time.Sleep(2 * time.Millisecond)
+		}
+		require.NoError(t, f.Close())
+
+		names, err := filepath.Glob(filepath.Join(dir, "events-*"))
+		require.NoError(t, err)
+		require.Len(t, names, 2, codec)
+		var lines []string
+		for _, name := range names {
+			assert.True(t, strings.HasSuffix(name, ".log"+CompressionExtension(codec)), name)
+			compressed, err := os.ReadFile(name)
+			require.NoError(t, err)
+			var r io.Reader = s2.NewReader(bytes.NewReader(compressed))
+			if codec == CompressionZstd {
+				r, err = zstd.NewReader(bytes.NewReader(compressed))
+				require.NoError(t, err)
+			}
+			data, err := io.ReadAll(r)
+			require.NoError(t, err, name)
+			lines = append(lines, string(data))
+		}
+		// The oldest ones are removed beyond maxBackups
+		assert.Equal(t, []string{"second\n", "third\n"}, lines, codec)
+		active, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "fourth\n", string(active), codec)
+	}
+}
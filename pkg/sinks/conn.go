@@ -0,0 +1,126 @@
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// connWriter writes to a connection it dials on first use, for the sinks speaking a protocol over TCP, UDP or TLS. A
+// failed connection is closed and dialed again on the next write.
+type connWriter struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	// keepAlive is the period of the TCP keep-alive probes, the default of the dialer if zero
+	keepAlive time.Duration
+	// handshake runs on every new connection before it is used, e.g. to authenticate
+	handshake func(conn net.Conn) error
+	// detectClose checks whether the server closed the connection before it is reused, only for the protocols where
+	// the server never writes. Otherwise the first write after the close is lost.
+	detectClose bool
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newConnWriter(network, address string, tlsConfig *tls.Config, timeout time.Duration) *connWriter {
+	if tlsConfig != nil && tlsConfig.ServerName == "" {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = endpointHost(address)
+	}
+	return &connWriter{network: network, address: address, tlsConfig: tlsConfig, timeout: timeout}
+}
+
+// Write writes the packets in order, each with a single write, so they stay separate datagrams on UDP
+func (w *connWriter) Write(ctx context.Context, packets ...[]byte) error {
+	return w.Do(ctx, func(conn net.Conn) error {
+		for _, p := range packets {
+			if _, err := conn.Write(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Do runs f with the connection, for the protocols that read responses. The connection is closed if f fails. If it
+// fails on a reused connection, it is tried once more on a new connection, the server might have closed the idle one.
+func (w *connWriter) Do(ctx context.Context, f func(conn net.Conn) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	reused := w.conn != nil
+	err := w.do(ctx, f)
+	if err != nil && reused {
+		err = w.do(ctx, f)
+	}
+	return err
+}
+
+func (w *connWriter) do(ctx context.Context, f func(conn net.Conn) error) error {
+	deadline := time.Now().Add(w.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if w.conn != nil && w.detectClose && closedByPeer(w.conn) {
+		w.conn.Close()
+		w.conn = nil
+	}
+	if w.conn == nil {
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		conn, err := dialContext(ctx, w.network, w.address)
+		if err != nil {
+			return err
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok && w.keepAlive > 0 {
+			_ = tcpConn.SetKeepAlivePeriod(w.keepAlive)
+		}
+		if w.tlsConfig != nil {
+			tlsConn := tls.Client(conn, w.tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return err
+			}
+			conn = tlsConn
+		}
+		if w.handshake != nil {
+			_ = conn.SetDeadline(deadline)
+			if err := w.handshake(conn); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+		w.conn = conn
+	}
+
+	_ = w.conn.SetDeadline(deadline)
+	if err := f(w.conn); err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+// closedByPeer reads from a connection the server never writes to, anything but a timeout means it is closed
+func closedByPeer(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := conn.Read(make([]byte, 1))
+	var netErr net.Error
+	return !errors.As(err, &netErr) || !netErr.Timeout()
+}
+
+func (w *connWriter) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
@@ -0,0 +1,47 @@
+package sinks
+
+import "errors"
+
+// DeliveryConfig sets how the events are delivered to a receiver. With several workers, the events are sent
+// concurrently and can arrive out of order, unless Ordered keys the workers by involved object, so the events of an
+// object are always delivered by the same worker, in the order they were seen.
+type DeliveryConfig struct {
+	Workers int  `yaml:"workers"`
+	Ordered bool `yaml:"ordered"`
+}
+
+func (c *DeliveryConfig) validate() error {
+	if c.Workers < 0 {
+		return errors.New("delivery.workers must not be negative")
+	}
+	return nil
+}
+
+// deliverySink carries the delivery config of the receiver to the registry
+type deliverySink struct {
+	Sink
+	cfg *DeliveryConfig
+}
+
+func (d *deliverySink) Unwrap() Sink {
+	return d.Sink
+}
+
+// WithDelivery attaches the delivery config to the sink
+func WithDelivery(s Sink, cfg *DeliveryConfig) Sink {
+	return &deliverySink{Sink: s, cfg: cfg}
+}
+
+// DeliveryOf returns the delivery config of the receiver of the sink, nil if it has none
+func DeliveryOf(s Sink) *DeliveryConfig {
+	for {
+		if d, ok := s.(*deliverySink); ok {
+			return d.cfg
+		}
+		w, ok := s.(wrappedSink)
+		if !ok {
+			return nil
+		}
+		s = w.Unwrap()
+	}
+}
@@ -0,0 +1,208 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	DynatraceAPIEvents = "events"
+	DynatraceAPILogs   = "logs"
+
+	defaultDynatraceEventType = `{{ if eq .Type "Warning" }}CUSTOM_ALERT{{ else }}CUSTOM_INFO{{ end }}`
+	defaultDynatraceTitle     = "{{ .Reason }} on {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}"
+)
+
+// defaultDynatraceProperties are the properties of the events, and the attributes of the log records
+var defaultDynatraceProperties = map[string]string{
+	"message":   "{{ .Message }}",
+	"namespace": "{{ .InvolvedObject.Namespace }}",
+	"kind":      "{{ .InvolvedObject.Kind }}",
+	"name":      "{{ .InvolvedObject.Name }}",
+	"reason":    "{{ .Reason }}",
+	"component": "{{ .Source.Component }}",
+	"cluster":   "{{ .ClusterName }}",
+}
+
+// DynatraceConfig sends the events to the Events API v2 of a Dynatrace environment, or as log records to its Logs
+// API. The entity selector attaches the events to the monitored entities it selects, e.g. the Kubernetes workload.
+type DynatraceConfig struct {
+	// URL is the environment URL, e.g. https://abc12345.live.dynatrace.com
+	URL string `yaml:"url"`
+	// APIToken needs the events.ingest scope, or logs.ingest for the logs API
+	APIToken string `yaml:"apiToken"`
+	// API is events, the default, or logs
+	API string `yaml:"api,omitempty"`
+	// EventType is a template, CUSTOM_ALERT for the warnings and CUSTOM_INFO otherwise by default
+	EventType      string `yaml:"eventType,omitempty"`
+	Title          string `yaml:"title,omitempty"`
+	EntitySelector string `yaml:"entitySelector,omitempty"`
+	// Properties replace the default properties, the empty ones are left out
+	Properties map[string]string `yaml:"properties,omitempty"`
+	// TimeoutMinutes is how long the event stays open, the default of Dynatrace if zero
+	TimeoutMinutes int `yaml:"timeoutMinutes,omitempty"`
+	// Layout is the content of the log records, the event as JSON by default
+	Layout map[string]interface{} `yaml:"layout"`
+	TLS    TLS                    `yaml:"tls"`
+}
+
+func (c *DynatraceConfig) validate() error {
+	if c.API != "" && c.API != DynatraceAPIEvents && c.API != DynatraceAPILogs {
+		return fmt.Errorf("dynatrace.api must be %s or %s, got %q", DynatraceAPIEvents, DynatraceAPILogs, c.API)
+	}
+	if c.TimeoutMinutes < 0 {
+		return errors.New("dynatrace.timeoutMinutes must not be negative")
+	}
+	return nil
+}
+
+type Dynatrace struct {
+	cfg    *DynatraceConfig
+	client *http.Client
+}
+
+func NewDynatraceSink(cfg *DynatraceConfig) (Sink, error) {
+	if cfg.URL == "" || cfg.APIToken == "" {
+		return nil, errors.New("dynatrace.url and dynatrace.apiToken config options must be non-empty")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.API == "" {
+		cfg.API = DynatraceAPIEvents
+	}
+	if cfg.EventType == "" {
+		cfg.EventType = defaultDynatraceEventType
+	}
+	if cfg.Title == "" {
+		cfg.Title = defaultDynatraceTitle
+	}
+	if cfg.Properties == nil {
+		cfg.Properties = defaultDynatraceProperties
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Dynatrace{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (d *Dynatrace) properties(ev *kube.EnhancedEvent) (map[string]string, error) {
+	properties := make(map[string]string, len(d.cfg.Properties))
+	for _, key := range sortedKeys(d.cfg.Properties) {
+		value, err := GetString(ev, d.cfg.Properties[key])
+		if err != nil {
+			return nil, fmt.Errorf("cannot render property %s: %w", key, err)
+		}
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	return properties, nil
+}
+
+// eventPayload is the event of the Events API v2
+func (d *Dynatrace) eventPayload(ev *kube.EnhancedEvent) (interface{}, error) {
+	payload := map[string]interface{}{"startTime": ev.GetTimestampMs()}
+	for field, tmpl := range map[string]string{
+		"eventType":      d.cfg.EventType,
+		"title":          d.cfg.Title,
+		"entitySelector": d.cfg.EntitySelector,
+	} {
+		if tmpl == "" {
+			continue
+		}
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("cannot render %s: %w", field, err)
+		}
+		payload[field] = value
+	}
+	if d.cfg.TimeoutMinutes > 0 {
+		payload["timeout"] = d.cfg.TimeoutMinutes
+	}
+	properties, err := d.properties(ev)
+	if err != nil {
+		return nil, err
+	}
+	payload["properties"] = properties
+	return payload, nil
+}
+
+// logPayload is a log record of the Logs API, the properties are its attributes
+func (d *Dynatrace) logPayload(ctx context.Context, ev *kube.EnhancedEvent) (interface{}, error) {
+	content, err := serializeEventWithLayout(resolveLayout(ctx, d.cfg.Layout), ev)
+	if err != nil {
+		return nil, err
+	}
+	properties, err := d.properties(ev)
+	if err != nil {
+		return nil, err
+	}
+	record := map[string]interface{}{
+		"content":   string(content),
+		"timestamp": ev.GetTimestampRFC3339(),
+		"severity":  "info",
+	}
+	if ev.Type == "Warning" {
+		record["severity"] = "warn"
+	}
+	for key, value := range properties {
+		record[key] = value
+	}
+	return []interface{}{record}, nil
+}
+
+func (d *Dynatrace) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	path := "/api/v2/events/ingest"
+	var payload interface{}
+	var err error
+	if d.cfg.API == DynatraceAPILogs {
+		path = "/api/v2/logs/ingest"
+		payload, err = d.logPayload(ctx, ev)
+	} else {
+		payload, err = d.eventPayload(ev)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(d.cfg.URL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Api-Token "+d.cfg.APIToken)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (d *Dynatrace) Close() {
+	d.client.CloseIdleConnections()
+}
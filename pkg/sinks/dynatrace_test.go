@@ -0,0 +1,78 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestDynatrace_Send(t *testing.T) {
+	var path, auth string
+	var payload interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+
+	s, err := NewDynatraceSink(&DynatraceConfig{
+		URL:            server.URL + "/",
+		APIToken:       "dt0c01.token",
+		EntitySelector: `type(CLOUD_APPLICATION_NAMESPACE),entityName.equals("{{ .InvolvedObject.Namespace }}")`,
+		TimeoutMinutes: 30,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Send(context.Background(), ev))
+	assert.Equal(t, "/api/v2/events/ingest", path)
+	assert.Equal(t, "Api-Token dt0c01.token", auth)
+	assert.Equal(t, map[string]interface{}{
+		"eventType":      "CUSTOM_ALERT",
+		"title":          "BackOff on Pod shop/checkout-1",
+		"entitySelector": `type(CLOUD_APPLICATION_NAMESPACE),entityName.equals("shop")`,
+		"timeout":        float64(30),
+		"startTime":      float64(ev.GetTimestampMs()),
+		"properties": map[string]interface{}{
+			"message":   "Back-off restarting failed container",
+			"namespace": "shop",
+			"kind":      "Pod",
+			"name":      "checkout-1",
+			"reason":    "BackOff",
+		},
+	}, payload)
+
+	s, err = NewDynatraceSink(&DynatraceConfig{
+		URL:        server.URL,
+		APIToken:   "dt0c01.token",
+		API:        DynatraceAPILogs,
+		Properties: map[string]string{"k8s.namespace.name": "{{ .InvolvedObject.Namespace }}"},
+		Layout:     map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Send(context.Background(), ev))
+	assert.Equal(t, "/api/v2/logs/ingest", path)
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"content":            `{"reason":"BackOff"}`,
+		"timestamp":          ev.GetTimestampRFC3339(),
+		"severity":           "warn",
+		"k8s.namespace.name": "shop",
+	}}, payload)
+
+	_, err = NewDynatraceSink(&DynatraceConfig{URL: server.URL, APIToken: "dt0c01.token", API: "metrics"})
+	assert.Error(t, err)
+}
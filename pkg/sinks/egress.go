@@ -0,0 +1,243 @@
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// EgressPolicy restricts the destinations the sinks are allowed to connect to. A destination is allowed if its
+// hostname matches one of AllowedHosts (glob patterns like *.slack.com are supported) or the IP address being dialed is
+// within one of AllowedCIDRs.
+type EgressPolicy struct {
+	AllowedHosts []string `yaml:"allowedHosts"`
+	AllowedCIDRs []string `yaml:"allowedCIDRs"`
+
+	networks []*net.IPNet
+}
+
+// egressPolicy is the active policy, it is nil unless SetEgressPolicy is called
+var egressPolicy *EgressPolicy
+
+// Validate parses the CIDRs and checks the host patterns
+func (p *EgressPolicy) Validate() error {
+	p.networks = nil
+	for _, c := range p.AllowedCIDRs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+		p.networks = append(p.networks, n)
+	}
+	for _, h := range p.AllowedHosts {
+		if _, err := path.Match(h, ""); err != nil {
+			return fmt.Errorf("invalid host pattern %q: %w", h, err)
+		}
+	}
+	return nil
+}
+
+func (p *EgressPolicy) hostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.AllowedHosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *EgressPolicy) ipAllowed(ip net.IP) bool {
+	for _, n := range p.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed checks a destination before it is dialed. Hostnames that are not explicitly allowed may still pass if they
+// resolve to an allowed network, which is verified at dial time.
+func (p *EgressPolicy) allowed(host string) bool {
+	if p.hostAllowed(host) {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return p.ipAllowed(ip)
+	}
+	return len(p.networks) > 0
+}
+
+// CheckReceiver validates all the endpoints the receiver is configured to send to
+func (p *EgressPolicy) CheckReceiver(r *ReceiverConfig) error {
+	for _, endpoint := range r.endpoints() {
+		host := endpointHost(endpoint)
+		if host == "" || p.hostAllowed(host) {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			if p.ipAllowed(ip) {
+				continue
+			}
+		} else if len(p.networks) > 0 {
+			// The hostname might resolve to an allowed network, this can only be enforced at dial time
+			continue
+		}
+		return fmt.Errorf("receiver %q endpoint %q is not allowed by the egress policy", r.Name, host)
+	}
+	return nil
+}
+
+// endpointHost extracts the hostname from a URL or host:port string
+func endpointHost(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return ""
+		}
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return endpoint
+}
+
+// dialContext wraps the dialer so that every connection is checked against the policy
+func (p *EgressPolicy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if p.hostAllowed(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if !p.allowed(host) {
+			return nil, fmt.Errorf("egress to %s is not allowed by the egress policy", host)
+		}
+
+		// The hostname is not allowed explicitly, so the resolved address must be within the allowed networks
+		d := *dialer
+		d.Control = func(network, address string, _ syscall.RawConn) error {
+			ipHost, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(ipHost)
+			if ip == nil || !p.ipAllowed(ip) {
+				return fmt.Errorf("egress to %s (%s) is not allowed by the egress policy", host, ipHost)
+			}
+			return nil
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// proxy returns the proxy of the environment for the request. Through a proxy, the dialer only sees the address of the
+// proxy, so the destination of the request is checked here instead.
+func (p *EgressPolicy) proxy() func(*http.Request) (*url.URL, error) {
+	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxyFunc(req.URL)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+		if err := p.checkDestination(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		return proxyURL, nil
+	}
+}
+
+// checkDestination checks a destination that is not dialed directly, the hostnames that are not explicitly allowed must
+// resolve to the allowed networks
+func (p *EgressPolicy) checkDestination(ctx context.Context, host string) error {
+	if p.hostAllowed(host) {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if p.ipAllowed(ip) {
+			return nil
+		}
+	} else if len(p.networks) > 0 {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		allowed := err == nil && len(addrs) > 0
+		for _, addr := range addrs {
+			allowed = allowed && p.ipAllowed(addr.IP)
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("egress to %s is not allowed by the egress policy", host)
+}
+
+// Dial implements proxy.Dialer for the clients that do not support a context
+func (p *EgressPolicy) Dial(network, addr string) (net.Conn, error) {
+	return p.dialContext(newDialer())(context.Background(), network, addr)
+}
+
+// DialTimeout and DialContext complete the dialer interface of the PostgreSQL driver
+func (p *EgressPolicy) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	d := newDialer()
+	d.Timeout = timeout
+	return p.dialContext(d)(context.Background(), network, addr)
+}
+
+func (p *EgressPolicy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return p.dialContext(newDialer())(ctx, network, addr)
+}
+
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// SetEgressPolicy validates and activates the policy. It also applies to the default HTTP transport which is used by
+// the sinks relying on third-party SDKs. It must be called before the sinks are created.
+func SetEgressPolicy(p *EgressPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	egressPolicy = p
+
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		t.DialContext = p.dialContext(newDialer())
+		t.Proxy = p.proxy()
+	}
+	return nil
+}
+
+// newHTTPTransport creates the transport for the HTTP based sinks, honoring the egress policy
+func newHTTPTransport(tlsClientConfig *tls.Config) *http.Transport {
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsClientConfig,
+	}
+	if egressPolicy != nil {
+		transport.DialContext = egressPolicy.dialContext(newDialer())
+		transport.Proxy = egressPolicy.proxy()
+	}
+	return transport
+}
+
+// dialContext connects to the address, honoring the egress policy, for the sinks that open connections themselves. The
+// Unix sockets never leave the node, the policy does not apply to them.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if egressPolicy != nil && !strings.HasPrefix(network, "unix") {
+		return egressPolicy.DialContext(ctx, network, addr)
+	}
+	return newDialer().DialContext(ctx, network, addr)
+}
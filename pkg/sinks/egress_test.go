@@ -0,0 +1,68 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEgressPolicy_CheckReceiver(t *testing.T) {
+	p := &EgressPolicy{AllowedHosts: []string{"*.example.com"}, AllowedCIDRs: []string{"10.0.0.0/8"}}
+	require.NoError(t, p.Validate())
+
+	allowed := &ReceiverConfig{Name: "ok", Webhook: &WebhookConfig{Endpoint: "https://hooks.example.com/x"}}
+	assert.NoError(t, p.CheckReceiver(allowed))
+
+	inNetwork := &ReceiverConfig{Name: "ip", Kafka: &KafkaConfig{Brokers: []string{"10.1.2.3:9092"}}}
+	assert.NoError(t, p.CheckReceiver(inNetwork))
+
+	denied := &ReceiverConfig{Name: "bad", Webhook: &WebhookConfig{Endpoint: "http://192.168.1.1/hook"}}
+	assert.Error(t, p.CheckReceiver(denied))
+}
+
+func TestEgressPolicy_EnforcedAtDialTime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	denied := &EgressPolicy{AllowedCIDRs: []string{"10.0.0.0/8"}}
+	require.NoError(t, denied.Validate())
+	_, err := denied.dialContext(&net.Dialer{})(context.Background(), "tcp", ts.Listener.Addr().String())
+	assert.ErrorContains(t, err, "not allowed")
+
+	allowed := &EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8"}}
+	require.NoError(t, allowed.Validate())
+	conn, err := allowed.dialContext(&net.Dialer{})(context.Background(), "tcp", ts.Listener.Addr().String())
+	require.NoError(t, err)
+	_ = conn.Close()
+}
+
+func TestEgressPolicy_EnforcedThroughProxy(t *testing.T) {
+	var connects []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects = append(connects, r.Host)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTPS_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "")
+
+	p := &EgressPolicy{AllowedHosts: []string{"hooks.example.com", "127.0.0.1"}}
+	require.NoError(t, p.Validate())
+	client := &http.Client{Transport: &http.Transport{Proxy: p.proxy(), DialContext: p.dialContext(&net.Dialer{})}}
+
+	// The proxy is allowed, the destination is not
+	_, err := client.Get("https://attacker.example.org/")
+	assert.ErrorContains(t, err, "egress to attacker.example.org is not allowed")
+	assert.Empty(t, connects)
+
+	_, err = client.Get("https://hooks.example.com/")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"hooks.example.com:443"}, connects)
+}
@@ -58,9 +58,7 @@ func NewElasticsearch(cfg *ElasticsearchConfig) (*Elasticsearch, error) {
 		Header:    header,
 		CloudID:   cfg.CloudID,
 		APIKey:    cfg.APIKey,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsClientConfig,
-		},
+		Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
 	})
 	if err != nil {
 		return nil, err
@@ -98,14 +96,15 @@ func formatIndexName(pattern string, when time.Time) string {
 }
 
 func (e *Elasticsearch) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, e.cfg.Layout)
 	var toSend []byte
 
 	if e.cfg.DeDot {
 		de := ev.DeDot()
 		ev = &de
 	}
-	if e.cfg.Layout != nil {
-		res, err := convertLayoutTemplate(e.cfg.Layout, ev)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
 		if err != nil {
 			return err
 		}
@@ -151,6 +150,13 @@ func (e *Elasticsearch) Send(ctx context.Context, ev *kube.EnhancedEvent) error
 		if err != nil {
 			return err
 		}
+		// The cluster rejects requests with 429 when it is overloaded
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return &RetryableError{
+				Err:        fmt.Errorf("indexing throttled: %s", string(rb)),
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			}
+		}
 		log.Error().Msgf("Indexing failed: %s", string(rb))
 	}
 	return nil
@@ -1,3 +1,357 @@
 package sinks
 
-// Just kidding, please don't implement this
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultEmailSubject       = "[{{ .Type }}] {{ .Reason }} on {{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}"
+	defaultEmailBody          = "{{ .Message }}\n\nObject: {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}\nReason: {{ .Reason }}\nCount: {{ .Count }}\n"
+	defaultEmailDigestSubject = "{{ .Count }} Kubernetes events"
+	defaultEmailDigestBody    = "{{ range .Events }}[{{ .Type }}] {{ .Reason }} on {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}: {{ .Message }}\n{{ end }}"
+	defaultEmailDigestHTML    = "<ul>{{ range .Events }}<li><b>{{ .Reason }}</b> on {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}: {{ .Message }}</li>{{ end }}</ul>"
+	emailTimeout              = 30 * time.Second
+)
+
+// EmailConfig sends the events as emails over SMTP. Every event is sent as its own email, or with a digest all the
+// events of an interval are sent as one email per recipient list, so a burst of events does not flood the inboxes.
+type EmailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TLSMode is starttls by default, tls for implicit TLS like on port 465, or none for a local relay
+	TLSMode string `yaml:"tlsMode"`
+	From    string `yaml:"from"`
+	// To are templates, e.g. to mail the owners of a namespace
+	To      []string           `yaml:"to"`
+	Subject string             `yaml:"subject"`
+	Body    string             `yaml:"body"`
+	HTML    bool               `yaml:"html"`
+	Digest  *EmailDigestConfig `yaml:"digest"`
+	TLS     TLS                `yaml:"tls"`
+}
+
+// EmailDigestConfig collects the events and sends them at most every interval, or when MaxEvents are collected. The
+// subject and the body are rendered with .Events and .Count.
+type EmailDigestConfig struct {
+	IntervalSeconds int    `yaml:"intervalSeconds"`
+	MaxEvents       int    `yaml:"maxEvents"`
+	Subject         string `yaml:"subject"`
+	Body            string `yaml:"body"`
+}
+
+type emailDigest struct {
+	Events []*kube.EnhancedEvent
+	Count  int
+}
+
+type Email struct {
+	cfg         *EmailConfig
+	tlsConfig   *tls.Config
+	batchWriter *batch.Writer
+}
+
+func NewEmailSink(cfg *EmailConfig) (Sink, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("email.host config option must be non-empty")
+	}
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("email.from and email.to config options must be non-empty")
+	}
+	switch cfg.TLSMode {
+	case "":
+		cfg.TLSMode = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		return nil, fmt.Errorf("email.tlsMode must be starttls, tls or none, got %q", cfg.TLSMode)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLSMode == "tls" {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultEmailSubject
+	}
+	if cfg.Body == "" {
+		cfg.Body = defaultEmailBody
+	}
+
+	tlsConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = cfg.Host
+	}
+	e := &Email{cfg: cfg, tlsConfig: tlsConfig}
+
+	if d := cfg.Digest; d != nil {
+		if d.IntervalSeconds == 0 {
+			d.IntervalSeconds = 300
+		}
+		if d.MaxEvents == 0 {
+			d.MaxEvents = 100
+		}
+		if d.Subject == "" {
+			d.Subject = defaultEmailDigestSubject
+		}
+		if d.Body == "" {
+			d.Body = defaultEmailDigestBody
+			if cfg.HTML {
+				d.Body = defaultEmailDigestHTML
+			}
+		}
+		for _, text := range []string{d.Subject, d.Body} {
+			if _, err := template.New("digest").Funcs(templateFuncs()).Parse(text); err != nil {
+				return nil, fmt.Errorf("email.digest template is invalid: %w", err)
+			}
+		}
+		e.batchWriter = batch.NewWriter(
+			batch.WriterConfig{
+				BatchSize:  d.MaxEvents,
+				MaxRetries: 3,
+				Interval:   time.Duration(d.IntervalSeconds) * time.Second,
+				Timeout:    emailTimeout,
+			},
+			e.sendDigests,
+		)
+		e.batchWriter.Start()
+	}
+	return e, nil
+}
+
+func (e *Email) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if e.batchWriter != nil {
+		e.batchWriter.Submit(ev)
+		return nil
+	}
+
+	to, err := e.recipients(ev)
+	if err != nil {
+		return err
+	}
+	subject, err := GetString(ev, e.cfg.Subject)
+	if err != nil {
+		return err
+	}
+	body, err := GetString(ev, e.cfg.Body)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, to, subject, body)
+}
+
+// recipients renders the recipients of the event, they are sorted so the digests can be grouped by them
+func (e *Email) recipients(ev *kube.EnhancedEvent) ([]string, error) {
+	return renderRecipients(ev, e.cfg.To)
+}
+
+// renderRecipients renders the address templates, a rendered template may contain several addresses separated by
+// commas. The addresses are sorted.
+func renderRecipients(ev *kube.EnhancedEvent, templates []string) ([]string, error) {
+	var to []string
+	for _, tmpl := range templates {
+		rendered, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range strings.Split(rendered, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+	}
+	if len(to) == 0 {
+		return nil, errors.New("the event has no recipients")
+	}
+	sort.Strings(to)
+	return to, nil
+}
+
+// sendDigests sends one email per recipient list with the events of the batch
+func (e *Email) sendDigests(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	groups := make(map[string][]int)
+	var keys []string
+	for i, item := range items {
+		to, err := e.recipients(item.(*kube.EnhancedEvent))
+		if err != nil {
+			log.Error().Err(err).Msg("email: cannot render the recipients")
+			// The event is not retried, it would fail the same way
+			res[i] = true
+			continue
+		}
+		key := strings.Join(to, ",")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range keys {
+		digest := emailDigest{}
+		for _, i := range groups[key] {
+			digest.Events = append(digest.Events, items[i].(*kube.EnhancedEvent))
+		}
+		digest.Count = len(digest.Events)
+
+		err := func() error {
+			subject, err := renderTemplate(e.cfg.Digest.Subject, digest)
+			if err != nil {
+				return err
+			}
+			body, err := renderTemplate(e.cfg.Digest.Body, digest)
+			if err != nil {
+				return err
+			}
+			return e.send(ctx, strings.Split(key, ","), subject, body)
+		}()
+		if err != nil {
+			log.Error().Err(err).Int("events", digest.Count).Msg("email: cannot send the digest")
+			continue
+		}
+		for _, i := range groups[key] {
+			res[i] = true
+		}
+	}
+	return res
+}
+
+func renderTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("template").Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// message builds the MIME message, the subject is encoded and the body quoted-printable
+func (e *Email) message(to []string, subject, body string) ([]byte, error) {
+	contentType := "text/plain"
+	if e.cfg.HTML {
+		contentType = "text/html"
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), e.cfg.Host)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Email) send(ctx context.Context, to []string, subject, body string) error {
+	msg, err := e.message(to, subject, body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, emailTimeout)
+	defer cancel()
+	conn, err := dialContext(ctx, "tcp", net.JoinHostPort(e.cfg.Host, strconv.Itoa(e.cfg.Port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if e.cfg.TLSMode == "tls" {
+		conn = tls.Client(conn, e.tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.cfg.TLSMode == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server does not support STARTTLS, set tlsMode to none to send without TLS")
+		}
+		if err := c.StartTLS(e.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(addressOf(e.cfg.From)); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addressOf(addr)); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// addressOf returns the address of "Name <address>"
+func addressOf(s string) string {
+	if start, end := strings.LastIndex(s, "<"), strings.LastIndex(s, ">"); start >= 0 && end > start {
+		return s[start+1 : end]
+	}
+	return s
+}
+
+func (e *Email) Close() {
+	if e.batchWriter != nil {
+		e.batchWriter.Stop()
+	}
+}
@@ -0,0 +1,205 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// fakeSMTPServer accepts every message without TLS and authentication
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTPServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = smtpMessage{from: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) received() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func parseMail(t *testing.T, data string) (*mail.Message, string) {
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	require.NoError(t, err)
+	var body strings.Builder
+	_, err = bufio.NewReader(quotedprintable.NewReader(m.Body)).WriteTo(&body)
+	require.NoError(t, err)
+	return m, body.String()
+}
+
+func TestEmail_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sink, err := NewEmailSink(&EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		TLSMode: "none",
+		From:    "Event Exporter <exporter@example.com>",
+		To:      []string{"oncall@example.com", "{{ .InvolvedObject.Namespace }}@example.com"},
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container – again"
+	ev.Count = 3
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "team-a"
+	ev.InvolvedObject.Name = "api-0"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "exporter@example.com", messages[0].from)
+	assert.Equal(t, []string{"oncall@example.com", "team-a@example.com"}, messages[0].to)
+
+	m, body := parseMail(t, messages[0].data)
+	assert.Equal(t, "[Warning] BackOff on Pod/api-0", m.Header.Get("Subject"))
+	assert.Equal(t, "text/plain; charset=utf-8", m.Header.Get("Content-Type"))
+	assert.Contains(t, body, "Back-off restarting failed container – again")
+	assert.Contains(t, body, "Count: 3")
+}
+
+func TestEmail_Digest(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sink, err := NewEmailSink(&EmailConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		TLSMode: "none",
+		From:    "exporter@example.com",
+		To:      []string{"{{ .InvolvedObject.Namespace }}@example.com"},
+		HTML:    true,
+		Digest:  &EmailDigestConfig{IntervalSeconds: 1, MaxEvents: 10},
+	})
+	require.NoError(t, err)
+
+	for i, ns := range []string{"team-a", "team-b", "team-a"} {
+		ev := &kube.EnhancedEvent{}
+		ev.Reason = "BackOff"
+		ev.InvolvedObject.Namespace = ns
+		ev.InvolvedObject.Name = "api-" + strconv.Itoa(i)
+		require.NoError(t, sink.Send(context.Background(), ev))
+	}
+	require.Eventually(t, func() bool { return len(server.received()) == 2 }, 5*time.Second, 50*time.Millisecond)
+	sink.Close()
+
+	messages := server.received()
+	var teamA smtpMessage
+	for _, m := range messages {
+		if m.to[0] == "team-a@example.com" {
+			teamA = m
+		}
+	}
+	m, body := parseMail(t, teamA.data)
+	assert.Equal(t, "2 Kubernetes events", m.Header.Get("Subject"))
+	assert.Equal(t, "text/html; charset=utf-8", m.Header.Get("Content-Type"))
+	assert.Contains(t, body, "team-a/api-0")
+	assert.Contains(t, body, "team-a/api-2")
+	assert.NotContains(t, body, "team-b")
+}
+
+func TestEmail_RequiresStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sink, err := NewEmailSink(&EmailConfig{
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "exporter@example.com",
+		To:   []string{"oncall@example.com"},
+	})
+	require.NoError(t, err)
+
+	err = sink.Send(context.Background(), &kube.EnhancedEvent{})
+	assert.ErrorContains(t, err, "does not support STARTTLS")
+	assert.Empty(t, server.received())
+}
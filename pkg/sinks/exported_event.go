@@ -0,0 +1,216 @@
+package sinks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	exportedEventGroup     = "kubernetes-event-exporter.giantswarm.io"
+	exportedEventVersion   = "v1alpha1"
+	exportedEventKind      = "ExportedEvent"
+	exportedEventManagedBy = "app.kubernetes.io/managed-by=kubernetes-event-exporter"
+	// exportedEventExpiresAt is the annotation with the time the resource is garbage collected
+	exportedEventExpiresAt = exportedEventGroup + "/expires-at"
+)
+
+var (
+	exportedEventResource = schema.GroupVersionResource{Group: exportedEventGroup, Version: exportedEventVersion, Resource: "exportedevents"}
+	kubeObjectNameUnsafe  = regexp.MustCompile(`[^a-z0-9.\-]+`)
+)
+
+// ExportedEventConfig persists the events as ExportedEvent custom resources, so other controllers and kubectl users
+// can consume the filtered and enriched events in the cluster. An event updates its resource when it is seen again.
+// The resources are deleted once their TTL expired, by a garbage collection running in every exporter.
+type ExportedEventConfig struct {
+	// Namespace is where the resources are created, the namespace of the event by default, or the default namespace
+	// for the cluster-scoped events
+	Namespace string `yaml:"namespace,omitempty"`
+	// Labels are templates, set on the resources so they can be selected, the empty ones are left out
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Layout is the spec of the resources, the event as JSON by default
+	Layout map[string]interface{} `yaml:"layout"`
+	// TTLSeconds is how long a resource is kept after the event was last seen, a day by default
+	TTLSeconds int `yaml:"ttlSeconds,omitempty"`
+	// GCIntervalSeconds is how often the expired resources are deleted, 5 minutes by default
+	GCIntervalSeconds int `yaml:"gcIntervalSeconds,omitempty"`
+}
+
+type ExportedEvent struct {
+	cfg    *ExportedEventConfig
+	client dynamic.Interface
+	ttl    time.Duration
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewExportedEventSink(cfg *ExportedEventConfig) (*ExportedEvent, error) {
+	k8sConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	client, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return newExportedEventSink(cfg, client)
+}
+
+func newExportedEventSink(cfg *ExportedEventConfig, client dynamic.Interface) (*ExportedEvent, error) {
+	if cfg.TTLSeconds < 0 || cfg.GCIntervalSeconds < 0 {
+		return nil, errors.New("exportedEvent.ttlSeconds and exportedEvent.gcIntervalSeconds must not be negative")
+	}
+	if cfg.TTLSeconds == 0 {
+		cfg.TTLSeconds = 24 * 60 * 60
+	}
+	if cfg.GCIntervalSeconds == 0 {
+		cfg.GCIntervalSeconds = 5 * 60
+	}
+	e := &ExportedEvent{
+		cfg:    cfg,
+		client: client,
+		ttl:    time.Duration(cfg.TTLSeconds) * time.Second,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.runGC(time.Duration(cfg.GCIntervalSeconds) * time.Second)
+	return e, nil
+}
+
+// kubeObjectName derives a valid object name, e.g. from the name of an event. A name too long is shortened and
+// suffixed with its hash.
+func kubeObjectName(name string) string {
+	valid := strings.Trim(kubeObjectNameUnsafe.ReplaceAllString(strings.ToLower(name), "-"), ".-")
+	if valid != "" && len(valid) <= 253 {
+		return valid
+	}
+	sum := sha256.Sum256([]byte(name))
+	if valid = strings.Trim(valid[:min(len(valid), 236)], ".-"); valid != "" {
+		valid += "-"
+	}
+	return valid + hex.EncodeToString(sum[:])[:16]
+}
+
+func (e *ExportedEvent) object(ctx context.Context, ev *kube.EnhancedEvent) (*unstructured.Unstructured, error) {
+	data, err := serializeEventWithLayout(resolveLayout(ctx, e.cfg.Layout), ev)
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("the layout of the spec must be an object: %w", err)
+	}
+
+	namespace := e.cfg.Namespace
+	if namespace == "" {
+		namespace = ev.Namespace
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	labels := map[string]string{"app.kubernetes.io/managed-by": "kubernetes-event-exporter"}
+	for _, key := range sortedKeys(e.cfg.Labels) {
+		value, err := GetString(ev, e.cfg.Labels[key])
+		if err != nil {
+			return nil, fmt.Errorf("cannot render label %s: %w", key, err)
+		}
+		if value != "" {
+			labels[key] = value
+		}
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(exportedEventGroup + "/" + exportedEventVersion)
+	obj.SetKind(exportedEventKind)
+	obj.SetNamespace(namespace)
+	// The name of the event is unique in its namespace
+	obj.SetName(kubeObjectName(ev.Name))
+	obj.SetLabels(labels)
+	obj.SetAnnotations(map[string]string{exportedEventExpiresAt: clock.Now().Add(e.ttl).UTC().Format(time.RFC3339)})
+	return obj, nil
+}
+
+func (e *ExportedEvent) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	obj, err := e.object(ctx, ev)
+	if err != nil {
+		return err
+	}
+	resource := e.client.Resource(exportedEventResource).Namespace(obj.GetNamespace())
+	_, err = resource.Create(ctx, obj, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// The event was seen again, its resource is updated and expires later
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = resource.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+func (e *ExportedEvent) runGC(interval time.Duration) {
+	defer close(e.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			if err := e.collect(context.Background()); err != nil {
+				log.Error().Err(err).Msg("exportedEvent: garbage collection failed")
+			}
+		}
+	}
+}
+
+// collect deletes the expired resources, in the configured namespace or in all of them
+func (e *ExportedEvent) collect(ctx context.Context) error {
+	list, err := e.client.Resource(exportedEventResource).Namespace(e.cfg.Namespace).List(ctx, metav1.ListOptions{LabelSelector: exportedEventManagedBy})
+	if err != nil {
+		return err
+	}
+	now := clock.Now()
+	for i := range list.Items {
+		item := &list.Items[i]
+		expiresAt, err := time.Parse(time.RFC3339, item.GetAnnotations()[exportedEventExpiresAt])
+		if err != nil || expiresAt.After(now) {
+			continue
+		}
+		err = e.client.Resource(exportedEventResource).Namespace(item.GetNamespace()).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ExportedEvent) Close() {
+	e.once.Do(func() {
+		close(e.stop)
+		<-e.done
+	})
+}
@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func newFakeExportedEventClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{exportedEventResource: "ExportedEventList"}, objects...)
+}
+
+func TestExportedEvent_Send(t *testing.T) {
+	client := newFakeExportedEventClient()
+	s, err := newExportedEventSink(&ExportedEventConfig{
+		Labels: map[string]string{"reason": "{{ .Reason }}", "team": `{{ index .InvolvedObject.Labels "team" }}`},
+		Layout: map[string]interface{}{"reason": "{{ .Reason }}", "count": "{{ .Count }}"},
+	}, client)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ev := &kube.EnhancedEvent{}
+	ev.Name = "checkout-1.17a8b2c3d4e5f6a7"
+	ev.Namespace = "shop"
+	ev.Reason = "BackOff"
+	ev.Count = 1
+	require.NoError(t, s.Send(context.Background(), ev))
+	// The same event seen again updates its resource
+	ev.Count = 2
+	require.NoError(t, s.Send(context.Background(), ev))
+
+	list, err := client.Resource(exportedEventResource).Namespace("shop").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	obj := list.Items[0]
+	assert.Equal(t, "ExportedEvent", obj.GetKind())
+	assert.Equal(t, "checkout-1.17a8b2c3d4e5f6a7", obj.GetName())
+	assert.Equal(t, map[string]string{"app.kubernetes.io/managed-by": "kubernetes-event-exporter", "reason": "BackOff"}, obj.GetLabels())
+	assert.Equal(t, map[string]interface{}{"reason": "BackOff", "count": "2"}, obj.Object["spec"])
+	expiresAt, err := time.Parse(time.RFC3339, obj.GetAnnotations()[exportedEventExpiresAt])
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, time.Minute)
+
+	// The cluster-scoped events go to the default namespace
+	ev = &kube.EnhancedEvent{}
+	ev.Name = "node-1.17a8b2c3d4e5f6a8"
+	require.NoError(t, s.Send(context.Background(), ev))
+	_, err = client.Resource(exportedEventResource).Namespace("default").Get(context.Background(), ev.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestExportedEvent_Collect(t *testing.T) {
+	exported := func(name, expiresAt string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(exportedEventGroup + "/" + exportedEventVersion)
+		obj.SetKind(exportedEventKind)
+		obj.SetNamespace("shop")
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "kubernetes-event-exporter"})
+		obj.SetAnnotations(map[string]string{exportedEventExpiresAt: expiresAt})
+		return obj
+	}
+	client := newFakeExportedEventClient(
+		exported("expired", time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)),
+		exported("current", time.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+	)
+	s, err := newExportedEventSink(&ExportedEventConfig{}, client)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.collect(context.Background()))
+	list, err := client.Resource(exportedEventResource).Namespace("shop").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, "current", list.Items[0].GetName())
+}
+
+func TestKubeObjectName(t *testing.T) {
+	assert.Equal(t, "falco-alert.17a8", kubeObjectName("Falco_Alert.17a8"))
+
+	name := kubeObjectName(strings.Repeat("a", 300))
+	assert.Len(t, name, 253)
+	assert.True(t, strings.HasPrefix(name, strings.Repeat("a", 236)+"-"))
+}
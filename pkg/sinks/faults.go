@@ -0,0 +1,138 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// FaultConfig injects failures into the receiver, to rehearse sink outages and check the retries and the alerting on
+// failed deliveries. It only takes effect when the exporter runs with -fault-injection.
+type FaultConfig struct {
+	// ErrorRate is the fraction of the events that fail, between 0 and 1
+	ErrorRate float64 `yaml:"errorRate"`
+	// Retryable makes the injected errors retryable, like the throttling responses of a provider
+	Retryable bool `yaml:"retryable"`
+	// Latency delays every event, e.g. 500ms, Jitter adds a random delay up to its value
+	Latency string `yaml:"latency"`
+	Jitter  string `yaml:"jitter"`
+	// TimeoutRate is the fraction of the events that hang for Timeout, 30s by default, or until the send is cancelled
+	TimeoutRate float64 `yaml:"timeoutRate"`
+	Timeout     string  `yaml:"timeout"`
+}
+
+// ErrInjectedFault is the error of the events failed by the fault injection
+var ErrInjectedFault = errors.New("injected fault")
+
+// faultInjection is off unless EnableFaultInjection is called
+var faultInjection bool
+
+// EnableFaultInjection activates the faults configured on the receivers, it must be called before the sinks are
+// created
+func EnableFaultInjection() {
+	faultInjection = true
+}
+
+func (c *FaultConfig) validate() error {
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return fmt.Errorf("faults.errorRate must be between 0 and 1, got %v", c.ErrorRate)
+	}
+	if c.TimeoutRate < 0 || c.TimeoutRate > 1 {
+		return fmt.Errorf("faults.timeoutRate must be between 0 and 1, got %v", c.TimeoutRate)
+	}
+	for name, value := range map[string]string{"latency": c.Latency, "jitter": c.Jitter, "timeout": c.Timeout} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("faults.%s must be a non-negative duration, got %q", name, value)
+		}
+	}
+	return nil
+}
+
+// faultSink fails and delays the events of the wrapped sink before they reach it
+type faultSink struct {
+	Sink
+	cfg     *FaultConfig
+	latency time.Duration
+	jitter  time.Duration
+	timeout time.Duration
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newFaultSink(s Sink, cfg *FaultConfig) (Sink, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	f := &faultSink{Sink: s, cfg: cfg, timeout: 30 * time.Second, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	// The durations are validated
+	if cfg.Latency != "" {
+		f.latency, _ = time.ParseDuration(cfg.Latency)
+	}
+	if cfg.Jitter != "" {
+		f.jitter, _ = time.ParseDuration(cfg.Jitter)
+	}
+	if cfg.Timeout != "" {
+		f.timeout, _ = time.ParseDuration(cfg.Timeout)
+	}
+	return f, nil
+}
+
+func (f *faultSink) Unwrap() Sink {
+	return f.Sink
+}
+
+func (f *faultSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	f.mu.Lock()
+	delay := f.latency
+	if f.jitter > 0 {
+		delay += time.Duration(f.rand.Int63n(int64(f.jitter)))
+	}
+	timeout := f.rand.Float64() < f.cfg.TimeoutRate
+	fail := f.rand.Float64() < f.cfg.ErrorRate
+	f.mu.Unlock()
+
+	if timeout {
+		delay = f.timeout
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		return err
+	}
+	if timeout {
+		log.Debug().Str("sink", SinkType(f.Sink)).Msg("Injected a timeout")
+		return fmt.Errorf("%w: timeout after %s", ErrInjectedFault, delay)
+	}
+	if fail {
+		log.Debug().Str("sink", SinkType(f.Sink)).Msg("Injected an error")
+		if f.cfg.Retryable {
+			return &RetryableError{Err: ErrInjectedFault}
+		}
+		return ErrInjectedFault
+	}
+	return f.Sink.Send(ctx, ev)
+}
+
+// sleepContext waits for the duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
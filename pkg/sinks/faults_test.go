@@ -0,0 +1,50 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestFaultSink(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	mem := &InMemory{Config: &InMemoryConfig{}}
+
+	s, err := newFaultSink(mem, &FaultConfig{ErrorRate: 1, Retryable: true})
+	require.NoError(t, err)
+	err = s.Send(context.Background(), ev)
+	var retryable *RetryableError
+	assert.ErrorAs(t, err, &retryable)
+	assert.ErrorIs(t, err, ErrInjectedFault)
+	assert.Empty(t, mem.Events)
+	assert.Equal(t, "*sinks.InMemory", SinkType(s))
+
+	s, err = newFaultSink(mem, &FaultConfig{Latency: "20ms"})
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, s.Send(context.Background(), ev))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.Len(t, mem.Events, 1)
+
+	// A timeout hangs until the send is cancelled
+	s, err = newFaultSink(mem, &FaultConfig{TimeoutRate: 1, Timeout: "1h"})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(s.Send(ctx, ev), context.DeadlineExceeded))
+	assert.Len(t, mem.Events, 1)
+}
+
+func TestFaultConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&FaultConfig{ErrorRate: 0.5, Latency: "1s", Jitter: "100ms"}).validate())
+	assert.Error(t, (&FaultConfig{ErrorRate: 1.5}).validate())
+	assert.Error(t, (&FaultConfig{TimeoutRate: -1}).validate())
+	assert.Error(t, (&FaultConfig{Latency: "soon"}).validate())
+	assert.Error(t, (&FaultConfig{Timeout: "-1s"}).validate())
+}
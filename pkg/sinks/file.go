@@ -4,9 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/rs/zerolog/log"
 	"gopkg.in/natefinch/lumberjack.v2"
 
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
@@ -17,10 +25,12 @@ type FileConfig struct {
 	MaxAge     int                    `yaml:"maxage"`
 	MaxBackups int                    `yaml:"maxbackups"`
 	DeDot      bool                   `yaml:"deDot"`
+	// Compression of the rotated files, gzip by default. The active file is appended to and never compressed.
+	Compression string `yaml:"compression"`
 }
 
 func (f *FileConfig) Validate() error {
-	return nil
+	return ValidateCompression(f.Compression)
 }
 
 type File struct {
@@ -31,11 +41,22 @@ type File struct {
 }
 
 func NewFileSink(config *FileConfig) (*File, error) {
-	writer := &lumberjack.Logger{
-		Filename:   config.Path,
-		MaxSize:    config.MaxSize,
-		MaxBackups: config.MaxBackups,
-		MaxAge:     config.MaxAge,
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var writer io.WriteCloser
+	switch config.Compression {
+	case CompressionZstd, CompressionSnappy:
+		writer = newCompressingFile(config)
+	default:
+		writer = &lumberjack.Logger{
+			Filename:   config.Path,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compression != CompressionNone,
+		}
 	}
 
 	return &File{
@@ -51,18 +72,191 @@ func (f *File) Close() {
 }
 
 func (f *File) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, f.layout)
 	if f.DeDot {
 		de := ev.DeDot()
 		ev = &de
 	}
-	if f.layout == nil {
+	if layout == nil {
 		return f.encoder.Encode(ev)
 	}
 
-	res, err := convertLayoutTemplate(f.layout, ev)
+	res, err := convertLayoutTemplate(layout, ev)
 	if err != nil {
 		return err
 	}
 
 	return f.encoder.Encode(res)
 }
+
+// fileBackupTimeFormat is the timestamp lumberjack adds to the names of the rotated files
+const fileBackupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressingFile rotates the file with lumberjack and compresses the rotated files itself, as lumberjack only
+// supports gzip. It also removes the old rotated files, lumberjack doesn't recognize the compressed ones as its own.
+type compressingFile struct {
+	logger     *lumberjack.Logger
+	codec      string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+
+	mu     sync.Mutex
+	size   int64
+	closed bool
+	mill   chan struct{}
+	done   chan struct{}
+}
+
+func newCompressingFile(config *FileConfig) *compressingFile {
+	maxSize := int64(config.MaxSize) * 1024 * 1024
+	if maxSize == 0 {
+		// The default of lumberjack
+		maxSize = 100 * 1024 * 1024
+	}
+	f := &compressingFile{
+		logger:     &lumberjack.Logger{Filename: config.Path, MaxSize: config.MaxSize},
+		codec:      config.Compression,
+		maxSize:    maxSize,
+		maxBackups: config.MaxBackups,
+		maxAge:     time.Duration(config.MaxAge) * 24 * time.Hour,
+		mill:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if info, err := os.Stat(config.Path); err == nil {
+		f.size = info.Size()
+	}
+	go f.run()
+	// Compresses the files rotated before a restart
+	f.mill <- struct{}{}
+	return f
+}
+
+// Write rotates the file before lumberjack would, so the rotated file is compressed right away
+func (f *compressingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.logger.Rotate(); err != nil {
+			return 0, err
+		}
+		f.size = 0
+		if !f.closed {
+			select {
+			case f.mill <- struct{}{}:
+			default:
+			}
+		}
+	}
+	n, err := f.logger.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close waits for the rotated files to be compressed
+func (f *compressingFile) Close() error {
+	f.mu.Lock()
+	err := f.logger.Close()
+	if !f.closed {
+		f.closed = true
+		close(f.mill)
+	}
+	f.mu.Unlock()
+	<-f.done
+	return err
+}
+
+func (f *compressingFile) run() {
+	defer close(f.done)
+	for range f.mill {
+		if err := f.compressBackups(); err != nil {
+			log.Error().Err(err).Str("path", f.logger.Filename).Msg("file: compressing the rotated files failed")
+		}
+	}
+}
+
+// compressBackups compresses the rotated files and removes the compressed ones beyond maxBackups or older than maxAge
+func (f *compressingFile) compressBackups() error {
+	dir := filepath.Dir(f.logger.Filename)
+	ext := filepath.Ext(f.logger.Filename)
+	prefix := strings.TrimSuffix(filepath.Base(f.logger.Filename), ext) + "-"
+	suffix := ext + CompressionExtension(f.codec)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		name    string
+		rotated time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		compressed := strings.HasSuffix(name, suffix)
+		stamp := strings.TrimPrefix(name, prefix)
+		if compressed {
+			stamp = strings.TrimSuffix(stamp, suffix)
+		} else if !strings.HasSuffix(name, ext) {
+			continue
+		} else {
+			stamp = strings.TrimSuffix(stamp, ext)
+		}
+		rotated, err := time.Parse(fileBackupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		if !compressed {
+			if err := compressFile(f.codec, filepath.Join(dir, name)); err != nil {
+				return err
+			}
+			name += CompressionExtension(f.codec)
+		}
+		backups = append(backups, backup{name: name, rotated: rotated})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].rotated.After(backups[j].rotated) })
+	for i, b := range backups {
+		if (f.maxBackups > 0 && i >= f.maxBackups) || (f.maxAge > 0 && clock.Since(b.rotated) > f.maxAge) {
+			if err := os.Remove(filepath.Join(dir, b.name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressFile replaces the file with its compressed copy
+func compressFile(codec, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+CompressionExtension(codec), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	w, err := NewCompressWriter(codec, dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -39,6 +39,7 @@ func NewFirehoseSink(cfg *FirehoseConfig) (Sink, error) {
 }
 
 func (f *FirehoseSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, f.cfg.Layout)
 	var toSend []byte
 
 	if f.cfg.DeDot {
@@ -46,8 +47,8 @@ func (f *FirehoseSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		ev = &de
 	}
 
-	if f.cfg.Layout != nil {
-		res, err := convertLayoutTemplate(f.cfg.Layout, ev)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
 		if err != nil {
 			return err
 		}
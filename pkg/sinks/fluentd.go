@@ -0,0 +1,273 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const defaultFluentdTag = "kubernetes.events"
+
+// FluentdConfig sends the events in batches to a Fluentd or Fluent Bit forward input. With RequireAck, a batch is only
+// done once the server acknowledged it, otherwise it is retried. Sending blocks while the server is slow, so the events
+// queue up in the exporter instead of being dropped.
+type FluentdConfig struct {
+	// Address is the host:port of the forward input
+	Address string `yaml:"address"`
+	// Protocol is tcp (default) or tls
+	Protocol string `yaml:"protocol"`
+	// Tag is a template, kubernetes.events by default
+	Tag        string                 `yaml:"tag"`
+	Layout     map[string]interface{} `yaml:"layout"`
+	RequireAck bool                   `yaml:"requireAck"`
+	// SharedKey enables the authentication of the forward protocol, the server and the exporter must use the same key
+	SharedKey string `yaml:"sharedKey" secret:"true"`
+	// SelfHostname is sent during the authentication, the hostname of the exporter by default
+	SelfHostname string `yaml:"selfHostname"`
+	// Username and Password are used if the server requires user authentication
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	TLS      TLS    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+type Fluentd struct {
+	cfg         *FluentdConfig
+	writer      *connWriter
+	batchWriter *batch.Writer
+}
+
+type fluentdEntry struct {
+	tag    string
+	time   time.Time
+	record map[string]interface{}
+}
+
+func NewFluentdSink(cfg *FluentdConfig) (*Fluentd, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("fluentd.address config option must be non-empty")
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = defaultFluentdTag
+	}
+	if cfg.SelfHostname == "" {
+		cfg.SelfHostname, _ = os.Hostname()
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	f := &Fluentd{cfg: cfg}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	switch cfg.Protocol {
+	case "", "tcp":
+		f.writer = newConnWriter("tcp", cfg.Address, nil, timeout)
+	case "tls":
+		tlsClientConfig, err := setupTLS(&cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		f.writer = newConnWriter("tcp", cfg.Address, tlsClientConfig, timeout)
+	default:
+		return nil, fmt.Errorf("fluentd.protocol must be tcp or tls, got %q", cfg.Protocol)
+	}
+	if cfg.SharedKey != "" {
+		f.writer.handshake = f.handshake
+	}
+
+	f.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    timeout,
+		},
+		f.write,
+	)
+	f.batchWriter.Start()
+	return f, nil
+}
+
+func (f *Fluentd) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	tag, err := GetString(ev, f.cfg.Tag)
+	if err != nil {
+		return err
+	}
+	body, err := serializeEventWithLayout(resolveLayout(ctx, f.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+	// The record is encoded as msgpack, so the JSON is decoded to generic values first
+	var record map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return err
+	}
+	f.batchWriter.Submit(&fluentdEntry{tag: tag, time: time.UnixMilli(ev.GetTimestampMs()), record: record})
+	return nil
+}
+
+func (f *Fluentd) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	// The forward mode sends the entries of one tag per message
+	var tags []string
+	groups := make(map[string][]int)
+	for i, item := range items {
+		tag := item.(*fluentdEntry).tag
+		if _, ok := groups[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		groups[tag] = append(groups[tag], i)
+	}
+
+	for _, tag := range tags {
+		entries := make([]interface{}, 0, len(groups[tag]))
+		for _, i := range groups[tag] {
+			entry := items[i].(*fluentdEntry)
+			entries = append(entries, []interface{}{msgpackEventTime(entry.time), entry.record})
+		}
+		if err := f.forward(ctx, tag, entries); err != nil {
+			log.Error().Err(err).Str("tag", tag).Int("events", len(entries)).Msg("fluentd: forward failed")
+			continue
+		}
+		for _, i := range groups[tag] {
+			res[i] = true
+		}
+	}
+	return res
+}
+
+// forward sends the entries in the forward mode and waits for the acknowledgement if required
+func (f *Fluentd) forward(ctx context.Context, tag string, entries []interface{}) error {
+	option := map[string]interface{}{"size": len(entries)}
+	var chunk string
+	if f.cfg.RequireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+	message, err := msgpackMarshal([]interface{}{tag, entries, option})
+	if err != nil {
+		return err
+	}
+
+	return f.writer.Do(ctx, func(conn net.Conn) error {
+		if _, err := conn.Write(message); err != nil {
+			return err
+		}
+		if chunk == "" {
+			return nil
+		}
+		response, err := msgpackDecode(conn)
+		if err != nil {
+			return fmt.Errorf("reading the ack: %w", err)
+		}
+		ack, _ := response.(map[string]interface{})
+		if ack == nil || msgpackString(ack["ack"]) != chunk {
+			return fmt.Errorf("unexpected ack %v for chunk %s", response, chunk)
+		}
+		return nil
+	})
+}
+
+// handshake authenticates the connection with the shared key, following the HELO, PING and PONG exchange of the
+// forward protocol
+func (f *Fluentd) handshake(conn net.Conn) error {
+	helo, err := msgpackDecode(conn)
+	if err != nil {
+		return fmt.Errorf("reading HELO: %w", err)
+	}
+	heloMessage, _ := helo.([]interface{})
+	if len(heloMessage) < 2 || msgpackString(heloMessage[0]) != "HELO" {
+		return fmt.Errorf("expected HELO, got %v", helo)
+	}
+	options, _ := heloMessage[1].(map[string]interface{})
+	nonce := msgpackString(options["nonce"])
+	authSalt := msgpackString(options["auth"])
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	sharedKeySalt := hex.EncodeToString(salt)
+	passwordDigest := ""
+	if authSalt != "" {
+		passwordDigest = sha512Hex(authSalt, f.cfg.Username, f.cfg.Password)
+	}
+	ping, err := msgpackMarshal([]interface{}{
+		"PING",
+		f.cfg.SelfHostname,
+		sharedKeySalt,
+		sha512Hex(sharedKeySalt, f.cfg.SelfHostname, nonce, f.cfg.SharedKey),
+		f.cfg.Username,
+		passwordDigest,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(ping); err != nil {
+		return err
+	}
+
+	pong, err := msgpackDecode(conn)
+	if err != nil {
+		return fmt.Errorf("reading PONG: %w", err)
+	}
+	pongMessage, _ := pong.([]interface{})
+	if len(pongMessage) < 5 || msgpackString(pongMessage[0]) != "PONG" {
+		return fmt.Errorf("expected PONG, got %v", pong)
+	}
+	if ok, _ := pongMessage[1].(bool); !ok {
+		return fmt.Errorf("authentication failed: %s", msgpackString(pongMessage[2]))
+	}
+	serverHostname := msgpackString(pongMessage[3])
+	if msgpackString(pongMessage[4]) != sha512Hex(sharedKeySalt, serverHostname, nonce, f.cfg.SharedKey) {
+		return errors.New("the server does not know the shared key")
+	}
+	return nil
+}
+
+func sha512Hex(parts ...string) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (f *Fluentd) Close() {
+	f.batchWriter.Stop()
+	f.writer.Close()
+}
@@ -0,0 +1,129 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpack_RoundTrip(t *testing.T) {
+	value := map[string]interface{}{
+		"string": "x",
+		"long":   string(make([]byte, 300)),
+		"ints":   []interface{}{int64(0), int64(-5), int64(200), int64(-70000), int64(1) << 40},
+		"float":  1.5,
+		"bool":   true,
+		"nil":    nil,
+		"nested": map[string]interface{}{"a": []interface{}{"b"}},
+	}
+	b, err := msgpackMarshal(value)
+	require.NoError(t, err)
+	r := &sliceReader{b: b}
+	decoded, err := msgpackDecode(r)
+	require.NoError(t, err)
+	assert.Equal(t, value, decoded)
+	assert.Empty(t, r.b)
+
+	_, err = msgpackMarshal(struct{}{})
+	assert.Error(t, err)
+}
+
+type sliceReader struct {
+	b []byte
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
+
+// fluentdServer is a forward input with shared key authentication that acknowledges the chunks
+func fluentdServer(t *testing.T, listener net.Listener, sharedKey string, messages chan<- []interface{}) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	nonce := "server-nonce"
+	helo, _ := msgpackMarshal([]interface{}{"HELO", map[string]interface{}{"nonce": nonce, "auth": "", "keepalive": true}})
+	_, _ = conn.Write(helo)
+	ping, err := msgpackDecode(conn)
+	require.NoError(t, err)
+	p := ping.([]interface{})
+	require.Equal(t, "PING", p[0])
+	salt := msgpackString(p[2])
+	authenticated := p[3] == sha512Hex(salt, msgpackString(p[1]), nonce, sharedKey)
+	pong, _ := msgpackMarshal([]interface{}{"PONG", authenticated, "", "fluentd-0", sha512Hex(salt, "fluentd-0", nonce, sharedKey)})
+	_, _ = conn.Write(pong)
+	if !authenticated {
+		return
+	}
+
+	for {
+		message, err := msgpackDecode(conn)
+		if err != nil {
+			return
+		}
+		m := message.([]interface{})
+		messages <- m
+		option := m[2].(map[string]interface{})
+		ack, _ := msgpackMarshal(map[string]interface{}{"ack": option["chunk"]})
+		_, _ = conn.Write(ack)
+	}
+}
+
+func TestFluentd_Forward(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	messages := make(chan []interface{}, 10)
+	go fluentdServer(t, listener, "secret", messages)
+
+	f, err := NewFluentdSink(&FluentdConfig{
+		Address:    listener.Addr().String(),
+		Tag:        "k8s.{{ .InvolvedObject.Namespace }}",
+		Layout:     map[string]interface{}{"reason": "{{ .Reason }}"},
+		RequireAck: true,
+		SharedKey:  "secret",
+	})
+	require.NoError(t, err)
+	defer f.Close()
+
+	var items []interface{}
+	for _, namespace := range []string{"shop", "kube-system", "shop"} {
+		items = append(items, &fluentdEntry{tag: "k8s." + namespace, time: time.Unix(1700000000, 5), record: map[string]interface{}{"reason": "BackOff"}})
+	}
+	assert.Equal(t, []bool{true, true, true}, f.write(context.Background(), items))
+
+	m := <-messages
+	assert.Equal(t, "k8s.shop", m[0])
+	entries := m[1].([]interface{})
+	require.Len(t, entries, 2)
+	entry := entries[0].([]interface{})
+	// The EventTime extension holds the seconds and the nanoseconds
+	assert.Equal(t, []byte{0x65, 0x53, 0xf1, 0x00, 0, 0, 0, 5}, entry[0])
+	assert.Equal(t, map[string]interface{}{"reason": "BackOff"}, entry[1])
+	assert.Equal(t, int64(2), m[2].(map[string]interface{})["size"])
+	m = <-messages
+	assert.Equal(t, "k8s.kube-system", m[0])
+}
+
+func TestFluentd_WrongSharedKey(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go fluentdServer(t, listener, "secret", nil)
+
+	f, err := NewFluentdSink(&FluentdConfig{Address: listener.Addr().String(), SharedKey: "wrong", MaxRetries: 1})
+	require.NoError(t, err)
+	defer f.Close()
+
+	entry := &fluentdEntry{tag: "k8s", time: time.Now(), record: map[string]interface{}{}}
+	assert.Equal(t, []bool{false}, f.write(context.Background(), []interface{}{entry}))
+}
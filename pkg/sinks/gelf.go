@@ -0,0 +1,224 @@
+package sinks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultGELFShortMessage = "{{ .Message }}"
+	defaultGELFChunkSize    = 1420
+	// gelfMaxChunks is the limit of the GELF spec, larger messages are dropped by the servers
+	gelfMaxChunks = 128
+)
+
+var (
+	defaultGELFFields = map[string]string{
+		"namespace": "{{ .InvolvedObject.Namespace }}",
+		"kind":      "{{ .InvolvedObject.Kind }}",
+		"name":      "{{ .InvolvedObject.Name }}",
+		"reason":    "{{ .Reason }}",
+		"type":      "{{ .Type }}",
+		"count":     "{{ .Count }}",
+		"component": "{{ .Source.Component }}",
+		"cluster":   "{{ .ClusterName }}",
+	}
+
+	gelfFieldName  = regexp.MustCompile(`^[\w.\-]+$`)
+	gelfChunkMagic = []byte{0x1e, 0x0f}
+)
+
+// GELFConfig sends the events as GELF messages to Graylog, over UDP with chunking and compression, TCP or TLS. The
+// fields become additional fields of the message and are templates.
+type GELFConfig struct {
+	// Address is the host:port of the GELF input
+	Address string `yaml:"address"`
+	// Protocol is udp (default), tcp or tls
+	Protocol string `yaml:"protocol"`
+	// Host is the source of the messages, the hostname of the exporter by default
+	Host         string            `yaml:"host,omitempty"`
+	ShortMessage string            `yaml:"shortMessage"`
+	FullMessage  string            `yaml:"fullMessage,omitempty"`
+	Fields       map[string]string `yaml:"fields"`
+	// Compression of the UDP messages is gzip (default) or none, TCP messages are never compressed
+	Compression string `yaml:"compression"`
+	// ChunkSize is the maximum size of the UDP datagrams, 1420 by default
+	ChunkSize      int `yaml:"chunkSize"`
+	TimeoutSeconds int `yaml:"timeoutSeconds"`
+	TLS            TLS `yaml:"tls"`
+}
+
+type GELF struct {
+	cfg    *GELFConfig
+	host   string
+	writer *connWriter
+}
+
+func NewGELFSink(cfg *GELFConfig) (Sink, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("gelf.address config option must be non-empty")
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = "udp"
+	}
+	if cfg.ShortMessage == "" {
+		cfg.ShortMessage = defaultGELFShortMessage
+	}
+	if cfg.Fields == nil {
+		cfg.Fields = defaultGELFFields
+	}
+	for name := range cfg.Fields {
+		if !gelfFieldName.MatchString(name) || name == "id" {
+			return nil, fmt.Errorf("gelf.fields: invalid field name %q", name)
+		}
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = CompressionGzip
+	}
+	if cfg.Compression != CompressionGzip && cfg.Compression != CompressionNone {
+		return nil, fmt.Errorf("gelf.compression must be gzip or none, got %q", cfg.Compression)
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = defaultGELFChunkSize
+	}
+	// A chunk needs room for its 12 bytes header
+	if cfg.ChunkSize <= 12 {
+		return nil, fmt.Errorf("gelf.chunkSize must be greater than 12, got %d", cfg.ChunkSize)
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	host, _ := os.Hostname()
+	g := &GELF{cfg: cfg, host: host}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	switch cfg.Protocol {
+	case "udp", "tcp":
+		g.writer = newConnWriter(cfg.Protocol, cfg.Address, nil, timeout)
+	case "tls":
+		tlsClientConfig, err := setupTLS(&cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		g.writer = newConnWriter("tcp", cfg.Address, tlsClientConfig, timeout)
+	default:
+		return nil, fmt.Errorf("gelf.protocol must be udp, tcp or tls, got %q", cfg.Protocol)
+	}
+	return g, nil
+}
+
+// message renders the event as a GELF 1.1 message
+func (g *GELF) message(ev *kube.EnhancedEvent) (map[string]interface{}, error) {
+	host := g.host
+	if g.cfg.Host != "" {
+		rendered, err := GetString(ev, g.cfg.Host)
+		if err != nil {
+			return nil, err
+		}
+		host = rendered
+	}
+	shortMessage, err := GetString(ev, g.cfg.ShortMessage)
+	if err != nil {
+		return nil, err
+	}
+	if shortMessage == "" {
+		// The short message is mandatory
+		shortMessage = ev.Reason
+	}
+
+	level := 6 // informational
+	if ev.Type == "Warning" {
+		level = 4
+	}
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": shortMessage,
+		"timestamp":     float64(ev.GetTimestampMs()) / 1000,
+		"level":         level,
+	}
+	if g.cfg.FullMessage != "" {
+		fullMessage, err := GetString(ev, g.cfg.FullMessage)
+		if err != nil {
+			return nil, err
+		}
+		msg["full_message"] = fullMessage
+	}
+	for name, text := range g.cfg.Fields {
+		value, err := GetString(ev, text)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			msg["_"+name] = value
+		}
+	}
+	return msg, nil
+}
+
+func (g *GELF) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	msg, err := g.message(ev)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if g.cfg.Protocol != "udp" {
+		// The stream inputs separate the messages by null bytes
+		return g.writer.Write(ctx, append(payload, 0))
+	}
+	payload, err = Compress(g.cfg.Compression, payload)
+	if err != nil {
+		return err
+	}
+	chunks, err := gelfChunks(payload, g.cfg.ChunkSize)
+	if err != nil {
+		return err
+	}
+	return g.writer.Write(ctx, chunks...)
+}
+
+// gelfChunks splits the payload into datagrams of at most size bytes, a payload that fits is sent as is
+func gelfChunks(payload []byte, size int) ([][]byte, error) {
+	if len(payload) <= size {
+		return [][]byte{payload}, nil
+	}
+	dataSize := size - 12
+	count := (len(payload) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("gelf: the message of %d bytes needs %d chunks, more than the limit of %d", len(payload), count, gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := make([]byte, 0, 12+end-i*dataSize)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, payload[i*dataSize:end]...))
+	}
+	return chunks, nil
+}
+
+func (g *GELF) Close() {
+	g.writer.Close()
+}
@@ -0,0 +1,125 @@
+package sinks
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func gelfTestEvent(message string) *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{ClusterName: "prod-eu"}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = message
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Name = "checkout-0"
+	return ev
+}
+
+func TestGELF_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewGELFSink(&GELFConfig{Address: conn.LocalAddr().String(), Host: "{{ .ClusterName }}", ChunkSize: 64})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Even compressed, the message is larger than the small chunk size
+	message := strings.Repeat("Back-off restarting failed container 8f3a1c", 10)
+	require.NoError(t, s.Send(context.Background(), gelfTestEvent(message)))
+
+	var payload []byte
+	var id []byte
+	buf := make([]byte, 1500)
+	for seq := 0; ; seq++ {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		datagram := buf[:n]
+		require.LessOrEqual(t, n, 64)
+		require.Equal(t, gelfChunkMagic, datagram[:2])
+		if id == nil {
+			id = append([]byte(nil), datagram[2:10]...)
+		}
+		assert.Equal(t, id, datagram[2:10])
+		assert.Equal(t, byte(seq), datagram[10])
+		payload = append(payload, datagram[12:]...)
+		if int(datagram[11]) == seq+1 {
+			break
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &msg))
+	assert.Equal(t, "1.1", msg["version"])
+	assert.Equal(t, "prod-eu", msg["host"])
+	assert.Equal(t, message, msg["short_message"])
+	assert.Equal(t, float64(4), msg["level"])
+	assert.Equal(t, "shop", msg["_namespace"])
+	assert.Equal(t, "BackOff", msg["_reason"])
+	// Empty fields are left out
+	assert.NotContains(t, msg, "_component")
+}
+
+func TestGELF_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s, err := NewGELFSink(&GELFConfig{
+		Address:  listener.Addr().String(),
+		Protocol: "tcp",
+		Fields:   map[string]string{"object": "{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Send(context.Background(), gelfTestEvent("first")))
+	require.NoError(t, s.Send(context.Background(), gelfTestEvent("second")))
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"first", "second"} {
+		frame, err := r.ReadBytes(0)
+		require.NoError(t, err)
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(frame[:len(frame)-1], &msg))
+		assert.Equal(t, want, msg["short_message"])
+		assert.Equal(t, "Pod/checkout-0", msg["_object"])
+	}
+}
+
+func TestGELFConfig(t *testing.T) {
+	_, err := NewGELFSink(&GELFConfig{})
+	assert.Error(t, err)
+	_, err = NewGELFSink(&GELFConfig{Address: "localhost:12201", Protocol: "http"})
+	assert.Error(t, err)
+	_, err = NewGELFSink(&GELFConfig{Address: "localhost:12201", Fields: map[string]string{"id": "{{ .UID }}"}})
+	assert.Error(t, err)
+}
+
+func TestGELFChunks(t *testing.T) {
+	_, err := gelfChunks(make([]byte, 129*10), 22)
+	assert.Error(t, err)
+	chunks, err := gelfChunks(make([]byte, 128*10), 22)
+	require.NoError(t, err)
+	assert.Len(t, chunks, 128)
+}
@@ -0,0 +1,208 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultGitLabURL         = "https://gitlab.com"
+	defaultGitLabTitle       = "[{{ .Type }}] {{ .Reason }} on {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}"
+	defaultGitLabDescription = "{{ .Message }}"
+	defaultGitLabComment     = "Occurred again: {{ .Message }}"
+	gitLabMaxTitle           = 255
+	gitLabMaxDescription     = 1000000
+)
+
+// GitLabConfig opens an issue in a GitLab project for every new event. The issues are deduplicated by the rendered
+// Fingerprint, which is hidden in the description: while an issue with the same fingerprint is open, a repeated
+// event adds a comment to it, or nothing if Comment is "-".
+type GitLabConfig struct {
+	// URL is the GitLab instance, gitlab.com by default
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+	// ProjectID is the numeric ID or the path of the project, like group/project
+	ProjectID   string   `yaml:"projectID"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description"`
+	Comment     string   `yaml:"comment"`
+	Labels      []string `yaml:"labels"`
+	Fingerprint string   `yaml:"fingerprint"`
+	TLS         TLS      `yaml:"tls"`
+}
+
+func (c *GitLabConfig) url() string {
+	if c.URL == "" {
+		return defaultGitLabURL
+	}
+	return c.URL
+}
+
+type GitLab struct {
+	cfg         *GitLabConfig
+	client      *http.Client
+	projectsURL string
+}
+
+func NewGitLabSink(cfg *GitLabConfig) (Sink, error) {
+	if cfg.Token == "" || cfg.ProjectID == "" {
+		return nil, errors.New("gitlab.token and gitlab.projectID config options must be non-empty")
+	}
+	if cfg.Title == "" {
+		cfg.Title = defaultGitLabTitle
+	}
+	if cfg.Description == "" {
+		cfg.Description = defaultGitLabDescription
+	}
+	if cfg.Comment == "" {
+		cfg.Comment = defaultGitLabComment
+	}
+	if cfg.Fingerprint == "" {
+		cfg.Fingerprint = "{{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}/{{ .Reason }}"
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &GitLab{
+		cfg:         cfg,
+		client:      &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+		projectsURL: strings.TrimRight(cfg.url(), "/") + "/api/v4/projects/" + url.PathEscape(cfg.ProjectID),
+	}, nil
+}
+
+type gitLabIssue struct {
+	IID int `json:"iid"`
+}
+
+// gitLabFingerprintMarker is the hidden line of the description identifying the issue of the fingerprint
+func gitLabFingerprintMarker(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "event-exporter-fingerprint:" + hex.EncodeToString(sum[:16])
+}
+
+func (g *GitLab) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	fingerprint, err := GetString(ev, g.cfg.Fingerprint)
+	if err != nil {
+		return err
+	}
+	marker := gitLabFingerprintMarker(fingerprint)
+
+	issue, err := g.findOpenIssue(ctx, marker)
+	if err != nil {
+		return err
+	}
+	if issue != nil {
+		if g.cfg.Comment == "-" {
+			return nil
+		}
+		body, err := GetString(ev, g.cfg.Comment)
+		if err != nil {
+			return err
+		}
+		return g.post(ctx, fmt.Sprintf("/issues/%d/notes", issue.IID), map[string]string{"body": body})
+	}
+
+	title, err := GetString(ev, g.cfg.Title)
+	if err != nil {
+		return err
+	}
+	description, err := GetString(ev, g.cfg.Description)
+	if err != nil {
+		return err
+	}
+	labels := make([]string, 0, len(g.cfg.Labels))
+	for _, tmpl := range g.cfg.Labels {
+		label, err := GetString(ev, tmpl)
+		if err != nil {
+			return err
+		}
+		// GitLab separates the labels by commas
+		if label = strings.TrimSpace(strings.ReplaceAll(label, ",", " ")); label != "" {
+			labels = append(labels, label)
+		}
+	}
+
+	title, _ = truncateRunes(strings.TrimSpace(title), gitLabMaxTitle)
+	if truncated, ok := truncateRunes(description, gitLabMaxDescription); ok {
+		description = truncated
+		countTruncatedPayload("gitlab")
+	}
+	return g.post(ctx, "/issues", map[string]string{
+		"title":       title,
+		"description": description + "\n\n<!-- " + marker + " -->",
+		"labels":      strings.Join(labels, ","),
+	})
+}
+
+// findOpenIssue returns the open issue whose description contains the marker, nil if there is none
+func (g *GitLab) findOpenIssue(ctx context.Context, marker string) (*gitLabIssue, error) {
+	params := url.Values{
+		"state":    {"opened"},
+		"search":   {marker},
+		"in":       {"description"},
+		"per_page": {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.projectsURL+"/issues?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := g.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var issues []gitLabIssue
+	if err := json.Unmarshal(body, &issues); err != nil {
+		return nil, fmt.Errorf("cannot decode the issues: %w", err)
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return &issues[0], nil
+}
+
+func (g *GitLab) post(ctx context.Context, path string, payload map[string]string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.projectsURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = g.do(req)
+	return err
+}
+
+func (g *GitLab) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("PRIVATE-TOKEN", g.cfg.Token)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return body, httpResponseError(resp, body)
+}
+
+func (g *GitLab) Close() {
+	g.client.CloseIdleConnections()
+}
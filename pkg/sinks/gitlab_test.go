@@ -0,0 +1,110 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// fakeGitLab keeps the created issues and notes of the project group/app
+type fakeGitLab struct {
+	mu     sync.Mutex
+	issues []map[string]string
+	notes  map[string][]string
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("PRIVATE-TOKEN") != "glpat-secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/api/v4/projects/group%2Fapp/issues"
+	path := r.URL.EscapedPath()
+	switch {
+	case r.Method == http.MethodGet && path == prefix:
+		found := []map[string]int{}
+		for i, issue := range f.issues {
+			if r.URL.Query().Get("state") == "opened" && issue["state"] == "opened" &&
+				strings.Contains(issue["description"], r.URL.Query().Get("search")) {
+				found = append(found, map[string]int{"iid": i + 1})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(found)
+	case r.Method == http.MethodPost && path == prefix:
+		var issue map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&issue)
+		issue["state"] = "opened"
+		f.issues = append(f.issues, issue)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, prefix+"/") && strings.HasSuffix(path, "/notes"):
+		var note map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&note)
+		iid := strings.TrimSuffix(strings.TrimPrefix(path, prefix+"/"), "/notes")
+		f.notes[iid] = append(f.notes[iid], note["body"])
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGitLab_Dedup(t *testing.T) {
+	gitlab := &fakeGitLab{notes: make(map[string][]string)}
+	ts := httptest.NewServer(gitlab)
+	defer ts.Close()
+
+	sink, err := NewGitLabSink(&GitLabConfig{
+		URL:       ts.URL,
+		Token:     "glpat-secret",
+		ProjectID: "group/app",
+		Labels:    []string{"kubernetes", "ns::{{ .InvolvedObject.Namespace }}"},
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	require.Len(t, gitlab.issues, 1)
+	issue := gitlab.issues[0]
+	assert.Equal(t, "[Warning] BackOff on Pod prod/api-0", issue["title"])
+	assert.Equal(t, "kubernetes,ns::prod", issue["labels"])
+	assert.True(t, strings.HasPrefix(issue["description"], "Back-off restarting failed container\n\n<!-- event-exporter-fingerprint:"))
+	assert.Equal(t, []string{"Occurred again: Back-off restarting failed container"}, gitlab.notes["1"])
+
+	// A closed issue is not reused
+	gitlab.issues[0]["state"] = "closed"
+	require.NoError(t, sink.Send(context.Background(), ev))
+	require.Len(t, gitlab.issues, 2)
+
+	// Another object gets its own issue
+	ev.InvolvedObject.Name = "api-1"
+	require.NoError(t, sink.Send(context.Background(), ev))
+	assert.Len(t, gitlab.issues, 3)
+}
+
+func TestGitLab_Errors(t *testing.T) {
+	_, err := NewGitLabSink(&GitLabConfig{Token: "glpat-secret"})
+	assert.Error(t, err)
+
+	ts := httptest.NewServer(&fakeGitLab{notes: make(map[string][]string)})
+	defer ts.Close()
+	sink, err := NewGitLabSink(&GitLabConfig{URL: ts.URL, Token: "wrong", ProjectID: "group/app"})
+	require.NoError(t, err)
+	assert.Error(t, sink.Send(context.Background(), &kube.EnhancedEvent{}))
+}
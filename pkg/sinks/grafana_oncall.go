@@ -0,0 +1,148 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultGrafanaOnCallGroupKey = "{{ .InvolvedObject.UID }}/{{ .Reason }}"
+	defaultGrafanaOnCallTitle    = "{{ .Reason }} on {{ .InvolvedObject.Kind }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}"
+	defaultGrafanaOnCallMessage  = "{{ .Message }}"
+)
+
+// GrafanaOnCallConfig sends the events to a formatted webhook integration of Grafana OnCall. OnCall groups the alerts
+// by the rendered GroupKey, sent as the alert_uid.
+type GrafanaOnCallConfig struct {
+	// URL is the integration URL shown by OnCall
+	URL      string `yaml:"url" secret:"true"`
+	GroupKey string `yaml:"groupKey"`
+	Title    string `yaml:"title"`
+	Message  string `yaml:"message"`
+	// Link is a template for the link to the upstream details, like a dashboard of the involved object
+	Link     string `yaml:"link,omitempty"`
+	ImageURL string `yaml:"imageURL,omitempty"`
+	// Details are additional templated fields of the payload, for the templates of the integration
+	Details map[string]string `yaml:"details,omitempty"`
+	// ResolveCondition is a template that should evaluate to a non-empty string for the events that resolve an alert.
+	// These events are sent with the ok state, which resolves the alert group of the ResolveGroupKey.
+	ResolveCondition string `yaml:"resolveCondition,omitempty"`
+	// ResolveGroupKey is the group key of the alert to resolve, defaults to GroupKey
+	ResolveGroupKey string `yaml:"resolveGroupKey,omitempty"`
+	TLS             TLS    `yaml:"tls"`
+}
+
+type GrafanaOnCall struct {
+	cfg    *GrafanaOnCallConfig
+	client *http.Client
+}
+
+func NewGrafanaOnCallSink(cfg *GrafanaOnCallConfig) (Sink, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("grafanaOnCall.url config option must be non-empty")
+	}
+	if cfg.GroupKey == "" {
+		cfg.GroupKey = defaultGrafanaOnCallGroupKey
+	}
+	if cfg.Title == "" {
+		cfg.Title = defaultGrafanaOnCallTitle
+	}
+	if cfg.Message == "" {
+		cfg.Message = defaultGrafanaOnCallMessage
+	}
+	if cfg.ResolveGroupKey == "" {
+		cfg.ResolveGroupKey = cfg.GroupKey
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &GrafanaOnCall{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (g *GrafanaOnCall) isResolve(ev *kube.EnhancedEvent) bool {
+	if g.cfg.ResolveCondition == "" {
+		return false
+	}
+	res, err := GetString(ev, g.cfg.ResolveCondition)
+	if err != nil {
+		log.Warn().Err(err).Str("template", g.cfg.ResolveCondition).Msg("Failed to execute resolveCondition template")
+		return false
+	}
+	return res != ""
+}
+
+func (g *GrafanaOnCall) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	state, groupKey := "alerting", g.cfg.GroupKey
+	if g.isResolve(ev) {
+		state, groupKey = "ok", g.cfg.ResolveGroupKey
+	}
+
+	payload := map[string]interface{}{"state": state}
+	for field, tmpl := range map[string]string{
+		"alert_uid":                groupKey,
+		"title":                    g.cfg.Title,
+		"message":                  g.cfg.Message,
+		"link_to_upstream_details": g.cfg.Link,
+		"image_url":                g.cfg.ImageURL,
+	} {
+		if tmpl == "" {
+			continue
+		}
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return fmt.Errorf("cannot render %s: %w", field, err)
+		}
+		payload[field] = value
+	}
+	if len(g.cfg.Details) > 0 {
+		details := make(map[string]string, len(g.cfg.Details))
+		for _, key := range sortedKeys(g.cfg.Details) {
+			value, err := GetString(ev, g.cfg.Details[key])
+			if err != nil {
+				return err
+			}
+			details[key] = value
+		}
+		payload["details"] = details
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (g *GrafanaOnCall) Close() {
+	g.client.CloseIdleConnections()
+}
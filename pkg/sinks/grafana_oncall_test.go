@@ -0,0 +1,57 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestGrafanaOnCall_Send(t *testing.T) {
+	var payloads []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer ts.Close()
+
+	sink, err := NewGrafanaOnCallSink(&GrafanaOnCallConfig{
+		URL:              ts.URL,
+		GroupKey:         "{{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}/{{ .Reason }}",
+		ResolveCondition: `{{ if eq .Reason "Started" }}true{{ end }}`,
+		ResolveGroupKey:  "{{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }}/BackOff",
+		Details:          map[string]string{"cluster": "{{ .ClusterName }}"},
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{ClusterName: "prod-eu"}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	ev.Type = "Normal"
+	ev.Reason = "Started"
+	ev.Message = "Started container api"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	require.Len(t, payloads, 2)
+	assert.Equal(t, map[string]interface{}{
+		"state":     "alerting",
+		"alert_uid": "prod/api-0/BackOff",
+		"title":     "BackOff on Pod prod/api-0",
+		"message":   "Back-off restarting failed container",
+		"details":   map[string]interface{}{"cluster": "prod-eu"},
+	}, payloads[0])
+	assert.Equal(t, "ok", payloads[1]["state"])
+	assert.Equal(t, "prod/api-0/BackOff", payloads[1]["alert_uid"])
+}
@@ -0,0 +1,158 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// HistoryFields are the fields the history and the silences can be filtered by
+var HistoryFields = []string{"namespace", "kind", "name", "reason", "type"}
+
+// HistoryRecord is the summary of an event kept in a history
+type HistoryRecord struct {
+	Time      time.Time
+	Namespace string
+	Kind      string
+	Name      string
+	Reason    string
+	Type      string
+	Message   string
+}
+
+func NewHistoryRecord(ev *kube.EnhancedEvent) HistoryRecord {
+	return HistoryRecord{
+		Time:      time.UnixMilli(ev.GetTimestampMs()),
+		Namespace: ev.InvolvedObject.Namespace,
+		Kind:      ev.InvolvedObject.Kind,
+		Name:      ev.InvolvedObject.Name,
+		Reason:    ev.Reason,
+		Type:      ev.Type,
+		Message:   ev.Message,
+	}
+}
+
+func (r *HistoryRecord) Field(name string) string {
+	switch name {
+	case "namespace":
+		return r.Namespace
+	case "kind":
+		return r.Kind
+	case "name":
+		return r.Name
+	case "reason":
+		return r.Reason
+	case "type":
+		return r.Type
+	}
+	return ""
+}
+
+// EventFilter matches the records whose fields equal all of its values
+type EventFilter map[string]string
+
+func (f EventFilter) Validate() error {
+	for field := range f {
+		if !isHistoryField(field) {
+			return fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(HistoryFields, ", "))
+		}
+	}
+	return nil
+}
+
+func (f EventFilter) Matches(r *HistoryRecord) bool {
+	for field, value := range f {
+		if r.Field(field) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the filter in a canonical form like namespace=prod,reason=BackOff
+func (f EventFilter) String() string {
+	pairs := make([]string, 0, len(f))
+	for field, value := range f {
+		pairs = append(pairs, field+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func isHistoryField(name string) bool {
+	for _, field := range HistoryFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EventHistory returns the most recent events matching the filter, the newest first
+type EventHistory interface {
+	Recent(ctx context.Context, filter EventFilter, limit int) ([]HistoryRecord, error)
+}
+
+// AsEventHistory returns the sink as an EventHistory if it keeps the events it receives, like the SQLite sink
+func AsEventHistory(s Sink) (EventHistory, bool) {
+	for {
+		if h, ok := s.(EventHistory); ok {
+			return h, true
+		}
+		w, ok := s.(wrappedSink)
+		if !ok {
+			return nil, false
+		}
+		s = w.Unwrap()
+	}
+}
+
+// MemoryHistory keeps the last events in a ring buffer
+type MemoryHistory struct {
+	mu      sync.Mutex
+	records []HistoryRecord
+	next    int
+	full    bool
+}
+
+func NewMemoryHistory(size int) *MemoryHistory {
+	return &MemoryHistory{records: make([]HistoryRecord, size)}
+}
+
+func (h *MemoryHistory) Add(ev *kube.EnhancedEvent) {
+	if len(h.records) == 0 {
+		return
+	}
+	record := NewHistoryRecord(ev)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+func (h *MemoryHistory) Recent(_ context.Context, filter EventFilter, limit int) ([]HistoryRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := h.next
+	if h.full {
+		n = len(h.records)
+	}
+	var ret []HistoryRecord
+	for i := 1; i <= n && len(ret) < limit; i++ {
+		r := &h.records[(h.next-i+len(h.records))%len(h.records)]
+		if filter.Matches(r) {
+			ret = append(ret, *r)
+		}
+	}
+	return ret, nil
+}
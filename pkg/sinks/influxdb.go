@@ -0,0 +1,230 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// InfluxDBConfig writes every event as a point to an InfluxDB v2 bucket using the line protocol. The measurement, the
+// tags and the fields are templates.
+type InfluxDBConfig struct {
+	URL         string            `yaml:"url"`
+	Org         string            `yaml:"org"`
+	Bucket      string            `yaml:"bucket"`
+	Token       string            `yaml:"token"`
+	Measurement string            `yaml:"measurement"`
+	Tags        map[string]string `yaml:"tags"`
+	Fields      map[string]string `yaml:"fields"`
+	TLS         TLS               `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+var (
+	defaultInfluxDBTags = map[string]string{
+		"namespace": "{{ .InvolvedObject.Namespace }}",
+		"kind":      "{{ .InvolvedObject.Kind }}",
+		"reason":    "{{ .Reason }}",
+		"type":      "{{ .Type }}",
+	}
+	defaultInfluxDBFields = map[string]string{
+		"message": "{{ .Message }}",
+		"name":    "{{ .InvolvedObject.Name }}",
+	}
+
+	influxDBMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxDBTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	influxDBFieldEscaper       = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+type InfluxDB struct {
+	cfg         *InfluxDBConfig
+	client      *http.Client
+	writeURL    string
+	batchWriter *batch.Writer
+}
+
+func NewInfluxDBSink(cfg *InfluxDBConfig) (*InfluxDB, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("influxdb.url config option must be non-empty")
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("influxdb.bucket config option must be non-empty")
+	}
+	if cfg.Measurement == "" {
+		cfg.Measurement = "kubernetes_events"
+	}
+	if cfg.Tags == nil {
+		cfg.Tags = defaultInfluxDBTags
+	}
+	if cfg.Fields == nil {
+		cfg.Fields = defaultInfluxDBFields
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("org", cfg.Org)
+	params.Set("bucket", cfg.Bucket)
+	params.Set("precision", "ms")
+
+	i := &InfluxDB{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		writeURL: strings.TrimRight(cfg.URL, "/") + "/api/v2/write?" + params.Encode(),
+	}
+	i.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		i.write,
+	)
+	i.batchWriter.Start()
+
+	return i, nil
+}
+
+// influxDBLine renders the event as a single point in the line protocol
+func influxDBLine(cfg *InfluxDBConfig, ev *kube.EnhancedEvent) (string, error) {
+	measurement, err := GetString(ev, cfg.Measurement)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(influxDBMeasurementEscaper.Replace(measurement))
+
+	for _, k := range sortedKeys(cfg.Tags) {
+		v, err := GetString(ev, cfg.Tags[k])
+		if err != nil {
+			return "", err
+		}
+		// Empty tag values are not allowed
+		if v == "" {
+			continue
+		}
+		sb.WriteString("," + influxDBTagEscaper.Replace(k) + "=" + influxDBTagEscaper.Replace(v))
+	}
+
+	fields := 0
+	for _, k := range sortedKeys(cfg.Fields) {
+		v, err := GetString(ev, cfg.Fields[k])
+		if err != nil {
+			return "", err
+		}
+		if fields == 0 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(influxDBTagEscaper.Replace(k) + `="` + influxDBFieldEscaper.Replace(v) + `"`)
+		fields++
+	}
+	if fields == 0 {
+		return "", errors.New("influxdb: a point requires at least one field")
+	}
+
+	sb.WriteString(fmt.Sprintf(" %d", ev.GetTimestampMs()))
+	return sb.String(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (i *InfluxDB) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := influxDBLine(i.cfg, ev)
+	if err != nil {
+		return err
+	}
+	i.batchWriter.Submit(line)
+	return nil
+}
+
+func (i *InfluxDB) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	buf := &bytes.Buffer{}
+	for _, item := range items {
+		buf.WriteString(item.(string))
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.writeURL, buf)
+	if err != nil {
+		log.Error().Err(err).Msg("influxdb: cannot create request")
+		return res
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.cfg.Token)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		log.Error().Err(err).Int("points", len(items)).Msg("influxdb: write failed")
+		return res
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	// 400 means the points were rejected, retrying would not help
+	if resp.StatusCode == http.StatusBadRequest {
+		log.Error().Str("response", string(body)).Int("points", len(items)).Msg("influxdb: points rejected")
+	} else if resp.StatusCode/100 != 2 {
+		log.Error().Int("status", resp.StatusCode).Str("response", string(body)).Msg("influxdb: write failed")
+		return res
+	}
+
+	for j := range res {
+		res[j] = true
+	}
+	return res
+}
+
+func (i *InfluxDB) Close() {
+	i.batchWriter.Stop()
+	i.client.CloseIdleConnections()
+}
@@ -0,0 +1,37 @@
+package sinks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestInfluxDBLine(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "default"
+	ev.Reason = "Back Off"
+	ev.Type = "Warning"
+	ev.Message = `Back-off restarting "app", path C:\app`
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Name = "app-1"
+	ev.FirstTimestamp = metav1.NewTime(time.UnixMilli(1700000000123))
+
+	cfg := &InfluxDBConfig{
+		Measurement: "kube events",
+		Tags:        defaultInfluxDBTags,
+		Fields:      defaultInfluxDBFields,
+	}
+	line, err := influxDBLine(cfg, ev)
+	require.NoError(t, err)
+	// The involved object has no namespace, so the tag is omitted
+	assert.Equal(t, `kube\ events,kind=Pod,reason=Back\ Off,type=Warning message="Back-off restarting \"app\", path C:\\app",name="app-1" 1700000000123`, line)
+
+	cfg.Fields = map[string]string{}
+	_, err = influxDBLine(cfg, ev)
+	assert.Error(t, err)
+}
@@ -24,7 +24,7 @@ type KafkaConfig struct {
 	Brokers          []string               `yaml:"brokers"`
 	Layout           map[string]interface{} `yaml:"layout"`
 	ClientId         string                 `yaml:"clientId"`
-	CompressionCodec string                 `yaml:"compressionCodec" default:"none"`
+	CompressionCodec string                 `yaml:"compressionCodec" default:"snappy"`
 	Version          string                 `yaml:"version"`
 	TLS              struct {
 		Enable             bool   `yaml:"enable"`
@@ -89,10 +89,11 @@ func NewKafkaSink(cfg *KafkaConfig) (Sink, error) {
 
 // Send an event to Kafka synchronously
 func (k *KafkaSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, k.cfg.Layout)
 	var toSend []byte
 
-	if k.cfg.Layout != nil {
-		res, err := convertLayoutTemplate(k.cfg.Layout, ev)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
 		if err != nil {
 			return err
 		}
@@ -149,9 +150,16 @@ func createSaramaProducer(cfg *KafkaConfig) (sarama.SyncProducer, error) {
 	// Necessary for SyncProducer
 	saramaConfig.Producer.Return.Successes = true
 	saramaConfig.Producer.Return.Errors = true
-	if _, ok := CompressionCodecs[cfg.CompressionCodec]; ok {
-		saramaConfig.Producer.Compression = CompressionCodecs[cfg.CompressionCodec]
+	// Snappy by default, the events compress well and every broker supports it
+	codecName := cfg.CompressionCodec
+	if codecName == "" {
+		codecName = CompressionSnappy
 	}
+	codec, ok := CompressionCodecs[codecName]
+	if !ok {
+		return nil, fmt.Errorf("unknown kafka compression codec %q, must be one of none, snappy, gzip, lz4 or zstd", codecName)
+	}
+	saramaConfig.Producer.Compression = codec
 
 	// TLS Client auth override
 	if cfg.TLS.Enable {
@@ -178,6 +186,12 @@ func createSaramaProducer(cfg *KafkaConfig) (sarama.SyncProducer, error) {
 
 			saramaConfig.Net.TLS.Config.Certificates = []tls.Certificate{cert}
 		}
+		ApplyTLSPolicy(saramaConfig.Net.TLS.Config)
+	}
+
+	if egressPolicy != nil {
+		saramaConfig.Net.Proxy.Enable = true
+		saramaConfig.Net.Proxy.Dialer = egressPolicy
 	}
 
 	// SASL Client auth
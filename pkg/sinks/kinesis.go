@@ -37,10 +37,11 @@ func NewKinesisSink(cfg *KinesisConfig) (Sink, error) {
 }
 
 func (k *KinesisSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, k.cfg.Layout)
 	var toSend []byte
 
-	if k.cfg.Layout != nil {
-		res, err := convertLayoutTemplate(k.cfg.Layout, ev)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,75 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// ConditionalLayout replaces the layout of the sink for the events where the When template renders to "true", for
+// example `{{ eq .Type "Warning" }}`
+type ConditionalLayout struct {
+	When   string                 `yaml:"when"`
+	Layout map[string]interface{} `yaml:"layout"`
+}
+
+type layoutContextKey struct{}
+
+// withLayout passes the layout selected for the event down to the sink
+func withLayout(ctx context.Context, layout map[string]interface{}) context.Context {
+	return context.WithValue(ctx, layoutContextKey{}, layout)
+}
+
+// resolveLayout returns the layout selected for the event if there is one, otherwise the layout configured on the sink
+func resolveLayout(ctx context.Context, layout map[string]interface{}) map[string]interface{} {
+	if selected, ok := ctx.Value(layoutContextKey{}).(map[string]interface{}); ok {
+		return selected
+	}
+	return layout
+}
+
+func validateConditionalLayouts(layouts []ConditionalLayout) error {
+	for i, l := range layouts {
+		if l.When == "" {
+			return fmt.Errorf("layouts[%d].when must be non-empty", i)
+		}
+		if l.Layout == nil {
+			return fmt.Errorf("layouts[%d].layout must be non-empty", i)
+		}
+		if _, err := template.New("when").Funcs(templateFuncs()).Parse(l.When); err != nil {
+			return fmt.Errorf("layouts[%d].when is invalid: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// conditionalLayoutSink selects the first layout whose condition matches the event. The preset is used when none of
+// them matches, and the layout of the sink if there is no preset either.
+type conditionalLayoutSink struct {
+	Sink
+	layouts []ConditionalLayout
+	preset  map[string]interface{}
+}
+
+func (s *conditionalLayoutSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	for _, l := range s.layouts {
+		res, err := GetString(ev, l.When)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(res) == "true" {
+			return s.Sink.Send(withLayout(ctx, l.Layout), ev)
+		}
+	}
+	if s.preset != nil {
+		return s.Sink.Send(withLayout(ctx, s.preset), ev)
+	}
+	return s.Sink.Send(ctx, ev)
+}
+
+func (s *conditionalLayoutSink) Unwrap() Sink {
+	return s.Sink
+}
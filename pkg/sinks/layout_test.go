@@ -0,0 +1,83 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+type layoutRecorder struct {
+	layouts []map[string]interface{}
+}
+
+func (r *layoutRecorder) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	r.layouts = append(r.layouts, resolveLayout(ctx, map[string]interface{}{"default": "yes"}))
+	return nil
+}
+
+func (r *layoutRecorder) Close() {}
+
+func TestConditionalLayoutSink(t *testing.T) {
+	warning := map[string]interface{}{"warning": "{{ .Message }}"}
+	pod := map[string]interface{}{"pod": "{{ .InvolvedObject.Name }}"}
+	rec := &layoutRecorder{}
+	sink := &conditionalLayoutSink{
+		Sink: rec,
+		layouts: []ConditionalLayout{
+			{When: `{{ eq .Type "Warning" }}`, Layout: warning},
+			{When: `{{ eq .InvolvedObject.Kind "Pod" }}`, Layout: pod},
+		},
+	}
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.InvolvedObject.Kind = "Pod"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	ev.Type = "Normal"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	ev.InvolvedObject.Kind = "Node"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	require.Equal(t, []map[string]interface{}{warning, pod, {"default": "yes"}}, rec.layouts)
+	require.Equal(t, "*sinks.layoutRecorder", SinkType(sink))
+}
+
+func TestValidateConditionalLayouts(t *testing.T) {
+	require.NoError(t, validateConditionalLayouts(nil))
+	require.Error(t, validateConditionalLayouts([]ConditionalLayout{{Layout: map[string]interface{}{}}}))
+	require.Error(t, validateConditionalLayouts([]ConditionalLayout{{When: "{{ .Type", Layout: map[string]interface{}{}}}))
+	require.Error(t, validateConditionalLayouts([]ConditionalLayout{{When: "{{ .Type }}"}}))
+}
+
+func TestAlertmanagerLayoutPreset(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "default"
+	ev.InvolvedObject.Name = "nginx"
+	ev.FirstTimestamp = v1.Time{Time: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	res, err := convertLayoutTemplate(alertmanagerLayout(), ev)
+	require.NoError(t, err)
+	require.Equal(t, "4", res["version"])
+
+	alerts := res["alerts"].([]interface{})
+	require.Len(t, alerts, 1)
+	alert := alerts[0].(map[string]interface{})
+	require.Equal(t, "2023-01-02T03:04:05Z", alert["startsAt"])
+
+	labels := alert["labels"].(map[string]interface{})
+	require.Equal(t, "BackOff", labels["alertname"])
+	require.Equal(t, "warning", labels["severity"])
+	require.Equal(t, "default", labels["namespace"])
+	require.Equal(t, "Back-off restarting failed container", alert["annotations"].(map[string]interface{})["description"])
+}
@@ -0,0 +1,189 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// TemplateConfig sets the timezone and the locale of the timestamp and humanize template functions of a receiver
+type TemplateConfig struct {
+	// Timezone is an IANA name like Europe/Berlin, UTC by default
+	Timezone string `yaml:"timezone"`
+	// Locale is one of en (default), en-US, en-GB, de, fr and es
+	Locale string `yaml:"locale"`
+}
+
+func (c *TemplateConfig) validate() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("template.timezone: %w", err)
+	}
+	if _, ok := languages[c.Locale]; !ok && c.Locale != "" {
+		return fmt.Errorf("template.locale: unknown locale %s", c.Locale)
+	}
+	return nil
+}
+
+// language holds how a locale writes the timestamps and the durations
+type language struct {
+	layout string
+	// units are the singular and plural names of seconds, minutes, hours and days
+	units [4][2]string
+	ago   string
+	in    string
+}
+
+var languages = map[string]language{
+	"en": {
+		layout: "2006-01-02 15:04:05 MST",
+		units:  [4][2]string{{"second", "seconds"}, {"minute", "minutes"}, {"hour", "hours"}, {"day", "days"}},
+		ago:    "%s ago",
+		in:     "in %s",
+	},
+	"en-US": {
+		layout: "Jan 2, 2006 3:04:05 PM MST",
+		units:  [4][2]string{{"second", "seconds"}, {"minute", "minutes"}, {"hour", "hours"}, {"day", "days"}},
+		ago:    "%s ago",
+		in:     "in %s",
+	},
+	"en-GB": {
+		layout: "2 Jan 2006 15:04:05 MST",
+		units:  [4][2]string{{"second", "seconds"}, {"minute", "minutes"}, {"hour", "hours"}, {"day", "days"}},
+		ago:    "%s ago",
+		in:     "in %s",
+	},
+	"de": {
+		layout: "02.01.2006 15:04:05 MST",
+		units:  [4][2]string{{"Sekunde", "Sekunden"}, {"Minute", "Minuten"}, {"Stunde", "Stunden"}, {"Tag", "Tagen"}},
+		ago:    "vor %s",
+		in:     "in %s",
+	},
+	"fr": {
+		layout: "02/01/2006 15:04:05 MST",
+		units:  [4][2]string{{"seconde", "secondes"}, {"minute", "minutes"}, {"heure", "heures"}, {"jour", "jours"}},
+		ago:    "il y a %s",
+		in:     "dans %s",
+	},
+	"es": {
+		layout: "02/01/2006 15:04:05 MST",
+		units:  [4][2]string{{"segundo", "segundos"}, {"minuto", "minutos"}, {"hora", "horas"}, {"día", "días"}},
+		ago:    "hace %s",
+		in:     "dentro de %s",
+	},
+}
+
+func languageOf(locale kube.Locale) language {
+	if l, ok := languages[locale.Language]; ok {
+		return l
+	}
+	return languages["en"]
+}
+
+// templateTime accepts the timestamps of the events, metav1.Time, metav1.MicroTime and time.Time, or pointers to them
+func templateTime(value interface{}) (time.Time, error) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case metav1.Time:
+		return t.Time, nil
+	case *metav1.Time:
+		if t != nil {
+			return t.Time, nil
+		}
+	case metav1.MicroTime:
+		return t.Time, nil
+	case *metav1.MicroTime:
+		if t != nil {
+			return t.Time, nil
+		}
+	default:
+		return time.Time{}, fmt.Errorf("cannot use %T as a time", value)
+	}
+	return time.Time{}, nil
+}
+
+// localeFuncs returns the template functions formatting the times in the timezone and the language of the locale
+func localeFuncs(locale kube.Locale) map[string]interface{} {
+	location := locale.Location
+	if location == nil {
+		location = time.UTC
+	}
+	lang := languageOf(locale)
+	return map[string]interface{}{
+		// timestamp formats the time with the layout of the locale, or the given Go layout
+		"timestamp": func(value interface{}, layout ...string) (string, error) {
+			t, err := templateTime(value)
+			if err != nil || t.IsZero() {
+				return "", err
+			}
+			if len(layout) > 0 {
+				return t.In(location).Format(layout[0]), nil
+			}
+			return t.In(location).Format(lang.layout), nil
+		},
+		// humanize writes the time relative to now, like "5 minutes ago"
+		"humanize": func(value interface{}) (string, error) {
+			t, err := templateTime(value)
+			if err != nil || t.IsZero() {
+				return "", err
+			}
+			return humanize(clock.Since(t), lang), nil
+		},
+	}
+}
+
+func humanize(d time.Duration, lang language) string {
+	format := lang.ago
+	if d < 0 {
+		d, format = -d, lang.in
+	}
+	unit, n := 0, int64(d/time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		unit, n = 3, int64(d/(24*time.Hour))
+	case d >= time.Hour:
+		unit, n = 2, int64(d/time.Hour)
+	case d >= time.Minute:
+		unit, n = 1, int64(d/time.Minute)
+	}
+	name := lang.units[unit][1]
+	if n == 1 {
+		name = lang.units[unit][0]
+	}
+	return strings.TrimSpace(fmt.Sprintf(format, fmt.Sprintf("%d %s", n, name)))
+}
+
+// localeSink sets the locale of the receiver on the events, for the templates of the sink
+type localeSink struct {
+	Sink
+	locale kube.Locale
+}
+
+func newLocaleSink(s Sink, cfg *TemplateConfig) (Sink, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &localeSink{Sink: s, locale: kube.Locale{Location: location, Language: cfg.Locale}}, nil
+}
+
+func (l *localeSink) Unwrap() Sink {
+	return l.Sink
+}
+
+func (l *localeSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	// A shallow copy, the event is shared with the other receivers
+	localized := *ev
+	localized.Locale = l.locale
+	return l.Sink.Send(ctx, &localized)
+}
@@ -0,0 +1,212 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// LogScaleConfig sends the events in batches to Falcon LogScale, formerly Humio, authenticated with an ingest token.
+// Without a parser the events are sent to the structured ingest API with their fields as attributes. With a parser they
+// are sent as JSON messages to the unstructured ingest API, which parses them with it, unless the ingest token has a
+// parser assigned, which takes precedence. The tags are templates, the events of a batch are sent in one request per
+// distinct tags.
+type LogScaleConfig struct {
+	// URL is the base URL of LogScale, e.g. https://cloud.community.humio.com
+	URL    string                 `yaml:"url"`
+	Token  string                 `yaml:"token"`
+	Parser string                 `yaml:"parser,omitempty"`
+	Tags   map[string]string      `yaml:"tags,omitempty"`
+	Layout map[string]interface{} `yaml:"layout"`
+	TLS    TLS                    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+type LogScale struct {
+	cfg         *LogScaleConfig
+	client      *http.Client
+	batchWriter *batch.Writer
+}
+
+type logScaleRecord struct {
+	tags      map[string]string
+	timestamp time.Time
+	body      []byte
+}
+
+type logScaleStructuredEvents struct {
+	Tags   map[string]string         `json:"tags,omitempty"`
+	Events []logScaleStructuredEvent `json:"events"`
+}
+
+type logScaleStructuredEvent struct {
+	Timestamp  string          `json:"timestamp"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+type logScaleUnstructuredEvents struct {
+	Type     string            `json:"type"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Messages []string          `json:"messages"`
+}
+
+func NewLogScaleSink(cfg *LogScaleConfig) (*LogScale, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, errors.New("logscale.url and logscale.token config options must be non-empty")
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	l := &LogScale{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+	}
+	l.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		l.write,
+	)
+	l.batchWriter.Start()
+	return l, nil
+}
+
+func (l *LogScale) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	body, err := serializeEventWithLayout(resolveLayout(ctx, l.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]string, len(l.cfg.Tags))
+	for name, text := range l.cfg.Tags {
+		value, err := GetString(ev, text)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			tags[name] = value
+		}
+	}
+	l.batchWriter.Submit(&logScaleRecord{tags: tags, timestamp: time.UnixMilli(ev.GetTimestampMs()), body: body})
+	return nil
+}
+
+// logScaleTagsKey identifies the rendered tags of a record
+func logScaleTagsKey(tags map[string]string) string {
+	var sb strings.Builder
+	for _, k := range sortedKeys(tags) {
+		sb.WriteString(k + "=" + tags[k] + "\x00")
+	}
+	return sb.String()
+}
+
+// payload builds the request body of the records sharing the same tags
+func (l *LogScale) payload(records []*logScaleRecord) (string, interface{}) {
+	tags := records[0].tags
+	if l.cfg.Parser != "" {
+		events := logScaleUnstructuredEvents{Type: l.cfg.Parser, Fields: tags}
+		for _, r := range records {
+			events.Messages = append(events.Messages, string(r.body))
+		}
+		return "/api/v1/ingest/humio-unstructured", []logScaleUnstructuredEvents{events}
+	}
+	events := logScaleStructuredEvents{Tags: tags}
+	for _, r := range records {
+		events.Events = append(events.Events, logScaleStructuredEvent{
+			Timestamp:  r.timestamp.UTC().Format(time.RFC3339Nano),
+			Attributes: r.body,
+		})
+	}
+	return "/api/v1/ingest/humio-structured", []logScaleStructuredEvents{events}
+}
+
+func (l *LogScale) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	var keys []string
+	groups := make(map[string][]int)
+	for i, item := range items {
+		key := logScaleTagsKey(item.(*logScaleRecord).tags)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range keys {
+		records := make([]*logScaleRecord, 0, len(groups[key]))
+		for _, i := range groups[key] {
+			records = append(records, items[i].(*logScaleRecord))
+		}
+		path, payload := l.payload(records)
+		if err := l.post(ctx, path, payload); err != nil {
+			log.Error().Err(err).Int("events", len(records)).Msg("logscale: ingest failed")
+			continue
+		}
+		for _, i := range groups[key] {
+			res[i] = true
+		}
+	}
+	return res
+}
+
+func (l *LogScale) post(ctx context.Context, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(l.cfg.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+l.cfg.Token)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return httpResponseError(resp, respBody)
+}
+
+func (l *LogScale) Close() {
+	l.batchWriter.Stop()
+	l.client.CloseIdleConnections()
+}
@@ -0,0 +1,102 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestLogScale_Write(t *testing.T) {
+	type request struct {
+		path string
+		auth string
+		body []map[string]interface{}
+	}
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		requests = append(requests, request{path: r.URL.Path, auth: r.Header.Get("Authorization"), body: body})
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	newItems := func(s *LogScale) []interface{} {
+		var items []interface{}
+		for _, e := range []struct{ namespace, reason string }{{"default", "Started"}, {"kube-system", "BackOff"}, {"default", "Killing"}} {
+			ev := &kube.EnhancedEvent{}
+			ev.InvolvedObject.Namespace = e.namespace
+			ev.Reason = e.reason
+			body, err := serializeEventWithLayout(s.cfg.Layout, ev)
+			require.NoError(t, err)
+			items = append(items, &logScaleRecord{
+				tags:      map[string]string{"namespace": e.namespace},
+				timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				body:      body,
+			})
+		}
+		return items
+	}
+
+	s, err := NewLogScaleSink(&LogScaleConfig{
+		URL:    server.URL + "/",
+		Token:  "ingest-token",
+		Tags:   map[string]string{"namespace": "{{ .InvolvedObject.Namespace }}"},
+		Layout: map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// The events are grouped by their tags, in one request each
+	assert.Equal(t, []bool{true, true, true}, s.write(context.Background(), newItems(s)))
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/api/v1/ingest/humio-structured", requests[0].path)
+	assert.Equal(t, "Bearer ingest-token", requests[0].auth)
+	assert.Equal(t, []map[string]interface{}{{
+		"tags": map[string]interface{}{"namespace": "default"},
+		"events": []interface{}{
+			map[string]interface{}{"timestamp": "2024-01-02T03:04:05Z", "attributes": map[string]interface{}{"reason": "Started"}},
+			map[string]interface{}{"timestamp": "2024-01-02T03:04:05Z", "attributes": map[string]interface{}{"reason": "Killing"}},
+		},
+	}}, requests[0].body)
+
+	// With a parser the events are sent as messages for it
+	requests = nil
+	s.cfg.Parser = "kubernetes-events"
+	assert.Equal(t, []bool{true, true, true}, s.write(context.Background(), newItems(s)))
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/api/v1/ingest/humio-unstructured", requests[1].path)
+	assert.Equal(t, []map[string]interface{}{{
+		"type":     "kubernetes-events",
+		"fields":   map[string]interface{}{"namespace": "kube-system"},
+		"messages": []interface{}{`{"reason":"BackOff"}`},
+	}}, requests[1].body)
+}
+
+func TestLogScale_Throttled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s, err := NewLogScaleSink(&LogScaleConfig{URL: server.URL, Token: "ingest-token"})
+	require.NoError(t, err)
+	defer s.Close()
+
+	items := []interface{}{&logScaleRecord{body: []byte(`{}`)}}
+	assert.Equal(t, []bool{false}, s.write(context.Background(), items))
+
+	_, err = NewLogScaleSink(&LogScaleConfig{URL: server.URL})
+	assert.Error(t, err)
+}
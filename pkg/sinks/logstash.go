@@ -1 +1,71 @@
 package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// LogstashConfig sends the events as JSON lines to the tcp input of Logstash with the json_lines codec, over TCP or
+// TLS. The connection is kept open with keep-alive probes and dialed again after a failure.
+type LogstashConfig struct {
+	// Address is the host:port of the tcp input
+	Address string `yaml:"address"`
+	// Protocol is tcp (default) or tls
+	Protocol         string                 `yaml:"protocol"`
+	Layout           map[string]interface{} `yaml:"layout"`
+	KeepAliveSeconds int                    `yaml:"keepAliveSeconds"`
+	TimeoutSeconds   int                    `yaml:"timeoutSeconds"`
+	TLS              TLS                    `yaml:"tls"`
+}
+
+type Logstash struct {
+	cfg    *LogstashConfig
+	writer *connWriter
+}
+
+func NewLogstashSink(cfg *LogstashConfig) (Sink, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("logstash.address config option must be non-empty")
+	}
+	if cfg.KeepAliveSeconds == 0 {
+		cfg.KeepAliveSeconds = 30
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	l := &Logstash{cfg: cfg}
+	switch cfg.Protocol {
+	case "", "tcp":
+		l.writer = newConnWriter("tcp", cfg.Address, nil, timeout)
+	case "tls":
+		tlsClientConfig, err := setupTLS(&cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		l.writer = newConnWriter("tcp", cfg.Address, tlsClientConfig, timeout)
+	default:
+		return nil, fmt.Errorf("logstash.protocol must be tcp or tls, got %q", cfg.Protocol)
+	}
+	l.writer.keepAlive = time.Duration(cfg.KeepAliveSeconds) * time.Second
+	// Logstash never writes to the connection, so a restarted Logstash is noticed before an event is lost
+	l.writer.detectClose = true
+	return l, nil
+}
+
+func (l *Logstash) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := serializeEventWithLayout(resolveLayout(ctx, l.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+	return l.writer.Write(ctx, append(line, '\n'))
+}
+
+func (l *Logstash) Close() {
+	l.writer.Close()
+}
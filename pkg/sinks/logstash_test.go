@@ -0,0 +1,43 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestLogstash_Reconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s, err := NewLogstashSink(&LogstashConfig{
+		Address: listener.Addr().String(),
+		Layout:  map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, reason := range []string{"BackOff", "Pulled"} {
+		ev := &kube.EnhancedEvent{}
+		ev.Reason = reason
+		require.NoError(t, s.Send(context.Background(), ev))
+
+		// Logstash restarts after every line, the next event goes to a new connection
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		line, err := bufio.NewReader(conn).ReadBytes('\n')
+		require.NoError(t, err)
+		var got map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &got))
+		assert.Equal(t, reason, got["reason"])
+		conn.Close()
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -43,10 +42,7 @@ func NewLoki(cfg *LokiConfig) (Sink, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup TLS: %w", err)
 	}
-	return &Loki{cfg: cfg, transport: &http.Transport{
-		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: tlsClientConfig,
-	}}, nil
+	return &Loki{cfg: cfg, transport: newHTTPTransport(tlsClientConfig)}, nil
 }
 
 func generateTimestamp() string {
@@ -54,7 +50,7 @@ func generateTimestamp() string {
 }
 
 func (l *Loki) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
-	eventBody, err := serializeEventWithLayout(l.cfg.Layout, ev)
+	eventBody, err := serializeEventWithLayout(resolveLayout(ctx, l.cfg.Layout), ev)
 	if err != nil {
 		return err
 	}
@@ -82,7 +78,7 @@ func (l *Loki) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 			log.Debug().Err(err).Msgf("parse template failed: %s", v)
 			req.Header.Add(k, v)
 		} else {
-			log.Debug().Msgf("request header: {%s: %s}", k, realValue)
+			log.Debug().Msgf("request header: {%s: %s}", k, redactHeader(k, realValue))
 			req.Header.Add(k, realValue)
 		}
 	}
@@ -100,8 +96,8 @@ func (l *Loki) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		return err
 	}
 
-	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-		return errors.New("not successfull (2xx) response: " + string(body))
+	if err := httpResponseError(resp, body); err != nil {
+		return err
 	}
 
 	return nil
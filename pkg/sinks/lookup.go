@@ -0,0 +1,203 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+)
+
+// LookupConfig enables the template functions reading small bits of the cluster state at render time, e.g. the
+// webhook URL of a team stored in a ConfigMap of its namespace. The values are cached and the requests rate limited,
+// so a burst of events does not turn into a burst of API requests. Only the data of ConfigMaps and the annotations of
+// Secrets can be read, never the data of Secrets.
+type LookupConfig struct {
+	// Namespaces are the namespaces the lookups may read, all if empty
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	// ServiceAccount is impersonated by the lookups as "namespace/name", so their access is scoped by its RBAC rather
+	// than the one of the exporter
+	ServiceAccount string `yaml:"serviceAccount,omitempty"`
+	// CacheTTLSeconds is how long the values, and the objects not found, are cached, 60 by default
+	CacheTTLSeconds int `yaml:"cacheTTLSeconds,omitempty"`
+	// QPS and Burst limit the requests to the API, 5 and 10 by default
+	QPS   float32 `yaml:"qps,omitempty"`
+	Burst int     `yaml:"burst,omitempty"`
+	// TimeoutSeconds bounds a lookup, including the wait for the rate limiter, 5 by default
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty"`
+}
+
+func (c *LookupConfig) Validate() error {
+	if c.CacheTTLSeconds < 0 || c.QPS < 0 || c.Burst < 0 || c.TimeoutSeconds < 0 {
+		return errors.New("cacheTTLSeconds, qps, burst and timeoutSeconds must not be negative")
+	}
+	if c.ServiceAccount != "" {
+		if namespace, name, ok := strings.Cut(c.ServiceAccount, "/"); !ok || namespace == "" || name == "" {
+			return fmt.Errorf("serviceAccount must be namespace/name, got %q", c.ServiceAccount)
+		}
+	}
+	return nil
+}
+
+// maxLookupCacheEntries bounds the cache, the expired entries are removed when it is full
+const maxLookupCacheEntries = 1000
+
+type lookupEntry struct {
+	value   string
+	expires time.Time
+}
+
+// Lookups reads the ConfigMaps and the Secrets for the template functions
+type Lookups struct {
+	cfg     *LookupConfig
+	client  kubernetes.Interface
+	limiter flowcontrol.RateLimiter
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[string]lookupEntry
+}
+
+// NewLookups returns the lookups with a client of their own, impersonating the service account if configured
+func NewLookups(cfg *LookupConfig, kubecfg *rest.Config) (*Lookups, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	kubecfg = rest.CopyConfig(kubecfg)
+	if cfg.ServiceAccount != "" {
+		namespace, name, _ := strings.Cut(cfg.ServiceAccount, "/")
+		kubecfg.Impersonate = rest.ImpersonationConfig{UserName: "system:serviceaccount:" + namespace + ":" + name}
+	}
+	client, err := kubernetes.NewForConfig(kubecfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return newLookups(cfg, client), nil
+}
+
+func newLookups(cfg *LookupConfig, client kubernetes.Interface) *Lookups {
+	qps, burst := cfg.QPS, cfg.Burst
+	if qps == 0 {
+		qps = 5
+	}
+	if burst == 0 {
+		burst = 10
+	}
+	l := &Lookups{
+		cfg:     cfg,
+		client:  client,
+		limiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		ttl:     time.Duration(cfg.CacheTTLSeconds) * time.Second,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+		cache:   make(map[string]lookupEntry),
+	}
+	if l.ttl == 0 {
+		l.ttl = time.Minute
+	}
+	if l.timeout == 0 {
+		l.timeout = 5 * time.Second
+	}
+	return l
+}
+
+// lookups is used by the template functions, they fail if it is not set
+var lookups *Lookups
+
+// SetLookups enables the lookup template functions
+func SetLookups(l *Lookups) {
+	lookups = l
+}
+
+// get returns the cached value of the key or reads it with fetch, an object not found is an empty value
+func (l *Lookups) get(namespace, key string, fetch func(ctx context.Context) (string, error)) (string, error) {
+	if len(l.cfg.Namespaces) > 0 && !slices.Contains(l.cfg.Namespaces, namespace) {
+		return "", fmt.Errorf("lookups are not allowed in the namespace %q", namespace)
+	}
+
+	l.mu.Lock()
+	entry, ok := l.cache[key]
+	l.mu.Unlock()
+	now := clock.Now()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	if err := l.limiter.Wait(ctx); err != nil {
+		return "", fmt.Errorf("lookup of %s rate limited: %w", key, err)
+	}
+	value, err := fetch(ctx)
+	if apierrors.IsNotFound(err) {
+		value, err = "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("lookup of %s failed: %w", key, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.cache) >= maxLookupCacheEntries {
+		for k, e := range l.cache {
+			if !now.Before(e.expires) {
+				delete(l.cache, k)
+			}
+		}
+		if len(l.cache) >= maxLookupCacheEntries {
+			l.cache = make(map[string]lookupEntry)
+		}
+	}
+	l.cache[key] = lookupEntry{value: value, expires: now.Add(l.ttl)}
+	return value, nil
+}
+
+func (l *Lookups) configMapValue(namespace, name, key string) (string, error) {
+	return l.get(namespace, "configmap/"+namespace+"/"+name+"/"+key, func(ctx context.Context) (string, error) {
+		cm, err := l.client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return cm.Data[key], nil
+	})
+}
+
+func (l *Lookups) secretAnnotation(namespace, name, annotation string) (string, error) {
+	return l.get(namespace, "secret/"+namespace+"/"+name+"/"+annotation, func(ctx context.Context) (string, error) {
+		secret, err := l.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return secret.Annotations[annotation], nil
+	})
+}
+
+var errLookupsDisabled = errors.New("the lookup template functions are disabled, set templateLookups in the config")
+
+// lookupConfigMapValue is available in templates as `lookupConfigMapValue "namespace" "name" "key"`, it returns an
+// empty string if the ConfigMap or the key does not exist
+func lookupConfigMapValue(namespace, name, key string) (string, error) {
+	if lookups == nil {
+		return "", errLookupsDisabled
+	}
+	return lookups.configMapValue(namespace, name, key)
+}
+
+// lookupSecretAnnotation is available in templates as `lookupSecretAnnotation "namespace" "name" "annotation"`, it
+// returns an empty string if the Secret or the annotation does not exist
+func lookupSecretAnnotation(namespace, name, annotation string) (string, error) {
+	if lookups == nil {
+		return "", errLookupsDisabled
+	}
+	return lookups.secretAnnotation(namespace, name, annotation)
+}
@@ -0,0 +1,62 @@
+package sinks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestLookups(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "alerting"},
+			Data:       map[string]string{"webhook": "https://hooks.example.com/team-a"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "pager", Annotations: map[string]string{"owner": "alice"}},
+			Data:       map[string][]byte{"token": []byte("secret")},
+		},
+	)
+	SetLookups(newLookups(&LookupConfig{Namespaces: []string{"team-a", "team-b"}}, client))
+	defer SetLookups(nil)
+
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.Namespace = "team-a"
+	value, err := GetString(ev, `{{ lookupConfigMapValue .InvolvedObject.Namespace "alerting" "webhook" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/team-a", value)
+
+	value, err = GetString(ev, `{{ lookupSecretAnnotation .InvolvedObject.Namespace "pager" "owner" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", value)
+
+	// The values are cached
+	_, err = GetString(ev, `{{ lookupConfigMapValue "team-a" "alerting" "webhook" }}`)
+	require.NoError(t, err)
+	assert.Len(t, client.Actions(), 2)
+
+	// Missing objects and keys are empty
+	value, err = GetString(ev, `{{ lookupConfigMapValue "team-b" "alerting" "webhook" | default "none" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "none", value)
+
+	// Only the allowed namespaces can be read
+	_, err = GetString(ev, `{{ lookupConfigMapValue "kube-system" "alerting" "webhook" }}`)
+	assert.Error(t, err)
+
+	SetLookups(nil)
+	_, err = GetString(ev, `{{ lookupConfigMapValue "team-a" "alerting" "webhook" }}`)
+	assert.ErrorContains(t, err, "disabled")
+}
+
+func TestLookupConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&LookupConfig{ServiceAccount: "monitoring/event-exporter-lookups"}).Validate())
+	assert.Error(t, (&LookupConfig{ServiceAccount: "event-exporter-lookups"}).Validate())
+	assert.Error(t, (&LookupConfig{QPS: -1}).Validate())
+}
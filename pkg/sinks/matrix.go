@@ -0,0 +1,140 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// MatrixConfig posts the events as messages to a Matrix room using the client-server API. The room, the message and
+// the formatted message are templates. The formatted message is HTML, clients without HTML support show the message.
+type MatrixConfig struct {
+	Homeserver       string `yaml:"homeserver"`
+	AccessToken      string `yaml:"accessToken"`
+	RoomID           string `yaml:"roomId"`
+	Message          string `yaml:"message"`
+	FormattedMessage string `yaml:"formattedMessage"`
+	// MsgType is m.notice by default, which bots are expected to use, or m.text
+	MsgType string `yaml:"msgType"`
+	TLS     TLS    `yaml:"tls"`
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+type Matrix struct {
+	cfg    *MatrixConfig
+	client *http.Client
+	txn    atomic.Uint64
+}
+
+func NewMatrixSink(cfg *MatrixConfig) (Sink, error) {
+	if cfg.Homeserver == "" {
+		return nil, errors.New("matrix.homeserver config option must be non-empty")
+	}
+	if cfg.RoomID == "" {
+		return nil, errors.New("matrix.roomId config option must be non-empty")
+	}
+	if cfg.AccessToken == "" {
+		return nil, errors.New("matrix.accessToken config option must be non-empty")
+	}
+	if cfg.Message == "" {
+		cfg.Message = "{{ .Message }}"
+	}
+	switch cfg.MsgType {
+	case "":
+		cfg.MsgType = "m.notice"
+	case "m.notice", "m.text":
+	default:
+		return nil, fmt.Errorf("matrix.msgType must be m.notice or m.text, got %q", cfg.MsgType)
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Matrix{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (m *Matrix) message(ev *kube.EnhancedEvent) (*matrixMessage, error) {
+	msg := &matrixMessage{MsgType: m.cfg.MsgType}
+
+	var err error
+	if msg.Body, err = GetString(ev, m.cfg.Message); err != nil {
+		return nil, err
+	}
+	if m.cfg.FormattedMessage != "" {
+		if msg.FormattedBody, err = GetString(ev, m.cfg.FormattedMessage); err != nil {
+			return nil, err
+		}
+		msg.Format = "org.matrix.custom.html"
+	}
+	return msg, nil
+}
+
+// transactionID identifies the message, the homeserver ignores a retried request with the same ID
+func (m *Matrix) transactionID(ev *kube.EnhancedEvent) string {
+	if ev.UID != "" {
+		return fmt.Sprintf("%s-%s-%d", ev.UID, ev.ResourceVersion, ev.Count)
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(m.txn.Add(1), 36)
+}
+
+func (m *Matrix) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	room, err := GetString(ev, m.cfg.RoomID)
+	if err != nil {
+		return err
+	}
+	msg, err := m.message(ev)
+	if err != nil {
+		return err
+	}
+	reqBody, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.cfg.Homeserver, "/"), url.PathEscape(room), url.PathEscape(m.transactionID(ev)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.cfg.AccessToken)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (m *Matrix) Close() {
+	m.client.CloseIdleConnections()
+}
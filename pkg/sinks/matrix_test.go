@@ -0,0 +1,57 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestMatrix_Send(t *testing.T) {
+	var path, auth string
+	var received matrixMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		auth = r.Header.Get("Authorization")
+		assert.Equal(t, http.MethodPut, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_, _ = w.Write([]byte(`{"event_id":"$abc"}`))
+	}))
+	defer ts.Close()
+
+	sink, err := NewMatrixSink(&MatrixConfig{
+		Homeserver:       ts.URL + "/",
+		AccessToken:      "syt_token",
+		RoomID:           "!ops:example.com",
+		FormattedMessage: "<b>{{ .Reason }}</b> {{ .Message }}",
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.UID = "1234"
+	ev.ResourceVersion = "42"
+	ev.Count = 3
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "/_matrix/client/v3/rooms/%21ops:example.com/send/m.room.message/1234-42-3", path)
+	assert.Equal(t, "Bearer syt_token", auth)
+	assert.Equal(t, matrixMessage{
+		MsgType:       "m.notice",
+		Body:          "Back-off restarting failed container",
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "<b>BackOff</b> Back-off restarting failed container",
+	}, received)
+}
+
+func TestMatrix_InvalidMsgType(t *testing.T) {
+	_, err := NewMatrixSink(&MatrixConfig{Homeserver: "https://matrix.example.com", AccessToken: "t", RoomID: "!r", MsgType: "m.image"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const defaultMezmoURL = "https://logs.mezmo.com/logs/ingest"
+
+// MezmoConfig sends the events in batches to the ingestion API of Mezmo, formerly LogDNA. The line of an event is its
+// layout, or the event as JSON. The metadata of a line has the involved object and its labels, with the Meta templates
+// added or overriding them.
+type MezmoConfig struct {
+	IngestionKey string `yaml:"ingestionKey" secret:"true"`
+	// URL is the ingestion endpoint, https://logs.mezmo.com/logs/ingest by default
+	URL      string   `yaml:"url,omitempty"`
+	Hostname string   `yaml:"hostname"`
+	Tags     []string `yaml:"tags,omitempty"`
+	// App is a template, kubernetes-event-exporter by default
+	App string `yaml:"app,omitempty"`
+	// Level is a template, WARN for the warnings and INFO otherwise by default
+	Level  string                 `yaml:"level,omitempty"`
+	Meta   map[string]string      `yaml:"meta,omitempty"`
+	Layout map[string]interface{} `yaml:"layout"`
+	TLS    TLS                    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+type Mezmo struct {
+	cfg         *MezmoConfig
+	client      *http.Client
+	batchWriter *batch.Writer
+}
+
+type mezmoLine struct {
+	Timestamp int64                  `json:"timestamp"`
+	Line      string                 `json:"line"`
+	App       string                 `json:"app"`
+	Level     string                 `json:"level"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+}
+
+func NewMezmoSink(cfg *MezmoConfig) (*Mezmo, error) {
+	if cfg.IngestionKey == "" || cfg.Hostname == "" {
+		return nil, errors.New("mezmo.ingestionKey and mezmo.hostname config options must be non-empty")
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultMezmoURL
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	m := &Mezmo{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+	}
+	m.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		m.write,
+	)
+	m.batchWriter.Start()
+	return m, nil
+}
+
+func (m *Mezmo) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := m.line(ctx, ev)
+	if err != nil {
+		return err
+	}
+	m.batchWriter.Submit(line)
+	return nil
+}
+
+func (m *Mezmo) line(ctx context.Context, ev *kube.EnhancedEvent) (*mezmoLine, error) {
+	body, err := serializeEventWithLayout(resolveLayout(ctx, m.cfg.Layout), ev)
+	if err != nil {
+		return nil, err
+	}
+	line := &mezmoLine{Timestamp: ev.GetTimestampMs(), Line: string(body), App: "kubernetes-event-exporter", Level: "INFO"}
+	if ev.Type == "Warning" {
+		line.Level = "WARN"
+	}
+	if m.cfg.App != "" {
+		if line.App, err = GetString(ev, m.cfg.App); err != nil {
+			return nil, err
+		}
+	}
+	if m.cfg.Level != "" {
+		if line.Level, err = GetString(ev, m.cfg.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	line.Meta = map[string]interface{}{
+		"namespace": ev.InvolvedObject.Namespace,
+		"kind":      ev.InvolvedObject.Kind,
+		"name":      ev.InvolvedObject.Name,
+		"reason":    ev.Reason,
+	}
+	if ev.ClusterName != "" {
+		line.Meta["cluster"] = ev.ClusterName
+	}
+	if len(ev.InvolvedObject.Labels) > 0 {
+		line.Meta["labels"] = ev.InvolvedObject.Labels
+	}
+	for key, text := range m.cfg.Meta {
+		value, err := GetString(ev, text)
+		if err != nil {
+			return nil, err
+		}
+		line.Meta[key] = value
+	}
+	return line, nil
+}
+
+func (m *Mezmo) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	lines := make([]*mezmoLine, len(items))
+	for i, item := range items {
+		lines[i] = item.(*mezmoLine)
+	}
+	if err := m.post(ctx, lines); err != nil {
+		log.Error().Err(err).Int("events", len(lines)).Msg("mezmo: ingest failed")
+		return res
+	}
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+func (m *Mezmo) post(ctx context.Context, lines []*mezmoLine) error {
+	body, err := json.Marshal(map[string]interface{}{"lines": lines})
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("hostname", m.cfg.Hostname)
+	query.Set("now", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if len(m.cfg.Tags) > 0 {
+		query.Set("tags", strings.Join(m.cfg.Tags, ","))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.URL+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.SetBasicAuth(m.cfg.IngestionKey, "")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return httpResponseError(resp, respBody)
+}
+
+func (m *Mezmo) Close() {
+	m.batchWriter.Stop()
+	m.client.CloseIdleConnections()
+}
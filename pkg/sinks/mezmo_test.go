@@ -0,0 +1,71 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestMezmo_Write(t *testing.T) {
+	var query map[string][]string
+	var user string
+	var body struct {
+		Lines []mezmoLine `json:"lines"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		user, _, _ = r.BasicAuth()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer server.Close()
+
+	m, err := NewMezmoSink(&MezmoConfig{
+		IngestionKey: "ingestion-key",
+		URL:          server.URL + "/logs/ingest",
+		Hostname:     "prod-eu",
+		Tags:         []string{"kubernetes", "events"},
+		App:          "{{ .Source.Component }}",
+		Meta:         map[string]string{"team": `{{ index .InvolvedObject.Labels "team" }}`},
+		Layout:       map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Source.Component = "kubelet"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+	ev.InvolvedObject.Labels = map[string]string{"app": "checkout", "team": "payments"}
+	line, err := m.line(context.Background(), ev)
+	require.NoError(t, err)
+
+	assert.Equal(t, []bool{true}, m.write(context.Background(), []interface{}{line}))
+	assert.Equal(t, "ingestion-key", user)
+	assert.Equal(t, "prod-eu", query["hostname"][0])
+	assert.Equal(t, "kubernetes,events", query["tags"][0])
+	require.Len(t, body.Lines, 1)
+	assert.Equal(t, `{"reason":"BackOff"}`, body.Lines[0].Line)
+	assert.Equal(t, "kubelet", body.Lines[0].App)
+	assert.Equal(t, "WARN", body.Lines[0].Level)
+	assert.Equal(t, map[string]interface{}{
+		"namespace": "shop",
+		"kind":      "Pod",
+		"name":      "checkout-1",
+		"reason":    "BackOff",
+		"labels":    map[string]interface{}{"app": "checkout", "team": "payments"},
+		"team":      "payments",
+	}, body.Lines[0].Meta)
+
+	_, err = NewMezmoSink(&MezmoConfig{IngestionKey: "ingestion-key"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package sinks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// MQTTConfig publishes events to an MQTT broker. The topic is a template, so events can be published to a topic per
+// namespace or reason.
+type MQTTConfig struct {
+	// Brokers are URLs like tcp://mosquitto:1883 or ssl://mosquitto:8883
+	Brokers  []string               `yaml:"brokers"`
+	ClientID string                 `yaml:"clientId"`
+	Username string                 `yaml:"username"`
+	Password string                 `yaml:"password"`
+	Topic    string                 `yaml:"topic"`
+	QoS      byte                   `yaml:"qos"`
+	Retained bool                   `yaml:"retained"`
+	TLS      TLS                    `yaml:"tls"`
+	Layout   map[string]interface{} `yaml:"layout"`
+	// TimeoutSeconds limits connecting and waiting for the broker to acknowledge a message
+	TimeoutSeconds   int `yaml:"timeoutSeconds"`
+	KeepAliveSeconds int `yaml:"keepAliveSeconds"`
+}
+
+type MQTT struct {
+	cfg     *MQTTConfig
+	client  mqtt.Client
+	timeout time.Duration
+}
+
+func NewMQTTSink(cfg *MQTTConfig) (*MQTT, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("mqtt.brokers config option must be non-empty")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("mqtt.topic config option must be non-empty")
+	}
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("mqtt.qos must be 0, 1 or 2, got %d", cfg.QoS)
+	}
+	if cfg.ClientID == "" {
+		hostname, _ := os.Hostname()
+		cfg.ClientID = "kubernetes-event-exporter-" + hostname
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	if cfg.KeepAliveSeconds == 0 {
+		cfg.KeepAliveSeconds = 30
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	opts := mqtt.NewClientOptions().
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetTLSConfig(tlsClientConfig).
+		SetConnectTimeout(timeout).
+		SetWriteTimeout(timeout).
+		SetKeepAlive(time.Duration(cfg.KeepAliveSeconds) * time.Second).
+		SetAutoReconnect(true).
+		SetConnectRetry(false).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warn().Err(err).Msg("mqtt: connection lost, reconnecting")
+		})
+	for _, broker := range cfg.Brokers {
+		opts.AddBroker(broker)
+	}
+	if egressPolicy != nil {
+		opts.SetCustomOpenConnectionFn(mqttOpenConnection)
+	}
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(timeout) {
+		return nil, errors.New("mqtt: timeout connecting to the broker")
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt: cannot connect to the broker: %w", err)
+	}
+
+	log.Info().Strs("brokers", cfg.Brokers).Str("clientId", cfg.ClientID).Msg("mqtt: connected")
+	return &MQTT{cfg: cfg, client: client, timeout: timeout}, nil
+}
+
+// mqttOpenConnection dials the broker through the egress policy. Websockets are not supported, as their dialer cannot
+// be replaced.
+func mqttOpenConnection(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+	switch uri.Scheme {
+	case "mqtt", "tcp":
+		return egressPolicy.DialTimeout("tcp", uri.Host, options.ConnectTimeout)
+	case "ssl", "tls", "mqtts", "mqtt+ssl", "tcps":
+		conn, err := egressPolicy.DialTimeout("tcp", uri.Host, options.ConnectTimeout)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig := options.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+			ApplyTLSPolicy(tlsConfig)
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = uri.Hostname()
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		ctx, cancel := context.WithTimeout(context.Background(), options.ConnectTimeout)
+		defer cancel()
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	default:
+		return nil, fmt.Errorf("mqtt: scheme %q is not supported with an egress policy", uri.Scheme)
+	}
+}
+
+func (m *MQTT) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	topic, err := GetString(ev, m.cfg.Topic)
+	if err != nil {
+		return err
+	}
+
+	payload, err := serializeEventWithLayout(resolveLayout(ctx, m.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+
+	token := m.client.Publish(topic, m.cfg.QoS, m.cfg.Retained, payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(m.timeout):
+		return fmt.Errorf("mqtt: timeout publishing to %s", topic)
+	}
+}
+
+func (m *MQTT) Close() {
+	m.client.Disconnect(uint(m.timeout.Milliseconds()))
+}
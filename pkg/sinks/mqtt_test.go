@@ -0,0 +1,18 @@
+package sinks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMQTTSink_Validation(t *testing.T) {
+	_, err := NewMQTTSink(&MQTTConfig{Topic: "events"})
+	assert.ErrorContains(t, err, "mqtt.brokers")
+
+	_, err = NewMQTTSink(&MQTTConfig{Brokers: []string{"tcp://localhost:1883"}})
+	assert.ErrorContains(t, err, "mqtt.topic")
+
+	_, err = NewMQTTSink(&MQTTConfig{Brokers: []string{"tcp://localhost:1883"}, Topic: "events", QoS: 3})
+	assert.ErrorContains(t, err, "mqtt.qos")
+}
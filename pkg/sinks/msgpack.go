@@ -0,0 +1,306 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// msgpackEventTime is the EventTime extension of the Fluentd forward protocol, a timestamp with nanoseconds
+type msgpackEventTime time.Time
+
+// msgpackEncoder writes the subset of MessagePack needed for the generic JSON values of the events and the layouts.
+// The keys of the maps are sorted, so the encoding is stable.
+type msgpackEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *msgpackEncoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func (e *msgpackEncoder) Encode(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			e.buf.WriteByte(0xc3)
+		} else {
+			e.buf.WriteByte(0xc2)
+		}
+	case int:
+		e.encodeInt(int64(v))
+	case int32:
+		e.encodeInt(int64(v))
+	case int64:
+		e.encodeInt(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			e.encodeInt(int64(v))
+		} else {
+			e.buf.WriteByte(0xcf)
+			e.writeUint(v, 8)
+		}
+	case float64:
+		e.buf.WriteByte(0xcb)
+		e.writeUint(math.Float64bits(v), 8)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			e.encodeInt(i)
+		} else if f, err := v.Float64(); err == nil {
+			return e.Encode(f)
+		} else {
+			return e.Encode(v.String())
+		}
+	case string:
+		e.writeHeader(len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		e.buf.WriteString(v)
+	case []byte:
+		e.writeHeader(len(v), 0, 0, 0xc4, 0xc5, 0xc6)
+		e.buf.Write(v)
+	case msgpackEventTime:
+		t := time.Time(v)
+		// fixext8 of type 0
+		e.buf.Write([]byte{0xd7, 0x00})
+		e.writeUint(uint64(t.Unix()), 4)
+		e.writeUint(uint64(t.Nanosecond()), 4)
+	case []interface{}:
+		e.writeHeader(len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+	case []string:
+		e.writeHeader(len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			_ = e.Encode(item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		e.writeHeader(len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			_ = e.Encode(k)
+			if err := e.Encode(v[k]); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		e.writeHeader(len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range sortedKeys(v) {
+			_ = e.Encode(k)
+			_ = e.Encode(v[k])
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot encode %T", v)
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		e.buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		e.buf.WriteByte(byte(i))
+	default:
+		e.buf.WriteByte(0xd3)
+		e.writeUint(uint64(i), 8)
+	}
+}
+
+// writeHeader writes the type and the length of a string, binary, array or map, using the fixed format for the lengths
+// below fixLimit
+func (e *msgpackEncoder) writeHeader(n int, fix byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case n < fixLimit:
+		e.buf.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		e.buf.Write([]byte{code8, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(code16)
+		e.writeUint(uint64(n), 2)
+	default:
+		e.buf.WriteByte(code32)
+		e.writeUint(uint64(n), 4)
+	}
+}
+
+func (e *msgpackEncoder) writeUint(v uint64, size int) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	e.buf.Write(b[8-size:])
+}
+
+// msgpackMarshal encodes a single value
+func msgpackMarshal(v interface{}) ([]byte, error) {
+	e := &msgpackEncoder{}
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// msgpackDecode reads a single value from r without reading ahead, so r can be a connection. Integers are decoded as
+// int64 or uint64, maps as map[string]interface{} and extensions as their raw data.
+func msgpackDecode(r io.Reader) (interface{}, error) {
+	code, err := msgpackRead(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	c := code[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return msgpackReadString(r, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return msgpackDecodeArray(r, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return msgpackDecodeMap(r, int(c&0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := msgpackReadUint(r, 1<<(c-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackRead(r, int(n))
+	case 0xca:
+		v, err := msgpackReadUint(r, 4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := msgpackReadUint(r, 8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := msgpackReadUint(r, 1<<(c-0xcc))
+		if err != nil || v > math.MaxInt64 {
+			return v, err
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, err := msgpackReadUint(r, size)
+		// Sign extend
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		// fixext, the type byte and the data
+		data, err := msgpackRead(r, 1+1<<(c-0xd4))
+		if err != nil {
+			return nil, err
+		}
+		return data[1:], nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := msgpackReadUint(r, 1<<(c-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		data, err := msgpackRead(r, 1+int(n))
+		if err != nil {
+			return nil, err
+		}
+		return data[1:], nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := msgpackReadUint(r, 1<<(c-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackReadString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := msgpackReadUint(r, 2<<(c-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackDecodeArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := msgpackReadUint(r, 2<<(c-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackDecodeMap(r, int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unknown type 0x%x", c)
+}
+
+func msgpackDecodeArray(r io.Reader, n int) ([]interface{}, error) {
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func msgpackDecodeMap(r io.Reader, n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+		m[msgpackString(k)] = v
+	}
+	return m, nil
+}
+
+// msgpackString returns a decoded string or binary as a string, the forward protocol allows both for its fields
+func msgpackString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func msgpackReadString(r io.Reader, n int) (string, error) {
+	b, err := msgpackRead(r, n)
+	return string(b), err
+}
+
+func msgpackReadUint(r io.Reader, size int) (uint64, error) {
+	b, err := msgpackRead(r, size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v, nil
+}
+
+func msgpackRead(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
@@ -45,9 +45,7 @@ func NewOpenSearch(cfg *OpenSearchConfig) (*OpenSearch, error) {
 		Addresses: cfg.Hosts,
 		Username:  cfg.Username,
 		Password:  cfg.Password,
-		Transport: &http.Transport{
-			TLSClientConfig: tlsClientConfig,
-		},
+		Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
 	})
 	if err != nil {
 		return nil, err
@@ -85,14 +83,15 @@ func osFormatIndexName(pattern string, when time.Time) string {
 }
 
 func (e *OpenSearch) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, e.cfg.Layout)
 	var toSend []byte
 
 	if e.cfg.DeDot {
 		de := ev.DeDot()
 		ev = &de
 	}
-	if e.cfg.Layout != nil {
-		res, err := convertLayoutTemplate(e.cfg.Layout, ev)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
 		if err != nil {
 			return err
 		}
@@ -138,6 +137,13 @@ func (e *OpenSearch) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		if err != nil {
 			return err
 		}
+		// The cluster rejects requests with 429 when it is overloaded
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return &RetryableError{
+				Err:        fmt.Errorf("indexing throttled: %s", string(rb)),
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			}
+		}
 		log.Error().Msgf("Indexing failed: %s", string(rb))
 	}
 	return nil
@@ -0,0 +1,233 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultTraceParentAnnotation = "traceparent"
+	otelSeverityInfo             = 9
+	otelSeverityWarn             = 13
+)
+
+// OpenTelemetryConfig sends events as OTLP log records to an OTLP/HTTP endpoint (e.g. the OpenTelemetry Collector).
+// When the involved object carries a trace context in its annotations, the log record is correlated to the trace so
+// the event shows up inline with the distributed trace.
+type OpenTelemetryConfig struct {
+	// Endpoint is the full OTLP/HTTP logs URL, e.g. http://otel-collector:4318/v1/logs
+	Endpoint string            `yaml:"endpoint"`
+	Headers  map[string]string `yaml:"headers"`
+	TLS      TLS               `yaml:"tls"`
+	// TraceParentAnnotation holds a W3C traceparent value. Defaults to "traceparent".
+	TraceParentAnnotation string `yaml:"traceParentAnnotation"`
+	// TraceIDAnnotation and SpanIDAnnotation are used if the trace context is stored as separate hex values
+	TraceIDAnnotation string `yaml:"traceIDAnnotation"`
+	SpanIDAnnotation  string `yaml:"spanIDAnnotation"`
+	// OnlyCorrelated drops events that do not carry a trace context
+	OnlyCorrelated     bool              `yaml:"onlyCorrelated"`
+	ServiceName        string            `yaml:"serviceName"`
+	ResourceAttributes map[string]string `yaml:"resourceAttributes"`
+}
+
+type OpenTelemetry struct {
+	cfg       *OpenTelemetryConfig
+	transport *http.Transport
+}
+
+func NewOpenTelemetrySink(cfg *OpenTelemetryConfig) (Sink, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("opentelemetry: endpoint is required")
+	}
+	if cfg.TraceParentAnnotation == "" {
+		cfg.TraceParentAnnotation = defaultTraceParentAnnotation
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "kubernetes-event-exporter"
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+	return &OpenTelemetry{cfg: cfg, transport: newHTTPTransport(tlsClientConfig)}, nil
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+// traceContext returns the hex encoded trace and span IDs from the involved object annotations
+func (o *OpenTelemetry) traceContext(ev *kube.EnhancedEvent) (string, string) {
+	annotations := ev.InvolvedObject.Annotations
+	if tp, ok := annotations[o.cfg.TraceParentAnnotation]; ok {
+		// version-traceid-parentid-flags
+		parts := strings.Split(strings.TrimSpace(tp), "-")
+		if len(parts) == 4 && isHexID(parts[1], 32) && isHexID(parts[2], 16) {
+			return parts[1], parts[2]
+		}
+		log.Debug().Str("traceparent", tp).Msg("opentelemetry: ignoring malformed traceparent annotation")
+	}
+
+	if o.cfg.TraceIDAnnotation != "" {
+		traceID := annotations[o.cfg.TraceIDAnnotation]
+		if isHexID(traceID, 32) {
+			spanID := annotations[o.cfg.SpanIDAnnotation]
+			if !isHexID(spanID, 16) {
+				spanID = ""
+			}
+			return traceID, spanID
+		}
+	}
+	return "", ""
+}
+
+func isHexID(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return false
+	}
+	// All-zero IDs are invalid according to the W3C spec
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *OpenTelemetry) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	traceID, spanID := o.traceContext(ev)
+	if traceID == "" && o.cfg.OnlyCorrelated {
+		return nil
+	}
+
+	severity, severityText := otelSeverityInfo, "INFO"
+	if ev.Type == "Warning" {
+		severity, severityText = otelSeverityWarn, "WARN"
+	}
+
+	ts := strconv.FormatInt(ev.GetTimestampMs()*1000000, 10)
+	record := otlpLogRecord{
+		TimeUnixNano:         ts,
+		ObservedTimeUnixNano: generateTimestamp(),
+		SeverityNumber:       severity,
+		SeverityText:         severityText,
+		Body:                 otlpAnyValue{StringValue: ev.Message},
+		TraceID:              traceID,
+		SpanID:               spanID,
+		Attributes: otlpAttributes(map[string]string{
+			"k8s.event.reason":           ev.Reason,
+			"k8s.event.type":             ev.Type,
+			"k8s.event.uid":              string(ev.UID),
+			"k8s.event.count":            strconv.Itoa(int(ev.Count)),
+			"k8s.namespace.name":         ev.Namespace,
+			"k8s.object.kind":            ev.InvolvedObject.Kind,
+			"k8s.object.name":            ev.InvolvedObject.Name,
+			"k8s.object.uid":             string(ev.InvolvedObject.UID),
+			"k8s.event.source.component": ev.Source.Component,
+			"k8s.event.source.host":      ev.Source.Host,
+		}),
+	}
+
+	resource := map[string]string{"service.name": o.cfg.ServiceName}
+	if ev.ClusterName != "" {
+		resource["k8s.cluster.name"] = ev.ClusterName
+	}
+	for k, v := range o.cfg.ResourceAttributes {
+		resource[k] = v
+	}
+
+	payload := map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": otlpAttributes(resource)},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]string{"name": "kubernetes-event-exporter"},
+				"logRecords": []otlpLogRecord{record},
+			}},
+		}},
+	}
+
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.Endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.cfg.Headers {
+		req.Header.Add(k, v)
+	}
+
+	client := &http.Client{Transport: withRequestLogging(o.transport)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := httpResponseError(resp, body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// otlpAttributes converts a map to OTLP attributes, skipping empty values
+func otlpAttributes(m map[string]string) []otlpKeyValue {
+	attrs := make([]otlpKeyValue, 0, len(m))
+	for k, v := range m {
+		if v == "" {
+			continue
+		}
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	return attrs
+}
+
+func (o *OpenTelemetry) Close() {
+	o.transport.CloseIdleConnections()
+}
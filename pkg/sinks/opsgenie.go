@@ -2,22 +2,35 @@ package sinks
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/alert"
 	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/rs/zerolog/log"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
 type OpsgenieConfig struct {
-	ApiKey      string            `yaml:"apiKey"`
-	URL         client.ApiUrl     `yaml:"URL"`
-	Priority    string            `yaml:"priority"`
-	Message     string            `yaml:"message"`
-	Alias       string            `yaml:"alias"`
-	Description string            `yaml:"description"`
-	Tags        []string          `yaml:"tags"`
-	Details     map[string]string `yaml:"details"`
+	ApiKey string        `yaml:"apiKey"`
+	URL    client.ApiUrl `yaml:"URL"`
+	// Priority is a template, its result is looked up in PriorityMapping or used as is
+	Priority string `yaml:"priority"`
+	// PriorityMapping maps the result of the priority template to an Opsgenie priority, e.g. Warning: P2
+	PriorityMapping map[string]string `yaml:"priorityMapping,omitempty"`
+	Message         string            `yaml:"message"`
+	Alias           string            `yaml:"alias"`
+	Description     string            `yaml:"description"`
+	Tags            []string          `yaml:"tags"`
+	Details         map[string]string `yaml:"details"`
+	// CloseCondition is a template that should evaluate to a non-empty string for the events that resolve an alert.
+	// Instead of creating an alert, these events close the open alert with the same alias.
+	CloseCondition string `yaml:"closeCondition,omitempty"`
+	// CloseAlias is the alias of the alert to close, defaults to Alias
+	CloseAlias string `yaml:"closeAlias,omitempty"`
+	CloseNote  string `yaml:"closeNote,omitempty"`
 }
 
 type OpsgenieSink struct {
@@ -34,6 +47,21 @@ func NewOpsgenieSink(config *OpsgenieConfig) (Sink, error) {
 		config.Priority = "P3"
 	}
 
+	for k, v := range config.PriorityMapping {
+		if !isOpsgeniePriority(v) {
+			return nil, fmt.Errorf("opsgenie.priorityMapping[%s] must be one of P1-P5, got %q", k, v)
+		}
+	}
+
+	if config.CloseCondition != "" {
+		if config.CloseAlias == "" {
+			config.CloseAlias = config.Alias
+		}
+		if config.CloseAlias == "" {
+			return nil, errors.New("opsgenie.alias or opsgenie.closeAlias must be set to close alerts")
+		}
+	}
+
 	alertClient, err := alert.NewClient(&client.Config{
 		ApiKey:         config.ApiKey,
 		OpsGenieAPIURL: config.URL,
@@ -49,9 +77,75 @@ func NewOpsgenieSink(config *OpsgenieConfig) (Sink, error) {
 	}, nil
 }
 
+func isOpsgeniePriority(p string) bool {
+	switch alert.Priority(p) {
+	case alert.P1, alert.P2, alert.P3, alert.P4, alert.P5:
+		return true
+	}
+	return false
+}
+
+// priority renders the priority template and maps the result, unknown values fall back to P3
+func (o *OpsgenieSink) priority(ev *kube.EnhancedEvent) (alert.Priority, error) {
+	p, err := GetString(ev, o.cfg.Priority)
+	if err != nil {
+		return "", err
+	}
+	p = strings.TrimSpace(p)
+	if mapped, ok := o.cfg.PriorityMapping[p]; ok {
+		p = mapped
+	}
+	if !isOpsgeniePriority(p) {
+		log.Warn().Str("priority", p).Msg("opsgenie: unknown priority, using P3")
+		return alert.P3, nil
+	}
+	return alert.Priority(p), nil
+}
+
+func (o *OpsgenieSink) isClose(ev *kube.EnhancedEvent) bool {
+	if o.cfg.CloseCondition == "" {
+		return false
+	}
+	res, err := GetString(ev, o.cfg.CloseCondition)
+	if err != nil {
+		log.Warn().Err(err).Str("template", o.cfg.CloseCondition).Msg("Failed to execute closeCondition template")
+		return false
+	}
+	return res != ""
+}
+
+func (o *OpsgenieSink) close(ctx context.Context, ev *kube.EnhancedEvent) error {
+	alias, err := GetString(ev, o.cfg.CloseAlias)
+	if err != nil {
+		return err
+	}
+	request := alert.CloseAlertRequest{
+		IdentifierType:  alert.ALIAS,
+		IdentifierValue: alias,
+	}
+	if o.cfg.CloseNote != "" {
+		note, err := GetString(ev, o.cfg.CloseNote)
+		if err != nil {
+			return err
+		}
+		request.Note = note
+	}
+
+	_, err = o.alertClient.Close(ctx, &request)
+	return err
+}
+
 func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if o.isClose(ev) {
+		return o.close(ctx, ev)
+	}
+
+	priority, err := o.priority(ev)
+	if err != nil {
+		return err
+	}
 	request := alert.CreateAlertRequest{
-		Priority: alert.Priority(o.cfg.Priority),
+		Priority: priority,
 	}
 
 	msg, err := GetString(ev, o.cfg.Message)
@@ -82,7 +176,10 @@ func (o *OpsgenieSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 			if err != nil {
 				return err
 			}
-			tags = append(tags, tag)
+			// Opsgenie rejects empty tags, e.g. of a label that is not set
+			if tag != "" {
+				tags = append(tags, tag)
+			}
 		}
 		request.Tags = tags
 	}
@@ -0,0 +1,53 @@
+package sinks
+
+import (
+	"testing"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/alert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestOpsgeniePriority(t *testing.T) {
+	o := &OpsgenieSink{cfg: &OpsgenieConfig{
+		Priority:        "{{ .Type }}",
+		PriorityMapping: map[string]string{"Warning": "P2", "Normal": "P5"},
+	}}
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	p, err := o.priority(ev)
+	require.NoError(t, err)
+	assert.Equal(t, alert.P2, p)
+
+	ev.Type = "Other"
+	p, err = o.priority(ev)
+	require.NoError(t, err)
+	assert.Equal(t, alert.P3, p)
+
+	o.cfg.Priority = "P1"
+	p, err = o.priority(ev)
+	require.NoError(t, err)
+	assert.Equal(t, alert.P1, p)
+}
+
+func TestNewOpsgenieSink_Validation(t *testing.T) {
+	_, err := NewOpsgenieSink(&OpsgenieConfig{ApiKey: "key", PriorityMapping: map[string]string{"Warning": "high"}})
+	assert.ErrorContains(t, err, "priorityMapping")
+
+	_, err = NewOpsgenieSink(&OpsgenieConfig{ApiKey: "key", CloseCondition: "{{ .Reason }}"})
+	assert.ErrorContains(t, err, "closeAlias")
+
+	s, err := NewOpsgenieSink(&OpsgenieConfig{ApiKey: "key", Alias: "{{ .InvolvedObject.UID }}", CloseCondition: `{{ if eq .Reason "NodeReady" }}true{{ end }}`})
+	require.NoError(t, err)
+	o := s.(*OpsgenieSink)
+	assert.Equal(t, "{{ .InvolvedObject.UID }}", o.cfg.CloseAlias)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "NodeReady"
+	assert.True(t, o.isClose(ev))
+	ev.Reason = "NodeNotReady"
+	assert.False(t, o.isClose(ev))
+}
@@ -0,0 +1,95 @@
+package sinks
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/metrics"
+)
+
+// Payload limits of the providers, larger payloads are rejected with a 4xx error
+const (
+	slackMaxTextLength = 40000
+	sqsMaxMessageSize  = 256 * 1024
+	snsMaxMessageSize  = 256 * 1024
+	zulipMaxContent    = 10000
+	zulipMaxTopic      = 60
+)
+
+const truncatedSuffix = "... [truncated]"
+
+// metricsStore is used to count the payloads that had to be truncated, it is nil unless SetMetricsStore is called
+var metricsStore *metrics.Store
+
+func SetMetricsStore(store *metrics.Store) {
+	metricsStore = store
+}
+
+func countTruncatedPayload(sink string) {
+	if metricsStore != nil {
+		metricsStore.PayloadsTruncated.WithLabelValues(sink).Inc()
+	}
+}
+
+// truncateRunes shortens s to at most max characters, including the suffix
+func truncateRunes(s string, max int) (string, bool) {
+	if utf8.RuneCountInString(s) <= max {
+		return s, false
+	}
+	runes := []rune(s)
+	keep := max - len(truncatedSuffix)
+	if keep < 0 {
+		keep = 0
+	}
+	return string(runes[:keep]) + truncatedSuffix, true
+}
+
+// truncateBytes shortens s to at most max bytes, including the suffix, without splitting a character
+func truncateBytes(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	keep := max - len(truncatedSuffix)
+	if keep <= 0 {
+		return ""
+	}
+	for keep > 0 && !utf8.RuneStart(s[keep]) {
+		keep--
+	}
+	return s[:keep] + truncatedSuffix
+}
+
+// fitEventPayload serializes the event like serializeEventWithLayout. When the payload is larger than maxBytes, the
+// annotations and labels, e.g. a last-applied-configuration, are dropped first, and the message is shortened if it
+// still does not fit.
+func fitEventPayload(sink string, layout map[string]interface{}, ev *kube.EnhancedEvent, maxBytes int) ([]byte, error) {
+	payload, err := serializeEventWithLayout(layout, ev)
+	if err != nil || len(payload) <= maxBytes {
+		return payload, err
+	}
+
+	shrunk := *ev
+	for step := 0; step < 3 && len(payload) > maxBytes; step++ {
+		switch {
+		case step == 0:
+			shrunk.Annotations = nil
+			shrunk.Labels = nil
+			shrunk.InvolvedObject.Annotations = nil
+			shrunk.InvolvedObject.Labels = nil
+		default:
+			// Removing a byte of the message removes at least a byte of the payload
+			shrunk.Message = truncateBytes(shrunk.Message, len(shrunk.Message)-(len(payload)-maxBytes))
+		}
+		payload, err = serializeEventWithLayout(layout, &shrunk)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(payload) > maxBytes {
+		return nil, fmt.Errorf("payload of %d bytes exceeds the limit of %d bytes", len(payload), maxBytes)
+	}
+
+	countTruncatedPayload(sink)
+	return payload, nil
+}
@@ -0,0 +1,60 @@
+package sinks
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	s, truncated := truncateRunes("short", 10)
+	assert.False(t, truncated)
+	assert.Equal(t, "short", s)
+
+	s, truncated = truncateRunes(strings.Repeat("ü", 100), 50)
+	assert.True(t, truncated)
+	assert.Equal(t, 50, utf8.RuneCountInString(s))
+	assert.True(t, strings.HasSuffix(s, truncatedSuffix))
+}
+
+func TestTruncateBytes(t *testing.T) {
+	s := truncateBytes(strings.Repeat("ü", 100), 50)
+	assert.LessOrEqual(t, len(s), 50)
+	assert.True(t, utf8.ValidString(s))
+}
+
+func TestFitEventPayload(t *testing.T) {
+	ev := &kube.EnhancedEvent{}
+	ev.Message = "small"
+	payload, err := fitEventPayload("test", nil, ev, 4096)
+	require.NoError(t, err)
+	assert.Equal(t, ev.ToJSON(), payload)
+
+	// The message is truncated
+	ev.Message = strings.Repeat("x", 8192)
+	payload, err = fitEventPayload("test", nil, ev, 4096)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(payload), 4096)
+	assert.Contains(t, string(payload), truncatedSuffix)
+	assert.Len(t, ev.Message, 8192, "the event itself is not modified")
+
+	// The annotations are dropped before the message is truncated
+	ev.Message = "Back-off restarting failed container " + strings.Repeat("m", 1024)
+	ev.InvolvedObject.Annotations = map[string]string{"kubectl.kubernetes.io/last-applied-configuration": strings.Repeat("y", 8192)}
+	payload, err = fitEventPayload("test", nil, ev, 4096)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(payload), 4096)
+	assert.NotContains(t, string(payload), "last-applied-configuration")
+	assert.Contains(t, string(payload), ev.Message)
+	assert.NotContains(t, string(payload), truncatedSuffix)
+
+	// Layouts that do not shrink with the message cannot be fitted
+	layout := map[string]interface{}{"data": strings.Repeat("z", 8192)}
+	_, err = fitEventPayload("test", layout, ev, 4096)
+	assert.Error(t, err)
+}
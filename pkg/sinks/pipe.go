@@ -44,16 +44,17 @@ func (f *Pipe) Close() {
 }
 
 func (f *Pipe) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, f.cfg.Layout)
 	if f.cfg.DeDot {
 		de := ev.DeDot()
 		ev = &de
 	}
 
-	if f.cfg.Layout == nil {
+	if layout == nil {
 		return f.encoder.Encode(ev)
 	}
 
-	res, err := convertLayoutTemplate(f.cfg.Layout, ev)
+	res, err := convertLayoutTemplate(layout, ev)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,218 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultPushoverEndpoint = "https://api.pushover.net/1/messages.json"
+	pushoverMaxMessage      = 1024
+	pushoverMaxTitle        = 250
+	pushoverEmergency       = 2
+)
+
+// PushoverConfig sends the events as push notifications to the devices of a Pushover user or group. The priority is
+// mapped from the event type, the rules override the priority, the device and the sound of the matching events.
+type PushoverConfig struct {
+	Token    string `yaml:"token"`
+	User     string `yaml:"user" secret:"true"`
+	Title    string `yaml:"title"`
+	Message  string `yaml:"message"`
+	URL      string `yaml:"url"`
+	URLTitle string `yaml:"urlTitle"`
+	// Priorities maps the event type to a priority from -2 to 2, Warning is 1 and any other type 0 by default
+	Priorities map[string]int `yaml:"priorities"`
+	Device     string         `yaml:"device"`
+	Sound      string         `yaml:"sound"`
+	// Retry and Expire in seconds are how often and how long the emergency priority is repeated until acknowledged
+	Retry    int            `yaml:"retry"`
+	Expire   int            `yaml:"expire"`
+	Rules    []PushoverRule `yaml:"rules"`
+	Endpoint string         `yaml:"endpoint"`
+	TLS      TLS            `yaml:"tls"`
+}
+
+// PushoverRule applies to the events where the When template renders to "true", the first matching rule is used
+type PushoverRule struct {
+	When     string `yaml:"when"`
+	Priority *int   `yaml:"priority"`
+	Device   string `yaml:"device"`
+	Sound    string `yaml:"sound"`
+}
+
+func (c *PushoverConfig) endpoint() string {
+	if c.Endpoint == "" {
+		return defaultPushoverEndpoint
+	}
+	return c.Endpoint
+}
+
+func validPushoverPriority(p int) bool {
+	return p >= -2 && p <= pushoverEmergency
+}
+
+type Pushover struct {
+	cfg    *PushoverConfig
+	client *http.Client
+}
+
+func NewPushoverSink(cfg *PushoverConfig) (Sink, error) {
+	if cfg.Token == "" || cfg.User == "" {
+		return nil, errors.New("pushover.token and pushover.user config options must be non-empty")
+	}
+	if cfg.Message == "" {
+		cfg.Message = "{{ .Message }}"
+	}
+	if cfg.Title == "" {
+		cfg.Title = "{{ .Reason }} on {{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}"
+	}
+	if cfg.Priorities == nil {
+		cfg.Priorities = map[string]int{"Warning": 1}
+	}
+	for eventType, p := range cfg.Priorities {
+		if !validPushoverPriority(p) {
+			return nil, fmt.Errorf("pushover.priorities.%s must be between -2 and 2, got %d", eventType, p)
+		}
+	}
+	for i, r := range cfg.Rules {
+		if r.When == "" {
+			return nil, fmt.Errorf("pushover.rules[%d].when must be non-empty", i)
+		}
+		if _, err := template.New("when").Funcs(templateFuncs()).Parse(r.When); err != nil {
+			return nil, fmt.Errorf("pushover.rules[%d].when is invalid: %w", i, err)
+		}
+		if r.Priority != nil && !validPushoverPriority(*r.Priority) {
+			return nil, fmt.Errorf("pushover.rules[%d].priority must be between -2 and 2, got %d", i, *r.Priority)
+		}
+	}
+	// Pushover rejects emergency messages without retry and expire, these are its limits
+	if cfg.Retry == 0 {
+		cfg.Retry = 60
+	}
+	if cfg.Expire == 0 {
+		cfg.Expire = 3600
+	}
+	if cfg.Retry < 30 || cfg.Expire > 10800 {
+		return nil, errors.New("pushover.retry must be at least 30 and pushover.expire at most 10800 seconds")
+	}
+	cfg.Endpoint = cfg.endpoint()
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Pushover{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (p *Pushover) message(ev *kube.EnhancedEvent) (url.Values, error) {
+	msg := url.Values{
+		"token": {p.cfg.Token},
+		"user":  {p.cfg.User},
+	}
+
+	for field, tmpl := range map[string]string{
+		"message":   p.cfg.Message,
+		"title":     p.cfg.Title,
+		"url":       p.cfg.URL,
+		"url_title": p.cfg.URLTitle,
+	} {
+		if tmpl == "" {
+			continue
+		}
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			msg.Set(field, value)
+		}
+	}
+	if msg.Get("message") == "" {
+		// Pushover rejects messages without a text
+		msg.Set("message", ev.Reason)
+	}
+	if message, truncated := truncateRunes(msg.Get("message"), pushoverMaxMessage); truncated {
+		msg.Set("message", message)
+		countTruncatedPayload("pushover")
+	}
+	if title, truncated := truncateRunes(msg.Get("title"), pushoverMaxTitle); truncated {
+		msg.Set("title", title)
+	}
+
+	priority, device, sound := p.cfg.Priorities[ev.Type], p.cfg.Device, p.cfg.Sound
+	for _, r := range p.cfg.Rules {
+		res, err := GetString(ev, r.When)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(res) != "true" {
+			continue
+		}
+		if r.Priority != nil {
+			priority = *r.Priority
+		}
+		if r.Device != "" {
+			device = r.Device
+		}
+		if r.Sound != "" {
+			sound = r.Sound
+		}
+		break
+	}
+
+	msg.Set("priority", strconv.Itoa(priority))
+	if priority == pushoverEmergency {
+		msg.Set("retry", strconv.Itoa(p.cfg.Retry))
+		msg.Set("expire", strconv.Itoa(p.cfg.Expire))
+	}
+	if device != "" {
+		msg.Set("device", device)
+	}
+	if sound != "" {
+		msg.Set("sound", sound)
+	}
+	return msg, nil
+}
+
+func (p *Pushover) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	msg, err := p.message(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.Endpoint, strings.NewReader(msg.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (p *Pushover) Close() {
+	p.client.CloseIdleConnections()
+}
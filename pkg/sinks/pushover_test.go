@@ -0,0 +1,90 @@
+package sinks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestPushover_Send(t *testing.T) {
+	var received url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		received = r.PostForm
+		_, _ = w.Write([]byte(`{"status":1,"request":"647d2300-702c-4b38-8b2f-d56326ae460b"}`))
+	}))
+	defer ts.Close()
+
+	sink, err := NewPushoverSink(&PushoverConfig{
+		Token:    "app-token",
+		User:     "user-key",
+		Endpoint: ts.URL,
+		Sound:    "pushover",
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "app-token", received.Get("token"))
+	assert.Equal(t, "user-key", received.Get("user"))
+	assert.Equal(t, "BackOff on Pod/api-0", received.Get("title"))
+	assert.Equal(t, "Back-off restarting failed container", received.Get("message"))
+	assert.Equal(t, "1", received.Get("priority"))
+	assert.Equal(t, "pushover", received.Get("sound"))
+	assert.Empty(t, received.Get("device"))
+}
+
+func TestPushover_Rules(t *testing.T) {
+	emergency := 2
+	sink, err := NewPushoverSink(&PushoverConfig{
+		Token:      "app-token",
+		User:       "user-key",
+		Priorities: map[string]int{"Warning": 1, "Normal": -1},
+		Rules: []PushoverRule{
+			{When: `{{ eq .Reason "OOMKilling" }}`, Priority: &emergency, Device: "oncall-phone", Sound: "siren"},
+			{When: `{{ eq .InvolvedObject.Namespace "dev" }}`, Sound: "none"},
+		},
+	})
+	require.NoError(t, err)
+	p := sink.(*Pushover)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "OOMKilling"
+	msg, err := p.message(ev)
+	require.NoError(t, err)
+	assert.Equal(t, "2", msg.Get("priority"))
+	assert.Equal(t, "oncall-phone", msg.Get("device"))
+	assert.Equal(t, "siren", msg.Get("sound"))
+	assert.Equal(t, "60", msg.Get("retry"))
+	assert.Equal(t, "3600", msg.Get("expire"))
+	// The message falls back to the reason
+	assert.Equal(t, "OOMKilling", msg.Get("message"))
+
+	ev = &kube.EnhancedEvent{}
+	ev.Type = "Normal"
+	ev.InvolvedObject.Namespace = "dev"
+	msg, err = p.message(ev)
+	require.NoError(t, err)
+	assert.Equal(t, "-1", msg.Get("priority"))
+	assert.Equal(t, "none", msg.Get("sound"))
+	assert.Empty(t, msg.Get("retry"))
+}
+
+func TestPushover_InvalidPriority(t *testing.T) {
+	_, err := NewPushoverSink(&PushoverConfig{Token: "t", User: "u", Priorities: map[string]int{"Warning": 3}})
+	assert.EqualError(t, err, "pushover.priorities.Warning must be between -2 and 2, got 3")
+}
@@ -1,10 +1,30 @@
 package sinks
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/rs/zerolog/log"
+)
 
 // Receiver allows receiving
 type ReceiverConfig struct {
-	Name          string               `yaml:"name"`
+	Name string `yaml:"name"`
+	// Group is the name of the receiver group whose settings are inherited
+	Group string `yaml:"group"`
+	// Layouts are evaluated in order, the first one matching the event replaces the layout of the sink
+	Layouts []ConditionalLayout `yaml:"layouts"`
+	// LayoutPreset is a predefined layout used instead of the layout of the sink, e.g. alertmanager
+	LayoutPreset string `yaml:"layoutPreset"`
+	// Retry configures retrying throttled requests, by default they are retried 3 times
+	Retry *RetryConfig `yaml:"retry"`
+	// Delivery sets the number of workers sending to the receiver and whether they keep the order per object
+	Delivery *DeliveryConfig `yaml:"delivery"`
+	// Faults injects errors, latency and timeouts, it only takes effect with the -fault-injection flag
+	Faults *FaultConfig `yaml:"faults"`
+	// Template sets the timezone and the locale of the time functions of the templates
+	Template      *TemplateConfig      `yaml:"template"`
 	InMemory      *InMemoryConfig      `yaml:"inMemory"`
 	Webhook       *WebhookConfig       `yaml:"webhook"`
 	File          *FileConfig          `yaml:"file"`
@@ -26,13 +46,331 @@ type ReceiverConfig struct {
 	BigQuery      *BigQueryConfig      `yaml:"bigquery"`
 	EventBridge   *EventBridgeConfig   `yaml:"eventbridge"`
 	Pipe          *PipeConfig          `yaml:"pipe"`
+	OpenTelemetry *OpenTelemetryConfig `yaml:"opentelemetry"`
+	ClickHouse    *ClickHouseConfig    `yaml:"clickhouse"`
+	Postgres      *PostgresConfig      `yaml:"postgres"`
+	SQLite        *SQLiteConfig        `yaml:"sqlite"`
+	MongoDB       *MongoDBConfig       `yaml:"mongodb"`
+	InfluxDB      *InfluxDBConfig      `yaml:"influxdb"`
+	MQTT          *MQTTConfig          `yaml:"mqtt"`
+	RocketChat    *RocketChatConfig    `yaml:"rocketchat"`
+	Matrix        *MatrixConfig        `yaml:"matrix"`
+	Webex         *WebexConfig         `yaml:"webex"`
+	Zulip         *ZulipConfig         `yaml:"zulip"`
+	Pushover      *PushoverConfig      `yaml:"pushover"`
+	Email         *EmailConfig         `yaml:"email"`
+	SES           *SESConfig           `yaml:"ses"`
+	Sentry        *SentryConfig        `yaml:"sentry"`
+	Aggregator    *AggregatorConfig    `yaml:"aggregator"`
+	GitLab        *GitLabConfig        `yaml:"gitlab"`
+	GrafanaOnCall *GrafanaOnCallConfig `yaml:"grafanaOnCall"`
+	SumoLogic     *SumoLogicConfig     `yaml:"sumoLogic"`
+	GELF          *GELFConfig          `yaml:"gelf"`
+	Fluentd       *FluentdConfig       `yaml:"fluentd"`
+	Logstash      *LogstashConfig      `yaml:"logstash"`
+	LogScale      *LogScaleConfig      `yaml:"logscale"`
+	Mezmo         *MezmoConfig         `yaml:"mezmo"`
+	Dynatrace     *DynatraceConfig     `yaml:"dynatrace"`
+	Vector        *VectorConfig        `yaml:"vector"`
+	Statsd        *StatsdConfig        `yaml:"statsd"`
+	WebSocket     *WebSocketConfig     `yaml:"websocket"`
+	Socket        *SocketConfig        `yaml:"socket"`
+	ExportedEvent *ExportedEventConfig `yaml:"exportedEvent"`
+	RemoteCluster *RemoteClusterConfig `yaml:"remoteCluster"`
+	CloudEvents   *CloudEventsConfig   `yaml:"cloudEvents"`
+
+	// setKeys are the keys set in the configs of the sinks by their key, nil unless the config was decoded from YAML
+	setKeys map[string]map[string]bool
 }
 
 func (r *ReceiverConfig) Validate() error {
-	return nil
+	if r.LayoutPreset != "" {
+		if _, ok := layoutPresets[r.LayoutPreset]; !ok {
+			return fmt.Errorf("unknown layout preset: %s", r.LayoutPreset)
+		}
+	}
+	if r.Delivery != nil {
+		if err := r.Delivery.validate(); err != nil {
+			return err
+		}
+	}
+	if r.Template != nil {
+		if err := r.Template.validate(); err != nil {
+			return err
+		}
+	}
+	if r.Faults != nil {
+		if err := r.Faults.validate(); err != nil {
+			return err
+		}
+	}
+	return validateConditionalLayouts(r.Layouts)
+}
+
+// tlsConfigs returns the TLS settings of the configured sink, if it has any
+func (r *ReceiverConfig) tlsConfigs() []*TLS {
+	var configs []*TLS
+	if r.Webhook != nil {
+		configs = append(configs, &r.Webhook.TLS)
+	}
+	if r.Loki != nil {
+		configs = append(configs, &r.Loki.TLS)
+	}
+	if r.Elasticsearch != nil {
+		configs = append(configs, &r.Elasticsearch.TLS)
+	}
+	if r.OpenSearch != nil {
+		configs = append(configs, &r.OpenSearch.TLS)
+	}
+	if r.OpenTelemetry != nil {
+		configs = append(configs, &r.OpenTelemetry.TLS)
+	}
+	if r.ClickHouse != nil {
+		configs = append(configs, &r.ClickHouse.TLS)
+	}
+	if r.MongoDB != nil {
+		configs = append(configs, &r.MongoDB.TLS)
+	}
+	if r.InfluxDB != nil {
+		configs = append(configs, &r.InfluxDB.TLS)
+	}
+	if r.MQTT != nil {
+		configs = append(configs, &r.MQTT.TLS)
+	}
+	if r.RocketChat != nil {
+		configs = append(configs, &r.RocketChat.TLS)
+	}
+	if r.Matrix != nil {
+		configs = append(configs, &r.Matrix.TLS)
+	}
+	if r.Webex != nil {
+		configs = append(configs, &r.Webex.TLS)
+	}
+	if r.Zulip != nil {
+		configs = append(configs, &r.Zulip.TLS)
+	}
+	if r.Pushover != nil {
+		configs = append(configs, &r.Pushover.TLS)
+	}
+	if r.Email != nil {
+		configs = append(configs, &r.Email.TLS)
+	}
+	if r.Sentry != nil {
+		configs = append(configs, &r.Sentry.TLS)
+	}
+	if r.Aggregator != nil {
+		configs = append(configs, &r.Aggregator.TLS)
+	}
+	if r.GitLab != nil {
+		configs = append(configs, &r.GitLab.TLS)
+	}
+	if r.GrafanaOnCall != nil {
+		configs = append(configs, &r.GrafanaOnCall.TLS)
+	}
+	if r.SumoLogic != nil {
+		configs = append(configs, &r.SumoLogic.TLS)
+	}
+	if r.GELF != nil {
+		configs = append(configs, &r.GELF.TLS)
+	}
+	if r.Fluentd != nil {
+		configs = append(configs, &r.Fluentd.TLS)
+	}
+	if r.Logstash != nil {
+		configs = append(configs, &r.Logstash.TLS)
+	}
+	if r.LogScale != nil {
+		configs = append(configs, &r.LogScale.TLS)
+	}
+	if r.Mezmo != nil {
+		configs = append(configs, &r.Mezmo.TLS)
+	}
+	if r.Dynatrace != nil {
+		configs = append(configs, &r.Dynatrace.TLS)
+	}
+	if r.Vector != nil {
+		configs = append(configs, &r.Vector.TLS)
+	}
+	if r.WebSocket != nil {
+		configs = append(configs, &r.WebSocket.TLS)
+	}
+	if r.Socket != nil {
+		configs = append(configs, &r.Socket.TLS)
+	}
+	if r.CloudEvents != nil {
+		configs = append(configs, &r.CloudEvents.TLS)
+	}
+	return configs
+}
+
+// endpoints returns the destinations of the configured sink as URLs or host:port pairs, if they are known upfront
+func (r *ReceiverConfig) endpoints() []string {
+	var endpoints []string
+	if r.Webhook != nil {
+		endpoints = append(endpoints, r.Webhook.Endpoint)
+	}
+	if r.Loki != nil {
+		endpoints = append(endpoints, r.Loki.URL)
+	}
+	if r.Teams != nil {
+		endpoints = append(endpoints, r.Teams.Endpoint)
+	}
+	if r.Elasticsearch != nil {
+		endpoints = append(endpoints, r.Elasticsearch.Hosts...)
+	}
+	if r.OpenSearch != nil {
+		endpoints = append(endpoints, r.OpenSearch.Hosts...)
+	}
+	if r.OpenTelemetry != nil {
+		endpoints = append(endpoints, r.OpenTelemetry.Endpoint)
+	}
+	if r.Kafka != nil {
+		endpoints = append(endpoints, r.Kafka.Brokers...)
+	}
+	if r.Syslog != nil {
+		endpoints = append(endpoints, r.Syslog.Address)
+	}
+	if r.Slack != nil {
+		endpoints = append(endpoints, "https://slack.com/api/")
+	}
+	if r.Opsgenie != nil {
+		u := r.Opsgenie.URL
+		if u == "" {
+			u = client.API_URL
+		}
+		endpoints = append(endpoints, "https://"+string(u))
+	}
+	if r.ClickHouse != nil {
+		endpoints = append(endpoints, r.ClickHouse.Endpoint)
+	}
+	if r.Postgres != nil {
+		endpoints = append(endpoints, postgresHost(r.Postgres.DSN))
+	}
+	if r.MongoDB != nil {
+		endpoints = append(endpoints, mongoDBHosts(r.MongoDB.URI)...)
+	}
+	if r.InfluxDB != nil {
+		endpoints = append(endpoints, r.InfluxDB.URL)
+	}
+	if r.MQTT != nil {
+		endpoints = append(endpoints, r.MQTT.Brokers...)
+	}
+	if r.RocketChat != nil {
+		endpoints = append(endpoints, r.RocketChat.Endpoint)
+	}
+	if r.Matrix != nil {
+		endpoints = append(endpoints, r.Matrix.Homeserver)
+	}
+	if r.Webex != nil {
+		endpoints = append(endpoints, r.Webex.endpoint())
+	}
+	if r.Zulip != nil {
+		endpoints = append(endpoints, r.Zulip.Site)
+	}
+	if r.Pushover != nil {
+		endpoints = append(endpoints, r.Pushover.endpoint())
+	}
+	if r.Email != nil {
+		endpoints = append(endpoints, r.Email.Host)
+	}
+	if r.Sentry != nil {
+		endpoints = append(endpoints, r.Sentry.DSN)
+	}
+	if r.Aggregator != nil {
+		endpoints = append(endpoints, r.Aggregator.Endpoint)
+	}
+	if r.GitLab != nil {
+		endpoints = append(endpoints, r.GitLab.url())
+	}
+	if r.GrafanaOnCall != nil {
+		endpoints = append(endpoints, r.GrafanaOnCall.URL)
+	}
+	if r.SumoLogic != nil {
+		endpoints = append(endpoints, r.SumoLogic.Endpoint)
+	}
+	if r.GELF != nil {
+		endpoints = append(endpoints, r.GELF.Address)
+	}
+	if r.Fluentd != nil {
+		endpoints = append(endpoints, r.Fluentd.Address)
+	}
+	if r.Logstash != nil {
+		endpoints = append(endpoints, r.Logstash.Address)
+	}
+	if r.LogScale != nil {
+		endpoints = append(endpoints, r.LogScale.URL)
+	}
+	if r.Mezmo != nil {
+		u := r.Mezmo.URL
+		if u == "" {
+			u = defaultMezmoURL
+		}
+		endpoints = append(endpoints, u)
+	}
+	if r.Dynatrace != nil {
+		endpoints = append(endpoints, r.Dynatrace.URL)
+	}
+	if r.Vector != nil {
+		endpoints = append(endpoints, r.Vector.Endpoint)
+	}
+	if r.Statsd != nil {
+		address := r.Statsd.Address
+		if address == "" {
+			address = defaultStatsdAddress
+		}
+		endpoints = append(endpoints, address)
+	}
+	if r.WebSocket != nil {
+		endpoints = append(endpoints, r.WebSocket.URL)
+	}
+	// The Unix sockets are local, the egress policy does not apply to them
+	if r.Socket != nil && !r.Socket.unix() {
+		endpoints = append(endpoints, r.Socket.Address)
+	}
+	if r.CloudEvents != nil {
+		endpoints = append(endpoints, r.CloudEvents.Endpoint)
+	}
+	return endpoints
 }
 
 func (r *ReceiverConfig) GetSink() (Sink, error) {
+	sink, err := r.getSink()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Faults != nil {
+		if !faultInjection {
+			log.Warn().Str("receiver", r.Name).Msg("Ignoring the faults of the receiver, fault injection is not enabled")
+		} else if sink, err = newFaultSink(sink, r.Faults); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.Layouts) > 0 || r.LayoutPreset != "" {
+		var preset map[string]interface{}
+		if r.LayoutPreset != "" {
+			newPreset, ok := layoutPresets[r.LayoutPreset]
+			if !ok {
+				return nil, fmt.Errorf("unknown layout preset: %s", r.LayoutPreset)
+			}
+			preset = newPreset()
+		}
+		sink = &conditionalLayoutSink{Sink: sink, layouts: r.Layouts, preset: preset}
+	}
+	if r.Template != nil {
+		if sink, err = newLocaleSink(sink, r.Template); err != nil {
+			return nil, err
+		}
+	}
+	sink = newRetrySink(sink, r.Retry)
+	if r.Delivery != nil {
+		sink = WithDelivery(sink, r.Delivery)
+	}
+	return sink, nil
+}
+
+func (r *ReceiverConfig) getSink() (Sink, error) {
 	if r.InMemory != nil {
 		// This reference is used for test purposes to count the events in the sink.
 		// It should not be used in production since it will only cause memory leak and (b)OOM
@@ -122,5 +460,133 @@ func (r *ReceiverConfig) GetSink() (Sink, error) {
 		return NewLoki(r.Loki)
 	}
 
+	if r.OpenTelemetry != nil {
+		return NewOpenTelemetrySink(r.OpenTelemetry)
+	}
+
+	if r.ClickHouse != nil {
+		return NewClickHouseSink(r.ClickHouse)
+	}
+
+	if r.Postgres != nil {
+		return NewPostgresSink(r.Postgres)
+	}
+
+	if r.SQLite != nil {
+		return NewSQLiteSink(r.SQLite)
+	}
+
+	if r.MongoDB != nil {
+		return NewMongoDBSink(r.MongoDB)
+	}
+
+	if r.InfluxDB != nil {
+		return NewInfluxDBSink(r.InfluxDB)
+	}
+
+	if r.MQTT != nil {
+		return NewMQTTSink(r.MQTT)
+	}
+
+	if r.RocketChat != nil {
+		return NewRocketChatSink(r.RocketChat)
+	}
+
+	if r.Matrix != nil {
+		return NewMatrixSink(r.Matrix)
+	}
+
+	if r.Webex != nil {
+		return NewWebexSink(r.Webex)
+	}
+
+	if r.Zulip != nil {
+		return NewZulipSink(r.Zulip)
+	}
+
+	if r.Pushover != nil {
+		return NewPushoverSink(r.Pushover)
+	}
+
+	if r.Email != nil {
+		return NewEmailSink(r.Email)
+	}
+
+	if r.SES != nil {
+		return NewSESSink(r.SES)
+	}
+
+	if r.Sentry != nil {
+		return NewSentrySink(r.Sentry)
+	}
+
+	if r.Aggregator != nil {
+		return NewAggregatorSink(r.Aggregator)
+	}
+
+	if r.GitLab != nil {
+		return NewGitLabSink(r.GitLab)
+	}
+
+	if r.GrafanaOnCall != nil {
+		return NewGrafanaOnCallSink(r.GrafanaOnCall)
+	}
+
+	if r.SumoLogic != nil {
+		return NewSumoLogicSink(r.SumoLogic)
+	}
+
+	if r.GELF != nil {
+		return NewGELFSink(r.GELF)
+	}
+
+	if r.Fluentd != nil {
+		return NewFluentdSink(r.Fluentd)
+	}
+
+	if r.Logstash != nil {
+		return NewLogstashSink(r.Logstash)
+	}
+
+	if r.LogScale != nil {
+		return NewLogScaleSink(r.LogScale)
+	}
+
+	if r.Mezmo != nil {
+		return NewMezmoSink(r.Mezmo)
+	}
+
+	if r.Dynatrace != nil {
+		return NewDynatraceSink(r.Dynatrace)
+	}
+
+	if r.Vector != nil {
+		return NewVectorSink(r.Vector)
+	}
+
+	if r.Statsd != nil {
+		return NewStatsdSink(r.Statsd)
+	}
+
+	if r.WebSocket != nil {
+		return NewWebSocketSink(r.WebSocket)
+	}
+
+	if r.Socket != nil {
+		return NewSocketSink(r.Socket)
+	}
+
+	if r.ExportedEvent != nil {
+		return NewExportedEventSink(r.ExportedEvent)
+	}
+
+	if r.RemoteCluster != nil {
+		return NewRemoteClusterSink(r.RemoteCluster)
+	}
+
+	if r.CloudEvents != nil {
+		return NewCloudEventsSink(r.CloudEvents)
+	}
+
 	return nil, errors.New("unknown sink")
 }
@@ -0,0 +1,171 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/goccy/go-yaml"
+)
+
+// ReceiverGroup holds the settings shared by its member receivers, e.g. many webhooks to the same platform. A member
+// inherits every setting it does not set itself.
+type ReceiverGroup struct {
+	Name     string          `yaml:"name"`
+	Retry    *RetryConfig    `yaml:"retry"`
+	Delivery *DeliveryConfig `yaml:"delivery"`
+	Template *TemplateConfig `yaml:"template"`
+	// TLS applies to the members whose sink has the common tls settings
+	TLS *TLS `yaml:"tls"`
+	// Settings are fields of the sink config like batchSize, headers or layout, they apply to the members whose sink
+	// has them. Maps like headers are merged, the keys of the member take precedence.
+	Settings map[string]interface{} `yaml:"settings"`
+}
+
+// ApplyGroup sets the settings of the group the receiver does not set itself
+func (r *ReceiverConfig) ApplyGroup(g *ReceiverGroup) error {
+	if r.Retry == nil && g.Retry != nil {
+		retry := *g.Retry
+		r.Retry = &retry
+	}
+	if r.Delivery == nil && g.Delivery != nil {
+		delivery := *g.Delivery
+		r.Delivery = &delivery
+	}
+	if r.Template == nil && g.Template != nil {
+		template := *g.Template
+		r.Template = &template
+	}
+	if g.TLS != nil {
+		for _, tls := range r.tlsConfigs() {
+			if *tls == (TLS{}) {
+				*tls = *g.TLS
+			}
+		}
+	}
+	if len(g.Settings) == 0 {
+		return nil
+	}
+
+	sink, err := r.sinkConfig()
+	if err != nil {
+		return err
+	}
+	// The settings are decoded like the sink config, so they are validated the same way
+	defaults := reflect.New(sink.config.Type())
+	out, err := yaml.Marshal(g.Settings)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(out, defaults.Interface()); err != nil {
+		return fmt.Errorf("receiver group %s: invalid settings: %w", g.Name, err)
+	}
+	var set map[string]bool
+	if r.setKeys != nil {
+		set = r.setKeys[sink.key]
+		if set == nil {
+			set = map[string]bool{}
+		}
+	}
+	inheritFields(sink.config, defaults.Elem(), set)
+	return nil
+}
+
+// UnmarshalYAML records the keys set in the configs of the sinks, so a group only fills in the ones the member left
+// out, also when the member sets them to their zero value
+func (r *ReceiverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ReceiverConfig
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	r.setKeys = make(map[string]map[string]bool, len(raw))
+	for name, value := range raw {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := make(map[string]bool, len(fields))
+		for key := range fields {
+			keys[key] = true
+		}
+		r.setKeys[name] = keys
+	}
+	return nil
+}
+
+// sinkField is the config struct of a sink and its key in the receiver config
+type sinkField struct {
+	key    string
+	config reflect.Value
+}
+
+// sinkConfigs returns the config structs of the sinks, the set pointer fields besides the retry, delivery, faults and
+// template configs
+func (r *ReceiverConfig) sinkConfigs() []sinkField {
+	var configs []sinkField
+	v := reflect.ValueOf(r).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Pointer || field.IsNil() || field.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		switch field.Interface().(type) {
+		case *RetryConfig, *DeliveryConfig, *FaultConfig, *TemplateConfig:
+			continue
+		}
+		configs = append(configs, sinkField{key: yamlName(v.Type().Field(i)), config: field.Elem()})
+	}
+	return configs
+}
+
+// sinkConfig returns the config struct of the sink
+func (r *ReceiverConfig) sinkConfig() (sinkField, error) {
+	configs := r.sinkConfigs()
+	if len(configs) == 0 {
+		return sinkField{}, errors.New("no sink is configured")
+	}
+	return configs[0], nil
+}
+
+// ValidateSingleSink returns an error unless exactly one sink is configured
+func (r *ReceiverConfig) ValidateSingleSink() error {
+	switch n := len(r.sinkConfigs()); n {
+	case 0:
+		return errors.New("no sink is configured")
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("receiver %s configures %d sinks, only one is allowed", r.Name, n)
+	}
+}
+
+// inheritFields sets the fields of dst whose keys are not set to the fields of src, maps are merged. Without the set
+// keys, e.g. for a config built in Go, the zero fields count as not set.
+func inheritFields(dst, src reflect.Value, set map[string]bool) {
+	for i := 0; i < dst.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		if !d.CanSet() || s.IsZero() {
+			continue
+		}
+		if d.Kind() == reflect.Map && !d.IsNil() {
+			iter := s.MapRange()
+			for iter.Next() {
+				if !d.MapIndex(iter.Key()).IsValid() {
+					d.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			continue
+		}
+		unset := d.IsZero()
+		if set != nil {
+			unset = !set[yamlName(dst.Type().Field(i))]
+		}
+		if unset {
+			d.Set(s)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	defaultRemoteClusterNamespace = "{{ if .Namespace }}{{ .Namespace }}{{ else }}default{{ end }}"
+	defaultRemoteClusterCluster   = "{{ .ClusterName }}"
+
+	// remoteClusterLabel is the label with the cluster an event was re-created from, so kubectl can select them
+	remoteClusterLabel = "kubernetes-event-exporter.giantswarm.io/cluster"
+	// remoteClusterAnnotationPrefix prefixes the annotations with the involved object of the source cluster
+	remoteClusterAnnotationPrefix = "kubernetes-event-exporter.giantswarm.io/source-"
+)
+
+// RemoteClusterConfig re-creates the events in another cluster, e.g. a management cluster, so the events of a fleet
+// can be browsed centrally with kubectl. The events are labeled with the cluster they come from. By default they keep
+// their involved object, which only exists in the source cluster. With Condensed, they involve an object of the
+// remote cluster instead, e.g. the Cluster resource representing the source cluster, and their message names the
+// original object.
+type RemoteClusterConfig struct {
+	// Kubeconfig is the path of the kubeconfig of the remote cluster, and Context the context to use in it
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context,omitempty"`
+	// Namespace is a template, the namespace of the event, or default for the cluster-scoped events, by default
+	Namespace string `yaml:"namespace,omitempty"`
+	// Cluster is a template, the value of the cluster label, the cluster name by default
+	Cluster   string               `yaml:"cluster,omitempty"`
+	Condensed *RemoteClusterObject `yaml:"condensed,omitempty"`
+}
+
+// RemoteClusterObject are the templates of the object the condensed events involve, in the namespace of the event
+type RemoteClusterObject struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+type RemoteCluster struct {
+	cfg    *RemoteClusterConfig
+	client kubernetes.Interface
+}
+
+func NewRemoteClusterSink(cfg *RemoteClusterConfig) (*RemoteCluster, error) {
+	if cfg.Kubeconfig == "" {
+		return nil, errors.New("remoteCluster.kubeconfig config option must be non-empty")
+	}
+	loader := &clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.Kubeconfig}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+	kubecfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loader, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the kubeconfig of the remote cluster: %w", err)
+	}
+	kubecfg.UserAgent = "kubernetes-event-exporter"
+	kubecfg.Dial = dialContext
+	client, err := kubernetes.NewForConfig(kubecfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return newRemoteClusterSink(cfg, client)
+}
+
+func newRemoteClusterSink(cfg *RemoteClusterConfig, client kubernetes.Interface) (*RemoteCluster, error) {
+	if c := cfg.Condensed; c != nil && (c.APIVersion == "" || c.Kind == "" || c.Name == "") {
+		return nil, errors.New("remoteCluster.condensed.apiVersion, kind and name config options must be non-empty")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = defaultRemoteClusterNamespace
+	}
+	if cfg.Cluster == "" {
+		cfg.Cluster = defaultRemoteClusterCluster
+	}
+	return &RemoteCluster{cfg: cfg, client: client}, nil
+}
+
+// labelValue shortens and cleans a value so it is a valid label value
+func labelValue(value string) string {
+	if len(validation.IsValidLabelValue(value)) == 0 {
+		return value
+	}
+	value = kubeObjectNameUnsafe.ReplaceAllString(strings.ToLower(value), "-")
+	return strings.Trim(value[:min(len(value), validation.LabelValueMaxLength)], ".-")
+}
+
+func (r *RemoteCluster) event(ev *kube.EnhancedEvent) (*corev1.Event, error) {
+	namespace, err := GetString(ev, r.cfg.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render the namespace: %w", err)
+	}
+	cluster, err := GetString(ev, r.cfg.Cluster)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render the cluster: %w", err)
+	}
+	// The events of the clusters share the namespaces, the cluster keeps their names apart
+	name := ev.Name
+	if cluster != "" {
+		name = cluster + "." + name
+	}
+
+	source := ev.InvolvedObject
+	remote := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeObjectName(name),
+			Namespace: namespace,
+			Labels:    map[string]string{remoteClusterLabel: labelValue(cluster)},
+			Annotations: map[string]string{
+				remoteClusterAnnotationPrefix + "namespace": source.Namespace,
+				remoteClusterAnnotationPrefix + "kind":      source.Kind,
+				remoteClusterAnnotationPrefix + "name":      source.Name,
+			},
+		},
+		Reason:              ev.Reason,
+		Message:             ev.Message,
+		Type:                ev.Type,
+		Count:               ev.Count,
+		FirstTimestamp:      ev.FirstTimestamp,
+		LastTimestamp:       ev.LastTimestamp,
+		Source:              ev.Source,
+		ReportingController: "kubernetes-event-exporter",
+		ReportingInstance:   cluster,
+	}
+	if remote.Count == 0 {
+		remote.Count = 1
+	}
+	if remote.LastTimestamp.IsZero() {
+		remote.LastTimestamp = metav1.NewTime(clock.Now())
+	}
+	if remote.FirstTimestamp.IsZero() {
+		remote.FirstTimestamp = remote.LastTimestamp
+	}
+
+	if r.cfg.Condensed == nil {
+		remote.InvolvedObject = source.ObjectReference
+		// The API requires the involved object to be in the namespace of the event
+		if remote.InvolvedObject.Namespace != "" {
+			remote.InvolvedObject.Namespace = namespace
+		}
+		return remote, nil
+	}
+
+	object := corev1.ObjectReference{Namespace: namespace}
+	if object.APIVersion, err = GetString(ev, r.cfg.Condensed.APIVersion); err != nil {
+		return nil, fmt.Errorf("cannot render the condensed apiVersion: %w", err)
+	}
+	if object.Kind, err = GetString(ev, r.cfg.Condensed.Kind); err != nil {
+		return nil, fmt.Errorf("cannot render the condensed kind: %w", err)
+	}
+	if object.Name, err = GetString(ev, r.cfg.Condensed.Name); err != nil {
+		return nil, fmt.Errorf("cannot render the condensed name: %w", err)
+	}
+	remote.InvolvedObject = object
+	remote.Message = fmt.Sprintf("%s %s: %s", source.Kind, strings.TrimPrefix(source.Namespace+"/"+source.Name, "/"), ev.Message)
+	return remote, nil
+}
+
+func (r *RemoteCluster) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	remote, err := r.event(ev)
+	if err != nil {
+		return err
+	}
+	events := r.client.CoreV1().Events(remote.Namespace)
+	_, err = events.Create(ctx, remote, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// The event was seen again in the source cluster
+	existing, err := events.Get(ctx, remote.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	existing.Count = remote.Count
+	existing.LastTimestamp = remote.LastTimestamp
+	existing.Message = remote.Message
+	_, err = events.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (r *RemoteCluster) Close() {
+}
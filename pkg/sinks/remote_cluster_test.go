@@ -0,0 +1,81 @@
+package sinks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func remoteClusterTestEvent() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Name = "checkout-1.17a8b2c3d4e5f6a7"
+	ev.Namespace = "shop"
+	ev.ClusterName = "prod-eu"
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.Count = 1
+	ev.InvolvedObject.APIVersion = "v1"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+	return ev
+}
+
+func TestRemoteCluster_Send(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s, err := newRemoteClusterSink(&RemoteClusterConfig{Namespace: "org-{{ .ClusterName }}"}, client)
+	require.NoError(t, err)
+
+	ev := remoteClusterTestEvent()
+	require.NoError(t, s.Send(context.Background(), ev))
+	// The event seen again updates the re-created one
+	ev.Count = 3
+	require.NoError(t, s.Send(context.Background(), ev))
+
+	remote, err := client.CoreV1().Events("org-prod-eu").Get(context.Background(), "prod-eu.checkout-1.17a8b2c3d4e5f6a7", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "prod-eu", remote.Labels[remoteClusterLabel])
+	assert.Equal(t, "shop", remote.Annotations[remoteClusterAnnotationPrefix+"namespace"])
+	assert.Equal(t, "Pod", remote.InvolvedObject.Kind)
+	assert.Equal(t, "checkout-1", remote.InvolvedObject.Name)
+	assert.Equal(t, "org-prod-eu", remote.InvolvedObject.Namespace)
+	assert.Equal(t, int32(3), remote.Count)
+	assert.Equal(t, "Back-off restarting failed container", remote.Message)
+	assert.Equal(t, "prod-eu", remote.ReportingInstance)
+}
+
+func TestRemoteCluster_Condensed(t *testing.T) {
+	s, err := newRemoteClusterSink(&RemoteClusterConfig{
+		Namespace: "org-acme",
+		Condensed: &RemoteClusterObject{
+			APIVersion: "cluster.x-k8s.io/v1beta1",
+			Kind:       "Cluster",
+			Name:       "{{ .ClusterName }}",
+		},
+	}, fake.NewSimpleClientset())
+	require.NoError(t, err)
+
+	remote, err := s.event(remoteClusterTestEvent())
+	require.NoError(t, err)
+	assert.Equal(t, "Cluster", remote.InvolvedObject.Kind)
+	assert.Equal(t, "prod-eu", remote.InvolvedObject.Name)
+	assert.Equal(t, "org-acme", remote.InvolvedObject.Namespace)
+	assert.Equal(t, "Pod shop/checkout-1: Back-off restarting failed container", remote.Message)
+
+	_, err = newRemoteClusterSink(&RemoteClusterConfig{Condensed: &RemoteClusterObject{Kind: "Cluster"}}, fake.NewSimpleClientset())
+	assert.Error(t, err)
+}
+
+func TestLabelValue(t *testing.T) {
+	assert.Equal(t, "Prod_EU", labelValue("Prod_EU"))
+	assert.Equal(t, "prod-eu-1", labelValue("prod eu/1"))
+	assert.Len(t, labelValue(strings.Repeat("a", 100)), 63)
+}
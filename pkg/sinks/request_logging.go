@@ -0,0 +1,219 @@
+package sinks
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const redacted = "REDACTED"
+
+// RequestLoggingConfig logs the outbound requests of the HTTP based sinks and their responses, to debug integrations.
+// Credentials are redacted: the Authorization and Cookie headers, headers and query parameters whose name contains
+// token, secret, password or key, user info of the URL and JSON body fields with such names.
+type RequestLoggingConfig struct {
+	// Bodies also logs the request and response bodies, up to MaxBodyBytes
+	Bodies       bool `yaml:"bodies"`
+	MaxBodyBytes int  `yaml:"maxBodyBytes"`
+	// RedactHeaders are additional headers to redact, e.g. a custom header carrying a credential
+	RedactHeaders []string `yaml:"redactHeaders"`
+}
+
+const defaultMaxLoggedBodyBytes = 4096
+
+// requestLogging is the active config, it is nil unless SetRequestLogging is called
+var requestLogging *RequestLoggingConfig
+
+// SetRequestLogging activates the logging for all HTTP based sinks. It also applies to the default HTTP transport which
+// is used by the sinks relying on third-party SDKs. It must be called before the sinks are created.
+func SetRequestLogging(cfg *RequestLoggingConfig) {
+	requestLogging = cfg
+	if requestLogging.MaxBodyBytes <= 0 {
+		requestLogging.MaxBodyBytes = defaultMaxLoggedBodyBytes
+	}
+	http.DefaultTransport = withRequestLogging(http.DefaultTransport)
+}
+
+// withRequestLogging wraps the transport if request logging is active
+func withRequestLogging(rt http.RoundTripper) http.RoundTripper {
+	if requestLogging == nil {
+		return rt
+	}
+	if _, ok := rt.(*loggingTransport); ok {
+		return rt
+	}
+	return &loggingTransport{next: rt, cfg: requestLogging}
+}
+
+type loggingTransport struct {
+	next http.RoundTripper
+	cfg  *RequestLoggingConfig
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	event := log.Info().
+		Str("method", req.Method).
+		Str("url", RedactURL(req.URL)).
+		Interface("requestHeaders", t.redactHeaders(req.Header))
+	if t.cfg.Bodies && req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			event = event.Str("requestBody", t.loggedBody(body, req.Header))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	event = event.Dur("duration", time.Since(start))
+	if err != nil {
+		event.Err(err).Msg("Outbound request failed")
+		return resp, err
+	}
+
+	event = event.Int("status", resp.StatusCode).Interface("responseHeaders", t.redactHeaders(resp.Header))
+	if t.cfg.Bodies && resp.Body != nil {
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if readErr == nil {
+			event = event.Str("responseBody", t.loggedBody(io.NopCloser(bytes.NewReader(data)), resp.Header))
+		}
+	}
+	event.Msg("Outbound request")
+	return resp, nil
+}
+
+func (t *loggingTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// loggedBody reads up to MaxBodyBytes of the body, compressed bodies are not logged
+func (t *loggingTransport) loggedBody(body io.ReadCloser, header http.Header) string {
+	defer body.Close()
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return "[" + encoding + " encoded]"
+	}
+	data, err := io.ReadAll(io.LimitReader(body, int64(t.cfg.MaxBodyBytes)+1))
+	if err != nil {
+		return ""
+	}
+	if len(data) > t.cfg.MaxBodyBytes {
+		return redactBody(truncateBytes(string(data), t.cfg.MaxBodyBytes))
+	}
+	return redactBody(string(data))
+}
+
+var sensitiveNamePattern = regexp.MustCompile(`(?i)token|secret|passw|api[-_]?key|^key$|auth|cookie|signature|credential`)
+
+// IsSensitiveName reports whether a header, query parameter or field with the name usually holds a credential
+func IsSensitiveName(name string) bool {
+	return sensitiveNamePattern.MatchString(name)
+}
+
+// secretFields are the fields of the sink configs tagged with secret:"true", by the YAML name of the sink. These are
+// the credentials whose name does not tell, like the webhook URLs carrying a token in their path.
+var secretFields = func() map[string]map[string]bool {
+	fields := make(map[string]map[string]bool)
+	receiver := reflect.TypeOf(ReceiverConfig{})
+	for i := 0; i < receiver.NumField(); i++ {
+		sink := receiver.Field(i)
+		if sink.Type.Kind() != reflect.Pointer || sink.Type.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		config := sink.Type.Elem()
+		for j := 0; j < config.NumField(); j++ {
+			if config.Field(j).Tag.Get("secret") != "true" {
+				continue
+			}
+			name := yamlName(sink)
+			if fields[name] == nil {
+				fields[name] = make(map[string]bool)
+			}
+			fields[name][yamlName(config.Field(j))] = true
+		}
+	}
+	return fields
+}()
+
+// IsSecretField reports whether the field of the sink config holds a credential, an empty sink matches any sink
+func IsSecretField(sink, field string) bool {
+	if sink != "" {
+		return secretFields[sink][field]
+	}
+	for _, fields := range secretFields {
+		if fields[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlName is the key of the field, the lower case field name if the tag does not set it
+func yamlName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func (t *loggingTransport) redactHeaders(header http.Header) map[string]string {
+	ret := make(map[string]string, len(header))
+	for k, v := range header {
+		value := strings.Join(v, ", ")
+		if sensitiveNamePattern.MatchString(k) || t.isRedactedHeader(k) {
+			value = redacted
+		}
+		ret[k] = value
+	}
+	return ret
+}
+
+func (t *loggingTransport) isRedactedHeader(name string) bool {
+	for _, h := range t.cfg.RedactHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeader returns the value to log for a header set by a sink
+func redactHeader(name, value string) string {
+	if IsSensitiveName(name) {
+		return redacted
+	}
+	return value
+}
+
+// RedactURL returns the URL without the user info and the values of query parameters with a sensitive name
+func RedactURL(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(redacted)
+	}
+	if c.RawQuery != "" {
+		query := c.Query()
+		for k := range query {
+			if sensitiveNamePattern.MatchString(k) {
+				query[k] = []string{redacted}
+			}
+		}
+		c.RawQuery = query.Encode()
+	}
+	return c.String()
+}
+
+var sensitiveJSONFieldPattern = regexp.MustCompile(`(?i)("[a-z0-9_-]*(?:token|secret|passw|api[-_]?key|auth|credential)[a-z0-9_-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// redactBody redacts the string values of JSON fields with a sensitive name
+func redactBody(body string) string {
+	return sensitiveJSONFieldPattern.ReplaceAllString(body, `$1"`+redacted+`"`)
+}
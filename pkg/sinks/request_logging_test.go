@@ -0,0 +1,67 @@
+package sinks
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingTransport_Redacts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc123")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok":true,"access_token":"resp-secret"}`))
+	}))
+	defer ts.Close()
+
+	output := &bytes.Buffer{}
+	logger := log.Logger
+	log.Logger = zerolog.New(output)
+	defer func() { log.Logger = logger }()
+
+	transport := &loggingTransport{
+		next: http.DefaultTransport,
+		cfg:  &RequestLoggingConfig{Bodies: true, MaxBodyBytes: defaultMaxLoggedBodyBytes, RedactHeaders: []string{"X-Custom"}},
+	}
+	client := &http.Client{Transport: transport}
+
+	u := strings.Replace(ts.URL, "http://", "http://user:hunter2@", 1) + "/hook?api_key=k3y&channel=ops"
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(`{"message":"hi","password":"p4ss"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	req.Header.Set("X-Custom", "c0stom")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Contains(t, string(body), "resp-secret", "the response body must still be readable by the sink")
+
+	logged := output.String()
+	for _, secret := range []string{"hunter2", "k3y", "t0ken", "c0stom", "p4ss", "abc123", "resp-secret"} {
+		assert.NotContains(t, logged, secret)
+	}
+	assert.Contains(t, logged, "channel=ops")
+	assert.Contains(t, logged, `\"message\":\"hi\"`)
+	assert.Contains(t, logged, `"status":200`)
+}
+
+func TestLoggedBody_Truncated(t *testing.T) {
+	transport := &loggingTransport{cfg: &RequestLoggingConfig{MaxBodyBytes: 20}}
+	body := transport.loggedBody(io.NopCloser(strings.NewReader(strings.Repeat("a", 100))), http.Header{})
+	assert.LessOrEqual(t, len(body), 20)
+	assert.True(t, strings.HasSuffix(body, truncatedSuffix))
+
+	gzipped := transport.loggedBody(io.NopCloser(strings.NewReader("...")), http.Header{"Content-Encoding": {"gzip"}})
+	assert.Equal(t, "[gzip encoded]", gzipped)
+}
@@ -0,0 +1,76 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// ResponseCaptureConfig extracts values from a JSON response body and stores them in the state store, so the
+// templates of later events can use them with `{{ stateValue "key" "name" }}`
+type ResponseCaptureConfig struct {
+	// Key is a template evaluating to the state store key, e.g. "incident/{{ .InvolvedObject.UID }}"
+	Key string `yaml:"key"`
+	// Values maps names to JSONPath expressions, e.g. incidentId: "{.data.id}" or "$.data.id"
+	Values map[string]string `yaml:"values"`
+	// TTLSeconds expires the key, so that for example a new incident is opened instead of updating an old one. By
+	// default the key is kept until the exporter restarts.
+	TTLSeconds int64 `yaml:"ttlSeconds"`
+}
+
+// captureResponse stores the selected values of the response body. Values that cannot be found are skipped.
+func captureResponse(cfg *ResponseCaptureConfig, ev *kube.EnhancedEvent, body []byte) error {
+	key, err := GetString(ev, cfg.Key)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("cannot parse response as JSON: %w", err)
+	}
+
+	values := make(map[string]string)
+	for name, expr := range cfg.Values {
+		jp := jsonpath.New(name).AllowMissingKeys(true)
+		if err := jp.Parse(normalizeJSONPath(expr)); err != nil {
+			return fmt.Errorf("invalid JSONPath %q: %w", expr, err)
+		}
+		buf := new(bytes.Buffer)
+		if err := jp.Execute(buf, data); err != nil {
+			return err
+		}
+		if buf.Len() > 0 {
+			values[name] = buf.String()
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	if err := stateStore.Set(key, values); err != nil {
+		return err
+	}
+	if cfg.TTLSeconds > 0 {
+		return stateStore.Expire(key, time.Duration(cfg.TTLSeconds)*time.Second)
+	}
+	return nil
+}
+
+// normalizeJSONPath accepts both the kubectl style {.a.b} and the common $.a.b notation
+func normalizeJSONPath(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "{") {
+		return expr
+	}
+	return "{" + strings.TrimPrefix(expr, "$") + "}"
+}
@@ -0,0 +1,180 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// SuccessConfig decides whether a response is a success beyond its status code, for the endpoints that answer 200
+// with an error object. The status codes are codes like 202 or classes like 2xx.
+type SuccessConfig struct {
+	// StatusCodes are the successful status codes, 2xx by default
+	StatusCodes []string `yaml:"statusCodes"`
+	// RetryableStatusCodes are retried by the retry settings of the receiver, 429 and 503 by default. Any other
+	// status code is a permanent failure.
+	RetryableStatusCodes []string `yaml:"retryableStatusCodes"`
+	// Body maps JSONPath expressions to the regular expressions their values must match, e.g. "{.ok}": "^true$"
+	Body map[string]string `yaml:"body"`
+	// Errors are JSONPath expressions that fail the response if they have a value, e.g. "{.error.message}"
+	Errors []string `yaml:"errors"`
+	// RetryBody makes the failures found in the body retryable
+	RetryBody bool `yaml:"retryBody"`
+}
+
+type successCriteria struct {
+	cfg       *SuccessConfig
+	success   []statusCodeMatcher
+	retryable []statusCodeMatcher
+	body      map[string]*regexp.Regexp
+}
+
+// statusCodeMatcher matches a status code, or a class with a zero code
+type statusCodeMatcher struct {
+	code  int
+	class int
+}
+
+func (m statusCodeMatcher) matches(code int) bool {
+	if m.code != 0 {
+		return m.code == code
+	}
+	return code/100 == m.class
+}
+
+func parseStatusCodes(field string, values []string) ([]statusCodeMatcher, error) {
+	matchers := make([]statusCodeMatcher, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if class, ok := strings.CutSuffix(value, "xx"); ok && len(class) == 1 && class[0] >= '1' && class[0] <= '5' {
+			matchers = append(matchers, statusCodeMatcher{class: int(class[0] - '0')})
+			continue
+		}
+		code, err := strconv.Atoi(value)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("success.%s: invalid status code %q", field, value)
+		}
+		matchers = append(matchers, statusCodeMatcher{code: code})
+	}
+	return matchers, nil
+}
+
+func newSuccessCriteria(cfg *SuccessConfig) (*successCriteria, error) {
+	c := &successCriteria{
+		cfg:  cfg,
+		body: make(map[string]*regexp.Regexp, len(cfg.Body)),
+	}
+	var err error
+	statusCodes := cfg.StatusCodes
+	if len(statusCodes) == 0 {
+		statusCodes = []string{"2xx"}
+	}
+	if c.success, err = parseStatusCodes("statusCodes", statusCodes); err != nil {
+		return nil, err
+	}
+	retryable := cfg.RetryableStatusCodes
+	if len(retryable) == 0 {
+		retryable = []string{strconv.Itoa(http.StatusTooManyRequests), strconv.Itoa(http.StatusServiceUnavailable)}
+	}
+	if c.retryable, err = parseStatusCodes("retryableStatusCodes", retryable); err != nil {
+		return nil, err
+	}
+
+	for expr, pattern := range cfg.Body {
+		if _, err := parseSuccessPath(expr); err != nil {
+			return nil, err
+		}
+		if c.body[expr], err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("success.body[%s]: %w", expr, err)
+		}
+	}
+	for _, expr := range cfg.Errors {
+		if _, err := parseSuccessPath(expr); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func parseSuccessPath(expr string) (*jsonpath.JSONPath, error) {
+	jp := jsonpath.New(expr).AllowMissingKeys(true)
+	if err := jp.Parse(normalizeJSONPath(expr)); err != nil {
+		return nil, fmt.Errorf("success: invalid JSONPath %q: %w", expr, err)
+	}
+	return jp, nil
+}
+
+// value returns the value of the expression, it is parsed again since a JSONPath cannot be executed concurrently
+func (c *successCriteria) value(expr string, data interface{}) (string, error) {
+	jp, err := parseSuccessPath(expr)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := jp.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// check returns nil for a successful response, a RetryableError for a failure that can succeed later
+func (c *successCriteria) check(resp *http.Response, body []byte) error {
+	for _, m := range c.success {
+		if m.matches(resp.StatusCode) {
+			return c.checkBody(body)
+		}
+	}
+	err := fmt.Errorf("unsuccessful response (%d): %s", resp.StatusCode, body)
+	for _, m := range c.retryable {
+		if m.matches(resp.StatusCode) {
+			return &RetryableError{Err: err, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+		}
+	}
+	return err
+}
+
+func (c *successCriteria) checkBody(body []byte) error {
+	if len(c.cfg.Body) == 0 && len(c.cfg.Errors) == 0 {
+		return nil
+	}
+	err := c.bodyError(body)
+	if err != nil && c.cfg.RetryBody {
+		return &RetryableError{Err: err}
+	}
+	return err
+}
+
+func (c *successCriteria) bodyError(body []byte) error {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("cannot parse response as JSON: %w", err)
+	}
+	for _, expr := range c.cfg.Errors {
+		value, err := c.value(expr, data)
+		if err != nil {
+			return err
+		}
+		// A null error is how many APIs say there is none
+		if value != "" && value != "<nil>" {
+			return errors.New("error in response: " + value)
+		}
+	}
+	for _, expr := range sortedKeys(c.cfg.Body) {
+		value, err := c.value(expr, data)
+		if err != nil {
+			return err
+		}
+		if !c.body[expr].MatchString(value) {
+			return fmt.Errorf("unexpected response: %s is %q", expr, value)
+		}
+	}
+	return nil
+}
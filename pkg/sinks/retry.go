@@ -0,0 +1,143 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// RetryableError is returned by the sinks when sending can succeed later, e.g. when the provider throttles
+type RetryableError struct {
+	Err error
+	// RetryAfter is the delay requested by the provider, zero if it did not ask for one
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s (retry after %s)", e.Err, e.RetryAfter)
+	}
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// httpResponseError returns nil for 2xx responses. The throttling responses 429 and 503 are retryable, honouring the
+// Retry-After header.
+func httpResponseError(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err := errors.New("not successfull (2xx) response: " + string(body))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return &RetryableError{Err: err, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+	return err
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// RetryConfig retries the events that failed with a retryable error, e.g. because the provider throttles. The delay
+// starts at BackoffSeconds and doubles with every attempt, a longer Retry-After of the provider takes precedence. Both
+// are capped at MaxBackoffSeconds. The events are retried 3 times by default, a MaxRetries of 0 disables retrying.
+type RetryConfig struct {
+	MaxRetries        *int `yaml:"maxRetries"`
+	BackoffSeconds    int  `yaml:"backoffSeconds"`
+	MaxBackoffSeconds int  `yaml:"maxBackoffSeconds"`
+}
+
+const defaultMaxRetries = 3
+
+var defaultRetryConfig = RetryConfig{
+	BackoffSeconds:    1,
+	MaxBackoffSeconds: 60,
+}
+
+// retrySink retries the retryable errors of the wrapped sink. Sending blocks while waiting, so the events of the
+// receiver queue up instead of failing during a throttling storm.
+type retrySink struct {
+	Sink
+	cfg        RetryConfig
+	maxRetries int
+}
+
+func newRetrySink(sink Sink, cfg *RetryConfig) Sink {
+	c := defaultRetryConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	maxRetries := defaultMaxRetries
+	if c.MaxRetries != nil {
+		maxRetries = *c.MaxRetries
+	}
+	if maxRetries <= 0 {
+		return sink
+	}
+	if c.BackoffSeconds == 0 {
+		c.BackoffSeconds = defaultRetryConfig.BackoffSeconds
+	}
+	if c.MaxBackoffSeconds == 0 {
+		c.MaxBackoffSeconds = defaultRetryConfig.MaxBackoffSeconds
+	}
+	return &retrySink{Sink: sink, cfg: c, maxRetries: maxRetries}
+}
+
+func (r *retrySink) Unwrap() Sink {
+	return r.Sink
+}
+
+func (r *retrySink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	backoff := time.Duration(r.cfg.BackoffSeconds) * time.Second
+	maxBackoff := time.Duration(r.cfg.MaxBackoffSeconds) * time.Second
+	for attempt := 0; ; attempt++ {
+		err := r.Sink.Send(ctx, ev)
+		var retryable *RetryableError
+		if err == nil || attempt >= r.maxRetries || !errors.As(err, &retryable) {
+			return err
+		}
+
+		delay := backoff
+		if retryable.RetryAfter > delay {
+			delay = retryable.RetryAfter
+		}
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+		log.Debug().Err(err).Str("sink", SinkType(r.Sink)).Int("attempt", attempt+1).Dur("delay", delay).Msg("Retrying event")
+
+		elapsed := make(chan struct{})
+		timer := clock.AfterFunc(delay, func() { close(elapsed) })
+		select {
+		case <-elapsed:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,118 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Mon, 01 Jan 2024 12:01:30 GMT", now))
+	assert.Zero(t, parseRetryAfter("Mon, 01 Jan 2024 11:59:00 GMT", now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
+
+func TestHTTPResponseError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusOK}
+	assert.NoError(t, httpResponseError(resp, nil))
+
+	resp = &http.Response{StatusCode: http.StatusBadRequest}
+	err := httpResponseError(resp, []byte("bad"))
+	var retryable *RetryableError
+	assert.False(t, errors.As(err, &retryable))
+
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"5"}}}
+	err = httpResponseError(resp, []byte("slow down"))
+	require.True(t, errors.As(err, &retryable))
+	assert.Equal(t, 5*time.Second, retryable.RetryAfter)
+}
+
+type flakySink struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakySink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *flakySink) Close() {}
+
+// delayClock runs the functions right away and records the delays they were scheduled with
+type delayClock struct {
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (c *delayClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *delayClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delays = append(c.delays, d)
+	return time.AfterFunc(0, f)
+}
+
+func TestRetrySink(t *testing.T) {
+	c := &delayClock{}
+	restore := clock.Set(c)
+	throttled := &RetryableError{Err: errors.New("throttled")}
+	slowDown := &RetryableError{Err: errors.New("slow down"), RetryAfter: 5 * time.Second}
+
+	flaky := &flakySink{errs: []error{throttled, slowDown, throttled}}
+	sink := newRetrySink(flaky, &RetryConfig{BackoffSeconds: 1})
+	assert.Equal(t, "*sinks.flakySink", SinkType(sink))
+	assert.NoError(t, sink.Send(context.Background(), &kube.EnhancedEvent{}))
+	assert.Equal(t, 4, flaky.calls)
+	assert.Equal(t, []time.Duration{time.Second, 5 * time.Second, 4 * time.Second}, c.delays)
+	restore()
+
+	// Other errors are not retried
+	flaky = &flakySink{errs: []error{errors.New("bad request")}}
+	sink = newRetrySink(flaky, nil)
+	assert.Error(t, sink.Send(context.Background(), &kube.EnhancedEvent{}))
+	assert.Equal(t, 1, flaky.calls)
+
+	// Retrying stops when the context is done
+	flaky = &flakySink{errs: []error{throttled, throttled}}
+	sink = newRetrySink(flaky, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sink.Send(ctx, &kube.EnhancedEvent{}), throttled)
+	assert.Equal(t, 1, flaky.calls)
+
+	// The retries run out
+	c = &delayClock{}
+	restore = clock.Set(c)
+	flaky = &flakySink{errs: []error{throttled, throttled, throttled, throttled, throttled}}
+	sink = newRetrySink(flaky, nil)
+	assert.ErrorIs(t, sink.Send(context.Background(), &kube.EnhancedEvent{}), throttled)
+	assert.Equal(t, 4, flaky.calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, c.delays)
+	restore()
+
+	// Retrying can be disabled
+	flaky = &flakySink{}
+	disabled := 0
+	assert.Same(t, flaky, newRetrySink(flaky, &RetryConfig{MaxRetries: &disabled}))
+}
@@ -0,0 +1,158 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// RocketChatConfig posts the events to a Rocket.Chat incoming webhook. The channel, the text and the attachment are
+// templates. Channel, Alias, Emoji and Avatar override the settings of the webhook integration.
+type RocketChatConfig struct {
+	Endpoint string `yaml:"endpoint" secret:"true"`
+	Channel  string `yaml:"channel"`
+	Alias    string `yaml:"alias"`
+	// Emoji is shown instead of the avatar, e.g. :warning:
+	Emoji  string `yaml:"emoji"`
+	Avatar string `yaml:"avatar"`
+	Text   string `yaml:"text"`
+	// The attachment is only added if one of its options is set
+	Title     string            `yaml:"title"`
+	TitleLink string            `yaml:"titleLink"`
+	Color     string            `yaml:"color"`
+	Fields    map[string]string `yaml:"fields"`
+	// ShortFields shows the fields side by side
+	ShortFields bool `yaml:"shortFields"`
+	TLS         TLS  `yaml:"tls"`
+}
+
+type rocketChatMessage struct {
+	Channel     string                 `json:"channel,omitempty"`
+	Alias       string                 `json:"alias,omitempty"`
+	Emoji       string                 `json:"emoji,omitempty"`
+	Avatar      string                 `json:"avatar,omitempty"`
+	Text        string                 `json:"text"`
+	Attachments []rocketChatAttachment `json:"attachments,omitempty"`
+}
+
+type rocketChatAttachment struct {
+	Title     string            `json:"title,omitempty"`
+	TitleLink string            `json:"title_link,omitempty"`
+	Color     string            `json:"color,omitempty"`
+	Fields    []rocketChatField `json:"fields,omitempty"`
+}
+
+type rocketChatField struct {
+	Short bool   `json:"short"`
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type RocketChat struct {
+	cfg    *RocketChatConfig
+	client *http.Client
+}
+
+func NewRocketChatSink(cfg *RocketChatConfig) (Sink, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("rocketchat.endpoint config option must be non-empty")
+	}
+	if cfg.Text == "" {
+		cfg.Text = "{{ .Message }}"
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &RocketChat{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (r *RocketChat) message(ev *kube.EnhancedEvent) (*rocketChatMessage, error) {
+	msg := &rocketChatMessage{
+		Alias:  r.cfg.Alias,
+		Emoji:  r.cfg.Emoji,
+		Avatar: r.cfg.Avatar,
+	}
+
+	var err error
+	if msg.Channel, err = GetString(ev, r.cfg.Channel); err != nil {
+		return nil, err
+	}
+	if msg.Text, err = GetString(ev, r.cfg.Text); err != nil {
+		return nil, err
+	}
+
+	if r.cfg.Title == "" && r.cfg.TitleLink == "" && r.cfg.Color == "" && len(r.cfg.Fields) == 0 {
+		return msg, nil
+	}
+
+	attachment := rocketChatAttachment{}
+	if attachment.Title, err = GetString(ev, r.cfg.Title); err != nil {
+		return nil, err
+	}
+	if attachment.TitleLink, err = GetString(ev, r.cfg.TitleLink); err != nil {
+		return nil, err
+	}
+	if attachment.Color, err = GetString(ev, r.cfg.Color); err != nil {
+		return nil, err
+	}
+	for k, v := range r.cfg.Fields {
+		value, err := GetString(ev, v)
+		if err != nil {
+			return nil, err
+		}
+		attachment.Fields = append(attachment.Fields, rocketChatField{Short: r.cfg.ShortFields, Title: k, Value: value})
+	}
+	sort.SliceStable(attachment.Fields, func(i, j int) bool {
+		return attachment.Fields[i].Title < attachment.Fields[j].Title
+	})
+	msg.Attachments = []rocketChatAttachment{attachment}
+
+	return msg, nil
+}
+
+func (r *RocketChat) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	msg, err := r.message(ev)
+	if err != nil {
+		return err
+	}
+
+	reqBody, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (r *RocketChat) Close() {
+	r.client.CloseIdleConnections()
+}
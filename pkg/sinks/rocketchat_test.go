@@ -0,0 +1,63 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestRocketChat_Send(t *testing.T) {
+	var received rocketChatMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	sink, err := NewRocketChatSink(&RocketChatConfig{
+		Endpoint: ts.URL,
+		Channel:  "#{{ .Namespace }}",
+		Emoji:    ":warning:",
+		Title:    "{{ .Reason }}",
+		Fields: map[string]string{
+			"Kind": "{{ .InvolvedObject.Kind }}",
+			"Name": "{{ .InvolvedObject.Name }}",
+		},
+		ShortFields: true,
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "prod"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Name: "api-0"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "#prod", received.Channel)
+	assert.Equal(t, ":warning:", received.Emoji)
+	assert.Equal(t, "Back-off restarting failed container", received.Text)
+	require.Len(t, received.Attachments, 1)
+	assert.Equal(t, "BackOff", received.Attachments[0].Title)
+	assert.Equal(t, []rocketChatField{
+		{Short: true, Title: "Kind", Value: "Pod"},
+		{Short: true, Title: "Name", Value: "api-0"},
+	}, received.Attachments[0].Fields)
+}
+
+func TestRocketChat_WithoutAttachment(t *testing.T) {
+	sink, err := NewRocketChatSink(&RocketChatConfig{Endpoint: "http://localhost"})
+	require.NoError(t, err)
+
+	msg, err := sink.(*RocketChat).message(&kube.EnhancedEvent{})
+	require.NoError(t, err)
+	assert.Empty(t, msg.Attachments)
+}
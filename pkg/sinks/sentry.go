@@ -0,0 +1,265 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/version"
+)
+
+const (
+	sentryMaxTagValue = 200
+	sentryMaxMessage  = 8192
+)
+
+var defaultSentryFingerprint = []string{
+	"{{ .InvolvedObject.Namespace }}",
+	"{{ .InvolvedObject.Kind }}",
+	"{{ .InvolvedObject.Name }}",
+	"{{ .Reason }}",
+}
+
+// SentryConfig turns the events into Sentry issues. The issues are grouped by the rendered Fingerprint, so repeated
+// events of an object, like CrashLoopBackOff, become occurrences of one issue.
+type SentryConfig struct {
+	DSN string `yaml:"dsn" secret:"true"`
+	// Types are the event types sent, only Warning events by default
+	Types       []string          `yaml:"types"`
+	Message     string            `yaml:"message"`
+	Fingerprint []string          `yaml:"fingerprint"`
+	Environment string            `yaml:"environment"`
+	Release     string            `yaml:"release"`
+	Tags        map[string]string `yaml:"tags"`
+	TLS         TLS               `yaml:"tls"`
+}
+
+type Sentry struct {
+	cfg      *SentryConfig
+	endpoint string
+	auth     string
+	client   *http.Client
+}
+
+// parseSentryDSN returns the envelope endpoint and the public key of a DSN like https://key@o1.ingest.sentry.io/42
+func parseSentryDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return "", "", errors.New("the public key is missing")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 || path[i+1:] == "" {
+		return "", "", errors.New("the project ID is missing")
+	}
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: path[:i] + "/api/" + path[i+1:] + "/envelope/"}
+	return endpoint.String(), u.User.Username(), nil
+}
+
+func NewSentrySink(cfg *SentryConfig) (Sink, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("sentry.dsn config option must be non-empty")
+	}
+	endpoint, key, err := parseSentryDSN(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("sentry.dsn is invalid: %w", err)
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = []string{"Warning"}
+	}
+	if cfg.Message == "" {
+		cfg.Message = "{{ .Reason }}: {{ .Message }}"
+	}
+	if len(cfg.Fingerprint) == 0 {
+		cfg.Fingerprint = defaultSentryFingerprint
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Sentry{
+		cfg:      cfg,
+		endpoint: endpoint,
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=kubernetes-event-exporter/%s, sentry_key=%s", version.Version, key),
+		client:   &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   float64           `json:"timestamp"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Platform    string            `json:"platform"`
+	Message     sentryMessage     `json:"message"`
+	Fingerprint []string          `json:"fingerprint"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]any    `json:"extra"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+// sentryEventID is derived from the event and its count, so a retried delivery is deduplicated by Sentry
+func sentryEventID(ev *kube.EnhancedEvent) (string, error) {
+	if ev.UID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(id), nil
+	}
+	sum := sha256.Sum256([]byte(string(ev.UID) + "/" + strconv.Itoa(int(ev.Count))))
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+func (s *Sentry) event(ev *kube.EnhancedEvent) (*sentryEvent, error) {
+	id, err := sentryEventID(ev)
+	if err != nil {
+		return nil, err
+	}
+	message, err := GetString(ev, s.cfg.Message)
+	if err != nil {
+		return nil, err
+	}
+	if truncated, ok := truncateRunes(message, sentryMaxMessage); ok {
+		message = truncated
+		countTruncatedPayload("sentry")
+	}
+
+	fingerprint := make([]string, 0, len(s.cfg.Fingerprint))
+	for _, tmpl := range s.cfg.Fingerprint {
+		part, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint = append(fingerprint, part)
+	}
+
+	level := "info"
+	if ev.Type == "Warning" {
+		level = "warning"
+	}
+
+	tags := map[string]string{
+		"kind":      ev.InvolvedObject.Kind,
+		"namespace": ev.InvolvedObject.Namespace,
+		"name":      ev.InvolvedObject.Name,
+		"reason":    ev.Reason,
+		"component": ev.Source.Component,
+	}
+	if ev.ClusterName != "" {
+		tags["cluster"] = ev.ClusterName
+	}
+	for name, tmpl := range s.cfg.Tags {
+		value, err := GetString(ev, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		tags[name] = value
+	}
+	for name, value := range tags {
+		if value == "" {
+			delete(tags, name)
+			continue
+		}
+		tags[name], _ = truncateRunes(value, sentryMaxTagValue)
+	}
+
+	environment, err := GetString(ev, s.cfg.Environment)
+	if err != nil {
+		return nil, err
+	}
+	release, err := GetString(ev, s.cfg.Release)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sentryEvent{
+		EventID:     id,
+		Timestamp:   float64(ev.GetTimestampMs()) / 1000,
+		Level:       level,
+		Logger:      "kubernetes-event-exporter",
+		Platform:    "other",
+		Message:     sentryMessage{Formatted: message},
+		Fingerprint: fingerprint,
+		Environment: environment,
+		Release:     release,
+		Tags:        tags,
+		Extra:       map[string]any{"event": json.RawMessage(ev.ToJSON())},
+	}, nil
+}
+
+func (s *Sentry) sends(ev *kube.EnhancedEvent) bool {
+	for _, t := range s.cfg.Types {
+		if t == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sentry) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	if !s.sends(ev) {
+		return nil
+	}
+	event, err := s.event(ev)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// An envelope is a header line and an item header line followed by the item
+	var buf bytes.Buffer
+	header, _ := json.Marshal(map[string]string{"event_id": event.EventID, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	buf.Write(header)
+	fmt.Fprintf(&buf, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	buf.Write(payload)
+	buf.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (s *Sentry) Close() {
+	s.client.CloseIdleConnections()
+}
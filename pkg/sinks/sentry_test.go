@@ -0,0 +1,93 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestParseSentryDSN(t *testing.T) {
+	endpoint, key, err := parseSentryDSN("https://abc@o1.ingest.sentry.io/42")
+	require.NoError(t, err)
+	assert.Equal(t, "https://o1.ingest.sentry.io/api/42/envelope/", endpoint)
+	assert.Equal(t, "abc", key)
+
+	endpoint, _, err = parseSentryDSN("http://abc@sentry.example.com/sentry/7")
+	require.NoError(t, err)
+	assert.Equal(t, "http://sentry.example.com/sentry/api/7/envelope/", endpoint)
+
+	_, _, err = parseSentryDSN("https://sentry.example.com/7")
+	assert.Error(t, err)
+	_, _, err = parseSentryDSN("https://abc@sentry.example.com/")
+	assert.Error(t, err)
+}
+
+func TestSentry_Send(t *testing.T) {
+	var auth string
+	var lines []string
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/api/42/envelope/", r.URL.Path)
+		auth = r.Header.Get("X-Sentry-Auth")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer ts.Close()
+
+	sink, err := NewSentrySink(&SentryConfig{
+		DSN:         strings.Replace(ts.URL, "http://", "http://public@", 1) + "/42",
+		Environment: `{{ index .InvolvedObject.Labels "env" }}`,
+		Release:     `{{ index .InvolvedObject.Labels "app.kubernetes.io/version" }}`,
+		Tags:        map[string]string{"team": `{{ index .InvolvedObject.Labels "team" }}`},
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.UID = "5b4a3c"
+	ev.Count = 3
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"}
+	ev.InvolvedObject.Labels = map[string]string{"env": "production", "app.kubernetes.io/version": "1.2.3", "team": "payments"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Contains(t, auth, "sentry_key=public")
+	require.Len(t, lines, 3)
+	var item map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &item))
+	assert.Equal(t, "event", item["type"])
+	assert.EqualValues(t, len(lines[2]), item["length"])
+
+	var event sentryEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Len(t, event.EventID, 32)
+	assert.Equal(t, "warning", event.Level)
+	assert.Equal(t, "BackOff: Back-off restarting failed container", event.Message.Formatted)
+	assert.Equal(t, []string{"prod", "Pod", "api-0", "BackOff"}, event.Fingerprint)
+	assert.Equal(t, "production", event.Environment)
+	assert.Equal(t, "1.2.3", event.Release)
+	assert.Equal(t, map[string]string{"kind": "Pod", "namespace": "prod", "name": "api-0", "reason": "BackOff", "team": "payments"}, event.Tags)
+
+	// The ID is stable across retries, Normal events are not sent by default
+	again, err := sink.(*Sentry).event(ev)
+	require.NoError(t, err)
+	assert.Equal(t, event.EventID, again.EventID)
+	ev.Type = "Normal"
+	require.NoError(t, sink.Send(context.Background(), ev))
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,127 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/aws/aws-sdk-go/service/sesv2/sesv2iface"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// SESConfig sends the events as emails with the Amazon SES API, for clusters that cannot reach an SMTP server. The
+// credentials come from the default chain, e.g. IRSA, and RoleARN is assumed if set. The recipients, the subject, the
+// body and the values of the tags are templates like in the email sink.
+type SESConfig struct {
+	Region  string   `yaml:"region"`
+	RoleARN string   `yaml:"roleARN"`
+	From    string   `yaml:"from"`
+	To      []string `yaml:"to"`
+	Subject string   `yaml:"subject"`
+	Body    string   `yaml:"body"`
+	HTML    bool     `yaml:"html"`
+	// ConfigurationSet applies the rules of the set, e.g. event publishing, and Tags are passed to its destinations
+	ConfigurationSet string            `yaml:"configurationSet"`
+	Tags             map[string]string `yaml:"tags"`
+	// Endpoint overrides the SES endpoint, e.g. for a VPC endpoint
+	Endpoint string `yaml:"endpoint"`
+}
+
+// sesTagValueInvalid matches the characters SES does not allow in tag values
+var sesTagValueInvalid = regexp.MustCompile(`[^A-Za-z0-9_.@-]`)
+
+type SESSink struct {
+	cfg *SESConfig
+	svc sesv2iface.SESV2API
+}
+
+func NewSESSink(cfg *SESConfig) (Sink, error) {
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("ses.from and ses.to config options must be non-empty")
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultEmailSubject
+	}
+	if cfg.Body == "" {
+		cfg.Body = defaultEmailBody
+	}
+
+	awsConfig := &aws.Config{Region: aws.String(cfg.Region)}
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	var configs []*aws.Config
+	if cfg.RoleARN != "" {
+		configs = append(configs, &aws.Config{Credentials: stscreds.NewCredentials(sess, cfg.RoleARN)})
+	}
+
+	return &SESSink{
+		cfg: cfg,
+		svc: sesv2.New(sess, configs...),
+	}, nil
+}
+
+func (s *SESSink) input(ev *kube.EnhancedEvent) (*sesv2.SendEmailInput, error) {
+	to, err := renderRecipients(ev, s.cfg.To)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := GetString(ev, s.cfg.Subject)
+	if err != nil {
+		return nil, err
+	}
+	body, err := GetString(ev, s.cfg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &sesv2.Content{Charset: aws.String("UTF-8"), Data: aws.String(body)}
+	msgBody := &sesv2.Body{Text: content}
+	if s.cfg.HTML {
+		msgBody = &sesv2.Body{Html: content}
+	}
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(s.cfg.From),
+		Destination:      &sesv2.Destination{ToAddresses: aws.StringSlice(to)},
+		Content: &sesv2.EmailContent{
+			Simple: &sesv2.Message{
+				Subject: &sesv2.Content{Charset: aws.String("UTF-8"), Data: aws.String(subject)},
+				Body:    msgBody,
+			},
+		},
+	}
+	if s.cfg.ConfigurationSet != "" {
+		input.ConfigurationSetName = aws.String(s.cfg.ConfigurationSet)
+	}
+	for _, name := range sortedKeys(s.cfg.Tags) {
+		value, err := GetString(ev, s.cfg.Tags[name])
+		if err != nil {
+			return nil, err
+		}
+		if value = sesTagValueInvalid.ReplaceAllString(value, "_"); value != "" {
+			input.EmailTags = append(input.EmailTags, &sesv2.MessageTag{Name: aws.String(name), Value: aws.String(value)})
+		}
+	}
+	return input, nil
+}
+
+func (s *SESSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	input, err := s.input(ev)
+	if err != nil {
+		return err
+	}
+	_, err = s.svc.SendEmailWithContext(ctx, input)
+	return err
+}
+
+func (s *SESSink) Close() {
+}
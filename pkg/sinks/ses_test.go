@@ -0,0 +1,61 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/aws/aws-sdk-go/service/sesv2/sesv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+type fakeSES struct {
+	sesv2iface.SESV2API
+	inputs []*sesv2.SendEmailInput
+}
+
+func (f *fakeSES) SendEmailWithContext(_ aws.Context, input *sesv2.SendEmailInput, _ ...request.Option) (*sesv2.SendEmailOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &sesv2.SendEmailOutput{MessageId: aws.String("1")}, nil
+}
+
+func TestSESSink_Send(t *testing.T) {
+	sink, err := NewSESSink(&SESConfig{
+		Region:           "eu-west-1",
+		From:             "exporter@example.com",
+		To:               []string{"{{ .InvolvedObject.Namespace }}@example.com, oncall@example.com"},
+		HTML:             true,
+		Body:             "<b>{{ .Reason }}</b>",
+		ConfigurationSet: "kube-events",
+		Tags:             map[string]string{"namespace": "{{ .InvolvedObject.Namespace }}", "reason": "{{ .Reason }} !"},
+	})
+	require.NoError(t, err)
+	fake := &fakeSES{}
+	sink.(*SESSink).svc = fake
+
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "team-a"
+	ev.InvolvedObject.Name = "api-0"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	require.Len(t, fake.inputs, 1)
+	input := fake.inputs[0]
+	assert.Equal(t, "exporter@example.com", *input.FromEmailAddress)
+	assert.Equal(t, []string{"oncall@example.com", "team-a@example.com"}, aws.StringValueSlice(input.Destination.ToAddresses))
+	assert.Equal(t, "[Warning] BackOff on Pod/api-0", *input.Content.Simple.Subject.Data)
+	assert.Nil(t, input.Content.Simple.Body.Text)
+	assert.Equal(t, "<b>BackOff</b>", *input.Content.Simple.Body.Html.Data)
+	assert.Equal(t, "kube-events", *input.ConfigurationSetName)
+	require.Len(t, input.EmailTags, 2)
+	assert.Equal(t, "namespace", *input.EmailTags[0].Name)
+	assert.Equal(t, "team-a", *input.EmailTags[0].Value)
+	assert.Equal(t, "BackOff__", *input.EmailTags[1].Value)
+}
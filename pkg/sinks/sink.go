@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
@@ -26,6 +27,22 @@ type BatchSink interface {
 	SendBatch([]*kube.EnhancedEvent) error
 }
 
+// wrappedSink is implemented by the sinks that add behaviour on top of another sink
+type wrappedSink interface {
+	Unwrap() Sink
+}
+
+// SinkType returns the type name of the underlying sink, for logging purposes
+func SinkType(s Sink) string {
+	for {
+		w, ok := s.(wrappedSink)
+		if !ok {
+			return reflect.TypeOf(s).String()
+		}
+		s = w.Unwrap()
+	}
+}
+
 type TLS struct {
 	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
 	ServerName         string `yaml:"serverName"`
@@ -63,5 +80,6 @@ func setupTLS(cfg *TLS) (*tls.Config, error) {
 	if len(cfg.KeyFile) == 0 && len(cfg.CertFile) > 0 {
 		return nil, errors.New("configured certFile but forget keyFile for client certificate authentication")
 	}
+	ApplyTLSPolicy(tlsClientConfig)
 	return tlsClientConfig, nil
 }
@@ -2,7 +2,10 @@ package sinks
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/slack-go/slack"
@@ -24,8 +27,13 @@ type SlackConfig struct {
 	// CompletionCondition is a template that should evaluate to a non-empty string for the event that is considered to be the completion of a thread.
 	CompletionCondition string `yaml:"completionCondition,omitempty"`
 	// CompletionEmoji is the emoji to add as a reaction to the first message in a thread when the completion event is received. Defaults to :white_check_mark:
-	CompletionEmoji string                `yaml:"completionEmoji,omitempty"`
-	Cache           *ConfigMapCacheConfig `yaml:"cache,omitempty"`
+	CompletionEmoji string `yaml:"completionEmoji,omitempty"`
+	// ThreadTTLSeconds starts a new thread for the key once the existing one is older, by default threads never expire
+	ThreadTTLSeconds int64                 `yaml:"threadTTLSeconds,omitempty"`
+	Cache            *ConfigMapCacheConfig `yaml:"cache,omitempty"`
+	// SilenceDurations adds a button per duration, e.g. 1h, to silence the event. It requires the silences to be
+	// enabled and the interactivity of the Slack app to point at the silences API.
+	SilenceDurations []string `yaml:"silenceDurations,omitempty"`
 }
 
 type SlackSink struct {
@@ -67,8 +75,13 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 	if err != nil {
 		return err
 	}
+	message, truncated := truncateRunes(message, slackMaxTextLength)
+	if truncated {
+		countTruncatedPayload("slack")
+	}
 
 	options := []slack.MsgOption{slack.MsgOptionText(message, true)}
+	var attachments []slack.Attachment
 	if s.cfg.Fields != nil {
 		fields := make([]slack.AttachmentField, 0)
 		for k, v := range s.cfg.Fields {
@@ -115,11 +128,18 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 			}
 		}
 
-		options = append(options, slack.MsgOptionAttachments(slackAttachment))
+		attachments = append(attachments, slackAttachment)
+	}
+
+	if len(s.cfg.SilenceDurations) > 0 && ev.SilenceKey != "" {
+		attachments = append(attachments, slackSilenceAttachment(s.cfg.SilenceDurations, ev.SilenceKey))
+	}
+	if len(attachments) > 0 {
+		options = append(options, slack.MsgOptionAttachments(attachments...))
 	}
 
 	if s.cfg.ThreadKey == "" {
-		_ch, _ts, _text, err := s.client.SendMessageContext(ctx, channel, options...)
+		_ch, _ts, _text, err := s.sendMessage(ctx, channel, options...)
 		log.Debug().Str("ch", _ch).Str("ts", _ts).Str("text", _text).Err(err).Msg("Slack Response")
 		return err
 	}
@@ -127,12 +147,12 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 	threadKey, err := GetString(ev, s.cfg.ThreadKey)
 	if err != nil {
 		log.Warn().Err(err).Str("template", s.cfg.ThreadKey).Msg("Failed to execute threadKey template")
-		_ch, _ts, _text, err := s.client.SendMessageContext(ctx, channel, options...)
+		_ch, _ts, _text, err := s.sendMessage(ctx, channel, options...)
 		log.Debug().Str("ch", _ch).Str("ts", _ts).Str("text", _text).Err(err).Msg("Slack Response")
 		return err
 	}
 	if threadKey == "" {
-		_ch, _ts, _text, err := s.client.SendMessageContext(ctx, channel, options...)
+		_ch, _ts, _text, err := s.sendMessage(ctx, channel, options...)
 		log.Debug().Str("ch", _ch).Str("ts", _ts).Str("text", _text).Err(err).Msg("Slack Response")
 		return err
 	}
@@ -147,13 +167,25 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		}
 	}
 
+	if locker, ok := s.cache.(threadLocker); ok {
+		unlock, err := locker.Lock(ctx, threadKey)
+		if err != nil {
+			return &RetryableError{Err: fmt.Errorf("cannot lock the thread: %w", err)}
+		}
+		defer unlock()
+	}
+
 	parentInfo, found := s.cache.Get(threadKey)
+	if found && parentInfo.expired(time.Duration(s.cfg.ThreadTTLSeconds)*time.Second) {
+		log.Debug().Str("threadKey", threadKey).Msg("Slack thread expired, starting a new one")
+		found = false
+	}
 
 	if found {
 		options = append(options, slack.MsgOptionTS(parentInfo.Timestamp))
 	}
 
-	_ch, _ts, _text, err := s.client.SendMessageContext(ctx, channel, options...)
+	_ch, _ts, _text, err := s.sendMessage(ctx, channel, options...)
 	log.Debug().Str("ch", _ch).Str("ts", _ts).Str("text", _text).Err(err).Msg("Slack Response")
 	if err != nil {
 		return err
@@ -176,6 +208,7 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 			err := s.cache.Set(threadKey, threadInfo{
 				Timestamp: _ts,
 				ChannelID: _ch,
+				CreatedAt: time.Now(),
 			})
 			if err != nil {
 				log.Warn().Err(err).Str("threadKey", threadKey).Msg("Failed to set thread in cache")
@@ -186,6 +219,37 @@ func (s *SlackSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 	return nil
 }
 
+// slackSilenceAttachment has the buttons to silence the event, the value of a button is the duration and the key
+// separated by a pipe
+func slackSilenceAttachment(durations []string, key string) slack.Attachment {
+	actions := make([]slack.AttachmentAction, 0, len(durations))
+	for _, d := range durations {
+		actions = append(actions, slack.AttachmentAction{
+			Name:  "silence",
+			Text:  "Silence " + d,
+			Type:  "button",
+			Value: d + "|" + key,
+		})
+	}
+	return slack.Attachment{
+		CallbackID: "silence",
+		Fallback:   "Silence the event",
+		Actions:    actions,
+	}
+}
+
+// sendMessage maps the rate limit errors of Slack to retryable errors
+func (s *SlackSink) sendMessage(ctx context.Context, channel string, options ...slack.MsgOption) (string, string, string, error) {
+	ch, ts, text, err := s.client.SendMessageContext(ctx, channel, options...)
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		err = &RetryableError{Err: err, RetryAfter: rateLimited.RetryAfter}
+	}
+	return ch, ts, text, err
+}
+
 func (s *SlackSink) Close() {
-	// No-op
+	if c, ok := s.cache.(*ConfigMapCache); ok {
+		c.Close()
+	}
 }
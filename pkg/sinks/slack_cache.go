@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
@@ -13,11 +14,20 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
 type threadInfo struct {
 	Timestamp string
 	ChannelID string
+	// CreatedAt is zero for the threads cached by older versions
+	CreatedAt time.Time `json:",omitempty"`
+}
+
+// expired reports whether the thread is older than the ttl, threads without a creation time never expire
+func (t threadInfo) expired(ttl time.Duration) bool {
+	return ttl > 0 && !t.CreatedAt.IsZero() && time.Since(t.CreatedAt) > ttl
 }
 
 type ThreadCache interface {
@@ -58,18 +68,42 @@ func (c *InMemoryCache) Delete(key string) error {
 	return nil
 }
 
+// ConfigMapCacheConfig persists the threads in a ConfigMap, which can be shared by multiple replicas. The in-memory
+// copy is reconciled with the ConfigMap periodically, picking up the threads of the other replicas and manual edits.
 type ConfigMapCacheConfig struct {
 	Name      string `yaml:"name"`
 	Namespace string `yaml:"namespace"`
+	// ReconcileIntervalSeconds defaults to 60, a negative value disables the reconciliation
+	ReconcileIntervalSeconds int `yaml:"reconcileIntervalSeconds,omitempty"`
+	// TTLSeconds prunes the threads older than this when reconciling, by default they are kept
+	TTLSeconds int64 `yaml:"ttlSeconds,omitempty"`
+	// Lock serializes the handling of a thread key between the replicas with a Lease, so that they do not start
+	// duplicate threads. The lock expires after LockDurationSeconds, 15 by default, if its holder crashes.
+	Lock                bool `yaml:"lock,omitempty"`
+	LockDurationSeconds int  `yaml:"lockDurationSeconds,omitempty"`
+}
+
+// threadLocker is implemented by the caches shared by replicas
+type threadLocker interface {
+	// Lock blocks until the thread key is locked and the cached thread is up-to-date, the returned function unlocks it
+	Lock(ctx context.Context, key string) (func(), error)
 }
 
+const defaultConfigMapCacheReconcileInterval = 60 * time.Second
+
 type ConfigMapCache struct {
 	client    kubernetes.Interface
 	namespace string
 	name      string
-	// We keep an in-memory copy for fast reads, but we should be careful about consistency.
+	ttl       time.Duration
+	lock      *kube.LeaseLock
+	// We keep an in-memory copy for fast reads, it is reconciled with the ConfigMap periodically
 	store map[string]threadInfo
-	mu    sync.RWMutex
+	// lastReconcile is when the in-memory copy was last replaced by the ConfigMap
+	lastReconcile time.Time
+	mu            sync.RWMutex
+	stop          chan struct{}
+	done          chan struct{}
 }
 
 func NewConfigMapCache(cfg *ConfigMapCacheConfig) (*ConfigMapCache, error) {
@@ -82,18 +116,42 @@ func NewConfigMapCache(cfg *ConfigMapCacheConfig) (*ConfigMapCache, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create k8s client: %w", err)
 	}
+	return newConfigMapCache(clientset, cfg)
+}
 
+func newConfigMapCache(client kubernetes.Interface, cfg *ConfigMapCacheConfig) (*ConfigMapCache, error) {
 	c := &ConfigMapCache{
-		client:    clientset,
+		client:    client,
 		namespace: cfg.Namespace,
 		name:      cfg.Name,
+		ttl:       time.Duration(cfg.TTLSeconds) * time.Second,
 		store:     make(map[string]threadInfo),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if cfg.Lock {
+		lock, err := kube.NewLeaseLock(client, cfg.Namespace, cfg.Name, time.Duration(cfg.LockDurationSeconds)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		c.lock = lock
 	}
 
 	if err := c.load(); err != nil {
 		return nil, err
 	}
 
+	interval := time.Duration(cfg.ReconcileIntervalSeconds) * time.Second
+	if interval == 0 {
+		interval = defaultConfigMapCacheReconcileInterval
+	}
+	if interval > 0 {
+		go c.run(interval)
+	} else {
+		close(c.done)
+	}
+
 	return c, nil
 }
 
@@ -114,35 +172,103 @@ func (c *ConfigMapCache) load() error {
 			if err != nil {
 				return fmt.Errorf("failed to create configmap: %w", err)
 			}
+			c.lastReconcile = time.Now()
 			return nil
 		}
 		return fmt.Errorf("failed to get configmap: %w", err)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store = parseThreads(cm)
+	c.lastReconcile = time.Now()
+	return nil
+}
+
+// parseThreads returns the threads stored in the ConfigMap, a corrupt value is logged and overwritten later
+func parseThreads(cm *corev1.ConfigMap) map[string]threadInfo {
+	threads := make(map[string]threadInfo)
 	if data, ok := cm.Data["threads"]; ok {
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		if err := json.Unmarshal([]byte(data), &c.store); err != nil {
+		if err := json.Unmarshal([]byte(data), &threads); err != nil {
 			log.Error().Err(err).Msg("Failed to unmarshal threads data from configmap")
-			// Don't fail, just start empty? Or maybe it's corrupt.
-			// We can overwrite it later.
 		}
 	}
-	return nil
+	return threads
+}
+
+func (c *ConfigMapCache) run(interval time.Duration) {
+	defer close(c.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.reconcile(); err != nil {
+				log.Warn().Err(err).Str("configmap", c.namespace+"/"+c.name).Msg("Failed to reconcile the thread cache")
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// reconcile replaces the in-memory copy by the ConfigMap, which is the source of truth. Threads missing in the
+// ConfigMap are only kept if they were created since the last reconcile, their save may have failed. The threads
+// older than the ttl are pruned.
+func (c *ConfigMapCache) reconcile() error {
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(context.Background(), c.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	remote := parseThreads(cm)
+
+	c.mu.Lock()
+	missing := make(map[string]threadInfo)
+	for key, info := range c.store {
+		if _, ok := remote[key]; !ok && info.CreatedAt.After(c.lastReconcile) {
+			remote[key] = info
+			missing[key] = info
+		}
+	}
+	var expired []string
+	for key, info := range remote {
+		if info.expired(c.ttl) {
+			delete(remote, key)
+			expired = append(expired, key)
+		}
+	}
+	c.store = remote
+	c.lastReconcile = time.Now()
+	c.mu.Unlock()
+
+	if len(missing) == 0 && len(expired) == 0 {
+		return nil
+	}
+	log.Debug().Int("restored", len(missing)).Int("pruned", len(expired)).Msg("Reconciled the thread cache")
+	return c.save(func(threads map[string]threadInfo) {
+		for key, info := range missing {
+			threads[key] = info
+		}
+		for _, key := range expired {
+			delete(threads, key)
+		}
+	})
 }
 
-func (c *ConfigMapCache) save() error {
+// save applies the change to the threads stored in the ConfigMap, instead of overwriting it with the in-memory copy,
+// so that the changes of other replicas are not lost
+func (c *ConfigMapCache) save(change func(threads map[string]threadInfo)) error {
 	// Retry on conflict ensures that if multiple upgrades happen simultaneously
 	// (or any other concurrent modification to the ConfigMap), we don't fail.
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		c.mu.RLock()
-		data, err := json.Marshal(c.store)
-		c.mu.RUnlock()
+		cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(context.Background(), c.name, metav1.GetOptions{})
 		if err != nil {
 			return err
 		}
 
-		cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(context.Background(), c.name, metav1.GetOptions{})
+		threads := parseThreads(cm)
+		change(threads)
+		data, err := json.Marshal(threads)
 		if err != nil {
 			return err
 		}
@@ -169,7 +295,9 @@ func (c *ConfigMapCache) Set(key string, info threadInfo) error {
 	c.store[key] = info
 	c.mu.Unlock()
 
-	return c.save()
+	return c.save(func(threads map[string]threadInfo) {
+		threads[key] = info
+	})
 }
 
 func (c *ConfigMapCache) Delete(key string) error {
@@ -177,5 +305,44 @@ func (c *ConfigMapCache) Delete(key string) error {
 	delete(c.store, key)
 	c.mu.Unlock()
 
-	return c.save()
+	return c.save(func(threads map[string]threadInfo) {
+		delete(threads, key)
+	})
+}
+
+// Lock locks the key if locking is enabled and re-reads its thread from the ConfigMap, another replica may have
+// changed it since the last reconcile
+func (c *ConfigMapCache) Lock(ctx context.Context, key string) (func(), error) {
+	if c.lock == nil {
+		return func() {}, nil
+	}
+	unlock, err := c.lock.Lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	info, ok := parseThreads(cm)[key]
+	c.mu.Lock()
+	if ok {
+		c.store[key] = info
+	} else {
+		delete(c.store, key)
+	}
+	c.mu.Unlock()
+	return unlock, nil
+}
+
+// Close stops the reconciliation
+func (c *ConfigMapCache) Close() {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
 }
@@ -0,0 +1,111 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapCache_SharedByReplicas(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cfg := &ConfigMapCacheConfig{Name: "threads", Namespace: "monitoring", ReconcileIntervalSeconds: -1}
+
+	a, err := newConfigMapCache(client, cfg)
+	require.NoError(t, err)
+	b, err := newConfigMapCache(client, cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Set("upgrade-a", threadInfo{Timestamp: "1", CreatedAt: time.Now()}))
+	require.NoError(t, b.Set("upgrade-b", threadInfo{Timestamp: "2", CreatedAt: time.Now()}))
+
+	// b saved its thread without overwriting the one of a
+	require.NoError(t, a.reconcile())
+	_, ok := a.Get("upgrade-a")
+	assert.True(t, ok)
+	info, ok := a.Get("upgrade-b")
+	assert.True(t, ok)
+	assert.Equal(t, "2", info.Timestamp)
+}
+
+func TestConfigMapCache_ReconcileExternalChanges(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	c, err := newConfigMapCache(client, &ConfigMapCacheConfig{
+		Name:                     "threads",
+		Namespace:                "monitoring",
+		ReconcileIntervalSeconds: -1,
+		TTLSeconds:               3600,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("deleted-manually", threadInfo{Timestamp: "1", CreatedAt: time.Now()}))
+	require.NoError(t, c.reconcile())
+
+	// Edit the ConfigMap like an operator would: remove a thread, add an expired and a legacy one
+	cm, err := client.CoreV1().ConfigMaps("monitoring").Get(context.Background(), "threads", metav1.GetOptions{})
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]threadInfo{
+		"expired": {Timestamp: "2", CreatedAt: time.Now().Add(-2 * time.Hour)},
+		"legacy":  {Timestamp: "3"},
+	})
+	require.NoError(t, err)
+	cm.Data["threads"] = string(data)
+	_, err = client.CoreV1().ConfigMaps("monitoring").Update(context.Background(), cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// A thread whose save did not make it to the ConfigMap yet is kept
+	c.mu.Lock()
+	c.store["unsaved"] = threadInfo{Timestamp: "4", CreatedAt: time.Now()}
+	c.mu.Unlock()
+
+	require.NoError(t, c.reconcile())
+
+	_, ok := c.Get("deleted-manually")
+	assert.False(t, ok)
+	_, ok = c.Get("expired")
+	assert.False(t, ok)
+	_, ok = c.Get("legacy")
+	assert.True(t, ok)
+	_, ok = c.Get("unsaved")
+	assert.True(t, ok)
+
+	cm, err = client.CoreV1().ConfigMaps("monitoring").Get(context.Background(), "threads", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, cm.Data["threads"], "expired")
+	assert.Contains(t, cm.Data["threads"], "unsaved")
+}
+
+func TestConfigMapCache_Close(t *testing.T) {
+	c, err := newConfigMapCache(fake.NewSimpleClientset(), &ConfigMapCacheConfig{Name: "threads", Namespace: "monitoring"})
+	require.NoError(t, err)
+	c.Close()
+	c.Close()
+}
+
+func TestConfigMapCache_LockRefreshesThread(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cfg := &ConfigMapCacheConfig{Name: "threads", Namespace: "monitoring", ReconcileIntervalSeconds: -1, Lock: true}
+
+	a, err := newConfigMapCache(client, cfg)
+	require.NoError(t, err)
+	b, err := newConfigMapCache(client, cfg)
+	require.NoError(t, err)
+
+	unlock, err := a.Lock(context.Background(), "upgrade")
+	require.NoError(t, err)
+	require.NoError(t, a.Set("upgrade", threadInfo{Timestamp: "1", CreatedAt: time.Now()}))
+	unlock()
+
+	// b sees the thread started by a right away, instead of starting a duplicate one
+	unlock, err = b.Lock(context.Background(), "upgrade")
+	require.NoError(t, err)
+	defer unlock()
+	info, ok := b.Get("upgrade")
+	assert.True(t, ok)
+	assert.Equal(t, "1", info.Timestamp)
+}
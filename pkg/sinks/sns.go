@@ -37,7 +37,7 @@ func NewSNSSink(cfg *SNSConfig) (Sink, error) {
 }
 
 func (s *SNSSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
-	toSend, e := serializeEventWithLayout(s.cfg.Layout, ev)
+	toSend, e := fitEventPayload("sns", resolveLayout(ctx, s.cfg.Layout), ev, snsMaxMessageSize)
 	if e != nil {
 		return e
 	}
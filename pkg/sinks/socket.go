@@ -0,0 +1,92 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// SocketConfig writes the events as lines to a plain socket, for the collectors that only accept raw input, or to a
+// Unix socket, for a node-local agent to consume them without a network hop or credentials. A line is the event as
+// JSON, its layout as JSON, or the Format template rendered as text. Over UDP and unixgram a line is a datagram.
+type SocketConfig struct {
+	// Address is the host:port of the collector, or the path of the Unix socket
+	Address string `yaml:"address"`
+	// Protocol is tcp (default), udp, tls, unix or unixgram
+	Protocol string                 `yaml:"protocol,omitempty"`
+	Layout   map[string]interface{} `yaml:"layout"`
+	// Format is the template of the line, it replaces the JSON encoding of the event
+	Format         string `yaml:"format,omitempty"`
+	TimeoutSeconds int    `yaml:"timeoutSeconds,omitempty"`
+	TLS            TLS    `yaml:"tls"`
+}
+
+type Socket struct {
+	cfg    *SocketConfig
+	writer *connWriter
+}
+
+func NewSocketSink(cfg *SocketConfig) (Sink, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("socket.address config option must be non-empty")
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = "tcp"
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	s := &Socket{cfg: cfg}
+	switch cfg.Protocol {
+	case "tcp", "udp", "unix", "unixgram":
+		s.writer = newConnWriter(cfg.Protocol, cfg.Address, nil, timeout)
+	case "tls":
+		tlsClientConfig, err := setupTLS(&cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup TLS: %w", err)
+		}
+		s.writer = newConnWriter("tcp", cfg.Address, tlsClientConfig, timeout)
+	default:
+		return nil, fmt.Errorf("socket.protocol must be tcp, udp, tls, unix or unixgram, got %q", cfg.Protocol)
+	}
+	// The collectors never write, a closed connection is only noticed by reading
+	s.writer.detectClose = cfg.Protocol != "udp" && cfg.Protocol != "unixgram"
+	return s, nil
+}
+
+func (c *SocketConfig) unix() bool {
+	return c.Protocol == "unix" || c.Protocol == "unixgram"
+}
+
+func (s *Socket) line(ctx context.Context, ev *kube.EnhancedEvent) ([]byte, error) {
+	if s.cfg.Format != "" {
+		line, err := GetString(ev, s.cfg.Format)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(line, "\n") + "\n"), nil
+	}
+	line, err := serializeEventWithLayout(resolveLayout(ctx, s.cfg.Layout), ev)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func (s *Socket) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := s.line(ctx, ev)
+	if err != nil {
+		return err
+	}
+	return s.writer.Write(ctx, line)
+}
+
+func (s *Socket) Close() {
+	s.writer.Close()
+}
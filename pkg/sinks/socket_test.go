@@ -0,0 +1,95 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func socketTestEvent(reason string) *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = reason
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+	return ev
+}
+
+func TestSocket_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s, err := NewSocketSink(&SocketConfig{
+		Address: listener.Addr().String(),
+		Layout:  map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Send(context.Background(), socketTestEvent("BackOff")))
+	require.NoError(t, s.Send(context.Background(), socketTestEvent("Pulled")))
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, `{"reason":"BackOff"}`, scanner.Text())
+	require.True(t, scanner.Scan())
+	assert.Equal(t, `{"reason":"Pulled"}`, scanner.Text())
+}
+
+func TestSocket_UDPFormat(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewSocketSink(&SocketConfig{
+		Address:  conn.LocalAddr().String(),
+		Protocol: "udp",
+		Format:   "{{ .Type }} {{ .InvolvedObject.Namespace }}/{{ .InvolvedObject.Name }} {{ .Reason }}",
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Send(context.Background(), socketTestEvent("BackOff")))
+
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Warning shop/checkout-1 BackOff\n", string(buf[:n]))
+
+	_, err = NewSocketSink(&SocketConfig{Address: "collector:5170", Protocol: "sctp"})
+	assert.Error(t, err)
+}
+
+func TestSocket_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensor.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	cfg := &SocketConfig{Address: path, Protocol: "unix"}
+	s, err := NewSocketSink(cfg)
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Send(context.Background(), socketTestEvent("BackOff")))
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	require.True(t, scanner.Scan())
+	assert.Contains(t, scanner.Text(), `"reason":"BackOff"`)
+
+	// The path is not an endpoint of the egress policy
+	assert.Empty(t, (&ReceiverConfig{Name: "sensor", Socket: cfg}).endpoints())
+}
@@ -0,0 +1,283 @@
+package sinks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	_ "modernc.org/sqlite"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// SQLiteConfig keeps a local, queryable history of the events in an embedded SQLite database. Old events are pruned
+// based on their age and the number of rows.
+type SQLiteConfig struct {
+	Path   string                 `yaml:"path"`
+	Table  string                 `yaml:"table"`
+	Layout map[string]interface{} `yaml:"layout"`
+	// Retention, events older than MaxAgeSeconds or beyond the newest MaxRows are deleted, zero disables the limit
+	MaxAgeSeconds        int64 `yaml:"maxAgeSeconds"`
+	MaxRows              int64 `yaml:"maxRows"`
+	PruneIntervalSeconds int   `yaml:"pruneIntervalSeconds"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+}
+
+const sqliteCreateTable = `CREATE TABLE IF NOT EXISTS %[1]s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp INTEGER NOT NULL,
+	cluster TEXT,
+	namespace TEXT,
+	kind TEXT,
+	name TEXT,
+	reason TEXT,
+	type TEXT,
+	uid TEXT,
+	payload TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (namespace);
+CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (reason);
+CREATE INDEX IF NOT EXISTS %[4]s ON %[1]s (timestamp)`
+
+type SQLite struct {
+	cfg         *SQLiteConfig
+	db          *sql.DB
+	table       string
+	batchWriter *batch.Writer
+	stopCh      chan struct{}
+	doneCh      chan struct{}
+}
+
+func NewSQLiteSink(cfg *SQLiteConfig) (*SQLite, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("sqlite.path config option must be non-empty")
+	}
+	if cfg.Table == "" {
+		cfg.Table = "kube_events"
+	}
+	if cfg.PruneIntervalSeconds == 0 {
+		cfg.PruneIntervalSeconds = 300
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 1
+	}
+
+	// WAL allows reading the history while the exporter is writing
+	dsn := "file:" + cfg.Path + "?" + url.Values{
+		"_pragma": []string{"journal_mode(WAL)", "busy_timeout(5000)"},
+	}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{
+		cfg:    cfg,
+		db:     db,
+		table:  sqliteQuoteIdentifier(cfg.Table),
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+
+	query := fmt.Sprintf(sqliteCreateTable, s.table,
+		sqliteQuoteIdentifier(cfg.Table+"_namespace_idx"),
+		sqliteQuoteIdentifier(cfg.Table+"_reason_idx"),
+		sqliteQuoteIdentifier(cfg.Table+"_timestamp_idx"),
+	)
+	if _, err := db.Exec(query); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot create sqlite table: %w", err)
+	}
+
+	s.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: 3,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    30 * time.Second,
+		},
+		s.insert,
+	)
+	s.batchWriter.Start()
+	go s.pruneLoop()
+
+	return s, nil
+}
+
+func sqliteQuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (s *SQLite) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	var payload []byte
+	layout := resolveLayout(ctx, s.cfg.Layout)
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
+		if err != nil {
+			return err
+		}
+		payload, err = json.Marshal(res)
+		if err != nil {
+			return err
+		}
+	} else {
+		payload = ev.ToJSON()
+	}
+
+	s.batchWriter.Submit([]interface{}{
+		ev.GetTimestampMs(),
+		ev.ClusterName,
+		ev.InvolvedObject.Namespace,
+		ev.InvolvedObject.Kind,
+		ev.InvolvedObject.Name,
+		ev.Reason,
+		ev.Type,
+		string(ev.UID),
+		string(payload),
+	})
+	return nil
+}
+
+// insert writes the batch in a single transaction
+func (s *SQLite) insert(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	err := func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+			"INSERT INTO %s (timestamp, cluster, namespace, kind, name, reason, type, uid, payload) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			s.table))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, item := range items {
+			if _, err := stmt.ExecContext(ctx, item.([]interface{})...); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		log.Error().Err(err).Int("rows", len(items)).Msg("sqlite: insert failed")
+		return res
+	}
+
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+func (s *SQLite) pruneLoop() {
+	defer close(s.doneCh)
+	if s.cfg.MaxAgeSeconds == 0 && s.cfg.MaxRows == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.cfg.PruneIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for {
+		s.prune()
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *SQLite) prune() {
+	if s.cfg.MaxAgeSeconds > 0 {
+		cutoff := time.Now().Add(-time.Duration(s.cfg.MaxAgeSeconds) * time.Second).UnixMilli()
+		res, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE timestamp < ?", s.table), cutoff)
+		if err != nil {
+			log.Error().Err(err).Msg("sqlite: cannot prune old events")
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			log.Debug().Int64("rows", n).Msg("sqlite: pruned old events")
+		}
+	}
+	if s.cfg.MaxRows > 0 {
+		query := fmt.Sprintf("DELETE FROM %[1]s WHERE id <= (SELECT id FROM %[1]s ORDER BY id DESC LIMIT 1 OFFSET ?)", s.table)
+		res, err := s.db.Exec(query, s.cfg.MaxRows)
+		if err != nil {
+			log.Error().Err(err).Msg("sqlite: cannot prune events over the row limit")
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			log.Debug().Int64("rows", n).Msg("sqlite: pruned events over the row limit")
+		}
+	}
+}
+
+func (s *SQLite) Close() {
+	s.batchWriter.Stop()
+	close(s.stopCh)
+	<-s.doneCh
+	_ = s.db.Close()
+}
+
+// Recent implements EventHistory, the message is read from the payload if it has one
+func (s *SQLite) Recent(ctx context.Context, filter EventFilter, limit int) ([]HistoryRecord, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var where []string
+	var args []interface{}
+	// The filter fields are the column names
+	for _, field := range HistoryFields {
+		if value, ok := filter[field]; ok {
+			where = append(where, field+" = ?")
+			args = append(args, value)
+		}
+	}
+	query := fmt.Sprintf("SELECT timestamp, namespace, kind, name, reason, type, payload FROM %s", s.table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []HistoryRecord
+	for rows.Next() {
+		var r HistoryRecord
+		var timestamp int64
+		var payload string
+		if err := rows.Scan(&timestamp, &r.Namespace, &r.Kind, &r.Name, &r.Reason, &r.Type, &payload); err != nil {
+			return nil, err
+		}
+		r.Time = time.UnixMilli(timestamp)
+		var fields struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal([]byte(payload), &fields) == nil {
+			r.Message = fields.Message
+		}
+		ret = append(ret, r)
+	}
+	return ret, rows.Err()
+}
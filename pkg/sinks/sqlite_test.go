@@ -0,0 +1,74 @@
+package sinks
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestSQLiteSinkPrunes(t *testing.T) {
+	s, err := NewSQLiteSink(&SQLiteConfig{
+		Path:          filepath.Join(t.TempDir(), "events.db"),
+		MaxAgeSeconds: 3600,
+		MaxRows:       2,
+	})
+	require.NoError(t, err)
+
+	for i, age := range []time.Duration{2 * time.Hour, 3 * time.Minute, 2 * time.Minute, time.Minute} {
+		ev := &kube.EnhancedEvent{}
+		ev.InvolvedObject.Namespace = "default"
+		ev.Reason = "Reason" + string(rune('A'+i))
+		ev.FirstTimestamp = v1.Time{Time: time.Now().Add(-age)}
+		require.NoError(t, s.Send(context.Background(), ev))
+	}
+	// Flushes the batch
+	s.batchWriter.Stop()
+	s.batchWriter.Start()
+
+	s.prune()
+
+	rows, err := s.db.Query("SELECT reason FROM kube_events ORDER BY id")
+	require.NoError(t, err)
+	var reasons []string
+	for rows.Next() {
+		var reason string
+		require.NoError(t, rows.Scan(&reason))
+		reasons = append(reasons, reason)
+	}
+	require.NoError(t, rows.Close())
+	require.Equal(t, []string{"ReasonC", "ReasonD"}, reasons)
+
+	s.Close()
+}
+
+func TestSQLiteSinkRecent(t *testing.T) {
+	s, err := NewSQLiteSink(&SQLiteConfig{Path: filepath.Join(t.TempDir(), "events.db")})
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, ns := range []string{"prod", "dev", "prod"} {
+		ev := &kube.EnhancedEvent{}
+		ev.InvolvedObject.Namespace = ns
+		ev.Reason = "BackOff"
+		ev.Message = "Back-off restarting in " + ns
+		require.NoError(t, s.Send(context.Background(), ev))
+	}
+	s.batchWriter.Stop()
+	s.batchWriter.Start()
+
+	history, ok := AsEventHistory(s)
+	require.True(t, ok)
+	records, err := history.Recent(context.Background(), EventFilter{"namespace": "prod"}, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "Back-off restarting in prod", records[0].Message)
+
+	_, err = history.Recent(context.Background(), EventFilter{"payload": "x"}, 10)
+	require.Error(t, err)
+}
@@ -47,7 +47,7 @@ func NewSQSSink(cfg *SQSConfig) (Sink, error) {
 }
 
 func (s *SQSSink) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
-	toSend, e := serializeEventWithLayout(s.cfg.Layout, ev)
+	toSend, e := fitEventPayload("sqs", resolveLayout(ctx, s.cfg.Layout), ev, sqsMaxMessageSize)
 	if e != nil {
 		return e
 	}
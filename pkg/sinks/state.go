@@ -0,0 +1,181 @@
+package sinks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+)
+
+// StateStore keeps small pieces of data produced while sending events, for example the ID of an incident created by
+// an API, so that templates rendered for later events can refer to them.
+type StateStore interface {
+	Get(key string) (map[string]string, bool)
+	Set(key string, values map[string]string) error
+	// Expire removes the key after the ttl, it is kept forever if Expire is never called
+	Expire(key string, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// stateStore is shared by all sinks and templates
+var stateStore StateStore = NewInMemoryStateStore()
+
+// SetStateStore replaces the shared state store, it must be called before the sinks are created
+func SetStateStore(s StateStore) {
+	stateStore = s
+}
+
+// GetStateStore returns the shared state store
+func GetStateStore() StateStore {
+	return stateStore
+}
+
+// stateSweepInterval is how often the expired keys are removed from memory
+const stateSweepInterval = time.Minute
+
+type InMemoryStateStore struct {
+	store     map[string]map[string]string
+	expires   map[string]time.Time
+	lastSweep time.Time
+	mu        sync.RWMutex
+}
+
+func NewInMemoryStateStore() *InMemoryStateStore {
+	return &InMemoryStateStore{
+		store:     make(map[string]map[string]string),
+		expires:   make(map[string]time.Time),
+		lastSweep: clock.Now(),
+	}
+}
+
+func (s *InMemoryStateStore) expired(key string, now time.Time) bool {
+	expiresAt, ok := s.expires[key]
+	return ok && !now.Before(expiresAt)
+}
+
+func (s *InMemoryStateStore) Get(key string) (map[string]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values, ok := s.store[key]
+	if !ok || s.expired(key, clock.Now()) {
+		return nil, false
+	}
+	ret := make(map[string]string, len(values))
+	for k, v := range values {
+		ret[k] = v
+	}
+	return ret, true
+}
+
+// Set merges the values into the existing ones for the key
+func (s *InMemoryStateStore) Set(key string, values map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clock.Now()
+	s.sweep(now)
+
+	existing, ok := s.store[key]
+	if !ok || s.expired(key, now) {
+		existing = make(map[string]string, len(values))
+		s.store[key] = existing
+		delete(s.expires, key)
+	}
+	for k, v := range values {
+		existing[k] = v
+	}
+	return nil
+}
+
+func (s *InMemoryStateStore) Expire(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.store[key]; ok {
+		s.expires[key] = clock.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *InMemoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.store, key)
+	delete(s.expires, key)
+	return nil
+}
+
+// sweep removes the expired keys, it must be called with the lock held
+func (s *InMemoryStateStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < stateSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key := range s.expires {
+		if s.expired(key, now) {
+			delete(s.store, key)
+			delete(s.expires, key)
+		}
+	}
+}
+
+// StateEntry is a key of the state store in a snapshot
+type StateEntry struct {
+	Values  map[string]string `json:"values"`
+	Expires *time.Time        `json:"expires,omitempty"`
+}
+
+// StateSnapshotter is implemented by the state stores that keep their state in memory, so it can be saved on
+// shutdown and restored on the next start
+type StateSnapshotter interface {
+	Snapshot() map[string]StateEntry
+	// Restore sets the keys of the snapshot that are not set yet
+	Restore(entries map[string]StateEntry)
+}
+
+func (s *InMemoryStateStore) Snapshot() map[string]StateEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := clock.Now()
+	entries := make(map[string]StateEntry, len(s.store))
+	for key, values := range s.store {
+		if s.expired(key, now) {
+			continue
+		}
+		entry := StateEntry{Values: make(map[string]string, len(values))}
+		for k, v := range values {
+			entry.Values[k] = v
+		}
+		if expiresAt, ok := s.expires[key]; ok {
+			entry.Expires = &expiresAt
+		}
+		entries[key] = entry
+	}
+	return entries
+}
+
+func (s *InMemoryStateStore) Restore(entries map[string]StateEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clock.Now()
+	for key, entry := range entries {
+		if _, ok := s.store[key]; ok && !s.expired(key, now) {
+			continue
+		}
+		if entry.Expires != nil && !now.Before(*entry.Expires) {
+			continue
+		}
+		s.store[key] = entry.Values
+		delete(s.expires, key)
+		if entry.Expires != nil {
+			s.expires[key] = *entry.Expires
+		}
+	}
+}
+
+// stateValue is available in templates as `stateValue "key" "field"`, it returns an empty string if nothing is stored
+func stateValue(key, field string) string {
+	values, ok := stateStore.Get(key)
+	if !ok {
+		return ""
+	}
+	return values[field]
+}
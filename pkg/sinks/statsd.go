@@ -0,0 +1,125 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	StatsdFlavorDogStatsD = "dogstatsd"
+	StatsdFlavorStatsd    = "statsd"
+
+	defaultStatsdAddress = "127.0.0.1:8125"
+	defaultStatsdMetric  = "kubernetes.events"
+)
+
+var (
+	defaultStatsdTags = map[string]string{
+		"reason":    "{{ .Reason }}",
+		"namespace": "{{ .InvolvedObject.Namespace }}",
+		"kind":      "{{ .InvolvedObject.Kind }}",
+		"type":      "{{ .Type }}",
+	}
+
+	statsdNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+	statsdTagUnsafe  = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", " ")
+)
+
+// StatsdConfig increments a counter per event instead of sending the event, for the event rates in a metrics system.
+// With DogStatsD the tags are sent as tags, with plain statsd their values are appended to the metric name in the
+// order of the tag names, e.g. kubernetes.events.Pod.BackOff.shop.Warning.
+type StatsdConfig struct {
+	// Address is the host:port of the statsd server, 127.0.0.1:8125 by default
+	Address string `yaml:"address,omitempty"`
+	// Protocol is udp (default) or tcp
+	Protocol string `yaml:"protocol,omitempty"`
+	// Flavor is dogstatsd (default) or statsd
+	Flavor string `yaml:"flavor,omitempty"`
+	// Metric is the name of the counter, kubernetes.events by default
+	Metric string `yaml:"metric,omitempty"`
+	// Tags are templates, the reason, the namespace, the kind and the type by default. The empty ones are left out.
+	Tags           map[string]string `yaml:"tags,omitempty"`
+	TimeoutSeconds int               `yaml:"timeoutSeconds,omitempty"`
+}
+
+type Statsd struct {
+	cfg    *StatsdConfig
+	writer *connWriter
+}
+
+func NewStatsdSink(cfg *StatsdConfig) (Sink, error) {
+	if cfg.Address == "" {
+		cfg.Address = defaultStatsdAddress
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = "udp"
+	}
+	if cfg.Protocol != "udp" && cfg.Protocol != "tcp" {
+		return nil, fmt.Errorf("statsd.protocol must be udp or tcp, got %q", cfg.Protocol)
+	}
+	if cfg.Flavor == "" {
+		cfg.Flavor = StatsdFlavorDogStatsD
+	}
+	if cfg.Flavor != StatsdFlavorDogStatsD && cfg.Flavor != StatsdFlavorStatsd {
+		return nil, fmt.Errorf("statsd.flavor must be %s or %s, got %q", StatsdFlavorDogStatsD, StatsdFlavorStatsd, cfg.Flavor)
+	}
+	if cfg.Metric == "" {
+		cfg.Metric = defaultStatsdMetric
+	}
+	if strings.ContainsAny(cfg.Metric, ":|@#\n") {
+		return nil, errors.New("statsd.metric must not contain any of :|@#")
+	}
+	if cfg.Tags == nil {
+		cfg.Tags = defaultStatsdTags
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 5
+	}
+	return &Statsd{
+		cfg:    cfg,
+		writer: newConnWriter(cfg.Protocol, cfg.Address, nil, time.Duration(cfg.TimeoutSeconds)*time.Second),
+	}, nil
+}
+
+// line renders the increment of the counter
+func (s *Statsd) line(ev *kube.EnhancedEvent) (string, error) {
+	name := s.cfg.Metric
+	var tags []string
+	for _, key := range sortedKeys(s.cfg.Tags) {
+		value, err := GetString(ev, s.cfg.Tags[key])
+		if err != nil {
+			return "", fmt.Errorf("cannot render tag %s: %w", key, err)
+		}
+		if value == "" {
+			continue
+		}
+		if s.cfg.Flavor == StatsdFlavorStatsd {
+			name += "." + statsdNameUnsafe.ReplaceAllString(value, "_")
+			continue
+		}
+		tags = append(tags, statsdTagUnsafe.Replace(key)+":"+statsdTagUnsafe.Replace(value))
+	}
+	line := name + ":1|c"
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line + "\n", nil
+}
+
+func (s *Statsd) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := s.line(ev)
+	if err != nil {
+		return err
+	}
+	return s.writer.Write(ctx, []byte(line))
+}
+
+func (s *Statsd) Close() {
+	s.writer.Close()
+}
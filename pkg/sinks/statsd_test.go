@@ -0,0 +1,65 @@
+package sinks
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func statsdTestEvent() *kube.EnhancedEvent {
+	ev := &kube.EnhancedEvent{}
+	ev.Type = "Warning"
+	ev.Reason = "BackOff"
+	ev.InvolvedObject.Kind = "Pod"
+	ev.InvolvedObject.Namespace = "shop"
+	ev.InvolvedObject.Name = "checkout-1"
+	return ev
+}
+
+func TestStatsd_DogStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewStatsdSink(&StatsdConfig{Address: conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Send(context.Background(), statsdTestEvent()))
+
+	buf := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "kubernetes.events:1|c|#kind:Pod,namespace:shop,reason:BackOff,type:Warning\n", string(buf[:n]))
+}
+
+func TestStatsd_Line(t *testing.T) {
+	s, err := NewStatsdSink(&StatsdConfig{
+		Flavor: StatsdFlavorStatsd,
+		Metric: "k8s.events",
+		Tags:   map[string]string{"1-kind": "{{ .InvolvedObject.Kind }}", "2-reason": "{{ .Reason }}", "3-host": "{{ .Source.Host }}"},
+	})
+	require.NoError(t, err)
+	ev := statsdTestEvent()
+	ev.Reason = "Failed Scheduling"
+	line, err := s.(*Statsd).line(ev)
+	require.NoError(t, err)
+	// The empty host is left out
+	assert.Equal(t, "k8s.events.Pod.Failed_Scheduling:1|c\n", line)
+
+	s, err = NewStatsdSink(&StatsdConfig{Tags: map[string]string{"message": "{{ .Message }}"}})
+	require.NoError(t, err)
+	ev.Message = "0/3 nodes: Insufficient cpu, node(s) had taint #1|2"
+	line, err = s.(*Statsd).line(ev)
+	require.NoError(t, err)
+	assert.Equal(t, "kubernetes.events:1|c|#message:0/3 nodes: Insufficient cpu_ node(s) had taint _1_2\n", line)
+
+	_, err = NewStatsdSink(&StatsdConfig{Flavor: "graphite"})
+	assert.Error(t, err)
+	_, err = NewStatsdSink(&StatsdConfig{Metric: "events|c"})
+	assert.Error(t, err)
+}
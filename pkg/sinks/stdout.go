@@ -3,21 +3,42 @@ package sinks
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
+const (
+	StdoutEncodingJSON   = "json"
+	StdoutEncodingLogfmt = "logfmt"
+	StdoutEncodingText   = "text"
+
+	defaultStdoutTextFormat = "{{ .GetTimestampISO8601 }} {{ .Type }} {{ .Namespace }}/{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }} {{ .Reason }}: {{ .Message }}"
+)
+
 type StdoutConfig struct {
 	// DeDot all labels and annotations in the event. For both the event and the involvedObject
 	DeDot  bool                   `yaml:"deDot"`
 	Layout map[string]interface{} `yaml:"layout"`
+	// Encoding is one of json (default), logfmt or text
+	Encoding string `yaml:"encoding"`
+	// Format is the template for a single line when the text encoding is used
+	Format string `yaml:"format"`
 }
 
 func (f *StdoutConfig) Validate() error {
-	return nil
+	switch f.Encoding {
+	case "", StdoutEncodingJSON, StdoutEncodingLogfmt, StdoutEncodingText:
+		return nil
+	default:
+		return fmt.Errorf("unknown stdout encoding: %s", f.Encoding)
+	}
 }
 
 type Stdout struct {
@@ -27,6 +48,13 @@ type Stdout struct {
 }
 
 func NewStdoutSink(config *StdoutConfig) (*Stdout, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Encoding == StdoutEncodingText && config.Format == "" {
+		config.Format = defaultStdoutTextFormat
+	}
+
 	logger := log.New(os.Stdout, "", 0)
 	writer := logger.Writer()
 
@@ -41,19 +69,103 @@ func (f *Stdout) Close() {
 }
 
 func (f *Stdout) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	layout := resolveLayout(ctx, f.cfg.Layout)
 	if f.cfg.DeDot {
 		de := ev.DeDot()
 		ev = &de
 	}
 
-	if f.cfg.Layout == nil {
-		return f.encoder.Encode(ev)
+	if f.cfg.Encoding == StdoutEncodingText {
+		line, err := GetString(ev, f.cfg.Format)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f.writer, strings.TrimRight(line, "\n")+"\n")
+		return err
+	}
+
+	var v interface{} = ev
+	if layout != nil {
+		res, err := convertLayoutTemplate(layout, ev)
+		if err != nil {
+			return err
+		}
+		v = res
 	}
 
-	res, err := convertLayoutTemplate(f.cfg.Layout, ev)
+	if f.cfg.Encoding == StdoutEncodingLogfmt {
+		return writeLogfmt(f.writer, v)
+	}
+	return f.encoder.Encode(v)
+}
+
+// writeLogfmt writes the value as a single logfmt line. Nested objects are flattened with dotted keys.
+func writeLogfmt(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
+	var data interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	fields := make(map[string]string)
+	flattenLogfmt("", data, fields)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtValue(fields[k]))
+	}
+	sb.WriteByte('\n')
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
 
-	return f.encoder.Encode(res)
+func flattenLogfmt(prefix string, v interface{}, out map[string]string) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			flattenLogfmt(join(k), item, out)
+		}
+	case []interface{}:
+		for i, item := range val {
+			flattenLogfmt(join(strconv.Itoa(i)), item, out)
+		}
+	case nil:
+		// Skip empty values to keep the line short
+	case string:
+		if val != "" {
+			out[prefix] = val
+		}
+	case float64:
+		out[prefix] = strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		out[prefix] = fmt.Sprint(val)
+	}
+}
+
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
 }
@@ -0,0 +1,205 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// SumoLogicConfig sends the events in batches to a hosted HTTP source of Sumo Logic. The category, the host and the
+// name override the source metadata per event and are templates; the events of a batch are sent in one request per
+// distinct metadata.
+type SumoLogicConfig struct {
+	// Endpoint is the unique URL of the HTTP source, it contains the token of the source
+	Endpoint string `yaml:"endpoint" secret:"true"`
+	Category string `yaml:"category,omitempty"`
+	Host     string `yaml:"host,omitempty"`
+	Name     string `yaml:"name,omitempty"`
+	// Fields are sent as X-Sumo-Fields and must be enabled on the source
+	Fields map[string]string      `yaml:"fields,omitempty"`
+	Layout map[string]interface{} `yaml:"layout"`
+	// Compression is gzip (default) or none
+	Compression string `yaml:"compression"`
+	TLS         TLS    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+type SumoLogic struct {
+	cfg         *SumoLogicConfig
+	client      *http.Client
+	fields      string
+	batchWriter *batch.Writer
+}
+
+// sumoLogicRecord is a serialized event with its source metadata
+type sumoLogicRecord struct {
+	metadata sumoLogicMetadata
+	line     []byte
+}
+
+type sumoLogicMetadata struct {
+	category, host, name string
+}
+
+func NewSumoLogicSink(cfg *SumoLogicConfig) (*SumoLogic, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("sumoLogic.endpoint config option must be non-empty")
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = CompressionGzip
+	}
+	if cfg.Compression != CompressionGzip && cfg.Compression != CompressionNone {
+		return nil, fmt.Errorf("sumoLogic.compression must be gzip or none, got %q", cfg.Compression)
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	fields := make([]string, 0, len(cfg.Fields))
+	for _, k := range sortedKeys(cfg.Fields) {
+		fields = append(fields, k+"="+cfg.Fields[k])
+	}
+
+	s := &SumoLogic{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		fields: strings.Join(fields, ","),
+	}
+	s.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		s.write,
+	)
+	s.batchWriter.Start()
+
+	return s, nil
+}
+
+func (s *SumoLogic) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := serializeEventWithLayout(resolveLayout(ctx, s.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+	record := &sumoLogicRecord{line: line}
+	for _, f := range []struct {
+		text   string
+		target *string
+	}{
+		{s.cfg.Category, &record.metadata.category},
+		{s.cfg.Host, &record.metadata.host},
+		{s.cfg.Name, &record.metadata.name},
+	} {
+		if f.text == "" {
+			continue
+		}
+		if *f.target, err = GetString(ev, f.text); err != nil {
+			return err
+		}
+	}
+	s.batchWriter.Submit(record)
+	return nil
+}
+
+func (s *SumoLogic) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+
+	// The metadata is set per request, so the batch is split by it, keeping the order of the events
+	var order []sumoLogicMetadata
+	groups := make(map[sumoLogicMetadata][]int)
+	for i, item := range items {
+		metadata := item.(*sumoLogicRecord).metadata
+		if _, ok := groups[metadata]; !ok {
+			order = append(order, metadata)
+		}
+		groups[metadata] = append(groups[metadata], i)
+	}
+
+	for _, metadata := range order {
+		buf := &bytes.Buffer{}
+		for _, i := range groups[metadata] {
+			buf.Write(items[i].(*sumoLogicRecord).line)
+			buf.WriteByte('\n')
+		}
+		if err := s.post(ctx, metadata, buf.Bytes()); err != nil {
+			log.Error().Err(err).Int("events", len(groups[metadata])).Str("category", metadata.category).Msg("sumologic: write failed")
+			continue
+		}
+		for _, i := range groups[metadata] {
+			res[i] = true
+		}
+	}
+	return res
+}
+
+func (s *SumoLogic) post(ctx context.Context, metadata sumoLogicMetadata, body []byte) error {
+	body, err := Compress(s.cfg.Compression, body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if encoding := CompressionContentEncoding(s.cfg.Compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	for header, value := range map[string]string{
+		"X-Sumo-Category": metadata.category,
+		"X-Sumo-Host":     metadata.host,
+		"X-Sumo-Name":     metadata.name,
+		"X-Sumo-Fields":   s.fields,
+	} {
+		if value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return httpResponseError(resp, respBody)
+}
+
+func (s *SumoLogic) Close() {
+	s.batchWriter.Stop()
+	s.client.CloseIdleConnections()
+}
@@ -0,0 +1,79 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestSumoLogic_Write(t *testing.T) {
+	type request struct {
+		header http.Header
+		lines  []string
+	}
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		mu.Lock()
+		requests = append(requests, request{header: r.Header, lines: strings.Split(strings.TrimSpace(string(body)), "\n")})
+		mu.Unlock()
+		if r.Header.Get("X-Sumo-Category") == "k8s/kube-system" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewSumoLogicSink(&SumoLogicConfig{
+		Endpoint: server.URL + "/receiver/v1/http/token",
+		Category: "k8s/{{ .InvolvedObject.Namespace }}",
+		Host:     "prod-eu",
+		Fields:   map[string]string{"team": "platform", "cluster": "prod-eu"},
+		Layout:   map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	var items []interface{}
+	for _, e := range []struct{ namespace, reason string }{{"default", "Started"}, {"kube-system", "BackOff"}, {"default", "Killing"}} {
+		ev := &kube.EnhancedEvent{}
+		ev.InvolvedObject.Namespace = e.namespace
+		ev.Reason = e.reason
+		line, err := serializeEventWithLayout(s.cfg.Layout, ev)
+		require.NoError(t, err)
+		category, err := GetString(ev, s.cfg.Category)
+		require.NoError(t, err)
+		items = append(items, &sumoLogicRecord{metadata: sumoLogicMetadata{category: category, host: s.cfg.Host}, line: line})
+	}
+
+	// The events are grouped by their category, the throttled group is retried
+	assert.Equal(t, []bool{true, false, true}, s.write(context.Background(), items))
+	require.Len(t, requests, 2)
+	assert.Equal(t, "k8s/default", requests[0].header.Get("X-Sumo-Category"))
+	assert.Equal(t, "prod-eu", requests[0].header.Get("X-Sumo-Host"))
+	assert.Empty(t, requests[0].header.Get("X-Sumo-Name"))
+	assert.Equal(t, "cluster=prod-eu,team=platform", requests[0].header.Get("X-Sumo-Fields"))
+	assert.Equal(t, "gzip", requests[0].header.Get("Content-Encoding"))
+	assert.Equal(t, []string{`{"reason":"Started"}`, `{"reason":"Killing"}`}, requests[0].lines)
+	assert.Equal(t, []string{`{"reason":"BackOff"}`}, requests[1].lines)
+}
+
+func TestSumoLogic_Config(t *testing.T) {
+	_, err := NewSumoLogicSink(&SumoLogicConfig{})
+	assert.Error(t, err)
+	_, err = NewSumoLogicSink(&SumoLogicConfig{Endpoint: "https://example.com", Compression: "zstd"})
+	assert.Error(t, err)
+}
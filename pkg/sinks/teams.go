@@ -8,12 +8,13 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
 type TeamsConfig struct {
-	Endpoint string                 `yaml:"endpoint"`
+	Endpoint string                 `yaml:"endpoint" secret:"true"`
 	Layout   map[string]interface{} `yaml:"layout"`
 	Headers  map[string]string      `yaml:"headers"`
 }
@@ -31,7 +32,7 @@ func (w *Teams) Close() {
 }
 
 func (w *Teams) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
-	event, err := serializeEventWithLayout(w.cfg.Layout, ev)
+	event, err := serializeEventWithLayout(resolveLayout(ctx, w.cfg.Layout), ev)
 	if err != nil {
 		return err
 	}
@@ -70,12 +71,18 @@ func (w *Teams) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		return err
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RetryableError{
+			Err:        fmt.Errorf("rate limited: %s", message),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("not 200: %s", message)
 	}
 	// see: https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using?tabs=cURL#rate-limiting-for-connectors
 	if strings.Contains(message, "Microsoft Teams endpoint returned HTTP error 429") {
-		return fmt.Errorf("rate limited: %s", message)
+		return &RetryableError{Err: fmt.Errorf("rate limited: %s", message)}
 	}
 
 	return nil
@@ -0,0 +1,119 @@
+package sinks
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSPolicy is enforced on every TLS connection opened by the sinks. It is meant for regulated environments where
+// only a minimum protocol version and a set of approved cipher suites are allowed.
+type TLSPolicy struct {
+	MinVersion   string   `yaml:"minVersion"`
+	CipherSuites []string `yaml:"cipherSuites"`
+	// AllowInsecureSkipVerify allows receivers to disable certificate verification, which is refused by default
+	// once a policy is configured.
+	AllowInsecureSkipVerify bool `yaml:"allowInsecureSkipVerify"`
+
+	minVersion   uint16
+	cipherSuites []uint16
+}
+
+// tlsPolicy is the active policy, it is nil unless SetTLSPolicy is called
+var tlsPolicy *TLSPolicy
+
+// Validate checks the policy and resolves the version and cipher suite names
+func (p *TLSPolicy) Validate() error {
+	if p.MinVersion != "" {
+		v, ok := tlsVersions[p.MinVersion]
+		if !ok {
+			return fmt.Errorf("unknown TLS version %q, must be one of 1.0, 1.1, 1.2, 1.3", p.MinVersion)
+		}
+		p.minVersion = v
+	}
+
+	// Only the suites Go considers secure can be approved
+	known := make(map[string]uint16)
+	for _, c := range tls.CipherSuites() {
+		known[c.Name] = c.ID
+	}
+
+	p.cipherSuites = nil
+	for _, name := range p.CipherSuites {
+		id, ok := known[name]
+		if !ok {
+			return fmt.Errorf("cipher suite %q is unknown or not considered secure", name)
+		}
+		p.cipherSuites = append(p.cipherSuites, id)
+	}
+	return nil
+}
+
+// CheckReceiver returns an error if the receiver configuration cannot comply with the policy
+func (p *TLSPolicy) CheckReceiver(r *ReceiverConfig) error {
+	if p.AllowInsecureSkipVerify {
+		return nil
+	}
+
+	insecure := false
+	for _, t := range r.tlsConfigs() {
+		insecure = insecure || t.InsecureSkipVerify
+	}
+	if r.Kafka != nil && r.Kafka.TLS.InsecureSkipVerify {
+		insecure = true
+	}
+
+	if insecure {
+		return fmt.Errorf("receiver %q sets insecureSkipVerify which is not allowed by the TLS policy", r.Name)
+	}
+	return nil
+}
+
+func (p *TLSPolicy) apply(cfg *tls.Config) {
+	if p.minVersion != 0 && cfg.MinVersion < p.minVersion {
+		cfg.MinVersion = p.minVersion
+	}
+	if len(p.cipherSuites) > 0 {
+		cfg.CipherSuites = p.cipherSuites
+	}
+	if !p.AllowInsecureSkipVerify {
+		cfg.InsecureSkipVerify = false
+	}
+}
+
+// SetTLSPolicy validates and activates the policy for all sinks. It also applies to the default HTTP transport which
+// is used by the sinks relying on third-party SDKs. It must be called before the sinks are created. A nil policy
+// removes the active one.
+func SetTLSPolicy(p *TLSPolicy) error {
+	if p == nil {
+		tlsPolicy = nil
+		return nil
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	tlsPolicy = p
+
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		p.apply(t.TLSClientConfig)
+	}
+	return nil
+}
+
+// ApplyTLSPolicy enforces the active policy, if any, on the given config. The configs built outside of the sinks,
+// such as the ingest server and the Falco client, go through it as well.
+func ApplyTLSPolicy(cfg *tls.Config) {
+	if tlsPolicy != nil {
+		tlsPolicy.apply(cfg)
+	}
+}
@@ -0,0 +1,33 @@
+package sinks
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSPolicy_Validate(t *testing.T) {
+	p := &TLSPolicy{MinVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+	require.NoError(t, p.Validate())
+
+	cfg := &tls.Config{InsecureSkipVerify: true}
+	p.apply(cfg)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, cfg.CipherSuites)
+	assert.False(t, cfg.InsecureSkipVerify)
+
+	assert.Error(t, (&TLSPolicy{MinVersion: "1.4"}).Validate())
+	assert.Error(t, (&TLSPolicy{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}).Validate())
+}
+
+func TestTLSPolicy_CheckReceiver(t *testing.T) {
+	p := &TLSPolicy{MinVersion: "1.2"}
+	r := &ReceiverConfig{Name: "hook", Webhook: &WebhookConfig{TLS: TLS{InsecureSkipVerify: true}}}
+
+	assert.Error(t, p.CheckReceiver(r))
+
+	p.AllowInsecureSkipVerify = true
+	assert.NoError(t, p.CheckReceiver(r))
+}
@@ -10,8 +10,25 @@ import (
 	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
 )
 
+// templateFuncs returns the sprig functions together with the functions provided by the exporter
+func templateFuncs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["stateValue"] = stateValue
+	funcs["lookupConfigMapValue"] = lookupConfigMapValue
+	funcs["lookupSecretAnnotation"] = lookupSecretAnnotation
+	for name, fn := range localeFuncs(kube.Locale{}) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
 func GetString(event *kube.EnhancedEvent, text string) (string, error) {
-	tmpl, err := template.New("template").Funcs(sprig.TxtFuncMap()).Parse(text)
+	funcs := templateFuncs()
+	// The time functions use the locale of the receiver
+	for name, fn := range localeFuncs(event.Locale) {
+		funcs[name] = fn
+	}
+	tmpl, err := template.New("template").Funcs(funcs).Parse(text)
 	if err != nil {
 		return "", err
 	}
@@ -79,6 +96,9 @@ func convertTemplate(value interface{}, ev *kube.EnhancedEvent) (interface{}, er
 			listConf[i] = t
 		}
 		return listConf, nil
+	case bool, int, int64, uint64, float64:
+		// Numbers and booleans are kept, e.g. for the version or the wrap option of an adaptive card
+		return v, nil
 	}
 	return nil, nil
 }
@@ -1,6 +1,7 @@
 package sinks
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -52,3 +53,50 @@ func TestLayoutConvert(t *testing.T) {
 
 	require.Equal(t, val2, ev.Message)
 }
+
+func TestGetString_Locale(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.LastTimestamp = v1.NewTime(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	ev.EventTime = v1.NewMicroTime(time.Now().Add(-3 * time.Hour))
+
+	res, err := GetString(ev, `{{ timestamp .LastTimestamp }} / {{ humanize .EventTime }}`)
+	require.NoError(t, err)
+	require.Equal(t, "2024-03-01 12:30:00 UTC / 3 hours ago", res)
+
+	ev.Locale = kube.Locale{Location: berlin, Language: "de"}
+	res, err = GetString(ev, `{{ timestamp .LastTimestamp }} / {{ humanize .EventTime }} / {{ timestamp .LastTimestamp "15:04" }}`)
+	require.NoError(t, err)
+	require.Equal(t, "01.03.2024 13:30:00 CET / vor 3 Stunden / 13:30", res)
+
+	ev.Locale = kube.Locale{Language: "en-US"}
+	res, err = GetString(ev, `{{ timestamp .LastTimestamp }} {{ timestamp .FirstTimestamp }}`)
+	require.NoError(t, err)
+	require.Equal(t, "Mar 1, 2024 12:30:00 PM UTC ", res)
+
+	_, err = GetString(ev, `{{ timestamp .Message }}`)
+	require.Error(t, err)
+}
+
+func TestReceiverConfig_Template(t *testing.T) {
+	cfg := &ReceiverConfig{
+		Name:     "local",
+		InMemory: &InMemoryConfig{},
+		Template: &TemplateConfig{Timezone: "Asia/Tokyo", Locale: "fr"},
+	}
+	require.NoError(t, cfg.Validate())
+	sink, err := cfg.GetSink()
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	require.NoError(t, sink.Send(context.Background(), ev))
+	require.Empty(t, ev.Locale.Language, "the event of the other receivers is not changed")
+	require.Len(t, cfg.InMemory.Ref.Events, 1)
+	require.Equal(t, "fr", cfg.InMemory.Ref.Events[0].Locale.Language)
+	require.Equal(t, "Asia/Tokyo", cfg.InMemory.Ref.Events[0].Locale.Location.String())
+
+	require.Error(t, (&ReceiverConfig{Template: &TemplateConfig{Timezone: "Mars/Olympus"}}).Validate())
+	require.Error(t, (&ReceiverConfig{Template: &TemplateConfig{Locale: "tlh"}}).Validate())
+}
@@ -0,0 +1,172 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/batch"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// VectorConfig sends the events in batches to the http_server source of Vector, or another observability pipeline
+// accepting newline delimited JSON, so the pipeline is the single egress point and buffers the events. The source
+// should decode the body with the json codec and the newline_delimited framing.
+//
+// With acknowledgements, the source must have them enabled too: Vector then responds once the events are delivered to
+// its sinks rather than once they are received, and with an error if a sink rejects them. The sink waits up to
+// ackTimeoutSeconds for the response and retries the batch if the delivery fails or times out.
+type VectorConfig struct {
+	Endpoint string            `yaml:"endpoint"`
+	Username string            `yaml:"username,omitempty"`
+	Password string            `yaml:"password,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	// Compression is none, gzip or zstd, set as the Content-Encoding the source decodes
+	Compression       string                 `yaml:"compression,omitempty"`
+	Acknowledgements  bool                   `yaml:"acknowledgements,omitempty"`
+	AckTimeoutSeconds int                    `yaml:"ackTimeoutSeconds,omitempty"`
+	Layout            map[string]interface{} `yaml:"layout"`
+	TLS               TLS                    `yaml:"tls"`
+	// Batching config
+	BatchSize       int `yaml:"batchSize"`
+	MaxRetries      int `yaml:"maxRetries"`
+	IntervalSeconds int `yaml:"intervalSeconds"`
+	TimeoutSeconds  int `yaml:"timeoutSeconds"`
+}
+
+func (c *VectorConfig) validate() error {
+	switch c.Compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("vector.compression must be none, gzip or zstd, got %q", c.Compression)
+	}
+	if c.AckTimeoutSeconds < 0 {
+		return errors.New("vector.ackTimeoutSeconds must not be negative")
+	}
+	return nil
+}
+
+type Vector struct {
+	cfg         *VectorConfig
+	client      *http.Client
+	batchWriter *batch.Writer
+}
+
+func NewVectorSink(cfg *VectorConfig) (*Vector, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("vector.endpoint config option must be non-empty")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.IntervalSeconds == 0 {
+		cfg.IntervalSeconds = 5
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 30
+	}
+	if cfg.AckTimeoutSeconds == 0 {
+		cfg.AckTimeoutSeconds = 60
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	// The response of an acknowledged request waits for the delivery to the sinks of the pipeline
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if cfg.Acknowledgements {
+		timeout = time.Duration(cfg.AckTimeoutSeconds) * time.Second
+	}
+	v := &Vector{
+		cfg: cfg,
+		client: &http.Client{
+			Transport: withRequestLogging(newHTTPTransport(tlsClientConfig)),
+			Timeout:   timeout,
+		},
+	}
+	v.batchWriter = batch.NewWriter(
+		batch.WriterConfig{
+			BatchSize:  cfg.BatchSize,
+			MaxRetries: cfg.MaxRetries,
+			Interval:   time.Duration(cfg.IntervalSeconds) * time.Second,
+			Timeout:    timeout,
+		},
+		v.write,
+	)
+	v.batchWriter.Start()
+	return v, nil
+}
+
+func (v *Vector) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	line, err := serializeEventWithLayout(resolveLayout(ctx, v.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+	v.batchWriter.Submit(line)
+	return nil
+}
+
+func (v *Vector) write(ctx context.Context, items []interface{}) []bool {
+	res := make([]bool, len(items))
+	var body bytes.Buffer
+	for _, item := range items {
+		body.Write(item.([]byte))
+		body.WriteByte('\n')
+	}
+	if err := v.post(ctx, body.Bytes()); err != nil {
+		log.Error().Err(err).Int("events", len(items)).Bool("acknowledgements", v.cfg.Acknowledgements).Msg("vector: send failed")
+		return res
+	}
+	for i := range res {
+		res[i] = true
+	}
+	return res
+}
+
+func (v *Vector) post(ctx context.Context, body []byte) error {
+	data, err := Compress(v.cfg.Compression, body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.cfg.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, value := range v.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if encoding := CompressionContentEncoding(v.cfg.Compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if v.cfg.Username != "" {
+		req.SetBasicAuth(v.cfg.Username, v.cfg.Password)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	return httpResponseError(resp, respBody)
+}
+
+func (v *Vector) Close() {
+	v.batchWriter.Stop()
+	v.client.CloseIdleConnections()
+}
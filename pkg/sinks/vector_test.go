@@ -0,0 +1,63 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVector_Write(t *testing.T) {
+	var header http.Header
+	var body string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		body = string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	v, err := NewVectorSink(&VectorConfig{
+		Endpoint:         server.URL,
+		Username:         "exporter",
+		Password:         "secret",
+		Headers:          map[string]string{"X-Cluster": "prod-eu"},
+		Compression:      CompressionGzip,
+		Acknowledgements: true,
+	})
+	require.NoError(t, err)
+	defer v.Close()
+
+	items := []interface{}{[]byte(`{"reason":"BackOff"}`), []byte(`{"reason":"Pulled"}`)}
+	assert.Equal(t, []bool{true, true}, v.write(context.Background(), items))
+	assert.Equal(t, "{\"reason\":\"BackOff\"}\n{\"reason\":\"Pulled\"}\n", body)
+	assert.Equal(t, "application/x-ndjson", header.Get("Content-Type"))
+	assert.Equal(t, "gzip", header.Get("Content-Encoding"))
+	assert.Equal(t, "prod-eu", header.Get("X-Cluster"))
+	user, password, _ := (&http.Request{Header: header}).BasicAuth()
+	assert.Equal(t, "exporter", user)
+	assert.Equal(t, "secret", password)
+
+	// The batch is retried when a sink of the pipeline rejects the events
+	status = http.StatusBadRequest
+	assert.Equal(t, []bool{false, false}, v.write(context.Background(), items))
+}
+
+func TestVectorConfig_Validate(t *testing.T) {
+	_, err := NewVectorSink(&VectorConfig{})
+	assert.Error(t, err)
+	_, err = NewVectorSink(&VectorConfig{Endpoint: "http://vector:8080", Compression: CompressionSnappy})
+	assert.Error(t, err)
+	_, err = NewVectorSink(&VectorConfig{Endpoint: "http://vector:8080", AckTimeoutSeconds: -1})
+	assert.Error(t, err)
+}
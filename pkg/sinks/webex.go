@@ -0,0 +1,131 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const defaultWebexEndpoint = "https://webexapis.com/v1/messages"
+
+// WebexConfig posts the events as messages to a Webex room with a bot token. The room ID and the markdown are
+// templates. With a card, an adaptive card rendered like a layout is attached and the markdown is the fallback for
+// clients that cannot show cards.
+type WebexConfig struct {
+	Token    string                 `yaml:"token"`
+	RoomID   string                 `yaml:"roomId"`
+	Markdown string                 `yaml:"markdown"`
+	Card     map[string]interface{} `yaml:"card"`
+	// Endpoint defaults to the messages API of webexapis.com
+	Endpoint string `yaml:"endpoint"`
+	TLS      TLS    `yaml:"tls"`
+}
+
+func (c *WebexConfig) endpoint() string {
+	if c.Endpoint == "" {
+		return defaultWebexEndpoint
+	}
+	return c.Endpoint
+}
+
+type webexMessage struct {
+	RoomID      string            `json:"roomId"`
+	Markdown    string            `json:"markdown"`
+	Attachments []webexAttachment `json:"attachments,omitempty"`
+}
+
+type webexAttachment struct {
+	ContentType string                 `json:"contentType"`
+	Content     map[string]interface{} `json:"content"`
+}
+
+type Webex struct {
+	cfg    *WebexConfig
+	client *http.Client
+}
+
+func NewWebexSink(cfg *WebexConfig) (Sink, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("webex.token config option must be non-empty")
+	}
+	if cfg.RoomID == "" {
+		return nil, errors.New("webex.roomId config option must be non-empty")
+	}
+	if cfg.Markdown == "" {
+		cfg.Markdown = "{{ .Message }}"
+	}
+	cfg.Endpoint = cfg.endpoint()
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Webex{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (w *Webex) message(ev *kube.EnhancedEvent) (*webexMessage, error) {
+	msg := &webexMessage{}
+
+	var err error
+	if msg.RoomID, err = GetString(ev, w.cfg.RoomID); err != nil {
+		return nil, err
+	}
+	if msg.Markdown, err = GetString(ev, w.cfg.Markdown); err != nil {
+		return nil, err
+	}
+	if w.cfg.Card != nil {
+		card, err := convertLayoutTemplate(w.cfg.Card, ev)
+		if err != nil {
+			return nil, err
+		}
+		msg.Attachments = []webexAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     card,
+		}}
+	}
+	return msg, nil
+}
+
+func (w *Webex) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	msg, err := w.message(ev)
+	if err != nil {
+		return err
+	}
+	reqBody, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.Endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+w.cfg.Token)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (w *Webex) Close() {
+	w.client.CloseIdleConnections()
+}
@@ -0,0 +1,67 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestWebex_SendCard(t *testing.T) {
+	var auth string
+	var received map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var cfg WebexConfig
+	require.NoError(t, yaml.Unmarshal([]byte(`
+token: bot-token
+roomId: "{{ .Namespace }}-room"
+markdown: "**{{ .Reason }}** {{ .Message }}"
+card:
+  type: AdaptiveCard
+  version: "1.3"
+  body:
+    - type: TextBlock
+      text: "{{ .Reason }}"
+      wrap: true
+      size: 2
+`), &cfg))
+	cfg.Endpoint = ts.URL
+	sink, err := NewWebexSink(&cfg)
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Namespace = "prod"
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "Bearer bot-token", auth)
+	assert.Equal(t, "prod-room", received["roomId"])
+	assert.Equal(t, "**BackOff** Back-off restarting failed container", received["markdown"])
+	attachment := received["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+	block := attachment["content"].(map[string]interface{})["body"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "BackOff", block["text"])
+	assert.Equal(t, true, block["wrap"])
+	assert.Equal(t, float64(2), block["size"])
+}
+
+func TestWebex_DefaultEndpoint(t *testing.T) {
+	cfg := &WebexConfig{Token: "t", RoomID: "r"}
+	_, err := NewWebexSink(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, defaultWebexEndpoint, cfg.Endpoint)
+}
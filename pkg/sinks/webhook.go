@@ -3,10 +3,10 @@ package sinks
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
@@ -14,10 +14,17 @@ import (
 )
 
 type WebhookConfig struct {
-	Endpoint string                 `yaml:"endpoint"`
-	TLS      TLS                    `yaml:"tls"`
-	Layout   map[string]interface{} `yaml:"layout"`
-	Headers  map[string]string      `yaml:"headers"`
+	// Endpoint can be a template, e.g. to update an incident created by a previous request
+	Endpoint string `yaml:"endpoint"`
+	// Method can be a template and defaults to POST
+	Method  string                 `yaml:"method"`
+	TLS     TLS                    `yaml:"tls"`
+	Layout  map[string]interface{} `yaml:"layout"`
+	Headers map[string]string      `yaml:"headers"`
+	// ResponseCapture stores values of the response body for use in later templates
+	ResponseCapture *ResponseCaptureConfig `yaml:"responseCapture"`
+	// Success decides whether a response is a success beyond its status code
+	Success *SuccessConfig `yaml:"success"`
 }
 
 func NewWebhook(cfg *WebhookConfig) (Sink, error) {
@@ -25,15 +32,21 @@ func NewWebhook(cfg *WebhookConfig) (Sink, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup TLS: %w", err)
 	}
-	return &Webhook{cfg: cfg, transport: &http.Transport{
-		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: tlsClientConfig,
-	}}, nil
+	transport := newHTTPTransport(tlsClientConfig)
+	w := &Webhook{cfg: cfg, transport: transport, client: &http.Client{Transport: withRequestLogging(transport)}}
+	if cfg.Success != nil {
+		if w.success, err = newSuccessCriteria(cfg.Success); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
 }
 
 type Webhook struct {
 	cfg       *WebhookConfig
 	transport *http.Transport
+	client    *http.Client
+	success   *successCriteria
 }
 
 func (w *Webhook) Close() {
@@ -41,12 +54,25 @@ func (w *Webhook) Close() {
 }
 
 func (w *Webhook) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
-	reqBody, err := serializeEventWithLayout(w.cfg.Layout, ev)
+	reqBody, err := serializeEventWithLayout(resolveLayout(ctx, w.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := GetString(ev, w.cfg.Endpoint)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, w.cfg.Endpoint, bytes.NewReader(reqBody))
+	method := http.MethodPost
+	if w.cfg.Method != "" {
+		method, err = GetString(ev, w.cfg.Method)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(method), endpoint, bytes.NewReader(reqBody))
 	if err != nil {
 		return err
 	}
@@ -58,14 +84,12 @@ func (w *Webhook) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 			log.Debug().Err(err).Msgf("parse template failed: %s", v)
 			req.Header.Add(k, v)
 		} else {
-			log.Debug().Msgf("request header: {%s: %s}", k, realValue)
+			log.Debug().Msgf("request header: {%s: %s}", k, redactHeader(k, realValue))
 			req.Header.Add(k, realValue)
 		}
 	}
 
-	client := http.DefaultClient
-	client.Transport = w.transport
-	resp, err := client.Do(req)
+	resp, err := w.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -76,8 +100,19 @@ func (w *Webhook) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
 		return err
 	}
 
-	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-		return errors.New("not successfull (2xx) response: " + string(body))
+	if w.success != nil {
+		err = w.success.check(resp, body)
+	} else {
+		err = httpResponseError(resp, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	if w.cfg.ResponseCapture != nil {
+		if err := captureResponse(w.cfg.ResponseCapture, ev, body); err != nil {
+			log.Warn().Err(err).Str("endpoint", endpoint).Msg("Cannot capture webhook response")
+		}
 	}
 
 	return nil
@@ -0,0 +1,100 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestWebhook_ResponseCapture(t *testing.T) {
+	var lastMethod, lastPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastMethod, lastPath = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data": {"id": "INC-42"}}`))
+	}))
+	defer ts.Close()
+
+	SetStateStore(NewInMemoryStateStore())
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.UID = "obj-1"
+	defaultTransport := http.DefaultClient.Transport
+
+	create, err := NewWebhook(&WebhookConfig{
+		Endpoint: ts.URL + "/incidents",
+		ResponseCapture: &ResponseCaptureConfig{
+			Key:    "incident/{{ .InvolvedObject.UID }}",
+			Values: map[string]string{"incidentId": "$.data.id"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, create.Send(context.Background(), ev))
+	assert.Equal(t, "INC-42", stateValue("incident/obj-1", "incidentId"))
+
+	update, err := NewWebhook(&WebhookConfig{
+		Endpoint: ts.URL + `/incidents/{{ stateValue (printf "incident/%s" .InvolvedObject.UID) "incidentId" }}`,
+		Method:   "patch",
+	})
+	require.NoError(t, err)
+	require.NoError(t, update.Send(context.Background(), ev))
+	assert.Equal(t, http.MethodPatch, lastMethod)
+	assert.Equal(t, "/incidents/INC-42", lastPath)
+	// The webhooks have their own client, the default one is shared with other sinks
+	assert.True(t, defaultTransport == http.DefaultClient.Transport)
+}
+
+func TestWebhook_Success(t *testing.T) {
+	status, response := http.StatusOK, ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer ts.Close()
+
+	sink, err := NewWebhook(&WebhookConfig{
+		Endpoint: ts.URL,
+		Success: &SuccessConfig{
+			StatusCodes:          []string{"2xx", "409"},
+			RetryableStatusCodes: []string{"5xx"},
+			Body:                 map[string]string{"$.ok": "^true$"},
+			Errors:               []string{"{.error.message}", "{.fault}"},
+		},
+	})
+	require.NoError(t, err)
+	send := func() error {
+		return sink.Send(context.Background(), &kube.EnhancedEvent{})
+	}
+	var retryable *RetryableError
+
+	response = `{"ok": true, "error": null, "fault": null}`
+	assert.NoError(t, send())
+
+	response = `{"ok": true, "error": {"message": "channel not found"}}`
+	err = send()
+	assert.ErrorContains(t, err, "channel not found")
+	assert.False(t, errors.As(err, &retryable))
+
+	response = `{"ok": false}`
+	assert.ErrorContains(t, send(), "unexpected response")
+
+	status, response = http.StatusConflict, `{"ok": true}`
+	assert.NoError(t, send())
+
+	status = http.StatusBadGateway
+	assert.True(t, errors.As(send(), &retryable))
+
+	status = http.StatusTooManyRequests
+	err = send()
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &retryable), "only the configured status codes are retried")
+
+	_, err = NewWebhook(&WebhookConfig{Endpoint: ts.URL, Success: &SuccessConfig{StatusCodes: []string{"2x"}}})
+	assert.Error(t, err)
+}
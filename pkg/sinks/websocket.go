@@ -0,0 +1,206 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+const (
+	websocketMinBackoff = time.Second
+	websocketMaxBackoff = 30 * time.Second
+)
+
+// WebSocketConfig pushes the events as JSON text frames over a WebSocket connection, e.g. to a live dashboard. The
+// connection is kept open and pinged, and dialed again when it fails. While it cannot be dialed, the events are
+// dropped, the dials backing off up to 30 seconds apart.
+type WebSocketConfig struct {
+	// URL is ws:// or wss://
+	URL string `yaml:"url"`
+	// Token is sent as a bearer token in the handshake request
+	Token   string            `yaml:"token,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// PingIntervalSeconds is how often the connection is pinged, 30 by default
+	PingIntervalSeconds int                    `yaml:"pingIntervalSeconds,omitempty"`
+	TimeoutSeconds      int                    `yaml:"timeoutSeconds,omitempty"`
+	Layout              map[string]interface{} `yaml:"layout"`
+	TLS                 TLS                    `yaml:"tls"`
+}
+
+type WebSocket struct {
+	cfg     *WebSocketConfig
+	dialer  *websocket.Dialer
+	header  http.Header
+	timeout time.Duration
+	done    chan struct{}
+
+	mu        sync.Mutex
+	conn      *websocket.Conn
+	backoff   time.Duration
+	nextDial  time.Time
+	closeOnce sync.Once
+}
+
+func NewWebSocketSink(cfg *WebSocketConfig) (*WebSocket, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("websocket.url config option must be non-empty")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("websocket.url is invalid: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("websocket.url must be a ws:// or wss:// URL, got %q", cfg.URL)
+	}
+	if cfg.PingIntervalSeconds == 0 {
+		cfg.PingIntervalSeconds = 30
+	}
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	header := http.Header{}
+	for name, value := range cfg.Headers {
+		header.Set(name, value)
+	}
+	if cfg.Token != "" {
+		header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	w := &WebSocket{
+		cfg: cfg,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			NetDialContext:   dialContext,
+			TLSClientConfig:  tlsClientConfig,
+			HandshakeTimeout: timeout,
+		},
+		header:  header,
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+	go w.keepAlive(time.Duration(cfg.PingIntervalSeconds) * time.Second)
+	return w, nil
+}
+
+// connect returns the connection, dialing it unless the last dial failed less than the backoff ago
+func (w *WebSocket) connect(ctx context.Context) (*websocket.Conn, error) {
+	if w.conn != nil {
+		return w.conn, nil
+	}
+	if time.Now().Before(w.nextDial) {
+		return nil, errors.New("websocket: not connected, waiting to dial again")
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	conn, resp, err := w.dialer.DialContext(ctx, w.cfg.URL, w.header)
+	if err != nil {
+		if w.backoff == 0 {
+			w.backoff = websocketMinBackoff
+		} else if w.backoff < websocketMaxBackoff {
+			w.backoff *= 2
+		}
+		w.nextDial = time.Now().Add(w.backoff)
+		if resp != nil {
+			return nil, fmt.Errorf("websocket: handshake failed with status %d: %w", resp.StatusCode, err)
+		}
+		return nil, err
+	}
+	w.backoff = 0
+	w.conn = conn
+	log.Info().Str("url", w.cfg.URL).Msg("websocket: connected")
+	go w.read(conn)
+	return conn, nil
+}
+
+// read discards the messages of the server, it processes the control frames and notices the closed connection
+func (w *WebSocket) read(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			w.mu.Lock()
+			w.drop(conn)
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+// drop closes the connection if it is still the current one, the next send dials a new one
+func (w *WebSocket) drop(conn *websocket.Conn) {
+	conn.Close()
+	if w.conn == conn {
+		w.conn = nil
+	}
+}
+
+func (w *WebSocket) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.conn != nil {
+				if err := w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(w.timeout)); err != nil {
+					log.Warn().Err(err).Str("url", w.cfg.URL).Msg("websocket: ping failed")
+					w.drop(w.conn)
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *WebSocket) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	frame, err := serializeEventWithLayout(resolveLayout(ctx, w.cfg.Layout), ev)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// A connection the server closed while idle fails on the first write, the frame is sent again on a new one
+	for attempt := 0; attempt < 2; attempt++ {
+		conn, err := w.connect(ctx)
+		if err != nil {
+			return err
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(w.timeout))
+		if err = conn.WriteMessage(websocket.TextMessage, frame); err == nil {
+			return nil
+		}
+		w.drop(conn)
+		if attempt == 1 {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WebSocket) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.conn != nil {
+			_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			w.drop(w.conn)
+		}
+	})
+}
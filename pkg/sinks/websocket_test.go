@@ -0,0 +1,97 @@
+package sinks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestWebSocket_Send(t *testing.T) {
+	var mu sync.Mutex
+	var frames []string
+	var conns []*websocket.Conn
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer dashboard-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		mu.Lock()
+		conns = append(conns, conn)
+		mu.Unlock()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			frames = append(frames, string(data))
+			mu.Unlock()
+		}
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	s, err := NewWebSocketSink(&WebSocketConfig{
+		URL:    url,
+		Token:  "dashboard-token",
+		Layout: map[string]interface{}{"reason": "{{ .Reason }}"},
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "BackOff"
+	require.NoError(t, s.Send(context.Background(), ev))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(frames) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The server closes the connection, the next event is sent on a new one
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.conn == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	ev.Reason = "Pulled"
+	require.NoError(t, s.Send(context.Background(), ev))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(frames) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{`{"reason":"BackOff"}`, `{"reason":"Pulled"}`}, frames)
+	assert.Len(t, conns, 2)
+
+	// A failed handshake is not dialed again before the backoff
+	unauthorized, err := NewWebSocketSink(&WebSocketConfig{URL: url})
+	require.NoError(t, err)
+	defer unauthorized.Close()
+	assert.ErrorContains(t, unauthorized.Send(context.Background(), ev), "status 401")
+	assert.ErrorContains(t, unauthorized.Send(context.Background(), ev), "waiting to dial again")
+}
+
+func TestWebSocketConfig_Validate(t *testing.T) {
+	_, err := NewWebSocketSink(&WebSocketConfig{})
+	assert.Error(t, err)
+	_, err = NewWebSocketSink(&WebSocketConfig{URL: "https://dashboard.example.com/events"})
+	assert.Error(t, err)
+}
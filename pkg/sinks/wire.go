@@ -0,0 +1,65 @@
+package sinks
+
+import (
+	"fmt"
+	"slices"
+)
+
+// The wire format of the batches exchanged by agents and aggregators. Before sending, an agent asks the aggregator
+// for the schema versions and the compressions it supports, so agents and aggregators of different versions keep
+// working together during upgrades.
+const (
+	// WireSchemaVersion is the newest schema version, version 1 is a JSON array of events
+	WireSchemaVersion = 1
+	// WireSchemaHeader carries the schema version of a batch, batches without it use version 1
+	WireSchemaHeader = "X-Event-Exporter-Schema-Version"
+)
+
+// WireSchemaVersions are the schema versions this exporter reads and writes
+var WireSchemaVersions = []int{1}
+
+// WireCompressions are the compressions this exporter reads, in the order of preference
+var WireCompressions = []string{CompressionZstd, CompressionGzip, CompressionNone}
+
+// WireHandshake is the answer of an aggregator to the handshake of an agent
+type WireHandshake struct {
+	SchemaVersions []int    `json:"schemaVersions"`
+	Compressions   []string `json:"compressions"`
+	// Version is the version of the aggregator, for the logs of the agents
+	Version string `json:"version,omitempty"`
+}
+
+// legacyWireHandshake is what the aggregators without the handshake support
+var legacyWireHandshake = WireHandshake{
+	SchemaVersions: []int{1},
+	Compressions:   []string{CompressionZstd, CompressionGzip, CompressionNone},
+}
+
+// wireFormat is the schema version and the compression an agent uses for an aggregator
+type wireFormat struct {
+	schemaVersion int
+	compression   string
+}
+
+// negotiateWireFormat picks the newest common schema version and the preferred compression if the aggregator supports
+// it, otherwise the first one of WireCompressions it supports
+func negotiateWireFormat(h WireHandshake, preferredCompression string) (wireFormat, error) {
+	format := wireFormat{}
+	for _, v := range WireSchemaVersions {
+		if v > format.schemaVersion && slices.Contains(h.SchemaVersions, v) {
+			format.schemaVersion = v
+		}
+	}
+	if format.schemaVersion == 0 {
+		return format, fmt.Errorf("no common schema version, the aggregator supports %v and the agent %v", h.SchemaVersions, WireSchemaVersions)
+	}
+	for _, c := range append([]string{preferredCompression}, WireCompressions...) {
+		if slices.Contains(h.Compressions, c) {
+			format.compression = c
+			return format, nil
+		}
+	}
+	// Every aggregator reads uncompressed batches
+	format.compression = CompressionNone
+	return format, nil
+}
@@ -0,0 +1,72 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateWireFormat(t *testing.T) {
+	format, err := negotiateWireFormat(WireHandshake{SchemaVersions: []int{1, 2}, Compressions: []string{"gzip", "zstd"}}, CompressionZstd)
+	require.NoError(t, err)
+	assert.Equal(t, wireFormat{schemaVersion: 1, compression: CompressionZstd}, format)
+
+	// An unsupported compression falls back to the preferred one of the aggregator
+	format, err = negotiateWireFormat(WireHandshake{SchemaVersions: []int{1}, Compressions: []string{"br", "gzip"}}, CompressionZstd)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionGzip, format.compression)
+
+	_, err = negotiateWireFormat(WireHandshake{SchemaVersions: []int{3}}, CompressionZstd)
+	assert.Error(t, err)
+}
+
+func TestAggregator_Handshake(t *testing.T) {
+	var mu sync.Mutex
+	var handshakes int
+	var batches []http.Header
+	// The aggregator is upgraded after the first batch, then rejects the old format once
+	upgraded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			handshakes++
+			if !upgraded {
+				// Aggregators predating the handshake only accept POST
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(WireHandshake{SchemaVersions: []int{1}, Compressions: []string{"gzip"}})
+			return
+		}
+		if upgraded && r.Header.Get("Content-Encoding") != "gzip" {
+			http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+			return
+		}
+		batches = append(batches, r.Header)
+		upgraded = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	a, err := NewAggregatorSink(&AggregatorConfig{Endpoint: server.URL, Token: "token"})
+	require.NoError(t, err)
+	defer a.Close()
+
+	items := []interface{}{json.RawMessage(`{"reason":"BackOff"}`)}
+	assert.Equal(t, []bool{true}, a.write(context.Background(), items))
+	assert.Equal(t, []bool{false}, a.write(context.Background(), items))
+	assert.Equal(t, []bool{true}, a.write(context.Background(), items))
+
+	require.Len(t, batches, 2)
+	assert.Equal(t, 2, handshakes)
+	assert.Equal(t, "zstd", batches[0].Get("Content-Encoding"))
+	assert.Equal(t, "gzip", batches[1].Get("Content-Encoding"))
+	assert.Equal(t, "1", batches[1].Get(WireSchemaHeader))
+}
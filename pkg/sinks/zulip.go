@@ -0,0 +1,126 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+// ZulipConfig posts the events as stream messages with a bot. The stream, the topic and the content are templates, so
+// e.g. the events of every namespace land in their own topic.
+type ZulipConfig struct {
+	// Site is the URL of the Zulip organization, e.g. https://example.zulipchat.com
+	Site     string `yaml:"site"`
+	BotEmail string `yaml:"botEmail"`
+	APIKey   string `yaml:"apiKey"`
+	Stream   string `yaml:"stream"`
+	Topic    string `yaml:"topic"`
+	Content  string `yaml:"content"`
+	TLS      TLS    `yaml:"tls"`
+}
+
+type Zulip struct {
+	cfg    *ZulipConfig
+	client *http.Client
+}
+
+func NewZulipSink(cfg *ZulipConfig) (Sink, error) {
+	if cfg.Site == "" {
+		return nil, errors.New("zulip.site config option must be non-empty")
+	}
+	if cfg.BotEmail == "" || cfg.APIKey == "" {
+		return nil, errors.New("zulip.botEmail and zulip.apiKey config options must be non-empty")
+	}
+	if cfg.Stream == "" {
+		return nil, errors.New("zulip.stream config option must be non-empty")
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = "{{ .InvolvedObject.Namespace }}"
+	}
+	if cfg.Content == "" {
+		cfg.Content = "**{{ .Reason }}** on `{{ .InvolvedObject.Kind }}/{{ .InvolvedObject.Name }}`: {{ .Message }}"
+	}
+
+	tlsClientConfig, err := setupTLS(&cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup TLS: %w", err)
+	}
+
+	return &Zulip{
+		cfg:    cfg,
+		client: &http.Client{Transport: withRequestLogging(newHTTPTransport(tlsClientConfig))},
+	}, nil
+}
+
+func (z *Zulip) message(ev *kube.EnhancedEvent) (url.Values, error) {
+	stream, err := GetString(ev, z.cfg.Stream)
+	if err != nil {
+		return nil, err
+	}
+	topic, err := GetString(ev, z.cfg.Topic)
+	if err != nil {
+		return nil, err
+	}
+	content, err := GetString(ev, z.cfg.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zulip rejects an empty topic, events of cluster-scoped objects have no namespace
+	topic = strings.TrimSpace(topic)
+	if topic == "" {
+		topic = "(no topic)"
+	}
+	// The topic is shortened without a suffix, so the events with the same long topic still share it
+	if runes := []rune(topic); len(runes) > zulipMaxTopic {
+		topic = string(runes[:zulipMaxTopic])
+	}
+	if truncated := truncateBytes(content, zulipMaxContent); truncated != content {
+		content = truncated
+		countTruncatedPayload("zulip")
+	}
+
+	return url.Values{
+		"type":    {"stream"},
+		"to":      {stream},
+		"topic":   {topic},
+		"content": {content},
+	}, nil
+}
+
+func (z *Zulip) Send(ctx context.Context, ev *kube.EnhancedEvent) error {
+	msg, err := z.message(ev)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(z.cfg.Site, "/") + "/api/v1/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(msg.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(z.cfg.BotEmail, z.cfg.APIKey)
+
+	resp, err := z.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return httpResponseError(resp, body)
+}
+
+func (z *Zulip) Close() {
+	z.client.CloseIdleConnections()
+}
@@ -0,0 +1,78 @@
+package sinks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+)
+
+func TestZulip_Send(t *testing.T) {
+	var received url.Values
+	var user, password string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/messages", r.URL.Path)
+		user, password, _ = r.BasicAuth()
+		require.NoError(t, r.ParseForm())
+		received = r.PostForm
+		_, _ = w.Write([]byte(`{"result":"success","id":42}`))
+	}))
+	defer ts.Close()
+
+	sink, err := NewZulipSink(&ZulipConfig{
+		Site:     ts.URL + "/",
+		BotEmail: "exporter-bot@example.zulipchat.com",
+		APIKey:   "key",
+		Stream:   "kubernetes",
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.Reason = "BackOff"
+	ev.Message = "Back-off restarting failed container"
+	ev.InvolvedObject.ObjectReference = corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"}
+	require.NoError(t, sink.Send(context.Background(), ev))
+
+	assert.Equal(t, "exporter-bot@example.zulipchat.com", user)
+	assert.Equal(t, "key", password)
+	assert.Equal(t, "stream", received.Get("type"))
+	assert.Equal(t, "kubernetes", received.Get("to"))
+	assert.Equal(t, "prod", received.Get("topic"))
+	assert.Equal(t, "**BackOff** on `Pod/api-0`: Back-off restarting failed container", received.Get("content"))
+}
+
+func TestZulip_Topic(t *testing.T) {
+	sink, err := NewZulipSink(&ZulipConfig{
+		Site:     "http://localhost",
+		BotEmail: "bot@example.com",
+		APIKey:   "key",
+		Stream:   "{{ .InvolvedObject.Kind }}",
+		Topic:    "{{ .InvolvedObject.Namespace }}{{ .Message }}",
+	})
+	require.NoError(t, err)
+
+	ev := &kube.EnhancedEvent{}
+	ev.InvolvedObject.Kind = "Node"
+	msg, err := sink.(*Zulip).message(ev)
+	require.NoError(t, err)
+	assert.Equal(t, "Node", msg.Get("to"))
+	assert.Equal(t, "(no topic)", msg.Get("topic"))
+
+	ev.Message = strings.Repeat("x", 100)
+	msg, err = sink.(*Zulip).message(ev)
+	require.NoError(t, err)
+	assert.Len(t, msg.Get("topic"), zulipMaxTopic)
+}
+
+func TestZulip_RequiresStream(t *testing.T) {
+	_, err := NewZulipSink(&ZulipConfig{Site: "http://localhost", BotEmail: "bot@example.com", APIKey: "key"})
+	assert.EqualError(t, err, "zulip.stream config option must be non-empty")
+}
@@ -0,0 +1,122 @@
+package testing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+)
+
+// FakeClock is a clock that only moves when advanced, the scheduled functions run in the goroutine advancing it
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, f: f, at: c.now.Add(d), active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock and runs the functions that became due, in the order of their time
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if t.active && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			c.now = end
+			c.prune()
+			c.mu.Unlock()
+			return
+		}
+		// The function sees the time it was scheduled for
+		c.now = next.at
+		next.active = false
+		c.mu.Unlock()
+		next.f()
+	}
+}
+
+// Set moves the clock to the time, which must not be before the current one
+func (c *FakeClock) Set(now time.Time) {
+	c.Advance(now.Sub(c.Now()))
+}
+
+// Pending returns the times of the scheduled functions
+func (c *FakeClock) Pending() []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var pending []time.Time
+	for _, t := range c.timers {
+		if t.active {
+			pending = append(pending, t.at)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Before(pending[j]) })
+	return pending
+}
+
+// prune drops the timers that cannot fire anymore, it must be called with the lock held
+func (c *FakeClock) prune() {
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.active {
+			active = append(active, t)
+		}
+	}
+	c.timers = active
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	f      func()
+	at     time.Time
+	active bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.at = t.clock.now.Add(d)
+	t.active = true
+	for _, other := range t.clock.timers {
+		if other == t {
+			return wasActive
+		}
+	}
+	// The timer was pruned after it fired or was stopped
+	t.clock.timers = append(t.clock.timers, t)
+	return wasActive
+}
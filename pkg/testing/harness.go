@@ -0,0 +1,221 @@
+// Package testing runs the engine of a config against fake events, so a config can be tested end to end, from the
+// events of a fake apiserver through the route to recording sinks that replace the receivers, with a controlled clock.
+//
+// The receivers keep their layouts and template settings, but not their sinks, receiver factories and Slack commands
+// are not started.
+package testing
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/clock"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/exporter"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/kube"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/setup"
+	"github.com/giantswarm/kubernetes-event-exporter/v2/pkg/sinks"
+)
+
+// Harness is an engine whose receivers record the events they get. It changes the clock and the state store of the
+// process until it is closed, so harnesses must not run in parallel.
+type Harness struct {
+	Config exporter.Config
+	Engine *exporter.Engine
+	Clock  *FakeClock
+
+	mu        sync.Mutex
+	seq       int
+	objects   map[string]*object
+	recorders map[string]*sinks.InMemory
+
+	restoreClock func()
+	stateStore   sinks.StateStore
+}
+
+// object is an object of the fake apiserver, the events it is involved in get its metadata
+type object struct {
+	meta    metav1.Object
+	deleted bool
+}
+
+// NewHarnessFromFile reads the config file like the exporter, with the environment variables expanded
+func NewHarnessFromFile(path string) (*Harness, error) {
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewHarness([]byte(os.ExpandEnv(string(configBytes))))
+}
+
+// NewHarness parses and validates the config and starts its engine, the clock starts at the current time
+func NewHarness(configBytes []byte) (*Harness, error) {
+	cfg, err := setup.ParseConfigFromBytes(configBytes)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	h := &Harness{
+		Clock:      NewFakeClock(time.Now().UTC().Truncate(time.Second)),
+		objects:    make(map[string]*object),
+		recorders:  make(map[string]*sinks.InMemory),
+		stateStore: sinks.GetStateStore(),
+	}
+	h.restoreClock = clock.Set(h.Clock)
+	sinks.SetStateStore(sinks.NewInMemoryStateStore())
+
+	// The sinks are replaced by in-memory ones, the settings of the receivers that change the events are kept
+	for i, r := range cfg.Receivers {
+		cfg.Receivers[i] = sinks.ReceiverConfig{
+			Name:         r.Name,
+			Layouts:      r.Layouts,
+			LayoutPreset: r.LayoutPreset,
+			Template:     r.Template,
+			InMemory:     &sinks.InMemoryConfig{},
+		}
+	}
+	cfg.ReceiverFactories = nil
+	cfg.SlackCommands = nil
+	h.Config = cfg
+	h.Engine = exporter.NewEngine(&h.Config, &exporter.SyncRegistry{})
+	// The in-memory sinks are created with the engine
+	for _, r := range h.Config.Receivers {
+		h.recorders[r.Name] = r.InMemory.Ref
+	}
+	return h, nil
+}
+
+// Close stops the engine and restores the clock and the state store
+func (h *Harness) Close() {
+	h.Engine.Stop()
+	h.restoreClock()
+	sinks.SetStateStore(h.stateStore)
+}
+
+func objectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// AddObject adds an object of the kind to the fake apiserver, the events it is involved in get its labels,
+// annotations and owner references
+func (h *Harness) AddObject(kind string, obj metav1.Object) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.objects[objectKey(kind, obj.GetNamespace(), obj.GetName())] = &object{meta: obj}
+}
+
+// DeleteObject marks the object as deleted, the events it is involved in keep its metadata like the cache of the
+// exporter does
+func (h *Harness) DeleteObject(kind, namespace, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if obj, ok := h.objects[objectKey(kind, namespace, name)]; ok {
+		obj.deleted = true
+	}
+}
+
+// Emit sends the event like the watcher of the exporter. The missing UID, type, count and timestamps are filled in,
+// the timestamps with the time of the clock. It returns false if the event was discarded before the engine, because
+// it is too old or not watched.
+func (h *Harness) Emit(event *corev1.Event) bool {
+	ev := event.DeepCopy()
+	now := h.Clock.Now()
+
+	h.mu.Lock()
+	h.seq++
+	if ev.UID == "" {
+		ev.UID = types.UID(fmt.Sprintf("event-%d", h.seq))
+	}
+	if ev.Name == "" {
+		ev.Name = fmt.Sprintf("%s.%d", ev.InvolvedObject.Name, h.seq)
+	}
+	obj := h.objects[objectKey(ev.InvolvedObject.Kind, ev.InvolvedObject.Namespace, ev.InvolvedObject.Name)]
+	h.mu.Unlock()
+
+	if ev.Namespace == "" {
+		ev.Namespace = ev.InvolvedObject.Namespace
+	}
+	if ev.Type == "" {
+		ev.Type = corev1.EventTypeNormal
+	}
+	if ev.Count == 0 {
+		ev.Count = 1
+	}
+	if ev.LastTimestamp.IsZero() && ev.EventTime.IsZero() {
+		ev.LastTimestamp = metav1.NewTime(now)
+	}
+	if ev.FirstTimestamp.IsZero() {
+		ev.FirstTimestamp = ev.LastTimestamp
+	}
+
+	if !h.watched(ev, now) {
+		return false
+	}
+
+	enhanced := &kube.EnhancedEvent{Event: *ev, ClusterName: h.Config.ClusterName}
+	enhanced.InvolvedObject.ObjectReference = ev.InvolvedObject
+	if obj != nil {
+		enhanced.InvolvedObject.Labels = obj.meta.GetLabels()
+		enhanced.InvolvedObject.Annotations = obj.meta.GetAnnotations()
+		enhanced.InvolvedObject.OwnerReferences = obj.meta.GetOwnerReferences()
+		enhanced.InvolvedObject.Deleted = obj.deleted
+	}
+	h.Engine.OnEvent(enhanced)
+	return true
+}
+
+// watched applies the namespace, the reasons and the maximum age of the config like the watcher
+func (h *Harness) watched(ev *corev1.Event, now time.Time) bool {
+	if h.Config.Namespace != "" && ev.Namespace != h.Config.Namespace {
+		return false
+	}
+	if len(h.Config.WatchReasons) > 0 {
+		watched := false
+		for _, reason := range h.Config.WatchReasons {
+			watched = watched || reason == ev.Reason
+		}
+		if !watched {
+			return false
+		}
+	}
+	timestamp := ev.LastTimestamp.Time
+	if timestamp.IsZero() {
+		timestamp = ev.EventTime.Time
+	}
+	return now.Sub(timestamp) <= time.Duration(h.Config.MaxEventAgeSeconds)*time.Second
+}
+
+// Received returns the events the receiver got, in order
+func (h *Harness) Received(receiver string) []*kube.EnhancedEvent {
+	recorder, ok := h.recorders[receiver]
+	if !ok {
+		return nil
+	}
+	return append([]*kube.EnhancedEvent(nil), recorder.Events...)
+}
+
+// Reset forgets the events the receivers got
+func (h *Harness) Reset() {
+	for _, recorder := range h.recorders {
+		recorder.Events = nil
+	}
+}
+
+// NewEvent returns a Normal event about the object, the type and the other fields can be changed before it is emitted
+func NewEvent(kind, namespace, name, reason, message string) *corev1.Event {
+	return &corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Namespace: namespace, Name: name},
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+	}
+}
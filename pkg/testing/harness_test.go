@@ -0,0 +1,97 @@
+package testing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	exportertesting "github.com/giantswarm/kubernetes-event-exporter/v2/pkg/testing"
+)
+
+const harnessConfig = `
+clusterName: prod-eu
+maxEventAgeSeconds: 60
+route:
+  routes:
+    - match:
+        - type: Warning
+          labels:
+            team: payments
+          receiver: payments
+    - match:
+        - receiver: everything
+watchdogs:
+  - name: backoff
+    match:
+      - reason: BackOff
+    quiet: 10m
+    receivers: ["payments"]
+receivers:
+  - name: payments
+    slack:
+      token: "xoxb-not-used"
+      channel: "#payments"
+      message: "{{ .Message }}"
+  - name: everything
+    webhook:
+      endpoint: "https://example.com/not-used"
+`
+
+func TestHarness(t *testing.T) {
+	h, err := exportertesting.NewHarness([]byte(harnessConfig))
+	require.NoError(t, err)
+	defer h.Close()
+
+	h.AddObject("Pod", &metav1.ObjectMeta{Namespace: "shop", Name: "checkout-0", Labels: map[string]string{"team": "payments"}})
+
+	ev := exportertesting.NewEvent("Pod", "shop", "checkout-0", "BackOff", "Back-off restarting failed container")
+	ev.Type = "Warning"
+	require.True(t, h.Emit(ev))
+	h.Clock.Advance(time.Minute)
+	require.True(t, h.Emit(ev))
+
+	received := h.Received("payments")
+	require.Len(t, received, 2)
+	assert.Equal(t, "prod-eu", received[0].ClusterName)
+	assert.Equal(t, "payments", received[0].InvolvedObject.Labels["team"])
+	assert.Len(t, h.Received("everything"), 2)
+
+	// The watchdog notices the quiet period on the controlled clock
+	h.Reset()
+	h.Clock.Advance(10 * time.Minute)
+	received = h.Received("payments")
+	require.Len(t, received, 1)
+	assert.Equal(t, "BackOffCleared", received[0].Reason)
+	assert.Empty(t, h.Received("everything"))
+
+	// Events older than maxEventAgeSeconds are discarded like by the watcher
+	old := exportertesting.NewEvent("Pod", "shop", "checkout-1", "Started", "Started container")
+	old.LastTimestamp = metav1.NewTime(h.Clock.Now().Add(-2 * time.Minute))
+	assert.False(t, h.Emit(old))
+}
+
+func TestHarness_InvalidConfig(t *testing.T) {
+	_, err := exportertesting.NewHarness([]byte("route: ["))
+	assert.Error(t, err)
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := exportertesting.NewFakeClock(start)
+
+	var fired []time.Time
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, c.Now()) })
+	stopped := c.AfterFunc(time.Second, func() { t.Error("a stopped timer fired") })
+	reset := c.AfterFunc(time.Second, func() { fired = append(fired, c.Now()) })
+	assert.True(t, stopped.Stop())
+	reset.Reset(3 * time.Second)
+	assert.Len(t, c.Pending(), 2)
+
+	c.Advance(5 * time.Second)
+	assert.Equal(t, []time.Time{start.Add(2 * time.Second), start.Add(3 * time.Second)}, fired)
+	assert.Equal(t, start.Add(5*time.Second), c.Now())
+	assert.Empty(t, c.Pending())
+}